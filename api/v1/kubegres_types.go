@@ -33,15 +33,250 @@ type KubegresDatabase struct {
 	StorageClassName *string `json:"storageClassName,omitempty"`
 }
 
+const (
+	BackUpTypeLogical  = "logical"
+	BackUpTypePhysical = "physical"
+)
+
 type KubegresBackUp struct {
 	Schedule    string `json:"schedule,omitempty"`
 	VolumeMount string `json:"volumeMount,omitempty"`
 	PvcName     string `json:"pvcName,omitempty"`
+
+	// Type is either "logical", to dump the databases with pg_dumpall, or "physical", to take a compressed tar of
+	// the data directory with pg_basebackup from a Replica. A physical backup is restored by unpacking it in the
+	// data directory of the new Primary, which is faster for large databases. Default: "physical" if
+	// 'walArchive.enabled' is true, otherwise "logical".
+	// +kubebuilder:validation:Enum=logical;physical
+	Type string `json:"type,omitempty"`
+
+	Destination KubegresBackUpDestination `json:"destination,omitempty"`
+
+	// Retention removes the old backups from the PVC 'pvcName' or from the bucket 'destination.s3', once a backup is
+	// taken. If not set, the backups are never removed. The backups taken by a KubegresBackup are not removed.
+	// If 'walArchive.enabled' is true, the archived WAL segments which are older than the oldest retained base backup
+	// are removed too, so a point-in-time recovery is only possible from a retained base backup.
+	Retention KubegresBackUpRetention `json:"retention,omitempty"`
+
+	// MaxHoursWithoutSuccess is the number of hours after which a Warning event is emitted if no backup has
+	// succeeded. If not set, only the failures of the backups are reported with Warning events.
+	// +kubebuilder:validation:Minimum=1
+	MaxHoursWithoutSuccess int32 `json:"maxHoursWithoutSuccess,omitempty"`
+
+	// WalArchive continuously archives the WAL segments of the Primary, so that a KubegresRestore can recover the
+	// database up to a point in time (see the field 'recoveryTarget' of KubegresRestore).
+	WalArchive KubegresWalArchive `json:"walArchive,omitempty"`
+}
+
+type KubegresWalArchive struct {
+	// Enabled sets 'archive_command' in PostgreSql. WAL segments are archived in the PVC 'backup.pvcName' in the folder
+	// "<backup.volumeMount>/<Kubegres name>-wal", or in an S3-compatible bucket if 's3' is set. When enabled, the
+	// field 'type' must be "physical", since the archived WAL segments can only be replayed on top of a base backup.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ArchiveTimeout is the maximum number of seconds before the current WAL segment is archived, even if it is not
+	// full. It bounds the amount of data which can be lost. Default: 60.
+	// +kubebuilder:validation:Minimum=1
+	ArchiveTimeout int32 `json:"archiveTimeout,omitempty"`
+
+	S3 *KubegresS3 `json:"s3,omitempty"`
+}
+
+type KubegresS3 struct {
+	// Endpoint is the URL of an S3-compatible endpoint, for example "http://minio.default.svc:9000".
+	// If not set, the endpoint of AWS S3 is used.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Bucket string `json:"bucket,omitempty"`
+
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecret is the name of a Secret with the keys "AWS_ACCESS_KEY_ID" and "AWS_SECRET_ACCESS_KEY".
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Image of the container transferring the files to and from S3. It must contain the AWS CLI.
+	Image string `json:"image,omitempty"`
+
+	Tls KubegresS3Tls `json:"tls,omitempty"`
+}
+
+type KubegresS3Tls struct {
+	// CaSecret is the name of a Secret with the key "ca.crt" containing the CA bundle used to verify the certificate
+	// of 'endpoint'. If not set, the CA bundle of the image is used.
+	CaSecret string `json:"caSecret,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the certificate of 'endpoint'. It should only be used for tests.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+type KubegresBackUpDestination struct {
+	// S3 uploads the backups in an S3-compatible bucket, under 'prefix'. When set, the fields 'pvcName' and
+	// 'volumeMount' of 'backup' are not required and the backups are only kept in the bucket.
+	S3 *KubegresS3 `json:"s3,omitempty"`
+}
+
+type KubegresBackUpRetention struct {
+	// KeepLast is the number of most recent backups to retain.
+	// +kubebuilder:validation:Minimum=0
+	KeepLast int32 `json:"keepLast,omitempty"`
+
+	// KeepDaily is the number of most recent days for which the last backup of the day is retained.
+	// +kubebuilder:validation:Minimum=0
+	KeepDaily int32 `json:"keepDaily,omitempty"`
+
+	// KeepWeekly is the number of most recent weeks for which the last backup of the week is retained.
+	// +kubebuilder:validation:Minimum=0
+	KeepWeekly int32 `json:"keepWeekly,omitempty"`
+
+	// KeepMonthly is the number of most recent months for which the last backup of the month is retained.
+	// +kubebuilder:validation:Minimum=0
+	KeepMonthly int32 `json:"keepMonthly,omitempty"`
+
+	// MaxAgeDays removes the backups older than the given number of days, even if they are retained by one of the
+	// fields 'keep*'. If none of the fields 'keep*' is set, all the backups younger than the given number of days are
+	// retained. The most recent backup is never removed.
+	// +kubebuilder:validation:Minimum=0
+	MaxAgeDays int32 `json:"maxAgeDays,omitempty"`
 }
 
 type KubegresFailover struct {
 	IsDisabled bool   `json:"isDisabled,omitempty"`
 	PromotePod string `json:"promotePod,omitempty"`
+
+	// MaxLagBytes is the maximum number of bytes of WAL that a Replica can be behind the last known location of the
+	// Primary to be promoted during an automatic failover. If not set, a Replica is promoted regardless of its lag.
+	// +kubebuilder:validation:Minimum=0
+	MaxLagBytes *int64 `json:"maxLagBytes,omitempty"`
+
+	// PromoteWhenLagUnknown allows an automatic failover when 'maxLagBytes' is set but the lag of the Replicas cannot
+	// be checked, because their WAL locations or the last WAL location of the Primary are unknown. By default, the
+	// failover does not happen in that case and the Primary has to be fixed manually.
+	PromoteWhenLagUnknown bool `json:"promoteWhenLagUnknown,omitempty"`
+
+	// Pvc is the policy applied to the PVC of a Primary or a Replica whose StatefulSet is removed by Kubegres:
+	// "retain" (default) keeps the PVC, "delete" deletes it and "reuse" attaches it to the next Replica to deploy,
+	// which rewinds its data with pg_rewind against the Primary or, if that fails, replaces it by a copy of the Primary.
+	// +kubebuilder:validation:Enum=retain;delete;reuse
+	Pvc string `json:"pvc,omitempty"`
+
+	// RewindFailedPrimary enables rewinding the data of a failed Primary with pg_rewind against the new Primary, so
+	// that the failed Primary rejoins the cluster as a Replica instead of deploying a new Replica with a full copy of
+	// the database. The PVC of a failed Primary is then retained, whatever the value of the field 'pvc'.
+	RewindFailedPrimary bool `json:"rewindFailedPrimary,omitempty"`
+}
+
+const (
+	FailoverPvcRetain = "retain"
+	FailoverPvcDelete = "delete"
+	FailoverPvcReuse  = "reuse"
+)
+
+type KubegresSwitchover struct {
+	// TargetPod is the name of a ready Replica Pod to promote as Primary during a planned switchover. Writes are
+	// fenced on the current Primary until the Replica catches up, then the Primary is stopped. The Replica is only
+	// promoted once it has replayed the shutdown checkpoint of the Primary, so that no data is lost. The current
+	// Primary then re-attaches as a Replica. Kubegres resets this field once the switchover is completed or aborted.
+	TargetPod string `json:"targetPod,omitempty"`
+}
+
+const (
+	ReplicationModeAsync  = "async"
+	ReplicationModeSync   = "sync"
+	ReplicationModeQuorum = "quorum"
+)
+
+type KubegresReplication struct {
+	// Mode is either "async" (default), "sync" to wait for the first ready Replicas, in order, to confirm each
+	// transaction or "quorum" to wait for any of the ready Replicas to confirm each transaction.
+	// +kubebuilder:validation:Enum=async;sync;quorum
+	Mode string `json:"mode,omitempty"`
+
+	// NumSyncStandbys is the number of Replicas which must confirm each transaction when the mode is "sync" or
+	// "quorum". It defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	NumSyncStandbys *int32 `json:"numSyncStandbys,omitempty"`
+}
+
+const (
+	PoolerPoolModeSession     = "session"
+	PoolerPoolModeTransaction = "transaction"
+	PoolerPoolModeStatement   = "statement"
+)
+
+type KubegresPooler struct {
+	// Enabled deploys a PgBouncer Deployment and Service in front of the Primary. They are named after the Kubegres
+	// resource with the suffix "-pooler" and they follow the Primary after a failover. PgBouncer looks up the passwords
+	// of the users with the user "kubegres_pooler", which is created by Kubegres with the password set in the env-var
+	// "POSTGRES_POOLER_PASSWORD". This env-var is required when the pooler is enabled. Superusers cannot connect
+	// through PgBouncer.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// EnabledForReplicas also deploys a PgBouncer Deployment and Service in front of the Replicas, named after the
+	// Kubegres resource with the suffix "-pooler-replica".
+	EnabledForReplicas bool `json:"enabledForReplicas,omitempty"`
+
+	// Image is the image of PgBouncer. It must be based on PgBouncer 1.20 or later, since the passwords are looked up
+	// in the database "postgres" whatever the database the users connect to.
+	Image string `json:"image,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	Port int32 `json:"port,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// +kubebuilder:validation:Enum=session;transaction;statement
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	MaxClientConn int32 `json:"maxClientConn,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	DefaultPoolSize int32 `json:"defaultPoolSize,omitempty"`
+
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type KubegresService struct {
+	// Type is the type of the Service. If not set, the Service is headless. "ClusterIP" allocates a virtual IP to
+	// the Service. Switching between a headless Service and another type re-creates the Service.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type v1.ServiceType `json:"type,omitempty"`
+
+	// Annotations are added to the Service. The annotations set by other controllers are kept.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Labels are added to the Service. The labels "app" and "replicationRole" are set by Kubegres and cannot be
+	// overridden.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// LoadBalancerSourceRanges restricts the client IPs allowed to access a Service of type "LoadBalancer".
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+
+	// ExternalTrafficPolicy can only be set for a Service of type "NodePort" or "LoadBalancer".
+	// +kubebuilder:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy v1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+}
+
+type KubegresReplicaReadyService struct {
+	// Enabled deploys a Service named after the Kubegres resource with the suffix "-replica-ready". Unlike the
+	// Service of the Replicas, it only selects the ready Replicas whose replication lag is known and below
+	// 'maxLagBytes', so that readers do not query stale data.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MaxLagBytes is the maximum number of bytes of WAL that a Replica can be behind the Primary to be selected by
+	// the Service. It defaults to 16MB, the size of a WAL segment.
+	// +kubebuilder:validation:Minimum=0
+	MaxLagBytes *int64 `json:"maxLagBytes,omitempty"`
+
+	// IncludePrimaryWhenNoReplicaReady selects the Primary when none of the Replicas is below 'maxLagBytes'.
+	IncludePrimaryWhenNoReplicaReady bool `json:"includePrimaryWhenNoReplicaReady,omitempty"`
+}
+
+type KubegresServices struct {
+	Primary      KubegresService             `json:"primary,omitempty"`
+	Replica      KubegresService             `json:"replica,omitempty"`
+	ReplicaReady KubegresReplicaReadyService `json:"replicaReady,omitempty"`
 }
 
 type KubegresScheduler struct {
@@ -73,13 +308,35 @@ type KubegresSpec struct {
 	CustomConfig     string                    `json:"customConfig,omitempty"`
 	Database         KubegresDatabase          `json:"database,omitempty"`
 	Failover         KubegresFailover          `json:"failover,omitempty"`
+	Switchover       KubegresSwitchover        `json:"switchover,omitempty"`
+	Replication      KubegresReplication       `json:"replication,omitempty"`
 	Backup           KubegresBackUp            `json:"backup,omitempty"`
+	Pooler           KubegresPooler            `json:"pooler,omitempty"`
+	Services         KubegresServices          `json:"services,omitempty"`
 	Env              []v1.EnvVar               `json:"env,omitempty"`
 	Scheduler        KubegresScheduler         `json:"scheduler,omitempty"`
 	Resources        v1.ResourceRequirements   `json:"resources,omitempty"`
 	Volume           Volume                    `json:"volume,omitempty"`
 	SecurityContext  *v1.PodSecurityContext    `json:"securityContext,omitempty"`
 	Probe            Probe                     `json:"probe,omitempty"`
+
+	// Sidecars are containers running next to the PostgreSql container in the Pods of the Primary and the Replicas,
+	// e.g. postgres_exporter or a log shipper. Their schema is not validated to keep the size of the CRD small.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+
+	// InitContainers are containers running before the PostgreSql container in the Pods of the Primary and the
+	// Replicas. In the Pods of the Replicas, they run after the init container copying the database of the Primary.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+
+	// PostgresMajorVersion is the major version of PostgreSql of the image. If not set, it is parsed from the tag of
+	// the image (e.g. 16 for "postgres:16.2"). When it changes, Kubegres upgrades the Primary with pg_upgrade and
+	// re-seeds the Replicas from the upgraded Primary.
+	// +kubebuilder:validation:Minimum=10
+	PostgresMajorVersion *int32 `json:"postgresMajorVersion,omitempty"`
 }
 
 // ----------------------- STATUS -----------------------------------------
@@ -104,17 +361,105 @@ type KubegresBlockingOperation struct {
 	StatefulSetSpecUpdateOperation KubegresStatefulSetSpecUpdateOperation `json:"statefulSetSpecUpdateOperation,omitempty"`
 }
 
+type KubegresInstanceStatus struct {
+	InstanceIndex int32  `json:"instanceIndex,omitempty"`
+	StatefulSet   string `json:"statefulSet,omitempty"`
+	PodName       string `json:"podName,omitempty"`
+	Role          string `json:"role,omitempty"`
+	IsReady       bool   `json:"isReady"`
+	IsStuck       bool   `json:"isStuck"`
+	NodeName      string `json:"nodeName,omitempty"`
+	Image         string `json:"image,omitempty"`
+	PvcName       string `json:"pvcName,omitempty"`
+}
+
+const (
+	ConditionTypeReady              = "Ready"
+	ConditionTypePrimaryAvailable   = "PrimaryAvailable"
+	ConditionTypeReplicasInSync     = "ReplicasInSync"
+	ConditionTypeBackupConfigured   = "BackupConfigured"
+	ConditionTypeSpecValid          = "SpecValid"
+	ConditionTypeFailoverInProgress = "FailoverInProgress"
+
+	PhaseCreating    = "Creating"
+	PhaseRunning     = "Running"
+	PhaseDegraded    = "Degraded"
+	PhaseUpdating    = "Updating"
+	PhaseFailingOver = "FailingOver"
+	PhaseFailed      = "Failed"
+)
+
+const (
+	MajorVersionUpgradePhaseRunning   = "Running"
+	MajorVersionUpgradePhaseSucceeded = "Succeeded"
+	MajorVersionUpgradePhaseFailed    = "Failed"
+)
+
+type KubegresMajorVersionUpgrade struct {
+	Phase            string `json:"phase,omitempty"`
+	FromMajorVersion int32  `json:"fromMajorVersion,omitempty"`
+	ToMajorVersion   int32  `json:"toMajorVersion,omitempty"`
+	FromImage        string `json:"fromImage,omitempty"`
+	ToImage          string `json:"toImage,omitempty"`
+
+	// RollbackDataDirectory is the directory, on the PVC of the Primary, where the data folder of the former major
+	// version is moved after a successful upgrade. Since pg_upgrade runs with '--link', it shares its data files with
+	// the upgraded data, so it cannot be used to roll back once the upgraded Primary has started. It can be removed
+	// manually once the upgrade is checked.
+	RollbackDataDirectory string `json:"rollbackDataDirectory,omitempty"`
+}
+
+type KubegresBackUpJobStatus struct {
+	JobName         string       `json:"jobName,omitempty"`
+	StartTime       *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime  *metav1.Time `json:"completionTime,omitempty"`
+	DurationSeconds int64        `json:"durationSeconds,omitempty"`
+
+	// FileName and SizeBytes are the name and the size of the backup file, as reported by the backup script.
+	FileName  string `json:"fileName,omitempty"`
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+}
+
+type KubegresBackUpStatus struct {
+	LastSuccessfulBackUp *KubegresBackUpJobStatus `json:"lastSuccessfulBackUp,omitempty"`
+	LastFailedBackUp     *KubegresBackUpJobStatus `json:"lastFailedBackUp,omitempty"`
+
+	// RetainedBackUps are the names of the backups retained by the field 'backup.retention', from the most recent to
+	// the oldest, as of the last run of the backup CronJob.
+	RetainedBackUps []string `json:"retainedBackUps,omitempty"`
+
+	LastPruneTime *metav1.Time `json:"lastPruneTime,omitempty"`
+
+	// OverdueSince is the time from which no backup has succeeded during the number of hours set in the field
+	// 'backup.maxHoursWithoutSuccess'. A Warning event is emitted once when it is set, and it is cleared once a
+	// backup succeeds.
+	OverdueSince *metav1.Time `json:"overdueSince,omitempty"`
+}
+
 type KubegresStatus struct {
-	LastCreatedInstanceIndex  int32                     `json:"lastCreatedInstanceIndex,omitempty"`
-	BlockingOperation         KubegresBlockingOperation `json:"blockingOperation,omitempty"`
-	PreviousBlockingOperation KubegresBlockingOperation `json:"previousBlockingOperation,omitempty"`
-	EnforcedReplicas          int32                     `json:"enforcedReplicas,omitempty"`
+	LastCreatedInstanceIndex  int32                       `json:"lastCreatedInstanceIndex,omitempty"`
+	BlockingOperation         KubegresBlockingOperation   `json:"blockingOperation,omitempty"`
+	PreviousBlockingOperation KubegresBlockingOperation   `json:"previousBlockingOperation,omitempty"`
+	EnforcedReplicas          int32                       `json:"enforcedReplicas,omitempty"`
+	Phase                     string                      `json:"phase,omitempty"`
+	Instances                 []KubegresInstanceStatus    `json:"instances,omitempty"`
+	PostgresMajorVersion      int32                       `json:"postgresMajorVersion,omitempty"`
+	MajorVersionUpgrade       KubegresMajorVersionUpgrade `json:"majorVersionUpgrade,omitempty"`
+	BackUp                    KubegresBackUpStatus        `json:"backup,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // ----------------------- RESOURCE ---------------------------------------
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Kubegres is the Schema for the kubegres API
 type Kubegres struct {
@@ -0,0 +1,86 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ----------------------- SPEC -------------------------------------------
+
+type KubegresBackupSpec struct {
+	// ClusterName is the name of the Kubegres resource to back up, in the same namespace. The backup is taken once,
+	// with the same script and destination as the backup CronJob of that Kubegres resource. Its field
+	// 'backup.schedule' must be set.
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+// ----------------------- STATUS -----------------------------------------
+
+const (
+	KubegresBackupPhasePending   = "Pending"
+	KubegresBackupPhaseRunning   = "Running"
+	KubegresBackupPhaseSucceeded = "Succeeded"
+	KubegresBackupPhaseFailed    = "Failed"
+)
+
+type KubegresBackupStatus struct {
+	Phase          string       `json:"phase,omitempty"`
+	JobName        string       `json:"jobName,omitempty"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// FileName and SizeBytes are the name and the size of the backup file, as reported by the backup script.
+	FileName  string `json:"fileName,omitempty"`
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+
+	// File is set once the backup has succeeded if it is stored in a PVC, and ObjectStore if it is stored in an S3
+	// bucket. A KubegresRestore restores this backup by setting the name of this resource in 'dataSource.backup'.
+	File        *File        `json:"file,omitempty"`
+	ObjectStore *ObjectStore `json:"objectStore,omitempty"`
+}
+
+// ----------------------- RESOURCE ---------------------------------------
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="File",type="string",JSONPath=".status.fileName"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// KubegresBackup is the Schema for the kubegresbackups API
+type KubegresBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KubegresBackupSpec   `json:"spec,omitempty"`
+	Status KubegresBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KubegresBackupList contains a list of KubegresBackup
+type KubegresBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KubegresBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KubegresBackup{}, &KubegresBackupList{})
+}
@@ -29,6 +29,15 @@ type File struct {
 	Snapshot  string `json:"snapshot,omitempty"`
 }
 
+type ObjectStore struct {
+	// S3 bucket containing the backup, with 'prefix' set to the location of the backups. For example, the field
+	// 'backup.destination.s3' of the Kubegres resource which took the backup.
+	S3 *KubegresS3 `json:"s3,omitempty"`
+
+	// Snapshot is the name of the backup file, relative to the prefix of 's3'.
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
 type Cluster struct {
 	// Name of cluster to duplicate
 	ClusterName string `json:"clusterName,omitempty"`
@@ -37,9 +46,112 @@ type Cluster struct {
 	ClusterSpec KubegresSpec `json:"clusterSpec,omitempty"`
 }
 
+type WalArchive struct {
+	// Folder, relative to 'file.mountPath', containing the archived WAL segments in the PVC 'file.pvcName'.
+	// Default: "<cluster.clusterName>-wal".
+	Folder string `json:"folder,omitempty"`
+
+	// S3 bucket containing the archived WAL segments, with 'prefix' set to the location of the segments.
+	// Default: the field 'backup.walArchive.s3' of the Kubegres resource 'cluster.clusterName', with the prefix
+	// "<prefix>/<cluster.clusterName>/wal".
+	S3 *KubegresS3 `json:"s3,omitempty"`
+}
+
 type DataSource struct {
-	File    File    `json:"file,omitempty"`
-	Cluster Cluster `json:"cluster,omitempty"`
+	File File `json:"file,omitempty"`
+
+	// ObjectStore restores a backup from an S3-compatible bucket instead of a PVC. Only one of 'file' and
+	// 'objectStore' can be set.
+	ObjectStore ObjectStore `json:"objectStore,omitempty"`
+
+	// Backup is the name of a KubegresBackup, in the same namespace, which has succeeded. When set, the backup is
+	// restored from the location in the status of the KubegresBackup and the fields 'file' and 'objectStore' are
+	// ignored. If 'cluster' is not set, the Kubegres resource which was backed up is used.
+	Backup string `json:"backup,omitempty"`
+
+	Cluster    Cluster    `json:"cluster,omitempty"`
+	WalArchive WalArchive `json:"walArchive,omitempty"`
+}
+
+// RecoveryTarget is the point up to which the archived WAL segments are replayed on top of a base backup.
+// Only one of 'time', 'lsn' or 'name' can be set.
+type RecoveryTarget struct {
+	// Time is a timestamp with time zone, for example "2023-04-05 14:30:00+00".
+	Time string `json:"time,omitempty"`
+
+	// Lsn is a WAL location, for example "0/3000060".
+	Lsn string `json:"lsn,omitempty"`
+
+	// Name is a restore point created with pg_create_restore_point().
+	Name string `json:"name,omitempty"`
+
+	// Inclusive sets whether the recovery stops just after (true) or just before (false) the target. Default: true.
+	Inclusive *bool `json:"inclusive,omitempty"`
+}
+
+// RestoreRetryPolicy reruns a failed restore job against a clean database.
+type RestoreRetryPolicy struct {
+	// MaxAttempts is the maximum number of times the restore job runs. Default: 1.
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// BackoffSeconds is the delay before the first retry. It doubles after each failed attempt, up to 1 hour.
+	// Default: 30.
+	BackoffSeconds int32 `json:"backoffSeconds,omitempty"`
+}
+
+// RestoreVerification checks the restored databases before the restore is marked as completed. Besides the checks
+// below, it always checks that the view 'pg_stat_database' is reachable and that the databases restored with the
+// field 'databases' or used by the checks below exist.
+type RestoreVerification struct {
+	// Queries are SQL queries run in the restored cluster.
+	Queries []VerificationQuery `json:"queries,omitempty"`
+
+	// RowCounts are the expected numbers of rows of tables in the restored cluster.
+	RowCounts []VerificationRowCount `json:"rowCounts,omitempty"`
+}
+
+// VerificationQuery is a SQL query run in the restored cluster.
+type VerificationQuery struct {
+	// Name of the check in the status of KubegresRestore.
+	Name string `json:"name"`
+
+	// Database in which the query runs. Default: postgres.
+	Database string `json:"database,omitempty"`
+
+	// Query to run. It should be read-only.
+	Query string `json:"query"`
+
+	// Expected is the output of the query, with the rows separated by new lines and the columns by '|'.
+	// If it is empty, the check passes if the query runs without any error.
+	Expected string `json:"expected,omitempty"`
+}
+
+// VerificationRowCount is the expected number of rows of a table in the restored cluster.
+type VerificationRowCount struct {
+	// Database of the table. Default: postgres.
+	Database string `json:"database,omitempty"`
+
+	// Table to count the rows of. It can be qualified with its schema, e.g. 'public.orders'.
+	Table string `json:"table"`
+
+	// Count is the expected number of rows.
+	Count int64 `json:"count"`
+}
+
+// RestoreDatabase is a database of a logical backup to restore.
+type RestoreDatabase struct {
+	// Name of the database in the backup.
+	Name string `json:"name"`
+
+	// RestoreAs is the name of the restored database. Default: 'name'.
+	RestoreAs string `json:"restoreAs,omitempty"`
+}
+
+// InPlace restores a backup into an existing Kubegres cluster.
+type InPlace struct {
+	// ConfirmClusterName must be equal to 'clusterName'. It confirms that the databases of the existing Kubegres
+	// cluster are overwritten by the backup.
+	ConfirmClusterName string `json:"confirmClusterName,omitempty"`
 }
 
 type KubegresRestoreSpec struct {
@@ -48,14 +160,64 @@ type KubegresRestoreSpec struct {
 	Resources    v1.ResourceRequirements `json:"resources,omitempty"`
 	ClusterName  string                  `json:"clusterName,omitempty"`
 	Env          []v1.EnvVar             `json:"env,omitempty"`
+
+	// RecoveryTarget restores the base backup 'dataSource.file.snapshot', taken while 'backup.walArchive' was enabled,
+	// into the Primary of the new Kubegres cluster and replays the archived WAL segments up to the given target.
+	RecoveryTarget *RecoveryTarget `json:"recoveryTarget,omitempty"`
+
+	// InPlace restores the backup into the existing Kubegres resource 'clusterName' instead of deploying a new one,
+	// so that the names of its services are unchanged. The cluster is scaled down to its Primary, the backup is
+	// restored into it and the Replicas are re-created from a copy of the restored Primary. The field 'dataSource.cluster'
+	// is ignored. Only logical backups can be restored in place. If the restore stops without completing, the cluster
+	// is scaled back up and the PVCs of its former Replicas are kept but not reused.
+	InPlace *InPlace `json:"inPlace,omitempty"`
+
+	// Databases restores only the given databases of a logical backup taken with pg_dumpall, the other databases are
+	// skipped. Default: all the databases.
+	Databases []RestoreDatabase `json:"databases,omitempty"`
+
+	// Roles restores only the given roles of a logical backup taken with pg_dumpall. Default: all the roles.
+	Roles []string `json:"roles,omitempty"`
+
+	// RetryPolicy reruns the restore job when it fails. Before each retry, the Kubegres resource deployed by the
+	// restore is deleted with its PVCs so that the backup is restored into a clean database. For an in-place restore,
+	// only the restore job is rerun since its backup drops the databases before restoring them.
+	RetryPolicy *RestoreRetryPolicy `json:"retryPolicy,omitempty"`
+
+	// CleanupOnFailure deletes the Kubegres resource deployed by the restore, with its PVCs, once the last attempt
+	// of the restore job has failed. It does not apply to an in-place restore.
+	CleanupOnFailure bool `json:"cleanupOnFailure,omitempty"`
+
+	// Verify runs checks against the restored cluster once the backup is restored. The restore is completed only if
+	// all the checks pass.
+	Verify *RestoreVerification `json:"verify,omitempty"`
 }
 
 // ----------------------- STATUS -----------------------------------------
 
+// RestoreVerificationResult is the result of a check of the field 'verify'.
+type RestoreVerificationResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
 type KubegresRestoreStatus struct {
 	IsCompleted  bool   `json:"isCompleted,omitempty"`
 	CurrentStage string `json:"stage,omitempty"`
 
+	// FailedAttempts is the number of times the restore job has failed.
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+
+	// LastFailureTime is the time when the restore job has failed for the last time.
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// FailureReason is the reason of the last failure of the restore job, from its termination message.
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// VerificationResults are the results of the checks of the field 'verify'.
+	VerificationResults []RestoreVerificationResult `json:"verificationResults,omitempty"`
+
 	//TODO: Display this in an event instead
 	// Reason       string `json:"reason,omitempty"`
 }
@@ -23,6 +23,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -46,7 +47,9 @@ func (in *Cluster) DeepCopy() *Cluster {
 func (in *DataSource) DeepCopyInto(out *DataSource) {
 	*out = *in
 	out.File = in.File
+	in.ObjectStore.DeepCopyInto(&out.ObjectStore)
 	in.Cluster.DeepCopyInto(&out.Cluster)
+	in.WalArchive.DeepCopyInto(&out.WalArchive)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSource.
@@ -74,13 +77,28 @@ func (in *File) DeepCopy() *File {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InPlace) DeepCopyInto(out *InPlace) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InPlace.
+func (in *InPlace) DeepCopy() *InPlace {
+	if in == nil {
+		return nil
+	}
+	out := new(InPlace)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Kubegres) DeepCopyInto(out *Kubegres) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Kubegres.
@@ -104,6 +122,9 @@ func (in *Kubegres) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresBackUp) DeepCopyInto(out *KubegresBackUp) {
 	*out = *in
+	in.Destination.DeepCopyInto(&out.Destination)
+	out.Retention = in.Retention
+	in.WalArchive.DeepCopyInto(&out.WalArchive)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackUp.
@@ -116,6 +137,209 @@ func (in *KubegresBackUp) DeepCopy() *KubegresBackUp {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackUpDestination) DeepCopyInto(out *KubegresBackUpDestination) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(KubegresS3)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackUpDestination.
+func (in *KubegresBackUpDestination) DeepCopy() *KubegresBackUpDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackUpDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackUpJobStatus) DeepCopyInto(out *KubegresBackUpJobStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackUpJobStatus.
+func (in *KubegresBackUpJobStatus) DeepCopy() *KubegresBackUpJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackUpJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackUpRetention) DeepCopyInto(out *KubegresBackUpRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackUpRetention.
+func (in *KubegresBackUpRetention) DeepCopy() *KubegresBackUpRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackUpRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackUpStatus) DeepCopyInto(out *KubegresBackUpStatus) {
+	*out = *in
+	if in.LastSuccessfulBackUp != nil {
+		in, out := &in.LastSuccessfulBackUp, &out.LastSuccessfulBackUp
+		*out = new(KubegresBackUpJobStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFailedBackUp != nil {
+		in, out := &in.LastFailedBackUp, &out.LastFailedBackUp
+		*out = new(KubegresBackUpJobStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetainedBackUps != nil {
+		in, out := &in.RetainedBackUps, &out.RetainedBackUps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastPruneTime != nil {
+		in, out := &in.LastPruneTime, &out.LastPruneTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OverdueSince != nil {
+		in, out := &in.OverdueSince, &out.OverdueSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackUpStatus.
+func (in *KubegresBackUpStatus) DeepCopy() *KubegresBackUpStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackUpStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackup) DeepCopyInto(out *KubegresBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackup.
+func (in *KubegresBackup) DeepCopy() *KubegresBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubegresBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackupList) DeepCopyInto(out *KubegresBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KubegresBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackupList.
+func (in *KubegresBackupList) DeepCopy() *KubegresBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubegresBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackupSpec) DeepCopyInto(out *KubegresBackupSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackupSpec.
+func (in *KubegresBackupSpec) DeepCopy() *KubegresBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresBackupStatus) DeepCopyInto(out *KubegresBackupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(File)
+		**out = **in
+	}
+	if in.ObjectStore != nil {
+		in, out := &in.ObjectStore, &out.ObjectStore
+		*out = new(ObjectStore)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresBackupStatus.
+func (in *KubegresBackupStatus) DeepCopy() *KubegresBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresBlockingOperation) DeepCopyInto(out *KubegresBlockingOperation) {
 	*out = *in
@@ -156,6 +380,11 @@ func (in *KubegresDatabase) DeepCopy() *KubegresDatabase {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresFailover) DeepCopyInto(out *KubegresFailover) {
 	*out = *in
+	if in.MaxLagBytes != nil {
+		in, out := &in.MaxLagBytes, &out.MaxLagBytes
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresFailover.
@@ -168,6 +397,21 @@ func (in *KubegresFailover) DeepCopy() *KubegresFailover {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresInstanceStatus) DeepCopyInto(out *KubegresInstanceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresInstanceStatus.
+func (in *KubegresInstanceStatus) DeepCopy() *KubegresInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresList) DeepCopyInto(out *KubegresList) {
 	*out = *in
@@ -200,13 +444,84 @@ func (in *KubegresList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresMajorVersionUpgrade) DeepCopyInto(out *KubegresMajorVersionUpgrade) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresMajorVersionUpgrade.
+func (in *KubegresMajorVersionUpgrade) DeepCopy() *KubegresMajorVersionUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresMajorVersionUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresPooler) DeepCopyInto(out *KubegresPooler) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresPooler.
+func (in *KubegresPooler) DeepCopy() *KubegresPooler {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresPooler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresReplicaReadyService) DeepCopyInto(out *KubegresReplicaReadyService) {
+	*out = *in
+	if in.MaxLagBytes != nil {
+		in, out := &in.MaxLagBytes, &out.MaxLagBytes
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresReplicaReadyService.
+func (in *KubegresReplicaReadyService) DeepCopy() *KubegresReplicaReadyService {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresReplicaReadyService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresReplication) DeepCopyInto(out *KubegresReplication) {
+	*out = *in
+	if in.NumSyncStandbys != nil {
+		in, out := &in.NumSyncStandbys, &out.NumSyncStandbys
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresReplication.
+func (in *KubegresReplication) DeepCopy() *KubegresReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresRestore) DeepCopyInto(out *KubegresRestore) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresRestore.
@@ -264,6 +579,43 @@ func (in *KubegresRestoreSpec) DeepCopyInto(out *KubegresRestoreSpec) {
 	*out = *in
 	in.DataSource.DeepCopyInto(&out.DataSource)
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RecoveryTarget != nil {
+		in, out := &in.RecoveryTarget, &out.RecoveryTarget
+		*out = new(RecoveryTarget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InPlace != nil {
+		in, out := &in.InPlace, &out.InPlace
+		*out = new(InPlace)
+		**out = **in
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]RestoreDatabase, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RestoreRetryPolicy)
+		**out = **in
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(RestoreVerification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresRestoreSpec.
@@ -279,6 +631,15 @@ func (in *KubegresRestoreSpec) DeepCopy() *KubegresRestoreSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresRestoreStatus) DeepCopyInto(out *KubegresRestoreStatus) {
 	*out = *in
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.VerificationResults != nil {
+		in, out := &in.VerificationResults, &out.VerificationResults
+		*out = make([]RestoreVerificationResult, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresRestoreStatus.
@@ -291,6 +652,37 @@ func (in *KubegresRestoreStatus) DeepCopy() *KubegresRestoreStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresS3) DeepCopyInto(out *KubegresS3) {
+	*out = *in
+	out.Tls = in.Tls
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresS3.
+func (in *KubegresS3) DeepCopy() *KubegresS3 {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresS3)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresS3Tls) DeepCopyInto(out *KubegresS3Tls) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresS3Tls.
+func (in *KubegresS3Tls) DeepCopy() *KubegresS3Tls {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresS3Tls)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresScheduler) DeepCopyInto(out *KubegresScheduler) {
 	*out = *in
@@ -318,6 +710,58 @@ func (in *KubegresScheduler) DeepCopy() *KubegresScheduler {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresService) DeepCopyInto(out *KubegresService) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresService.
+func (in *KubegresService) DeepCopy() *KubegresService {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresServices) DeepCopyInto(out *KubegresServices) {
+	*out = *in
+	in.Primary.DeepCopyInto(&out.Primary)
+	in.Replica.DeepCopyInto(&out.Replica)
+	in.ReplicaReady.DeepCopyInto(&out.ReplicaReady)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresServices.
+func (in *KubegresServices) DeepCopy() *KubegresServices {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresServices)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubegresSpec) DeepCopyInto(out *KubegresSpec) {
 	*out = *in
@@ -332,8 +776,12 @@ func (in *KubegresSpec) DeepCopyInto(out *KubegresSpec) {
 		copy(*out, *in)
 	}
 	in.Database.DeepCopyInto(&out.Database)
-	out.Failover = in.Failover
-	out.Backup = in.Backup
+	in.Failover.DeepCopyInto(&out.Failover)
+	out.Switchover = in.Switchover
+	in.Replication.DeepCopyInto(&out.Replication)
+	in.Backup.DeepCopyInto(&out.Backup)
+	in.Pooler.DeepCopyInto(&out.Pooler)
+	in.Services.DeepCopyInto(&out.Services)
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]corev1.EnvVar, len(*in))
@@ -350,6 +798,25 @@ func (in *KubegresSpec) DeepCopyInto(out *KubegresSpec) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.Probe.DeepCopyInto(&out.Probe)
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostgresMajorVersion != nil {
+		in, out := &in.PostgresMajorVersion, &out.PostgresMajorVersion
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresSpec.
@@ -397,6 +864,20 @@ func (in *KubegresStatus) DeepCopyInto(out *KubegresStatus) {
 	*out = *in
 	out.BlockingOperation = in.BlockingOperation
 	out.PreviousBlockingOperation = in.PreviousBlockingOperation
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]KubegresInstanceStatus, len(*in))
+		copy(*out, *in)
+	}
+	out.MajorVersionUpgrade = in.MajorVersionUpgrade
+	in.BackUp.DeepCopyInto(&out.BackUp)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresStatus.
@@ -409,6 +890,61 @@ func (in *KubegresStatus) DeepCopy() *KubegresStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresSwitchover) DeepCopyInto(out *KubegresSwitchover) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresSwitchover.
+func (in *KubegresSwitchover) DeepCopy() *KubegresSwitchover {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresSwitchover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubegresWalArchive) DeepCopyInto(out *KubegresWalArchive) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(KubegresS3)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubegresWalArchive.
+func (in *KubegresWalArchive) DeepCopy() *KubegresWalArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(KubegresWalArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStore) DeepCopyInto(out *ObjectStore) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(KubegresS3)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStore.
+func (in *ObjectStore) DeepCopy() *ObjectStore {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Probe) DeepCopyInto(out *Probe) {
 	*out = *in
@@ -434,6 +970,126 @@ func (in *Probe) DeepCopy() *Probe {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryTarget) DeepCopyInto(out *RecoveryTarget) {
+	*out = *in
+	if in.Inclusive != nil {
+		in, out := &in.Inclusive, &out.Inclusive
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryTarget.
+func (in *RecoveryTarget) DeepCopy() *RecoveryTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoveryTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreDatabase) DeepCopyInto(out *RestoreDatabase) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreDatabase.
+func (in *RestoreDatabase) DeepCopy() *RestoreDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreRetryPolicy) DeepCopyInto(out *RestoreRetryPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreRetryPolicy.
+func (in *RestoreRetryPolicy) DeepCopy() *RestoreRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreVerification) DeepCopyInto(out *RestoreVerification) {
+	*out = *in
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = make([]VerificationQuery, len(*in))
+		copy(*out, *in)
+	}
+	if in.RowCounts != nil {
+		in, out := &in.RowCounts, &out.RowCounts
+		*out = make([]VerificationRowCount, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreVerification.
+func (in *RestoreVerification) DeepCopy() *RestoreVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreVerificationResult) DeepCopyInto(out *RestoreVerificationResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreVerificationResult.
+func (in *RestoreVerificationResult) DeepCopy() *RestoreVerificationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreVerificationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationQuery) DeepCopyInto(out *VerificationQuery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationQuery.
+func (in *VerificationQuery) DeepCopy() *VerificationQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationRowCount) DeepCopyInto(out *VerificationRowCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationRowCount.
+func (in *VerificationRowCount) DeepCopy() *VerificationRowCount {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationRowCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
@@ -485,3 +1141,23 @@ func (in *VolumeClaimTemplate) DeepCopy() *VolumeClaimTemplate {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WalArchive) DeepCopyInto(out *WalArchive) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(KubegresS3)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WalArchive.
+func (in *WalArchive) DeepCopy() *WalArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(WalArchive)
+	in.DeepCopyInto(out)
+	return out
+}
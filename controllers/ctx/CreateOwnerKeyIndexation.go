@@ -23,6 +23,7 @@ package ctx
 import (
 	"context"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	postgresV1 "reactive-tech.io/kubegres/api/v1"
@@ -69,9 +70,9 @@ func CreateOwnerKeyIndexation(mgr ctrl.Manager,
 		return err
 	}
 
-	if err := mgr.GetFieldIndexer().IndexField(ctx, &core.ConfigMap{}, DeploymentOwnerKey, func(rawObj client.Object) []string {
-		// grab the CustomConfig object, extract the owner...
-		depl := rawObj.(*core.ConfigMap)
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &batch.CronJob{}, DeploymentOwnerKey, func(rawObj client.Object) []string {
+		// grab the BackUp CronJob object, extract the owner...
+		depl := rawObj.(*batch.CronJob)
 		owner := metav1.GetControllerOf(depl)
 		if owner == nil {
 			return nil
@@ -87,20 +88,38 @@ func CreateOwnerKeyIndexation(mgr ctrl.Manager,
 		return err
 	}
 
-	if err := mgr.GetFieldIndexer().IndexField(ctx, &core.Pod{}, DeploymentOwnerKey, func(rawObj client.Object) []string {
-		// grab the Pod object, extract the owner...
-		depl := rawObj.(*core.Pod)
-		owner := metav1.GetControllerOf(depl)
-		if owner == nil {
+	// The Pods and the PVCs are created by the StatefulSets, so they are indexed by the Kubegres resource of their
+	// StatefulSet rather than by their owner
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &core.Pod{}, InstanceOwnerKey, func(rawObj client.Object) []string {
+		kubegresName := GetKubegresNameOfPod(rawObj.(*core.Pod))
+		if kubegresName == "" {
 			return nil
 		}
-		// ...make sure it's a Kubegres...
-		if owner.APIVersion != postgresV1.GroupVersion.String() || owner.Kind != KindKubegres {
+		return []string{kubegresName}
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &core.PersistentVolumeClaim{}, InstanceOwnerKey, func(rawObj client.Object) []string {
+		kubegresName := GetKubegresNameOfPvc(rawObj.(*core.PersistentVolumeClaim))
+		if kubegresName == "" {
 			return nil
 		}
+		return []string{kubegresName}
+	}); err != nil {
+		return err
+	}
 
-		// ...and if so, return it
-		return []string{owner.Name}
+	// The ConfigMaps and the Secrets used by a Kubegres resource are not owned by it, so the Kubegres resources are
+	// indexed by the names of the ConfigMaps and the Secrets they use
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &postgresV1.Kubegres{}, ConfigMapRefKey, func(rawObj client.Object) []string {
+		return GetReferencedConfigMapNames(rawObj.(*postgresV1.Kubegres))
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &postgresV1.Kubegres{}, SecretRefKey, func(rawObj client.Object) []string {
+		return GetReferencedSecretNames(rawObj.(*postgresV1.Kubegres))
 	}); err != nil {
 		return err
 	}
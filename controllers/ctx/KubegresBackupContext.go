@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ctx
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+)
+
+type KubegresBackupContext struct {
+	KubegresBackup *v1.KubegresBackup
+	Status         *status.BackupStatusWrapper
+	Ctx            context.Context
+	Log            log.LogWrapper[*v1.KubegresBackup]
+	Client         client.Client
+}
+
+const (
+	KindKubegresBackup           = "KubegresBackup"
+	BackupJobSuffix              = "-backup-job"
+	BackupJobKubegresTargetField = ".spec.clusterName"
+	ManagedByKubegresBackupLabel = "managed-by-kubegres-backup"
+	// OnDemandBackUpFileNameSuffix is appended to the name of the Kubegres resource in the name of the backup files
+	// taken by a KubegresBackup. As a Kubegres name cannot contain '_', these files never match the backups removed
+	// by the field 'backup.retention'.
+	OnDemandBackUpFileNameSuffix = "_ondemand"
+)
+
+func CreateKubegresBackupContext(kubegresBackup *v1.KubegresBackup,
+	status *status.BackupStatusWrapper,
+	ctx context.Context,
+	log log.LogWrapper[*v1.KubegresBackup],
+	client client.Client) KubegresBackupContext {
+
+	return KubegresBackupContext{
+		KubegresBackup: kubegresBackup,
+		Status:         status,
+		Ctx:            ctx,
+		Log:            log,
+		Client:         client,
+	}
+}
+
+func (r *KubegresBackupContext) GetBackupJobName() string {
+	return r.KubegresBackup.Name + BackupJobSuffix
+}
+
+// GetClusterBackUpCronJobName returns the name of the backup CronJob of the Kubegres resource to back up. The Job
+// taking the backup is created from its template.
+func (r *KubegresBackupContext) GetClusterBackUpCronJobName() string {
+	return CronJobNamePrefix + r.KubegresBackup.Spec.ClusterName
+}
+
+func (r *KubegresBackupContext) GetNamespacesresourceName(resourceName string) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: r.KubegresBackup.Namespace,
+		Name:      resourceName,
+	}
+}
+
+// IsCompleted returns true once the backup has either succeeded or failed. A KubegresBackup takes a single backup.
+func (r *KubegresBackupContext) IsCompleted() bool {
+	phase := r.KubegresBackup.Status.Phase
+	return phase == v1.KubegresBackupPhaseSucceeded || phase == v1.KubegresBackupPhaseFailed
+}
@@ -40,8 +40,12 @@ type KubegresContext struct {
 
 const (
 	PrimaryRoleName                        = "primary"
+	ReplicaRoleName                        = "replica"
 	KindKubegres                           = "Kubegres"
 	DeploymentOwnerKey                     = ".metadata.controller"
+	InstanceOwnerKey                       = ".metadata.labels.app"
+	ConfigMapRefKey                        = ".spec.configMapRefs"
+	SecretRefKey                           = ".spec.secretRefs"
 	DatabaseVolumeName                     = "postgres-db"
 	BaseConfigMapVolumeName                = "base-config"
 	CustomConfigMapVolumeName              = "custom-config"
@@ -53,6 +57,45 @@ const (
 	EnvVarNamePgData                       = "PGDATA"
 	EnvVarNameOfPostgresSuperUserPsw       = "POSTGRES_PASSWORD"
 	EnvVarNameOfPostgresReplicationUserPsw = "POSTGRES_REPLICATION_PASSWORD"
+	EnvVarNameOfPostgresPoolerUserPsw      = "POSTGRES_POOLER_PASSWORD"
+	ReusablePvcAnnotationKey               = "kubegres.reactive-tech.io/reusable-pvc"
+	FailedPrimaryPvcAnnotationKey          = "kubegres.reactive-tech.io/failed-primary-pvc"
+	AppliedServiceKeysAnnotationKey        = "kubegres.reactive-tech.io/applied-service-keys"
+	MajorVersionUpgradeJobNameSuffix       = "-major-version-upgrade"
+	FormerPrimaryCheckerPodNameSuffix      = "-switchover-checker"
+	PoolerNameSuffix                       = "-pooler"
+	PoolerConfigHashAnnotationKey          = "kubegres.reactive-tech.io/pooler-config-hash"
+	PoolerPrimaryAnnotationKey             = "kubegres.reactive-tech.io/primary-statefulset"
+	PoolerAuthUserName                     = "kubegres_pooler"
+	PoolerAuthSchemaName                   = "kubegres_pooler"
+	PoolerAuthLookupFunction               = PoolerAuthSchemaName + ".user_lookup"
+	DefaultPoolerImage                     = "edoburu/pgbouncer:v1.23.1-p3"
+	DefaultPoolerPortNumber                = 6432
+	DefaultPoolerReplicas                  = 1
+	DefaultPoolerMaxClientConn             = 100
+	DefaultPoolerDefaultPoolSize           = 20
+	WalArchiveVolumeName                   = "wal-archive"
+	WalArchiveFolderSuffix                 = "-wal"
+	WalArchiveSpoolFolder                  = "wal-archive-spool"
+	WalRestoreFolder                       = "wal-restore"
+	WalArchiveUploaderContainerName        = "wal-archive-uploader"
+	EnvVarNameWalArchiveFolder             = "WAL_ARCHIVE_FOLDER"
+	DefaultWalArchiveTimeout               = 60
+	DefaultS3Image                         = "amazon/aws-cli:2.13.0"
+	S3CaVolumeName                         = "s3-ca"
+	S3CaVolumeMount                        = "/etc/kubegres/s3-ca"
+	S3CaSecretKey                          = "ca.crt"
+	DefaultBackUpVolumeMount               = "/var/lib/backup"
+	BackUpContainerName                    = "backup-postgres"
+	BackUpUploaderContainerName            = "backup-uploader"
+	BackUpPrunerContainerName              = "backup-pruner"
+	WalArchivePrunerContainerName          = "wal-archive-pruner"
+	BackUpRetentionVolumeName              = "backup-retention"
+	BackUpRetentionVolumeMount             = "/tmp/backup-retention"
+	ReplicaInitContainerName               = "setup-replica-data-directory"
+	ReplicaReadyServiceNameSuffix          = "-replica-ready"
+	ReplicaReadyLabelKey                   = "kubegres.reactive-tech.io/replica-ready"
+	DefaultReplicaReadyMaxLagBytes         = 16 * 1024 * 1024
 )
 
 func (r *KubegresContext) GetServiceResourceName(isPrimary bool) string {
@@ -62,13 +105,178 @@ func (r *KubegresContext) GetServiceResourceName(isPrimary bool) string {
 	return r.Kubegres.Name + "-replica"
 }
 
+func (r *KubegresContext) GetReplicaReadyServiceResourceName() string {
+	return r.Kubegres.Name + ReplicaReadyServiceNameSuffix
+}
+
+func (r *KubegresContext) GetServiceSpec(isPrimary bool) v1.KubegresService {
+	if isPrimary {
+		return r.Kubegres.Spec.Services.Primary
+	}
+	return r.Kubegres.Spec.Services.Replica
+}
+
+func (r *KubegresContext) GetPoolerResourceName(isPrimary bool) string {
+	if isPrimary {
+		return r.Kubegres.Name + PoolerNameSuffix
+	}
+	return r.Kubegres.Name + PoolerNameSuffix + "-replica"
+}
+
+func (r *KubegresContext) GetPoolerConfigMapName() string {
+	return r.Kubegres.Name + PoolerNameSuffix + "-config"
+}
+
 func (r *KubegresContext) GetStatefulSetResourceName(instanceIndex int32) string {
 	return r.Kubegres.Name + "-" + strconv.Itoa(int(instanceIndex))
 }
 
+func (r *KubegresContext) GetDatabasePvcResourceName(statefulSetName string) string {
+	return DatabaseVolumeName + "-" + statefulSetName + "-0"
+}
+
 func (r *KubegresContext) IsReservedVolumeName(volumeName string) bool {
 	return volumeName == DatabaseVolumeName ||
 		volumeName == BaseConfigMapVolumeName ||
 		volumeName == CustomConfigMapVolumeName ||
+		volumeName == WalArchiveVolumeName ||
+		volumeName == S3CaVolumeName ||
 		strings.Contains(volumeName, "kube-api")
 }
+
+// IsReservedContainerName returns true if the given name is used by a container set by Kubegres in the Pods of
+// the StatefulSets. The name of the PostgreSql container is the name of its StatefulSet, e.g. 'mypostgres-1'.
+func (r *KubegresContext) IsReservedContainerName(containerName string) bool {
+	if containerName == WalArchiveUploaderContainerName || containerName == ReplicaInitContainerName {
+		return true
+	}
+
+	instanceIndex := strings.TrimPrefix(containerName, r.Kubegres.Name+"-")
+	_, err := strconv.Atoi(instanceIndex)
+	return instanceIndex != containerName && err == nil
+}
+
+func (r *KubegresContext) GetMajorVersionUpgradeJobName() string {
+	return r.Kubegres.Name + MajorVersionUpgradeJobNameSuffix
+}
+
+func (r *KubegresContext) GetFormerPrimaryCheckerPodName() string {
+	return r.Kubegres.Name + FormerPrimaryCheckerPodNameSuffix
+}
+
+// IsPhysicalBackUp returns true if the backup CronJob takes base backups with pg_basebackup rather than dumps
+// with pg_dumpall.
+func (r *KubegresContext) IsPhysicalBackUp() bool {
+	return r.Kubegres.Spec.Backup.Type == v1.BackUpTypePhysical
+}
+
+// IsBackUpInS3 returns true if the backup CronJob uploads the backups in an S3 bucket rather than in the PVC
+// 'backup.pvcName'.
+func (r *KubegresContext) IsBackUpInS3() bool {
+	return r.Kubegres.Spec.Backup.Destination.S3 != nil
+}
+
+// IsBackUpRetentionEnabled returns true if the backup CronJob removes the old backups once a backup is taken.
+func (r *KubegresContext) IsBackUpRetentionEnabled() bool {
+	retention := r.Kubegres.Spec.Backup.Retention
+	return retention.KeepLast > 0 ||
+		retention.KeepDaily > 0 ||
+		retention.KeepWeekly > 0 ||
+		retention.KeepMonthly > 0 ||
+		retention.MaxAgeDays > 0
+}
+
+// GetBackUpFolder returns the folder where the backup CronJob writes the backups. If they are uploaded in S3, it is
+// a temporary folder from which they are uploaded.
+func (r *KubegresContext) GetBackUpFolder() string {
+	backUpVolumeMount := r.Kubegres.Spec.Backup.VolumeMount
+	if backUpVolumeMount == "" && r.IsBackUpInS3() {
+		return DefaultBackUpVolumeMount
+	}
+	return backUpVolumeMount
+}
+
+func (r *KubegresContext) IsWalArchiveEnabled() bool {
+	return r.Kubegres.Spec.Backup.WalArchive.Enabled
+}
+
+func (r *KubegresContext) IsWalArchivedInS3() bool {
+	return r.IsWalArchiveEnabled() && r.Kubegres.Spec.Backup.WalArchive.S3 != nil
+}
+
+// GetWalArchiveFolder returns the folder where PostgreSql copies the WAL segments to archive. If they are archived
+// in S3, it is a spool folder in the PVC of the database from which the segments are uploaded.
+func (r *KubegresContext) GetWalArchiveFolder() string {
+	if r.IsWalArchivedInS3() {
+		return r.Kubegres.Spec.Database.VolumeMount + "/" + WalArchiveSpoolFolder
+	}
+	return GetWalArchiveFolderInBackUpPvc(r.Kubegres.Spec.Backup.VolumeMount, r.Kubegres.Name)
+}
+
+// GetWalArchiveFolderInBackUpPvc returns the folder where the WAL segments of the given Kubegres resource are
+// archived, in a backup PVC mounted in the given path.
+func GetWalArchiveFolderInBackUpPvc(backUpVolumeMount, kubegresName string) string {
+	return backUpVolumeMount + "/" + kubegresName + WalArchiveFolderSuffix
+}
+
+// GetWalArchiveS3Url returns the S3 location where the WAL segments of the given Kubegres resource are archived.
+func GetWalArchiveS3Url(s3 v1.KubegresS3, kubegresName string) string {
+	return GetS3Url(s3) + "/" + kubegresName + "/wal"
+}
+
+func GetS3Url(s3 v1.KubegresS3) string {
+	url := "s3://" + s3.Bucket
+	if prefix := strings.Trim(s3.Prefix, "/"); prefix != "" {
+		url += "/" + prefix
+	}
+	return url
+}
+
+// GetExpectedPostgresMajorVersion returns the major version of PostgreSql set in the field 'postgresMajorVersion' or,
+// if not set, parsed from the tag of the image. The returned boolean is false if it is unknown (e.g. "postgres:latest").
+func (r *KubegresContext) GetExpectedPostgresMajorVersion() (int32, bool) {
+	if r.Kubegres.Spec.PostgresMajorVersion != nil {
+		return *r.Kubegres.Spec.PostgresMajorVersion, true
+	}
+	return ParsePostgresMajorVersionFromImage(r.Kubegres.Spec.Image)
+}
+
+// IsPostgresMajorVersionChanged returns true if the expected major version of PostgreSql is known and is different
+// from the major version running on the Primary.
+func (r *KubegresContext) IsPostgresMajorVersionChanged() bool {
+	expected, isKnown := r.GetExpectedPostgresMajorVersion()
+	current := r.Status.GetPostgresMajorVersion()
+	return isKnown && current > 0 && expected != current
+}
+
+// IsPostgresMajorVersionUnknown returns true if the major version running on the Primary is not known, e.g. when
+// the Primary cannot be queried and the major version cannot be parsed from its image.
+func (r *KubegresContext) IsPostgresMajorVersionUnknown() bool {
+	return r.Status.GetPostgresMajorVersion() == 0
+}
+
+// ParsePostgresMajorVersionFromImage parses the major version of PostgreSql from the leading digits of the tag of
+// an image, e.g. 16 for "postgres:16.2-alpine". The returned boolean is false if the tag does not start with digits.
+func ParsePostgresMajorVersionFromImage(image string) (int32, bool) {
+
+	if digestIndex := strings.Index(image, "@"); digestIndex >= 0 {
+		image = image[:digestIndex]
+	}
+
+	tagIndex := strings.LastIndex(image, ":")
+	if tagIndex < 0 || strings.Contains(image[tagIndex:], "/") {
+		return 0, false
+	}
+
+	tag := image[tagIndex+1:]
+	nbreDigits := 0
+	for nbreDigits < len(tag) && tag[nbreDigits] >= '0' && tag[nbreDigits] <= '9' {
+		nbreDigits++
+	}
+
+	majorVersion, err := strconv.ParseInt(tag[:nbreDigits], 10, 32)
+	if err != nil || majorVersion <= 0 {
+		return 0, false
+	}
+	return int32(majorVersion), true
+}
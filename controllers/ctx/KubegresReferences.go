@@ -0,0 +1,115 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctx
+
+import (
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reactive-tech.io/kubegres/api/v1"
+)
+
+// GetReferencedConfigMapNames returns the names of the ConfigMaps used by the given Kubegres resource. The base
+// ConfigMap is always returned since it is mounted in all StatefulSets, even when 'spec.customConfig' is set.
+func GetReferencedConfigMapNames(kubegres *v1.Kubegres) []string {
+
+	names := []string{BaseConfigMapName}
+	if kubegres.Spec.CustomConfig != "" && kubegres.Spec.CustomConfig != BaseConfigMapName {
+		names = append(names, kubegres.Spec.CustomConfig)
+	}
+
+	for _, envVar := range kubegres.Spec.Env {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.ConfigMapKeyRef != nil {
+			names = appendIfMissing(names, envVar.ValueFrom.ConfigMapKeyRef.Name)
+		}
+	}
+
+	return names
+}
+
+// GetReferencedSecretNames returns the names of the Secrets used by the given Kubegres resource: the Secrets of its
+// environment variables, such as the passwords of the superuser and of the replication user, and the Secrets of
+// its S3 buckets.
+func GetReferencedSecretNames(kubegres *v1.Kubegres) []string {
+
+	var names []string
+	for _, envVar := range kubegres.Spec.Env {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+			names = appendIfMissing(names, envVar.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+
+	for _, s3 := range []*v1.KubegresS3{kubegres.Spec.Backup.Destination.S3, kubegres.Spec.Backup.WalArchive.S3} {
+		if s3 == nil {
+			continue
+		}
+		names = appendIfMissing(names, s3.CredentialsSecret)
+		names = appendIfMissing(names, s3.Tls.CaSecret)
+	}
+
+	return names
+}
+
+// GetKubegresNameOfPod returns the name of the Kubegres resource of the given Pod, or an empty string if the Pod
+// was not created by the StatefulSet of a Kubegres resource. The Pods are owned by the StatefulSets and not by the
+// Kubegres resource, so its name is read from the label "app" that the StatefulSets set in their Pods.
+func GetKubegresNameOfPod(pod *core.Pod) string {
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.APIVersion != apps.SchemeGroupVersion.String() || owner.Kind != "StatefulSet" {
+		return ""
+	}
+
+	kubegresName := pod.Labels["app"]
+	if kubegresName == "" || owner.Name != kubegresName+"-"+pod.Labels["index"] {
+		return ""
+	}
+
+	return kubegresName
+}
+
+// GetKubegresNameOfPvc returns the name of the Kubegres resource of the given database PVC, or an empty string if
+// the PVC was not created by the StatefulSet of a Kubegres resource. The StatefulSets do not set any owner in the
+// PVCs, so its name is read from the label "app" that the StatefulSets set in their PVCs.
+func GetKubegresNameOfPvc(pvc *core.PersistentVolumeClaim) string {
+
+	kubegresName := pvc.Labels["app"]
+	if kubegresName == "" || pvc.Name != DatabaseVolumeName+"-"+kubegresName+"-"+pvc.Labels["index"]+"-0" {
+		return ""
+	}
+
+	return kubegresName
+}
+
+func appendIfMissing(names []string, name string) []string {
+
+	if name == "" {
+		return names
+	}
+
+	for _, existingName := range names {
+		if existingName == name {
+			return names
+		}
+	}
+
+	return append(names, name)
+}
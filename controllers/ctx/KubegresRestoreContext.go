@@ -18,6 +18,10 @@ package ctx
 
 import (
 	"context"
+	"path"
+	"reflect"
+	"strings"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,6 +35,7 @@ import (
 type KubegresRestoreContext struct {
 	KubegresRestore           *v1.KubegresRestore
 	SourceKubegresClusterSpec v1.KubegresSpec
+	DataSourceBackup          *v1.KubegresBackup
 	Status                    *status.RestoreStatusWrapper
 	Ctx                       context.Context
 	Log                       log.LogWrapper[*v1.KubegresRestore]
@@ -41,18 +46,33 @@ const (
 	KindKubegresRestore           = "KubegresRestore"
 	RestoreJobSuffix              = "-job"
 	RestoreJobKubegresTargetField = ".spec.clusterName"
+	RestoreJobBackupSourceField   = ".spec.dataSource.backup"
 	ManagedByKubegresRestoreLabel = "managed-by-kubegres-restore"
 	FileCheckerPodSuffix          = "-file-checker"
+	VerificationJobSuffix         = "-verify-job"
+	DefaultVerificationDatabase   = "postgres"
+	ObjectStoreSnapshotFolder     = "/tmp/kubegres-snapshot"
+
+	// InPlaceRestoreReplicasAnnotationKey is set on a Kubegres resource scaled down for an in-place restore. Its value
+	// is the number of instances to scale back up to once the backup is restored.
+	InPlaceRestoreReplicasAnnotationKey = "kubegres.reactive-tech.io/replicas-before-restore"
+
+	DefaultRestoreRetryBackoff = 30 * time.Second
+	MaxRestoreRetryBackoff     = time.Hour
 )
 
 const (
 	StageCheckingSnapshotFile  = "Checking snapshot file"
 	StageDeployingCluster      = "Deploying Kubegres Cluster"
+	StageScalingDownCluster    = "Scaling down Kubegres Cluster to its Primary"
 	StageWaitingForCluster     = "Waiting for Kubegres Cluster to be ready"
 	StageRestoreJobIsDeploying = "Waiting for restore job to deploy"
 	StageRestoreJobIsRunning   = "Restoring database from snaphot"
 	StageRestoreJobIsCompleted = "Restorejob completed succesfully"
 	StageRestoreJobFailed      = "Restorejob has stopped due to fatal error"
+	StageWaitingForRetry       = "Waiting to retry the restore job"
+	StageVerifyingRestore      = "Verifying restored databases"
+	StageVerificationFailed    = "Verification of restored databases has failed"
 )
 
 func CreateKubegresRestoreContext(kubegresRestore *v1.KubegresRestore,
@@ -67,6 +87,9 @@ func CreateKubegresRestoreContext(kubegresRestore *v1.KubegresRestore,
 		Log:             log,
 		Client:          client,
 	}
+	if err := kubegresRestoreContext.loadDataSourceBackup(); err != nil {
+		return kubegresRestoreContext, err
+	}
 	sourceKubegresClusterSpec, err := kubegresRestoreContext.assignSourceKubegresCluserSpec(kubegresRestore)
 	if err != nil {
 		return kubegresRestoreContext, err
@@ -84,6 +107,15 @@ func (r *KubegresRestoreContext) GetRestoreJobName() string {
 	return r.KubegresRestore.Name + RestoreJobSuffix
 }
 
+func (r *KubegresRestoreContext) GetVerificationJobName() string {
+	return r.KubegresRestore.Name + VerificationJobSuffix
+}
+
+// IsVerificationEnabled returns true if the restored databases are checked by the field 'verify'.
+func (r *KubegresRestoreContext) IsVerificationEnabled() bool {
+	return r.KubegresRestore.Spec.Verify != nil
+}
+
 func (r *KubegresRestoreContext) GetNamespacesresourceName(name string) types.NamespacedName {
 	return types.NamespacedName{
 		Namespace: r.KubegresRestore.Namespace,
@@ -92,7 +124,142 @@ func (r *KubegresRestoreContext) GetNamespacesresourceName(name string) types.Na
 }
 
 func (r *KubegresRestoreContext) ShouldRestoreFromExistingCluster() bool {
-	return r.KubegresRestore.Spec.DataSource.Cluster.ClusterName != ""
+	return !r.IsInPlaceRestore() && r.KubegresRestore.Spec.DataSource.Cluster.ClusterName != ""
+}
+
+// IsInPlaceRestore returns true if the backup is restored into the existing Kubegres resource 'clusterName'.
+func (r *KubegresRestoreContext) IsInPlaceRestore() bool {
+	return r.KubegresRestore.Spec.InPlace != nil
+}
+
+// IsObjectStoreSource returns true if the backup to restore is downloaded from the field 'dataSource.objectStore'
+// rather than read from the PVC 'dataSource.file.pvcName'.
+func (r *KubegresRestoreContext) IsObjectStoreSource() bool {
+	return r.KubegresRestore.Spec.DataSource.ObjectStore.S3 != nil
+}
+
+// GetSnapshotFolder returns the folder where the backup to restore is read from in the restore job.
+func (r *KubegresRestoreContext) GetSnapshotFolder() string {
+	if r.IsObjectStoreSource() {
+		return ObjectStoreSnapshotFolder
+	}
+	return r.KubegresRestore.Spec.DataSource.File.Mountpath
+}
+
+// GetSnapshotFilePath returns the path of the backup to restore in the restore job.
+func (r *KubegresRestoreContext) GetSnapshotFilePath() string {
+	if r.IsObjectStoreSource() {
+		return path.Join(ObjectStoreSnapshotFolder, r.KubegresRestore.Spec.DataSource.ObjectStore.Snapshot)
+	}
+	fileSpec := r.KubegresRestore.Spec.DataSource.File
+	return path.Join(fileSpec.Mountpath, fileSpec.Snapshot)
+}
+
+// GetObjectStoreSnapshotUrl returns the S3 location of the backup to restore from the field 'dataSource.objectStore'.
+func (r *KubegresRestoreContext) GetObjectStoreSnapshotUrl() string {
+	objectStore := r.KubegresRestore.Spec.DataSource.ObjectStore
+	return GetS3Url(*objectStore.S3) + "/" + objectStore.Snapshot
+}
+
+// IsPointInTimeRecovery returns true if a base backup is restored with the archived WAL segments replayed up to the
+// field 'recoveryTarget', instead of replaying a logical backup.
+func (r *KubegresRestoreContext) IsPointInTimeRecovery() bool {
+	return r.KubegresRestore.Spec.RecoveryTarget != nil
+}
+
+// GetTargetPrimaryDbPvcName returns the name of the PVC of the Primary of the Kubegres cluster to create, in which
+// a base backup is restored before the cluster is deployed.
+func (r *KubegresRestoreContext) GetTargetPrimaryDbPvcName() string {
+	return DatabaseVolumeName + "-" + r.KubegresRestore.Spec.ClusterName + "-1-0"
+}
+
+// GetWalArchiveS3 returns the S3 bucket containing the archived WAL segments to replay, with the prefix of their
+// location. It returns nil if the archived WAL segments are in the PVC 'dataSource.file.pvcName'.
+func (r *KubegresRestoreContext) GetWalArchiveS3() *v1.KubegresS3 {
+
+	walArchive := r.KubegresRestore.Spec.DataSource.WalArchive
+	if walArchive.S3 != nil {
+		return walArchive.S3
+	}
+
+	sourceClusterName := r.KubegresRestore.Spec.DataSource.Cluster.ClusterName
+	sourceS3 := r.SourceKubegresClusterSpec.Backup.WalArchive.S3
+	if walArchive.Folder != "" || sourceClusterName == "" || sourceS3 == nil {
+		return nil
+	}
+
+	s3 := *sourceS3
+	s3.Prefix = strings.TrimPrefix(GetWalArchiveS3Url(s3, sourceClusterName), "s3://"+s3.Bucket+"/")
+	return &s3
+}
+
+// GetWalArchiveFolder returns the folder in the PVC 'dataSource.file.pvcName' containing the archived WAL segments
+// to replay. It returns an empty string if it cannot be found or if the backup is restored from an object store.
+func (r *KubegresRestoreContext) GetWalArchiveFolder() string {
+
+	if r.IsObjectStoreSource() {
+		return ""
+	}
+
+	fileSpec := r.KubegresRestore.Spec.DataSource.File
+	walArchive := r.KubegresRestore.Spec.DataSource.WalArchive
+	if walArchive.Folder != "" {
+		return path.Join(fileSpec.Mountpath, walArchive.Folder)
+	}
+
+	sourceClusterName := r.KubegresRestore.Spec.DataSource.Cluster.ClusterName
+	if sourceClusterName == "" {
+		return ""
+	}
+	return GetWalArchiveFolderInBackUpPvc(fileSpec.Mountpath, sourceClusterName)
+}
+
+// IsPartialRestore returns true if only some of the databases or roles of a logical backup are restored.
+func (r *KubegresRestoreContext) IsPartialRestore() bool {
+	restoreSpec := r.KubegresRestore.Spec
+	return len(restoreSpec.Databases) > 0 || len(restoreSpec.Roles) > 0
+}
+
+// GetDatabasesToRestore returns the databases to restore in the format expected by the restore script: separated by
+// spaces, each one as "name" or "name:restoreAs".
+func (r *KubegresRestoreContext) GetDatabasesToRestore() string {
+	var databases []string
+	for _, database := range r.KubegresRestore.Spec.Databases {
+		if database.RestoreAs != "" && database.RestoreAs != database.Name {
+			databases = append(databases, database.Name+":"+database.RestoreAs)
+		} else {
+			databases = append(databases, database.Name)
+		}
+	}
+	return strings.Join(databases, " ")
+}
+
+// GetMaxAttempts returns the maximum number of times the restore job runs, from the field 'retryPolicy.maxAttempts'.
+func (r *KubegresRestoreContext) GetMaxAttempts() int32 {
+	retryPolicy := r.KubegresRestore.Spec.RetryPolicy
+	if retryPolicy == nil || retryPolicy.MaxAttempts < 1 {
+		return 1
+	}
+	return retryPolicy.MaxAttempts
+}
+
+// GetRetryBackoff returns the delay before rerunning the restore job after the given number of failed attempts.
+// The delay set in the field 'retryPolicy.backoffSeconds' doubles after each failed attempt, up to 1 hour.
+func (r *KubegresRestoreContext) GetRetryBackoff(failedAttempts int32) time.Duration {
+	backoff := DefaultRestoreRetryBackoff
+	retryPolicy := r.KubegresRestore.Spec.RetryPolicy
+	if retryPolicy != nil && retryPolicy.BackoffSeconds > 0 {
+		backoff = time.Duration(retryPolicy.BackoffSeconds) * time.Second
+	}
+
+	for i := int32(1); i < failedAttempts && backoff < MaxRestoreRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > MaxRestoreRetryBackoff {
+		return MaxRestoreRetryBackoff
+	}
+	return backoff
 }
 
 func (r *KubegresRestoreContext) AreResourcesSpecifiedForRestoreJob() bool {
@@ -100,17 +267,63 @@ func (r *KubegresRestoreContext) AreResourcesSpecifiedForRestoreJob() bool {
 	return restoreSpec.Resources.Requests != nil || restoreSpec.Resources.Limits != nil
 }
 
+// IsBackupSource returns true if the backup to restore is taken by the KubegresBackup 'dataSource.backup'.
+func (r *KubegresRestoreContext) IsBackupSource() bool {
+	return r.KubegresRestore.Spec.DataSource.Backup != ""
+}
+
+// loadDataSourceBackup loads the KubegresBackup 'dataSource.backup'. Once it has succeeded, the location of its
+// backup file and the Kubegres resource which was backed up are set in memory in the field 'dataSource', so that
+// the backup is restored in the same way as if they had been set by the user.
+func (r *KubegresRestoreContext) loadDataSourceBackup() error {
+	if !r.IsBackupSource() {
+		return nil
+	}
+
+	dataSource := &r.KubegresRestore.Spec.DataSource
+	kubegresBackup := &v1.KubegresBackup{}
+	err := r.Client.Get(r.Ctx, r.GetNamespacesresourceName(dataSource.Backup), kubegresBackup)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		r.Log.ErrorEvent("KubegresBackupLoadingErr", err, "Unable to load any deployed KubegresBackup.", "KubegresBackup name", dataSource.Backup)
+		return err
+	}
+	r.DataSourceBackup = kubegresBackup
+
+	if kubegresBackup.Status.Phase != v1.KubegresBackupPhaseSucceeded {
+		return nil
+	}
+
+	dataSource.File = v1.File{}
+	dataSource.ObjectStore = v1.ObjectStore{}
+	if kubegresBackup.Status.File != nil {
+		dataSource.File = *kubegresBackup.Status.File
+	}
+	if kubegresBackup.Status.ObjectStore != nil {
+		dataSource.ObjectStore = *kubegresBackup.Status.ObjectStore.DeepCopy()
+	}
+
+	if dataSource.Cluster.ClusterName == "" && reflect.DeepEqual(dataSource.Cluster.ClusterSpec, v1.KubegresSpec{}) {
+		dataSource.Cluster.ClusterName = kubegresBackup.Spec.ClusterName
+	}
+	return nil
+}
+
 func (r *KubegresRestoreContext) assignSourceKubegresCluserSpec(kubegresRestore *v1.KubegresRestore) (v1.KubegresSpec, error) {
-	if r.ShouldRestoreFromExistingCluster() {
-		return r.getKubegresSpecFromExistingCluster()
+	if r.IsInPlaceRestore() {
+		return r.getKubegresSpecFromExistingCluster(r.KubegresRestore.Spec.ClusterName)
+	} else if r.ShouldRestoreFromExistingCluster() {
+		return r.getKubegresSpecFromExistingCluster(r.KubegresRestore.Spec.DataSource.Cluster.ClusterName)
 	} else {
 		return r.KubegresRestore.Spec.DataSource.Cluster.ClusterSpec, nil
 	}
 }
 
-func (r *KubegresRestoreContext) getKubegresSpecFromExistingCluster() (v1.KubegresSpec, error) {
+func (r *KubegresRestoreContext) getKubegresSpecFromExistingCluster(clusterName string) (v1.KubegresSpec, error) {
 	cluster := &v1.Kubegres{}
-	clusterKey := r.GetNamespacesresourceName(r.KubegresRestore.Spec.DataSource.Cluster.ClusterName)
+	clusterKey := r.GetNamespacesresourceName(clusterName)
 	err := r.Client.Get(r.Ctx, clusterKey, cluster)
 	if err != nil && apierrors.IsNotFound(err) {
 		r.Log.ErrorEvent("KubegresSpecFromExistingClusterErr", err, "Unable to get Kubegres specification from non-existing source cluster", "ClusterName", clusterKey.Name)
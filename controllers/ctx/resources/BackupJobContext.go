@@ -0,0 +1,64 @@
+package resources
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"reactive-tech.io/kubegres/controllers/spec/checker"
+	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+type BackupJobContext struct {
+	LogWrapper            log.LogWrapper[*v1.KubegresBackup]
+	KubegresBackupContext ctx.KubegresBackupContext
+	BackupStatusWrapper   *status.BackupStatusWrapper
+	KubegresBackupStates  states.KubegresBackupStates
+	BackupSpecChecker     checker.BackupSpecChecker
+
+	ResourcesCountSpecEnforcer resources_count_spec.ResourcesCountSpecEnforcer
+}
+
+func CreateBackupJobContext(kubegresBackup *v1.KubegresBackup,
+	ctx2 context.Context,
+	logger logr.Logger,
+	client client.Client,
+	recorder record.EventRecorder) (bc *BackupJobContext, err error) {
+
+	bc = &BackupJobContext{}
+
+	bc.LogWrapper = log.LogWrapper[*v1.KubegresBackup]{Resource: kubegresBackup, Logger: logger, Recorder: recorder}
+	bc.BackupStatusWrapper = &status.BackupStatusWrapper{
+		KubegresBackup: kubegresBackup,
+		Ctx:            ctx2,
+		Log:            bc.LogWrapper,
+		Client:         client,
+	}
+
+	bc.KubegresBackupContext = ctx.CreateKubegresBackupContext(kubegresBackup, bc.BackupStatusWrapper, ctx2, bc.LogWrapper, client)
+
+	bc.KubegresBackupStates, err = states.LoadKubegresBackupStates(bc.KubegresBackupContext)
+	if err != nil {
+		return bc, err
+	}
+
+	bc.BackupSpecChecker = checker.CreateBackupSpecChecker(bc.KubegresBackupContext, bc.KubegresBackupStates)
+
+	bc.addResourcesCountSpecEnforcers()
+
+	return bc, nil
+}
+
+func (r *BackupJobContext) addResourcesCountSpecEnforcers() {
+	backupJobCountSpecEnforcer := resources_count_spec.CreateBackupJobCountSpecEnforcer(r.KubegresBackupContext, r.KubegresBackupStates)
+
+	r.ResourcesCountSpecEnforcer = resources_count_spec.ResourcesCountSpecEnforcer{}
+	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(&backupJobCountSpecEnforcer)
+}
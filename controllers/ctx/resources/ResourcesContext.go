@@ -31,8 +31,10 @@ import (
 	"reactive-tech.io/kubegres/controllers/ctx/status"
 	"reactive-tech.io/kubegres/controllers/operation"
 	log3 "reactive-tech.io/kubegres/controllers/operation/log"
+	"reactive-tech.io/kubegres/controllers/postgres"
 	"reactive-tech.io/kubegres/controllers/spec/checker"
 	"reactive-tech.io/kubegres/controllers/spec/defaultspec"
+	"reactive-tech.io/kubegres/controllers/spec/enforcer/replication_spec"
 	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec"
 	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec/statefulset"
 	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec/statefulset/failover"
@@ -40,6 +42,7 @@ import (
 	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
 	log2 "reactive-tech.io/kubegres/controllers/states/log"
+	status2 "reactive-tech.io/kubegres/controllers/status"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -50,23 +53,37 @@ type ResourcesContext struct {
 	ResourcesStates              states.ResourcesStates
 	ResourcesStatesLogger        log2.ResourcesStatesLogger
 	SpecChecker                  checker.SpecChecker
+	KubegresConditionsUpdater    status2.KubegresConditionsUpdater
+	KubegresInstancesUpdater     status2.KubegresInstancesUpdater
+	KubegresMetricsUpdater       status2.KubegresMetricsUpdater
+	KubegresBackUpUpdater        status2.KubegresBackUpUpdater
+	PostgresClient               *postgres.PostgresClient
 	DefaultStorageClass          defaultspec.DefaultStorageClass
 	CustomConfigSpecHelper       template.CustomConfigSpecHelper
+	WalArchiveSpecHelper         template.WalArchiveSpecHelper
+	ExtraContainersSpecHelper    template.ExtraContainersSpecHelper
 	ResourcesCreatorFromTemplate template.ResourcesCreatorFromTemplate
 	ResourcesCountSpecEnforcer   resources_count_spec.ResourcesCountSpecEnforcer
 	AllStatefulSetsSpecEnforcer  statefulset_spec.AllStatefulSetsSpecEnforcer
 	StatefulSetsSpecsEnforcer    statefulset_spec.StatefulSetsSpecsEnforcer
 
-	BlockingOperation          *operation.BlockingOperation
-	BlockingOperationLogger    log3.BlockingOperationLogger
-	PrimaryToReplicaFailOver   failover.PrimaryToReplicaFailOver
-	PrimaryDbCountSpecEnforcer statefulset.PrimaryDbCountSpecEnforcer
-	ReplicaDbCountSpecEnforcer statefulset.ReplicaDbCountSpecEnforcer
-
-	BaseConfigMapCountSpecEnforcer resources_count_spec.BaseConfigMapCountSpecEnforcer
-	StatefulSetCountSpecEnforcer   resources_count_spec.StatefulSetCountSpecEnforcer
-	ServicesCountSpecEnforcer      resources_count_spec.ServicesCountSpecEnforcer
-	BackUpCronJobCountSpecEnforcer resources_count_spec.BackUpCronJobCountSpecEnforcer
+	SynchronousStandbysSpecEnforcer replication_spec.SynchronousStandbysSpecEnforcer
+
+	BlockingOperation           *operation.BlockingOperation
+	BlockingOperationLogger     log3.BlockingOperationLogger
+	DbPvcPolicy                 failover.DbPvcPolicy
+	PrimaryToReplicaFailOver    failover.PrimaryToReplicaFailOver
+	PrimaryToReplicaSwitchover  failover.PrimaryToReplicaSwitchover
+	MajorVersionUpgradeEnforcer statefulset.MajorVersionUpgradeEnforcer
+	PrimaryDbCountSpecEnforcer  statefulset.PrimaryDbCountSpecEnforcer
+	ReplicaDbCountSpecEnforcer  statefulset.ReplicaDbCountSpecEnforcer
+
+	BaseConfigMapCountSpecEnforcer       resources_count_spec.BaseConfigMapCountSpecEnforcer
+	StatefulSetCountSpecEnforcer         resources_count_spec.StatefulSetCountSpecEnforcer
+	ServicesCountSpecEnforcer            resources_count_spec.ServicesCountSpecEnforcer
+	ReplicaReadyServiceCountSpecEnforcer resources_count_spec.ReplicaReadyServiceCountSpecEnforcer
+	PoolerCountSpecEnforcer              resources_count_spec.PoolerCountSpecEnforcer
+	BackUpCronJobCountSpecEnforcer       resources_count_spec.BackUpCronJobCountSpecEnforcer
 }
 
 func CreateResourcesContext(kubegres *postgresV1.Kubegres,
@@ -107,7 +124,9 @@ func CreateResourcesContext(kubegres *postgresV1.Kubegres,
 
 	rc.BlockingOperationLogger = log3.CreateBlockingOperationLogger(rc.KubegresContext, rc.BlockingOperation)
 
-	if rc.ResourcesStates, err = states.LoadResourcesStates(rc.KubegresContext); err != nil {
+	rc.PostgresClient = postgres.CreatePostgresClient(rc.KubegresContext)
+
+	if rc.ResourcesStates, err = states.LoadResourcesStates(rc.KubegresContext, rc.PostgresClient); err != nil {
 		return nil, err
 	}
 
@@ -115,18 +134,65 @@ func CreateResourcesContext(kubegres *postgresV1.Kubegres,
 
 	rc.SpecChecker = checker.CreateSpecChecker(rc.KubegresContext, rc.ResourcesStates)
 
+	rc.KubegresConditionsUpdater = status2.CreateKubegresConditionsUpdater(rc.KubegresContext, rc.ResourcesStates, rc.BlockingOperation)
+	rc.KubegresInstancesUpdater = status2.CreateKubegresInstancesUpdater(rc.KubegresContext, rc.ResourcesStates)
+	rc.KubegresMetricsUpdater = status2.CreateKubegresMetricsUpdater(rc.KubegresContext, rc.ResourcesStates, rc.BlockingOperation)
+	rc.KubegresBackUpUpdater = status2.CreateKubegresBackUpUpdater(rc.KubegresContext, rc.ResourcesStates)
+
 	rc.CustomConfigSpecHelper = template.CreateCustomConfigSpecHelper(rc.KubegresContext, rc.ResourcesStates)
+	rc.WalArchiveSpecHelper = template.CreateWalArchiveSpecHelper(rc.KubegresContext)
+	rc.ExtraContainersSpecHelper = template.CreateExtraContainersSpecHelper(rc.KubegresContext)
 
 	resourceTemplateLoader := template.ResourceTemplateLoader{}
-	rc.ResourcesCreatorFromTemplate = template.CreateResourcesCreatorFromTemplate(rc.KubegresContext, rc.CustomConfigSpecHelper, resourceTemplateLoader)
+	rc.ResourcesCreatorFromTemplate = template.CreateResourcesCreatorFromTemplate(rc.KubegresContext, rc.CustomConfigSpecHelper, rc.WalArchiveSpecHelper, rc.ExtraContainersSpecHelper, resourceTemplateLoader)
 
 	addResourcesCountSpecEnforcers(rc)
 	addStatefulSetSpecEnforcers(rc)
+	addReplicationSpecEnforcers(rc)
 	addBlockingOperationConfigs(rc)
 
 	return rc, nil
 }
 
+// CreateResourcesContextForAdmission creates the context used by the validating webhook of a Kubegres resource.
+// Unlike CreateResourcesContext, it does not update the spec of the Kubegres resource and it only loads the states
+// checked by the SpecChecker.
+func CreateResourcesContextForAdmission(kubegres *postgresV1.Kubegres,
+	ctx context.Context,
+	logger logr.Logger,
+	client client.Client,
+	recorder record.EventRecorder) (rc *ResourcesContext, err error) {
+
+	setReplicaFieldToZeroIfNil(kubegres)
+
+	rc = &ResourcesContext{}
+
+	rc.LogWrapper = log.LogWrapper[*postgresV1.Kubegres]{Resource: kubegres, Logger: logger, Recorder: recorder}
+
+	rc.KubegresStatusWrapper = &status.KubegresStatusWrapper{
+		Kubegres: kubegres,
+		Ctx:      ctx,
+		Log:      rc.LogWrapper,
+		Client:   client,
+	}
+
+	rc.KubegresContext = ctx2.KubegresContext{
+		Kubegres: kubegres,
+		Status:   rc.KubegresStatusWrapper,
+		Ctx:      ctx,
+		Log:      rc.LogWrapper,
+		Client:   client,
+	}
+
+	if rc.ResourcesStates, err = states.LoadResourcesStatesForSpecCheck(rc.KubegresContext); err != nil {
+		return nil, err
+	}
+
+	rc.SpecChecker = checker.CreateSpecCheckerForAdmission(rc.KubegresContext, rc.ResourcesStates)
+
+	return rc, nil
+}
+
 func setReplicaFieldToZeroIfNil(kubegres *postgresV1.Kubegres) {
 	if kubegres.Spec.Replicas != nil {
 		return
@@ -138,19 +204,26 @@ func setReplicaFieldToZeroIfNil(kubegres *postgresV1.Kubegres) {
 
 func addResourcesCountSpecEnforcers(rc *ResourcesContext) {
 
-	rc.PrimaryToReplicaFailOver = failover.CreatePrimaryToReplicaFailOver(rc.KubegresContext, rc.ResourcesStates, rc.BlockingOperation)
+	rc.DbPvcPolicy = failover.CreateDbPvcPolicy(rc.KubegresContext, rc.ResourcesStates)
+	rc.PrimaryToReplicaFailOver = failover.CreatePrimaryToReplicaFailOver(rc.KubegresContext, rc.ResourcesStates, rc.BlockingOperation, rc.DbPvcPolicy)
+	rc.PrimaryToReplicaSwitchover = failover.CreatePrimaryToReplicaSwitchover(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation, rc.PostgresClient)
 	rc.PrimaryDbCountSpecEnforcer = statefulset.CreatePrimaryDbCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation, rc.PrimaryToReplicaFailOver)
-	rc.ReplicaDbCountSpecEnforcer = statefulset.CreateReplicaDbCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation)
-	rc.StatefulSetCountSpecEnforcer = resources_count_spec.CreateStatefulSetCountSpecEnforcer(rc.PrimaryDbCountSpecEnforcer, rc.ReplicaDbCountSpecEnforcer)
+	rc.ReplicaDbCountSpecEnforcer = statefulset.CreateReplicaDbCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation, rc.DbPvcPolicy)
+	rc.MajorVersionUpgradeEnforcer = statefulset.CreateMajorVersionUpgradeEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation)
+	rc.StatefulSetCountSpecEnforcer = resources_count_spec.CreateStatefulSetCountSpecEnforcer(rc.MajorVersionUpgradeEnforcer, rc.PrimaryToReplicaSwitchover, rc.PrimaryDbCountSpecEnforcer, rc.ReplicaDbCountSpecEnforcer)
 
 	rc.BaseConfigMapCountSpecEnforcer = resources_count_spec.CreateBaseConfigMapCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.BlockingOperation)
 	rc.ServicesCountSpecEnforcer = resources_count_spec.CreateServicesCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate)
+	rc.ReplicaReadyServiceCountSpecEnforcer = resources_count_spec.CreateReplicaReadyServiceCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate)
+	rc.PoolerCountSpecEnforcer = resources_count_spec.CreatePoolerCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate, rc.PostgresClient)
 	rc.BackUpCronJobCountSpecEnforcer = resources_count_spec.CreateBackUpCronJobCountSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.ResourcesCreatorFromTemplate)
 
 	rc.ResourcesCountSpecEnforcer = resources_count_spec.ResourcesCountSpecEnforcer{}
 	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.BaseConfigMapCountSpecEnforcer)
 	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.StatefulSetCountSpecEnforcer)
 	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.ServicesCountSpecEnforcer)
+	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.ReplicaReadyServiceCountSpecEnforcer)
+	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.PoolerCountSpecEnforcer)
 	rc.ResourcesCountSpecEnforcer.AddSpecEnforcer(&rc.BackUpCronJobCountSpecEnforcer)
 }
 
@@ -159,6 +232,7 @@ func addStatefulSetSpecEnforcers(rc *ResourcesContext) {
 	portSpecEnforcer := statefulset_spec.CreatePortSpecEnforcer(rc.KubegresContext, rc.ResourcesStates)
 	storageClassSizeSpecEnforcer := statefulset_spec.CreateStorageClassSizeSpecEnforcer(rc.KubegresContext, rc.ResourcesStates)
 	customConfigSpecEnforcer := statefulset_spec.CreateCustomConfigSpecEnforcer(rc.CustomConfigSpecHelper)
+	walArchiveSpecEnforcer := statefulset_spec.CreateWalArchiveSpecEnforcer(rc.WalArchiveSpecHelper)
 	affinitySpecEnforcer := statefulset_spec.CreateAffinitySpecEnforcer(rc.KubegresContext)
 	tolerationsSpecEnforcer := statefulset_spec.CreateTolerationsSpecEnforcer(rc.KubegresContext)
 	resourcesSpecEnforcer := statefulset_spec.CreateResourcesSpecEnforcer(rc.KubegresContext)
@@ -166,12 +240,16 @@ func addStatefulSetSpecEnforcers(rc *ResourcesContext) {
 	securityContextSpecEnforcer := statefulset_spec.CreateSecurityContextSpecEnforcer(rc.KubegresContext)
 	livenessProbeSpecEnforcer := statefulset_spec.CreateLivenessProbeSpecEnforcer(rc.KubegresContext)
 	readinessProbeSpecEnforcer := statefulset_spec.CreateReadinessProbeSpecEnforcer(rc.KubegresContext)
+	extraContainersSpecEnforcer := statefulset_spec.CreateExtraContainersSpecEnforcer(rc.ExtraContainersSpecHelper)
+	clusterNameSpecEnforcer := statefulset_spec.CreateClusterNameSpecEnforcer(rc.KubegresContext)
+	baseConfigSpecEnforcer := statefulset_spec.CreateBaseConfigSpecEnforcer(rc.ResourcesStates, rc.ResourcesCreatorFromTemplate)
 
 	rc.StatefulSetsSpecsEnforcer = statefulset_spec.CreateStatefulSetsSpecsEnforcer(rc.KubegresContext)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&imageSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&portSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&storageClassSizeSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&customConfigSpecEnforcer)
+	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&walArchiveSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&affinitySpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&tolerationsSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&resourcesSpecEnforcer)
@@ -179,19 +257,39 @@ func addStatefulSetSpecEnforcers(rc *ResourcesContext) {
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&securityContextSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&livenessProbeSpecEnforcer)
 	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&readinessProbeSpecEnforcer)
+	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&extraContainersSpecEnforcer)
+	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&clusterNameSpecEnforcer)
+	rc.StatefulSetsSpecsEnforcer.AddSpecEnforcer(&baseConfigSpecEnforcer)
 
 	rc.AllStatefulSetsSpecEnforcer = statefulset_spec.CreateAllStatefulSetsSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.BlockingOperation, rc.StatefulSetsSpecsEnforcer)
 }
 
+func addReplicationSpecEnforcers(rc *ResourcesContext) {
+	rc.SynchronousStandbysSpecEnforcer = replication_spec.CreateSynchronousStandbysSpecEnforcer(rc.KubegresContext, rc.ResourcesStates, rc.PostgresClient)
+}
+
 func addBlockingOperationConfigs(rc *ResourcesContext) {
 
 	rc.BlockingOperation.AddConfig(rc.BaseConfigMapCountSpecEnforcer.CreateOperationConfig())
+	rc.BlockingOperation.AddConfig(rc.BaseConfigMapCountSpecEnforcer.CreateOperationConfigForUpdating())
 
 	rc.BlockingOperation.AddConfig(rc.PrimaryDbCountSpecEnforcer.CreateOperationConfigForPrimaryDbDeploying())
 	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaFailOver.CreateOperationConfigWaitingBeforeForFailingOver())
 	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaFailOver.CreateOperationConfigForFailingOver())
 
+	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaSwitchover.CreateOperationConfigForFencingPrimary())
+	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaSwitchover.CreateOperationConfigForStoppingPrimary())
+	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaSwitchover.CreateOperationConfigForCheckingPrimary())
+	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaSwitchover.CreateOperationConfigForPromotingReplica())
+	rc.BlockingOperation.AddConfig(rc.PrimaryToReplicaSwitchover.CreateOperationConfigForReattachingPrimary())
+
+	rc.BlockingOperation.AddConfig(rc.MajorVersionUpgradeEnforcer.CreateOperationConfigForStoppingPrimary())
+	rc.BlockingOperation.AddConfig(rc.MajorVersionUpgradeEnforcer.CreateOperationConfigForRunningJob())
+	rc.BlockingOperation.AddConfig(rc.MajorVersionUpgradeEnforcer.CreateOperationConfigForStartingPrimary())
+	rc.BlockingOperation.AddConfig(rc.MajorVersionUpgradeEnforcer.CreateOperationConfigForRollingBack())
+
 	rc.BlockingOperation.AddConfig(rc.ReplicaDbCountSpecEnforcer.CreateOperationConfigForReplicaDbDeploying())
+	rc.BlockingOperation.AddConfig(rc.ReplicaDbCountSpecEnforcer.CreateOperationConfigForReplicaDbRejoining())
 	rc.BlockingOperation.AddConfig(rc.ReplicaDbCountSpecEnforcer.CreateOperationConfigForReplicaDbUndeploying())
 
 	rc.BlockingOperation.AddConfig(rc.AllStatefulSetsSpecEnforcer.CreateOperationConfigForStatefulSetSpecUpdating())
@@ -25,7 +25,8 @@ type RestoreJobContext struct {
 	RestoreResourcesStatesLogger log2.RestoreResourcesStatesLogger
 	RestoreSpecChecker           checker.RestoreSpecChecker
 
-	ResourcesCountSpecEnforcer resources_count_spec.ResourcesCountSpecEnforcer
+	ResourcesCountSpecEnforcer   resources_count_spec.ResourcesCountSpecEnforcer
+	FailedRestoreJobSpecEnforcer *resources_count_spec.FailedRestoreJobSpecEnforcer
 }
 
 func CreateRestoreJobContext(kubegresRestore *v1.KubegresRestore,
@@ -64,12 +65,18 @@ func CreateRestoreJobContext(kubegresRestore *v1.KubegresRestore,
 
 func (r *RestoreJobContext) addResourcesCountSpecEnforcers(sourceKubegresSpec v1.KubegresSpec) {
 	r.ResourcesCountSpecEnforcer = resources_count_spec.ResourcesCountSpecEnforcer{}
+	failedRestoreJobSpecEnforcer := resources_count_spec.CreateFailedRestoreJobSpecEnforcer(r.KubegresRestoreContext, r.RestoreResourceStates)
 	fileCheckerPodCountSpecEnforcer := resources_count_spec.CreateFileCheckerPodCountSpecEnforcer(r.KubegresRestoreContext, r.RestoreResourceStates)
 	kubegresCountSpecEnforcer := resources_count_spec.CreateKubegresCountSpecEnforcer(r.KubegresRestoreContext, r.RestoreResourceStates, sourceKubegresSpec)
 	jobCountSpecEnforcer := resources_count_spec.CreateJobCountSpecEnforcer(r.KubegresRestoreContext, r.RestoreResourceStates, sourceKubegresSpec)
+	verificationJobCountSpecEnforcer := resources_count_spec.CreateVerificationJobCountSpecEnforcer(r.KubegresRestoreContext, r.RestoreResourceStates, sourceKubegresSpec)
+
+	r.FailedRestoreJobSpecEnforcer = &failedRestoreJobSpecEnforcer
 
 	r.ResourcesCountSpecEnforcer = resources_count_spec.ResourcesCountSpecEnforcer{}
+	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(r.FailedRestoreJobSpecEnforcer)
 	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(&fileCheckerPodCountSpecEnforcer)
+	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(&verificationJobCountSpecEnforcer)
 	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(&kubegresCountSpecEnforcer)
 	r.ResourcesCountSpecEnforcer.AddSpecEnforcer(&jobCountSpecEnforcer)
 }
@@ -0,0 +1,110 @@
+package status
+
+import (
+	"context"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type BackupStatusWrapper struct {
+	KubegresBackup       *v1.KubegresBackup
+	Ctx                  context.Context
+	Log                  log.LogWrapper[*v1.KubegresBackup]
+	Client               client.Client
+	statusFieldsToUpdate map[string]interface{}
+}
+
+func (r *BackupStatusWrapper) GetPhase() string {
+	return r.KubegresBackup.Status.Phase
+}
+
+func (r *BackupStatusWrapper) SetPhase(value string) {
+	if r.KubegresBackup.Status.Phase != value {
+		r.addStatusFieldToUpdate("Phase", value)
+		r.KubegresBackup.Status.Phase = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetJobName(value string) {
+	if r.KubegresBackup.Status.JobName != value {
+		r.addStatusFieldToUpdate("JobName", value)
+		r.KubegresBackup.Status.JobName = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetStartTime(value *metav1.Time) {
+	if !reflect.DeepEqual(r.KubegresBackup.Status.StartTime, value) {
+		r.addStatusFieldToUpdate("StartTime", value)
+		r.KubegresBackup.Status.StartTime = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetCompletionTime(value *metav1.Time) {
+	if !reflect.DeepEqual(r.KubegresBackup.Status.CompletionTime, value) {
+		r.addStatusFieldToUpdate("CompletionTime", value)
+		r.KubegresBackup.Status.CompletionTime = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetFileName(value string) {
+	if r.KubegresBackup.Status.FileName != value {
+		r.addStatusFieldToUpdate("FileName", value)
+		r.KubegresBackup.Status.FileName = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetSizeBytes(value int64) {
+	if r.KubegresBackup.Status.SizeBytes != value {
+		r.addStatusFieldToUpdate("SizeBytes", value)
+		r.KubegresBackup.Status.SizeBytes = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetFile(value *v1.File) {
+	if !reflect.DeepEqual(r.KubegresBackup.Status.File, value) {
+		r.addStatusFieldToUpdate("File", value)
+		r.KubegresBackup.Status.File = value
+	}
+}
+
+func (r *BackupStatusWrapper) SetObjectStore(value *v1.ObjectStore) {
+	if !reflect.DeepEqual(r.KubegresBackup.Status.ObjectStore, value) {
+		r.addStatusFieldToUpdate("ObjectStore", value)
+		r.KubegresBackup.Status.ObjectStore = value
+	}
+}
+
+func (r *BackupStatusWrapper) UpdateStatusIfChanged() error {
+	if r.statusFieldsToUpdate == nil {
+		return nil
+	}
+
+	for statusFieldName, statusFieldValue := range r.statusFieldsToUpdate {
+		r.Log.Info("Updating KubegresBackup' status: ",
+			"Field", statusFieldName, "New value", statusFieldValue)
+
+	}
+
+	err := r.Client.Status().Update(r.Ctx, r.KubegresBackup)
+
+	if err != nil {
+		r.Log.Error(err, "Failed to update KubegresBackup status")
+
+	} else {
+		r.Log.Info("KubegresBackup status updated.")
+	}
+
+	return err
+}
+
+func (r *BackupStatusWrapper) addStatusFieldToUpdate(statusFieldName string, newValue interface{}) {
+	if r.statusFieldsToUpdate == nil {
+		r.statusFieldsToUpdate = make(map[string]interface{})
+	}
+
+	r.statusFieldsToUpdate[statusFieldName] = newValue
+}
@@ -2,8 +2,11 @@ package status
 
 import (
 	"context"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -51,6 +54,77 @@ func (r *KubegresStatusWrapper) SetPreviousBlockingOperation(value v1.KubegresBl
 	r.Kubegres.Status.PreviousBlockingOperation = value
 }
 
+func (r *KubegresStatusWrapper) GetPhase() string {
+	return r.Kubegres.Status.Phase
+}
+
+func (r *KubegresStatusWrapper) SetPhase(value string) {
+	if r.Kubegres.Status.Phase != value {
+		r.addStatusFieldToUpdate("Phase", value)
+		r.Kubegres.Status.Phase = value
+	}
+}
+
+func (r *KubegresStatusWrapper) GetInstances() []v1.KubegresInstanceStatus {
+	return r.Kubegres.Status.Instances
+}
+
+func (r *KubegresStatusWrapper) SetInstances(value []v1.KubegresInstanceStatus) {
+	if !reflect.DeepEqual(r.Kubegres.Status.Instances, value) {
+		r.addStatusFieldToUpdate("Instances", value)
+		r.Kubegres.Status.Instances = value
+	}
+}
+
+func (r *KubegresStatusWrapper) GetPostgresMajorVersion() int32 {
+	return r.Kubegres.Status.PostgresMajorVersion
+}
+
+func (r *KubegresStatusWrapper) SetPostgresMajorVersion(value int32) {
+	if r.Kubegres.Status.PostgresMajorVersion != value {
+		r.addStatusFieldToUpdate("PostgresMajorVersion", value)
+		r.Kubegres.Status.PostgresMajorVersion = value
+	}
+}
+
+func (r *KubegresStatusWrapper) GetMajorVersionUpgrade() v1.KubegresMajorVersionUpgrade {
+	return r.Kubegres.Status.MajorVersionUpgrade
+}
+
+func (r *KubegresStatusWrapper) SetMajorVersionUpgrade(value v1.KubegresMajorVersionUpgrade) {
+	r.addStatusFieldToUpdate("MajorVersionUpgrade", value)
+	r.Kubegres.Status.MajorVersionUpgrade = value
+}
+
+func (r *KubegresStatusWrapper) GetBackUp() v1.KubegresBackUpStatus {
+	return r.Kubegres.Status.BackUp
+}
+
+func (r *KubegresStatusWrapper) SetBackUp(value v1.KubegresBackUpStatus) {
+	if !reflect.DeepEqual(r.Kubegres.Status.BackUp, value) {
+		r.addStatusFieldToUpdate("BackUp", value)
+		r.Kubegres.Status.BackUp = value
+	}
+}
+
+func (r *KubegresStatusWrapper) GetCondition(conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(r.Kubegres.Status.Conditions, conditionType)
+}
+
+func (r *KubegresStatusWrapper) SetCondition(value metav1.Condition) {
+	existingCondition := r.GetCondition(value.Type)
+	if existingCondition != nil &&
+		existingCondition.Status == value.Status &&
+		existingCondition.Reason == value.Reason &&
+		existingCondition.Message == value.Message &&
+		existingCondition.ObservedGeneration == value.ObservedGeneration {
+		return
+	}
+
+	r.addStatusFieldToUpdate("Conditions."+value.Type, value)
+	meta.SetStatusCondition(&r.Kubegres.Status.Conditions, value)
+}
+
 func (r *KubegresStatusWrapper) UpdateStatusIfChanged() error {
 	if r.statusFieldsToUpdate == nil {
 		return nil
@@ -2,7 +2,9 @@ package status
 
 import (
 	"context"
+	"reflect"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx/log"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,6 +40,42 @@ func (r *RestoreStatusWrapper) SetCurrentStage(value string) {
 	}
 }
 
+func (r *RestoreStatusWrapper) GetFailedAttempts() int32 {
+	return r.KubegresRestore.Status.FailedAttempts
+}
+
+func (r *RestoreStatusWrapper) SetFailedAttempts(value int32) {
+	if r.KubegresRestore.Status.FailedAttempts != value {
+		r.addStatusFieldToUpdate("FailedAttempts", value)
+		r.KubegresRestore.Status.FailedAttempts = value
+	}
+}
+
+func (r *RestoreStatusWrapper) GetLastFailureTime() *metav1.Time {
+	return r.KubegresRestore.Status.LastFailureTime
+}
+
+func (r *RestoreStatusWrapper) SetLastFailureTime(value *metav1.Time) {
+	if !reflect.DeepEqual(r.KubegresRestore.Status.LastFailureTime, value) {
+		r.addStatusFieldToUpdate("LastFailureTime", value)
+		r.KubegresRestore.Status.LastFailureTime = value
+	}
+}
+
+func (r *RestoreStatusWrapper) SetFailureReason(value string) {
+	if r.KubegresRestore.Status.FailureReason != value {
+		r.addStatusFieldToUpdate("FailureReason", value)
+		r.KubegresRestore.Status.FailureReason = value
+	}
+}
+
+func (r *RestoreStatusWrapper) SetVerificationResults(value []v1.RestoreVerificationResult) {
+	if !reflect.DeepEqual(r.KubegresRestore.Status.VerificationResults, value) {
+		r.addStatusFieldToUpdate("VerificationResults", value)
+		r.KubegresRestore.Status.VerificationResults = value
+	}
+}
+
 func (r *RestoreStatusWrapper) UpdateStatusIfChanged() error {
 	if r.statusFieldsToUpdate == nil {
 		return nil
@@ -24,19 +24,30 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctx2 "reactive-tech.io/kubegres/controllers/ctx"
 	"reactive-tech.io/kubegres/controllers/ctx/resources"
+	"reactive-tech.io/kubegres/controllers/metrics"
+	"reactive-tech.io/kubegres/controllers/states"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	kubegresv1 "reactive-tech.io/kubegres/api/v1"
 )
 
+const replicationStatesRefreshPeriod = 30 * time.Second
+
 // KubegresReconciler reconciles a Kubegres object
 type KubegresReconciler struct {
 	client.Client
@@ -55,7 +66,10 @@ type KubegresReconciler struct {
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="apps",resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="batch",resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="storage.k8s.io",resources=storageclasses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -70,6 +84,8 @@ func (r *KubegresReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	kubegres, err := r.getDeployedKubegresResource(ctx, req)
 	if err != nil {
 		r.Logger.Info("Kubegres resource does not exist")
+		metrics.DeleteKubegresMetrics(req.NamespacedName)
+		states.DeleteLastKnownPrimary(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
@@ -94,18 +110,37 @@ func (r *KubegresReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	specCheckResult, err := resourcesContext.SpecChecker.CheckSpec()
 	if err != nil {
 		return r.returnn(ctrl.Result{}, err, resourcesContext)
+	}
+
+	resourcesContext.KubegresConditionsUpdater.UpdateSpecValidCondition(specCheckResult)
 
-	} else if specCheckResult.HasSpecFatalError {
+	if specCheckResult.HasSpecFatalError {
 		return r.returnn(ctrl.Result{}, nil, resourcesContext)
 	}
 
-	return r.returnn(ctrl.Result{}, r.enforceSpec(resourcesContext), resourcesContext)
+	return r.returnn(r.requeueToRefreshReplicationStates(resourcesContext), r.enforceSpec(resourcesContext), resourcesContext)
+}
+
+// The replication lag of Replicas changes without any event from Kubernetes. We requeue regularly so that
+// the metrics based on the replication states are refreshed. The requeue also detects the overdue backups.
+// Without Replicas and without backups, nothing changes without an event from Kubernetes.
+func (r *KubegresReconciler) requeueToRefreshReplicationStates(resourcesContext *resources.ResourcesContext) ctrl.Result {
+	if resourcesContext.ResourcesStates.StatefulSets.Replicas.NbreDeployed == 0 &&
+		resourcesContext.KubegresContext.Kubegres.Spec.Backup.Schedule == "" {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: replicationStatesRefreshPeriod}
 }
 
 func (r *KubegresReconciler) returnn(result ctrl.Result,
 	err error,
 	resourcesContext *resources.ResourcesContext) (ctrl.Result, error) {
 
+	resourcesContext.KubegresConditionsUpdater.UpdateConditions()
+	resourcesContext.KubegresInstancesUpdater.UpdateInstances()
+	resourcesContext.KubegresMetricsUpdater.UpdateMetrics()
+	resourcesContext.KubegresBackUpUpdater.UpdateBackUp()
+
 	errStatusUpt := resourcesContext.KubegresContext.Status.UpdateStatusIfChanged()
 	if errStatusUpt != nil && err == nil {
 		return result, errStatusUpt
@@ -137,7 +172,12 @@ func (r *KubegresReconciler) enforceSpec(resourcesContext *resources.ResourcesCo
 		return err
 	}
 
-	return r.enforceAllStatefulSetsSpec(resourcesContext)
+	err = r.enforceAllStatefulSetsSpec(resourcesContext)
+	if err != nil {
+		return err
+	}
+
+	return r.enforceReplicationSpec(resourcesContext)
 }
 
 func (r *KubegresReconciler) enforceResourcesCountSpec(resourcesContext *resources.ResourcesContext) error {
@@ -148,6 +188,17 @@ func (r *KubegresReconciler) enforceAllStatefulSetsSpec(resourcesContext *resour
 	return resourcesContext.AllStatefulSetsSpecEnforcer.EnforceSpec()
 }
 
+func (r *KubegresReconciler) enforceReplicationSpec(resourcesContext *resources.ResourcesContext) error {
+	return resourcesContext.SynchronousStandbysSpecEnforcer.EnforceSpec()
+}
+
+// GetObjectsNotCached returns the kinds of resources which the client of the manager reads directly from the API
+// server. The Secrets and the ConfigMaps are not cached so that the operator does not keep in memory the ones of the
+// whole cluster. Their changes are watched with their metadata only.
+func GetObjectsNotCached() []client.Object {
+	return []client.Object{&core.Secret{}, &core.ConfigMap{}}
+}
+
 func (r *KubegresReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	ctx := context.Background()
@@ -159,6 +210,91 @@ func (r *KubegresReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kubegresv1.Kubegres{}).
 		Owns(&apps.StatefulSet{}).
+		Owns(&apps.Deployment{}).
 		Owns(&core.Service{}).
+		Owns(&core.ConfigMap{}, builder.OnlyMetadata).
+		Owns(&batch.CronJob{}).
+		Watches(&source.Kind{Type: &batch.Job{}}, handler.EnqueueRequestsFromMapFunc(r.mapBackUpJobToKubegres)).
+		Watches(&source.Kind{Type: &core.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.mapPodToKubegres)).
+		Watches(&source.Kind{Type: &core.PersistentVolumeClaim{}}, handler.EnqueueRequestsFromMapFunc(r.mapPvcToKubegres)).
+		Watches(&source.Kind{Type: &core.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToKubegres), builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &core.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.mapSecretToKubegres), builder.OnlyMetadata).
 		Complete(r)
 }
+
+// mapBackUpJobToKubegres returns the Kubegres resource owning the backup CronJob which created the given Job, so that
+// the status of the backups is updated as soon as a Job completes or fails.
+func (r *KubegresReconciler) mapBackUpJobToKubegres(job client.Object) []reconcile.Request {
+
+	for _, ownerReference := range job.GetOwnerReferences() {
+		if ownerReference.Kind != "CronJob" || !strings.HasPrefix(ownerReference.Name, ctx2.CronJobNamePrefix) {
+			continue
+		}
+
+		kubegresName := strings.TrimPrefix(ownerReference.Name, ctx2.CronJobNamePrefix)
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: job.GetNamespace(), Name: kubegresName}},
+		}
+	}
+
+	return nil
+}
+
+// mapPodToKubegres returns the Kubegres resource of the StatefulSet which created the given Pod, so that a failure
+// of a Primary or of a Replica is handled as soon as its Pod changes.
+func (r *KubegresReconciler) mapPodToKubegres(pod client.Object) []reconcile.Request {
+	return r.createRequest(pod.GetNamespace(), ctx2.GetKubegresNameOfPod(pod.(*core.Pod)))
+}
+
+// mapPvcToKubegres returns the Kubegres resource of the StatefulSet which created the given database PVC, so that
+// a change of its size or of its annotations is enforced straight away.
+func (r *KubegresReconciler) mapPvcToKubegres(pvc client.Object) []reconcile.Request {
+	return r.createRequest(pvc.GetNamespace(), ctx2.GetKubegresNameOfPvc(pvc.(*core.PersistentVolumeClaim)))
+}
+
+// mapConfigMapToKubegres returns the Kubegres resources using the given ConfigMap, such as the base ConfigMap or
+// the ConfigMap set in 'spec.customConfig'.
+func (r *KubegresReconciler) mapConfigMapToKubegres(configMap client.Object) []reconcile.Request {
+	return r.createRequestsForIndexedKubegres(configMap, ctx2.ConfigMapRefKey)
+}
+
+// mapSecretToKubegres returns the Kubegres resources using the given Secret, in their environment variables or to
+// access their S3 buckets.
+func (r *KubegresReconciler) mapSecretToKubegres(secret client.Object) []reconcile.Request {
+	return r.createRequestsForIndexedKubegres(secret, ctx2.SecretRefKey)
+}
+
+func (r *KubegresReconciler) createRequestsForIndexedKubegres(object client.Object, indexKey string) []reconcile.Request {
+
+	list := &kubegresv1.KubegresList{}
+	opts := []client.ListOption{
+		client.InNamespace(object.GetNamespace()),
+		client.MatchingFields{indexKey: object.GetName()},
+	}
+
+	if err := r.Client.List(context.Background(), list, opts...); err != nil {
+		r.Logger.Error(err, "Unable to load the Kubegres resources referencing a resource.",
+			"Namespace", object.GetNamespace(), "Name", object.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, kubegres := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: kubegres.Namespace, Name: kubegres.Name},
+		})
+	}
+
+	return requests
+}
+
+func (r *KubegresReconciler) createRequest(namespace, kubegresName string) []reconcile.Request {
+
+	if kubegresName == "" {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: namespace, Name: kubegresName}},
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	ctx2 "reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/resources"
+	"reactive-tech.io/kubegres/controllers/spec/defaultspec"
+)
+
+// KubegresWebhook sets the default values and checks the spec of a Kubegres resource before it is stored, so that
+// a request with an invalid spec is rejected instead of being reported by an error event once the resource is stored.
+// The controller keeps setting the default values and checking the spec, since the webhooks are optional.
+type KubegresWebhook struct {
+	Client   client.Client
+	Logger   logr.Logger
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:webhook:path=/mutate-kubegres-reactive-tech-io-v1-kubegres,mutating=true,failurePolicy=fail,sideEffects=None,groups=kubegres.reactive-tech.io,resources=kubegres,verbs=create;update,versions=v1,name=mkubegres.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-kubegres-reactive-tech-io-v1-kubegres,mutating=false,failurePolicy=fail,sideEffects=None,groups=kubegres.reactive-tech.io,resources=kubegres,verbs=create;update,versions=v1,name=vkubegres.kb.io,admissionReviewVersions=v1
+
+func (r *KubegresWebhook) Default(ctx context.Context, obj runtime.Object) error {
+
+	kubegres, ok := obj.(*kubegresv1.Kubegres)
+	if !ok {
+		return errors.New("The admission request does not contain a Kubegres resource")
+	}
+
+	kubegresContext := ctx2.KubegresContext{
+		Kubegres: kubegres,
+		Ctx:      ctx,
+		Log:      log.LogWrapper[*kubegresv1.Kubegres]{Resource: kubegres, Logger: r.Logger, Recorder: r.Recorder},
+		Client:   r.Client,
+	}
+
+	defaultStorageClass := defaultspec.CreateDefaultStorageClass(kubegresContext)
+	return defaultspec.SetDefaultForUndefinedSpecValuesInAdmission(kubegresContext, defaultStorageClass)
+}
+
+func (r *KubegresWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+
+	kubegres, ok := obj.(*kubegresv1.Kubegres)
+	if !ok {
+		return errors.New("The admission request does not contain a Kubegres resource")
+	}
+
+	errorMessages, err := r.checkSpec(ctx, kubegres)
+	if err != nil {
+		return err
+	}
+
+	return createAdmissionError(ctx2.KindKubegres, errorMessages)
+}
+
+// When a Kubegres resource is updated, only the errors which did not exist before the update are rejected. Otherwise,
+// any updates would be rejected once the spec is invalid, including the updates of the controller rolling back the spec.
+func (r *KubegresWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+
+	oldKubegres, ok := oldObj.(*kubegresv1.Kubegres)
+	if !ok {
+		return errors.New("The admission request does not contain a Kubegres resource")
+	}
+
+	newKubegres, ok := newObj.(*kubegresv1.Kubegres)
+	if !ok {
+		return errors.New("The admission request does not contain a Kubegres resource")
+	}
+
+	if newKubegres.DeletionTimestamp != nil || reflect.DeepEqual(oldKubegres.Spec, newKubegres.Spec) {
+		return nil
+	}
+
+	errorMessages, err := r.checkSpec(ctx, newKubegres)
+	if err != nil {
+		return err
+	}
+
+	if len(errorMessages) > 0 && oldKubegres.Spec.Database.StorageClassName != nil {
+		previousErrorMessages, err := r.checkSpec(ctx, oldKubegres)
+		if err != nil {
+			return err
+		}
+		errorMessages = getNewErrorMessages(errorMessages, previousErrorMessages)
+	}
+
+	return createAdmissionError(ctx2.KindKubegres, errorMessages)
+}
+
+func (r *KubegresWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (r *KubegresWebhook) checkSpec(ctx context.Context, kubegres *kubegresv1.Kubegres) ([]string, error) {
+
+	resourcesContext, err := resources.CreateResourcesContextForAdmission(kubegres.DeepCopy(), ctx, r.Logger, r.Client, r.Recorder)
+	if err != nil {
+		return nil, err
+	}
+
+	return resourcesContext.SpecChecker.CheckSpecForAdmission()
+}
+
+// SetupWebhookWithManager registers the defaulting and the validating webhooks with the Manager.
+func (r *KubegresWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kubegresv1.Kubegres{}).
+		WithDefaulter(r).
+		WithValidator(r).
+		Complete()
+}
+
+func getNewErrorMessages(errorMessages, previousErrorMessages []string) []string {
+
+	previousErrors := make(map[string]bool)
+	for _, previousErrorMessage := range previousErrorMessages {
+		previousErrors[previousErrorMessage] = true
+	}
+
+	var newErrorMessages []string
+	for _, errorMessage := range errorMessages {
+		if !previousErrors[errorMessage] {
+			newErrorMessages = append(newErrorMessages, errorMessage)
+		}
+	}
+
+	return newErrorMessages
+}
+
+func createAdmissionError(kind string, errorMessages []string) error {
+	if len(errorMessages) == 0 {
+		return nil
+	}
+	return errors.New("The spec of the " + kind + " resource is invalid. " + strings.Join(errorMessages, " "))
+}
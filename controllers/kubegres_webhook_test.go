@@ -0,0 +1,192 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	storage "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+const (
+	externalTrafficPolicyErrMsgToTest    = "'spec.services.primary.externalTrafficPolicy' is set"
+	loadBalancerSourceRangesErrMsgToTest = "'spec.services.primary.loadBalancerSourceRanges' is set"
+)
+
+func TestDefaultSetsUndefinedSpecValues(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	kubegres := createDefaultedKubegresToTest(t, webhook)
+
+	if kubegres.Spec.Port != ctx.DefaultContainerPortNumber {
+		t.Errorf("Expected the default port %d, got %d", ctx.DefaultContainerPortNumber, kubegres.Spec.Port)
+	}
+	if kubegres.Spec.Database.StorageClassName == nil || *kubegres.Spec.Database.StorageClassName != "standard" {
+		t.Errorf("Expected the default storage class 'standard', got %v", kubegres.Spec.Database.StorageClassName)
+	}
+}
+
+func TestValidateCreateAcceptsValidSpec(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	kubegres := createDefaultedKubegresToTest(t, webhook)
+
+	if err := webhook.ValidateCreate(context.Background(), kubegres); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateCreateRejectsInvalidSpec(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	kubegres := createDefaultedKubegresToTest(t, webhook)
+	kubegres.Spec.Services.Primary.ExternalTrafficPolicy = core.ServiceExternalTrafficPolicyTypeLocal
+
+	err := webhook.ValidateCreate(context.Background(), kubegres)
+
+	if err == nil || !strings.Contains(err.Error(), externalTrafficPolicyErrMsgToTest) {
+		t.Errorf("Expected an error containing \"%s\", got: %v", externalTrafficPolicyErrMsgToTest, err)
+	}
+}
+
+func TestValidateUpdateRejectsNewErrorsOnly(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	oldKubegres := createDefaultedKubegresToTest(t, webhook)
+	oldKubegres.Spec.Services.Primary.ExternalTrafficPolicy = core.ServiceExternalTrafficPolicyTypeLocal
+
+	newKubegres := oldKubegres.DeepCopy()
+	newKubegres.Spec.Services.Primary.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+
+	err := webhook.ValidateUpdate(context.Background(), oldKubegres, newKubegres)
+
+	if err == nil || !strings.Contains(err.Error(), loadBalancerSourceRangesErrMsgToTest) {
+		t.Fatalf("Expected an error containing \"%s\", got: %v", loadBalancerSourceRangesErrMsgToTest, err)
+	}
+	if strings.Contains(err.Error(), externalTrafficPolicyErrMsgToTest) {
+		t.Errorf("Expected the error which existed before the update to not be rejected, got: %v", err)
+	}
+}
+
+func TestValidateUpdateAcceptsUpdateKeepingExistingErrors(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	oldKubegres := createDefaultedKubegresToTest(t, webhook)
+	oldKubegres.Spec.Services.Primary.ExternalTrafficPolicy = core.ServiceExternalTrafficPolicyTypeLocal
+
+	newKubegres := oldKubegres.DeepCopy()
+	newKubegres.Spec.Image = "postgres:16.1"
+
+	if err := webhook.ValidateUpdate(context.Background(), oldKubegres, newKubegres); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+// The spec of a Kubegres resource stored before the webhooks were enabled may not have its default values set. Its
+// errors cannot be compared with the errors of the updated spec.
+func TestValidateUpdateRejectsAllErrorsWhenPreviousSpecIsNotDefaulted(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	oldKubegres := createDefaultedKubegresToTest(t, webhook)
+	oldKubegres.Spec.Services.Primary.ExternalTrafficPolicy = core.ServiceExternalTrafficPolicyTypeLocal
+	oldKubegres.Spec.Database.StorageClassName = nil
+
+	newKubegres := oldKubegres.DeepCopy()
+	newKubegres.Spec.Database.StorageClassName = createDefaultedKubegresToTest(t, webhook).Spec.Database.StorageClassName
+
+	err := webhook.ValidateUpdate(context.Background(), oldKubegres, newKubegres)
+
+	if err == nil || !strings.Contains(err.Error(), externalTrafficPolicyErrMsgToTest) {
+		t.Errorf("Expected an error containing \"%s\", got: %v", externalTrafficPolicyErrMsgToTest, err)
+	}
+}
+
+func TestValidateUpdateAcceptsDeletedResourceAndUnchangedSpec(t *testing.T) {
+	webhook := createKubegresWebhookToTest()
+	oldKubegres := createDefaultedKubegresToTest(t, webhook)
+	oldKubegres.Spec.Services.Primary.ExternalTrafficPolicy = core.ServiceExternalTrafficPolicyTypeLocal
+
+	newKubegres := oldKubegres.DeepCopy()
+	if err := webhook.ValidateUpdate(context.Background(), oldKubegres, newKubegres); err != nil {
+		t.Errorf("Expected an unchanged spec to be accepted, got: %v", err)
+	}
+
+	deletionTimestamp := metav1.Now()
+	newKubegres.DeletionTimestamp = &deletionTimestamp
+	newKubegres.Spec.Services.Primary.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	if err := webhook.ValidateUpdate(context.Background(), oldKubegres, newKubegres); err != nil {
+		t.Errorf("Expected a resource being deleted to be accepted, got: %v", err)
+	}
+}
+
+func TestGetNewErrorMessages(t *testing.T) {
+	newErrorMessages := getNewErrorMessages([]string{"error 1", "error 2", "error 3"}, []string{"error 2", "error 4"})
+
+	if len(newErrorMessages) != 2 || newErrorMessages[0] != "error 1" || newErrorMessages[1] != "error 3" {
+		t.Errorf("Expected the errors [error 1 error 3], got %v", newErrorMessages)
+	}
+	if getNewErrorMessages([]string{"error 1"}, []string{"error 1"}) != nil {
+		t.Error("Expected no new error when all the errors existed before")
+	}
+}
+
+func createKubegresWebhookToTest() *KubegresWebhook {
+	defaultStorageClass := &storage.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "standard", Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}},
+		Provisioner: "kubernetes.io/no-provisioner",
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kubegresv1.AddToScheme(scheme)
+
+	return &KubegresWebhook{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(defaultStorageClass).Build(),
+		Logger:   logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+// createDefaultedKubegresToTest returns a valid Kubegres resource with the default values set by the webhook.
+func createDefaultedKubegresToTest(t *testing.T, webhook *KubegresWebhook) *kubegresv1.Kubegres {
+	var replicas int32 = 3
+	kubegres := &kubegresv1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: kubegresv1.KubegresSpec{
+			Replicas: &replicas,
+			Image:    "postgres:16",
+			Database: kubegresv1.KubegresDatabase{Size: "200Mi"},
+			Env: []core.EnvVar{
+				{Name: ctx.EnvVarNameOfPostgresSuperUserPsw, Value: "superUserPassword"},
+				{Name: ctx.EnvVarNameOfPostgresReplicationUserPsw, Value: "replicationUserPassword"},
+			},
+		},
+	}
+
+	if err := webhook.Default(context.Background(), kubegres); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return kubegres
+}
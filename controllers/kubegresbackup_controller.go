@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/resources"
+)
+
+// KubegresBackupReconciler reconciles a KubegresBackup object
+type KubegresBackupReconciler struct {
+	client.Client
+	Logger   logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresbackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresbackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresbackups/finalizers,verbs=update
+
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegres,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="batch",resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile takes a single backup of the Kubegres cluster referenced by a KubegresBackup resource, by running
+// a Job created from the template of the cluster's backup CronJob.
+func (r *KubegresBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	kubegresBackup, err := r.getDeployedKubegresBackupResource(ctx, req)
+	if err != nil {
+		return ctrl.Result{}, nil
+	}
+
+	backupJobContext, err := resources.CreateBackupJobContext(kubegresBackup, ctx, r.Logger, r.Client, r.Recorder)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if backupJobContext.KubegresBackupContext.IsCompleted() {
+		return ctrl.Result{}, nil
+	}
+
+	specCheckResult := backupJobContext.BackupSpecChecker.CheckSpec()
+	if specCheckResult.HasSpecFatalError {
+		if backupJobContext.BackupStatusWrapper.GetPhase() == "" {
+			backupJobContext.BackupStatusWrapper.SetPhase(kubegresv1.KubegresBackupPhasePending)
+		}
+		return r.returnn(ctrl.Result{}, nil, backupJobContext)
+	}
+
+	return r.returnn(ctrl.Result{}, backupJobContext.ResourcesCountSpecEnforcer.EnforceSpec(), backupJobContext)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KubegresBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kubegresv1.KubegresBackup{}, ctx.BackupJobKubegresTargetField, func(rawObj client.Object) []string {
+		kubegresBackup := rawObj.(*kubegresv1.KubegresBackup)
+
+		if kubegresBackup.Spec.ClusterName == "" {
+			return nil
+		}
+
+		return []string{kubegresBackup.Spec.ClusterName}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kubegresv1.KubegresBackup{}).
+		Watches(
+			&source.Kind{Type: &kubegresv1.Kubegres{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForKubegres),
+		).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+func (r *KubegresBackupReconciler) returnn(result ctrl.Result,
+	err error,
+	resourcesContext *resources.BackupJobContext) (ctrl.Result, error) {
+
+	errStatusUpt := resourcesContext.BackupStatusWrapper.UpdateStatusIfChanged()
+	if errStatusUpt != nil && err == nil {
+		return result, errStatusUpt
+	}
+
+	return result, err
+}
+
+func (r *KubegresBackupReconciler) getDeployedKubegresBackupResource(ctx context.Context, req ctrl.Request) (*kubegresv1.KubegresBackup, error) {
+	// Allow Kubernetes to update its system.
+	time.Sleep(1 * time.Second)
+
+	kubegresBackup := &kubegresv1.KubegresBackup{}
+	err := r.Client.Get(ctx, req.NamespacedName, kubegresBackup)
+	if err == nil {
+		return kubegresBackup, nil
+	}
+
+	r.Logger.Info("KubegresBackup resource does not exist")
+	return &kubegresv1.KubegresBackup{}, err
+}
+
+// findObjectsForKubegres returns the KubegresBackup resources which are waiting for the given Kubegres to be
+// deployed or to have a backup CronJob.
+func (r *KubegresBackupReconciler) findObjectsForKubegres(kubegres client.Object) []reconcile.Request {
+	kubegresBackupList := &kubegresv1.KubegresBackupList{}
+	listOps := &client.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector(ctx.BackupJobKubegresTargetField, kubegres.GetName()),
+		Namespace:     kubegres.GetNamespace(),
+	}
+	err := r.Client.List(context.Background(), kubegresBackupList, listOps)
+	if err != nil {
+		r.Logger.Error(err, "Unable to list all kubegres backup resources", "Kubegres", kubegres.GetName())
+		return []reconcile.Request{}
+	}
+
+	var requests []reconcile.Request
+	for _, item := range kubegresBackupList.Items {
+		if item.Status.Phase == kubegresv1.KubegresBackupPhaseSucceeded || item.Status.Phase == kubegresv1.KubegresBackupPhaseFailed {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      item.GetName(),
+				Namespace: item.GetNamespace(),
+			},
+		})
+	}
+
+	return requests
+}
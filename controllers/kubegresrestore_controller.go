@@ -51,8 +51,9 @@ type KubegresRestoreReconciler struct {
 //+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresrestores/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresrestores/finalizers,verbs=update
 
-//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegres,verbs=get;list;watch;create;update;patch
-//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegres,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kubegres.reactive-tech.io,resources=kubegresbackups,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
 //+kubebuilder:rbac:groups="apps",resources=statefulsets,verbs=get;list;watch
@@ -97,7 +98,18 @@ func (r *KubegresRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	restoreJobContext.RestoreResourcesStatesLogger.Log()
 
 	// ### 3. Enforce resources
-	return r.returnn(ctrl.Result{}, restoreJobContext.ResourcesCountSpecEnforcer.EnforceSpec(), restoreJobContext)
+	err = restoreJobContext.ResourcesCountSpecEnforcer.EnforceSpec()
+	return r.returnn(r.requeueToRetryFailedRestore(restoreJobContext), err, restoreJobContext)
+}
+
+// A failed restore job is rerun once the backoff of the field 'retryPolicy' has elapsed. Since there isn't any
+// event from Kubernetes at that time, we requeue.
+func (r *KubegresRestoreReconciler) requeueToRetryFailedRestore(restoreJobContext *resources.RestoreJobContext) ctrl.Result {
+	nbreSecondsLeftBeforeRetry := restoreJobContext.FailedRestoreJobSpecEnforcer.GetNbreSecondsLeftBeforeRetry()
+	if nbreSecondsLeftBeforeRetry > 0 {
+		return ctrl.Result{RequeueAfter: time.Duration(nbreSecondsLeftBeforeRetry) * time.Second}
+	}
+	return ctrl.Result{}
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -114,12 +126,28 @@ func (r *KubegresRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kubegresv1.KubegresRestore{}, ctx.RestoreJobBackupSourceField, func(rawObj client.Object) []string {
+		kubegresRestore := rawObj.(*kubegresv1.KubegresRestore)
+
+		if kubegresRestore.Spec.DataSource.Backup == "" {
+			return nil
+		}
+
+		return []string{kubegresRestore.Spec.DataSource.Backup}
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kubegresv1.KubegresRestore{}).
 		Watches(
 			&source.Kind{Type: &kubegresv1.Kubegres{}},
 			handler.EnqueueRequestsFromMapFunc(r.findObjectsForKubegres),
 		).
+		Watches(
+			&source.Kind{Type: &kubegresv1.KubegresBackup{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForKubegresBackup),
+		).
 		Owns(&batchv1.Job{}).
 		Owns(&core.Pod{}).
 		Complete(r)
@@ -176,3 +204,29 @@ func (r *KubegresRestoreReconciler) findObjectsForKubegres(kubegres client.Objec
 	r.Logger.Info("KUBEGRES UPDATE", "Kubegres name", kubegres.GetName(), "Requests", requests)
 	return requests
 }
+
+// findObjectsForKubegresBackup returns the KubegresRestore resources restoring the backup taken by the given
+// KubegresBackup, so that they are reconciled once the backup has succeeded.
+func (r *KubegresRestoreReconciler) findObjectsForKubegresBackup(kubegresBackup client.Object) []reconcile.Request {
+	kubegresRestoreList := &kubegresv1.KubegresRestoreList{}
+	listOps := &client.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector(ctx.RestoreJobBackupSourceField, kubegresBackup.GetName()),
+		Namespace:     kubegresBackup.GetNamespace(),
+	}
+	err := r.Client.List(context.Background(), kubegresRestoreList, listOps)
+	if err != nil {
+		r.Logger.Error(err, "Unable to list all kubegres restore resources", "KubegresBackup", kubegresBackup.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, len(kubegresRestoreList.Items))
+	for i, item := range kubegresRestoreList.Items {
+		requests[i] = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      item.GetName(),
+				Namespace: item.GetNamespace(),
+			},
+		}
+	}
+	return requests
+}
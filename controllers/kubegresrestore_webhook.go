@@ -0,0 +1,114 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx/resources"
+	"reactive-tech.io/kubegres/controllers/spec/checker"
+)
+
+// KubegresRestoreWebhook checks the spec of a KubegresRestore resource before it is stored, so that a request with
+// an invalid spec is rejected instead of being reported by an error event once the resource is stored.
+type KubegresRestoreWebhook struct {
+	Client   client.Client
+	Logger   logr.Logger
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:webhook:path=/validate-kubegres-reactive-tech-io-v1-kubegresrestore,mutating=false,failurePolicy=fail,sideEffects=None,groups=kubegres.reactive-tech.io,resources=kubegresrestores,verbs=create;update,versions=v1,name=vkubegresrestore.kb.io,admissionReviewVersions=v1
+
+func (r *KubegresRestoreWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+
+	kubegresRestore, ok := obj.(*kubegresv1.KubegresRestore)
+	if !ok {
+		return errors.New("The admission request does not contain a KubegresRestore resource")
+	}
+
+	errorMessages, err := r.checkSpec(ctx, kubegresRestore)
+	if err != nil {
+		return err
+	}
+
+	return createAdmissionError("KubegresRestore", errorMessages)
+}
+
+// As for a Kubegres resource, only the errors which did not exist before the update are rejected.
+func (r *KubegresRestoreWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+
+	oldKubegresRestore, ok := oldObj.(*kubegresv1.KubegresRestore)
+	if !ok {
+		return errors.New("The admission request does not contain a KubegresRestore resource")
+	}
+
+	newKubegresRestore, ok := newObj.(*kubegresv1.KubegresRestore)
+	if !ok {
+		return errors.New("The admission request does not contain a KubegresRestore resource")
+	}
+
+	if newKubegresRestore.DeletionTimestamp != nil || reflect.DeepEqual(oldKubegresRestore.Spec, newKubegresRestore.Spec) {
+		return nil
+	}
+
+	errorMessages, err := r.checkSpec(ctx, newKubegresRestore)
+	if err != nil {
+		return err
+	}
+
+	if len(errorMessages) > 0 {
+		previousErrorMessages, err := r.checkSpec(ctx, oldKubegresRestore)
+		if err != nil {
+			return err
+		}
+		errorMessages = getNewErrorMessages(errorMessages, previousErrorMessages)
+	}
+
+	return createAdmissionError("KubegresRestore", errorMessages)
+}
+
+func (r *KubegresRestoreWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (r *KubegresRestoreWebhook) checkSpec(ctx context.Context, kubegresRestore *kubegresv1.KubegresRestore) ([]string, error) {
+
+	restoreJobContext, err := resources.CreateRestoreJobContext(kubegresRestore.DeepCopy(), ctx, r.Logger, r.Client, r.Recorder)
+	if err != nil {
+		return nil, err
+	}
+
+	restoreSpecChecker := checker.CreateRestoreSpecCheckerForAdmission(restoreJobContext.KubegresRestoreContext, restoreJobContext.RestoreResourceStates)
+	return restoreSpecChecker.CheckSpecForAdmission()
+}
+
+// SetupWebhookWithManager registers the validating webhook with the Manager.
+func (r *KubegresRestoreWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kubegresv1.KubegresRestore{}).
+		WithValidator(r).
+		Complete()
+}
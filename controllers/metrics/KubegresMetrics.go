@@ -0,0 +1,243 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sync"
+)
+
+// The metrics below are registered in the registry of controller-runtime.
+// They are exposed by the metrics endpoint of the manager, which is configured in "main.go".
+
+const (
+	metricsNamespace = "kubegres"
+
+	labelNamespace   = "namespace"
+	labelName        = "name"
+	labelInstance    = "instance"
+	labelOperationId = "operation_id"
+	labelStepId      = "step_id"
+	labelOutcome     = "outcome"
+
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+var (
+	primaryReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "primary_ready",
+		Help:      "Whether the Primary PostgreSql of a Kubegres resource is ready (1) or not (0).",
+	}, []string{labelNamespace, labelName})
+
+	replicasReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "replicas_ready",
+		Help:      "Number of ready Replica PostgreSql of a Kubegres resource.",
+	}, []string{labelNamespace, labelName})
+
+	replicaLagBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "replica_lag_bytes",
+		Help:      "Number of bytes of WAL written by the Primary and not replayed yet by a Replica PostgreSql.",
+	}, []string{labelNamespace, labelName, labelInstance})
+
+	blockingOperationActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocking_operation_active",
+		Help:      "Set to 1 with the id and step of the blocking operation which is active for a Kubegres resource.",
+	}, []string{labelNamespace, labelName, labelOperationId, labelStepId})
+
+	blockingOperationSecondsToTimeOut = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocking_operation_seconds_to_timeout",
+		Help:      "Number of seconds left before the active blocking operation of a Kubegres resource times out.",
+	}, []string{labelNamespace, labelName})
+
+	failOversTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "failovers_total",
+		Help:      "Number of times a Replica PostgreSql was promoted as Primary.",
+	}, []string{labelNamespace, labelName})
+
+	blockingOperationTimeOutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocking_operation_timeouts_total",
+		Help:      "Number of blocking operations which timed-out, by operation id. Spec updates which timed-out have the operation id \"Enforcing StatefulSet's Spec\".",
+	}, []string{labelNamespace, labelName, labelOperationId})
+
+	backUpJobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "backup_jobs_total",
+		Help:      "Number of back-up Jobs which completed, by outcome.",
+	}, []string{labelNamespace, labelName, labelOutcome})
+
+	restoresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "restores_total",
+		Help:      "Number of restore Jobs which completed, by outcome.",
+	}, []string{labelNamespace, labelName, labelOutcome})
+)
+
+// Keeps track of the labels and back-up Jobs of each Kubegres resource, so that series which are not relevant anymore
+// can be deleted and each back-up Job is counted once.
+var (
+	mutex                       sync.Mutex
+	replicaLagInstances         = make(map[types.NamespacedName][]string)
+	blockingOperationLabels     = make(map[types.NamespacedName][]string)
+	timedOutOperationIds        = make(map[types.NamespacedName]map[string]bool)
+	countedBackUpJobsByKubegres = make(map[types.NamespacedName]map[types.UID]bool)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		primaryReady,
+		replicasReady,
+		replicaLagBytes,
+		blockingOperationActive,
+		blockingOperationSecondsToTimeOut,
+		failOversTotal,
+		blockingOperationTimeOutsTotal,
+		backUpJobsTotal,
+		restoresTotal,
+	)
+}
+
+func SetReadyInstances(kubegres types.NamespacedName, isPrimaryReady bool, nbreReadyReplicas int32) {
+	primaryReady.WithLabelValues(kubegres.Namespace, kubegres.Name).Set(boolToFloat(isPrimaryReady))
+	replicasReady.WithLabelValues(kubegres.Namespace, kubegres.Name).Set(float64(nbreReadyReplicas))
+}
+
+// SetReplicasLag sets the lag in bytes of each Replica, by StatefulSet name. The series of Replicas which are not in
+// the given map are deleted.
+func SetReplicasLag(kubegres types.NamespacedName, lagInBytesByInstance map[string]int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, instance := range replicaLagInstances[kubegres] {
+		if _, exists := lagInBytesByInstance[instance]; !exists {
+			replicaLagBytes.DeleteLabelValues(kubegres.Namespace, kubegres.Name, instance)
+		}
+	}
+
+	var instances []string
+	for instance, lagInBytes := range lagInBytesByInstance {
+		replicaLagBytes.WithLabelValues(kubegres.Namespace, kubegres.Name, instance).Set(float64(lagInBytes))
+		instances = append(instances, instance)
+	}
+	replicaLagInstances[kubegres] = instances
+}
+
+func SetBlockingOperation(kubegres types.NamespacedName, operationId, stepId string, nbreSecondsLeftBeforeTimeOut int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if previousLabels, exists := blockingOperationLabels[kubegres]; exists {
+		if previousLabels[0] == operationId && previousLabels[1] == stepId {
+			blockingOperationSecondsToTimeOut.WithLabelValues(kubegres.Namespace, kubegres.Name).Set(float64(nbreSecondsLeftBeforeTimeOut))
+			return
+		}
+		blockingOperationActive.DeleteLabelValues(kubegres.Namespace, kubegres.Name, previousLabels[0], previousLabels[1])
+		delete(blockingOperationLabels, kubegres)
+	}
+
+	if operationId != "" {
+		blockingOperationActive.WithLabelValues(kubegres.Namespace, kubegres.Name, operationId, stepId).Set(1)
+		blockingOperationLabels[kubegres] = []string{operationId, stepId}
+	}
+
+	blockingOperationSecondsToTimeOut.WithLabelValues(kubegres.Namespace, kubegres.Name).Set(float64(nbreSecondsLeftBeforeTimeOut))
+}
+
+func IncFailOvers(kubegres types.NamespacedName) {
+	failOversTotal.WithLabelValues(kubegres.Namespace, kubegres.Name).Inc()
+}
+
+func IncBlockingOperationTimeOuts(kubegres types.NamespacedName, operationId string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if timedOutOperationIds[kubegres] == nil {
+		timedOutOperationIds[kubegres] = make(map[string]bool)
+	}
+	timedOutOperationIds[kubegres][operationId] = true
+
+	blockingOperationTimeOutsTotal.WithLabelValues(kubegres.Namespace, kubegres.Name, operationId).Inc()
+}
+
+func IncRestores(kubegres types.NamespacedName, outcome string) {
+	restoresTotal.WithLabelValues(kubegres.Namespace, kubegres.Name, outcome).Inc()
+}
+
+// CountBackUpJobs increments the counter of back-up Jobs for each given completed Job which was not counted yet.
+// The given Jobs must be all Jobs which currently exist for the back-up CronJob of the Kubegres resource.
+func CountBackUpJobs(kubegres types.NamespacedName, outcomeByJobUid map[types.UID]string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	previouslyCountedJobs := countedBackUpJobsByKubegres[kubegres]
+	countedJobs := make(map[types.UID]bool)
+
+	for jobUid, outcome := range outcomeByJobUid {
+		if !previouslyCountedJobs[jobUid] {
+			backUpJobsTotal.WithLabelValues(kubegres.Namespace, kubegres.Name, outcome).Inc()
+		}
+		countedJobs[jobUid] = true
+	}
+
+	countedBackUpJobsByKubegres[kubegres] = countedJobs
+}
+
+// DeleteKubegresMetrics deletes all series of a Kubegres resource which does not exist anymore.
+func DeleteKubegresMetrics(kubegres types.NamespacedName) {
+	SetReplicasLag(kubegres, nil)
+	SetBlockingOperation(kubegres, "", "", 0)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	primaryReady.DeleteLabelValues(kubegres.Namespace, kubegres.Name)
+	replicasReady.DeleteLabelValues(kubegres.Namespace, kubegres.Name)
+	blockingOperationSecondsToTimeOut.DeleteLabelValues(kubegres.Namespace, kubegres.Name)
+	failOversTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name)
+	backUpJobsTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name, OutcomeSucceeded)
+	backUpJobsTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name, OutcomeFailed)
+	restoresTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name, OutcomeSucceeded)
+	restoresTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name, OutcomeFailed)
+
+	for operationId := range timedOutOperationIds[kubegres] {
+		blockingOperationTimeOutsTotal.DeleteLabelValues(kubegres.Namespace, kubegres.Name, operationId)
+	}
+
+	delete(replicaLagInstances, kubegres)
+	delete(countedBackUpJobsByKubegres, kubegres)
+	delete(timedOutOperationIds, kubegres)
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestSetReplicasLagDeletesTheSeriesOfRemovedReplicas(t *testing.T) {
+	kubegres := types.NamespacedName{Namespace: "default", Name: "lag"}
+	defer DeleteKubegresMetrics(kubegres)
+
+	SetReplicasLag(kubegres, map[string]int64{"lag-2": 100, "lag-3": 200})
+	SetReplicasLag(kubegres, map[string]int64{"lag-3": 300})
+
+	assertNbreSeries(t, replicaLagBytes, kubegres, 1)
+	assertValue(t, replicaLagBytes.WithLabelValues(kubegres.Namespace, kubegres.Name, "lag-3"), 300)
+}
+
+func TestSetBlockingOperationReplacesThePreviousOperation(t *testing.T) {
+	kubegres := types.NamespacedName{Namespace: "default", Name: "operation"}
+	defer DeleteKubegresMetrics(kubegres)
+
+	SetBlockingOperation(kubegres, "FailOver", "Waiting", 10)
+	SetBlockingOperation(kubegres, "FailOver", "Promoting", 5)
+
+	assertNbreSeries(t, blockingOperationActive, kubegres, 1)
+	assertValue(t, blockingOperationActive.WithLabelValues(kubegres.Namespace, kubegres.Name, "FailOver", "Promoting"), 1)
+	assertValue(t, blockingOperationSecondsToTimeOut.WithLabelValues(kubegres.Namespace, kubegres.Name), 5)
+
+	SetBlockingOperation(kubegres, "", "", 0)
+
+	assertNbreSeries(t, blockingOperationActive, kubegres, 0)
+}
+
+func TestCountBackUpJobsCountsEachJobOnce(t *testing.T) {
+	kubegres := types.NamespacedName{Namespace: "default", Name: "backup"}
+	defer DeleteKubegresMetrics(kubegres)
+
+	CountBackUpJobs(kubegres, map[types.UID]string{"job-1": OutcomeSucceeded})
+	CountBackUpJobs(kubegres, map[types.UID]string{"job-1": OutcomeSucceeded, "job-2": OutcomeFailed})
+	CountBackUpJobs(kubegres, map[types.UID]string{"job-1": OutcomeSucceeded, "job-2": OutcomeFailed})
+
+	assertValue(t, backUpJobsTotal.WithLabelValues(kubegres.Namespace, kubegres.Name, OutcomeSucceeded), 1)
+	assertValue(t, backUpJobsTotal.WithLabelValues(kubegres.Namespace, kubegres.Name, OutcomeFailed), 1)
+}
+
+func TestDeleteKubegresMetricsDeletesAllSeries(t *testing.T) {
+	kubegres := types.NamespacedName{Namespace: "default", Name: "deleted"}
+
+	SetReadyInstances(kubegres, true, 2)
+	SetReplicasLag(kubegres, map[string]int64{"deleted-2": 100})
+	SetBlockingOperation(kubegres, "FailOver", "Waiting", 10)
+	IncFailOvers(kubegres)
+	IncBlockingOperationTimeOuts(kubegres, "FailOver")
+	IncBlockingOperationTimeOuts(kubegres, "Switchover")
+	IncRestores(kubegres, OutcomeSucceeded)
+	IncRestores(kubegres, OutcomeFailed)
+	CountBackUpJobs(kubegres, map[types.UID]string{"job-1": OutcomeSucceeded})
+
+	DeleteKubegresMetrics(kubegres)
+
+	for _, collector := range []*prometheus.GaugeVec{primaryReady, replicasReady, replicaLagBytes, blockingOperationActive, blockingOperationSecondsToTimeOut} {
+		assertNbreSeries(t, collector, kubegres, 0)
+	}
+
+	for _, collector := range []*prometheus.CounterVec{failOversTotal, blockingOperationTimeOutsTotal, backUpJobsTotal, restoresTotal} {
+		assertNbreSeries(t, collector, kubegres, 0)
+	}
+}
+
+func assertValue(t *testing.T, collector prometheus.Collector, expectedValue float64) {
+	t.Helper()
+	if value := testutil.ToFloat64(collector); value != expectedValue {
+		t.Errorf("Expected the value %v, got %v", expectedValue, value)
+	}
+}
+
+// assertNbreSeries checks the number of series of the given Kubegres resource. The other tests use other Kubegres
+// resources, so that their series are not counted.
+func assertNbreSeries(t *testing.T, collector prometheus.Collector, kubegres types.NamespacedName, expectedNbreSeries int) {
+	t.Helper()
+
+	metricChannel := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metricChannel)
+		close(metricChannel)
+	}()
+
+	nbreSeries := 0
+	for metric := range metricChannel {
+		if hasLabels(metric, kubegres) {
+			nbreSeries++
+		}
+	}
+
+	if nbreSeries != expectedNbreSeries {
+		t.Errorf("Expected %d series for the Kubegres '%s', got %d", expectedNbreSeries, kubegres, nbreSeries)
+	}
+}
+
+func hasLabels(metric prometheus.Metric, kubegres types.NamespacedName) bool {
+	metricDto := &dto.Metric{}
+	if err := metric.Write(metricDto); err != nil {
+		return false
+	}
+
+	labels := make(map[string]string)
+	for _, label := range metricDto.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels[labelNamespace] == kubegres.Namespace && labels[labelName] == kubegres.Name
+}
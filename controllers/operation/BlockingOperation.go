@@ -21,8 +21,10 @@ limitations under the License.
 package operation
 
 import (
+	"k8s.io/apimachinery/pkg/types"
 	v1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
 	"time"
 )
 
@@ -195,7 +197,11 @@ func (r *BlockingOperation) removeOperationIfNotActive() {
 	hasOperationTimedOut := r.hasOperationTimedOut()
 
 	if hasOperationTimedOut {
-		r.activeOperation.HasTimedOut = true
+
+		if !r.activeOperation.HasTimedOut {
+			r.activeOperation.HasTimedOut = true
+			r.onOperationTimedOut()
+		}
 
 		if !r.hasCompletionChecker() {
 
@@ -221,6 +227,20 @@ func (r *BlockingOperation) removeOperationIfNotActive() {
 	}
 }
 
+// The flag "HasTimedOut" is saved in the status so that a timed-out operation is counted once in the metrics,
+// even if it stays active during several reconciliations. The steps without a completion checker are timers which
+// always time out (e.g. waiting before a failover), so they are not counted in the metrics.
+func (r *BlockingOperation) onOperationTimedOut() {
+	if r.hasCompletionChecker() {
+		kubegres := types.NamespacedName{
+			Namespace: r.kubegresContext.Kubegres.Namespace,
+			Name:      r.kubegresContext.Kubegres.Name,
+		}
+		metrics.IncBlockingOperationTimeOuts(kubegres, r.activeOperation.OperationId)
+	}
+	r.kubegresContext.Status.SetBlockingOperation(r.activeOperation)
+}
+
 func (r *BlockingOperation) isOperationInTransition() bool {
 	return r.activeOperation.StepId == TransitionOperationStepId
 }
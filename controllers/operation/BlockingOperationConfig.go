@@ -28,15 +28,30 @@ const (
 
 	OperationIdBaseConfigCountSpecEnforcement = "Base config count spec enforcement"
 	OperationStepIdBaseConfigDeploying        = "Base config is deploying"
+	OperationStepIdBaseConfigUpdating         = "Base config is updating"
 
 	OperationIdPrimaryDbCountSpecEnforcement         = "Primary DB count spec enforcement"
 	OperationStepIdPrimaryDbDeploying                = "Primary DB is deploying"
 	OperationStepIdPrimaryDbWaitingBeforeFailingOver = "Waiting few seconds before failing over by promoting a Replica DB as a Primary DB"
 	OperationStepIdPrimaryDbFailingOver              = "Failing over by promoting a Replica DB as a Primary DB"
 
+	OperationIdSwitchover                       = "Switchover of Primary DB"
+	OperationStepIdSwitchoverFencingPrimary     = "Primary DB is fenced until the Replica DB to promote catches up"
+	OperationStepIdSwitchoverStoppingPrimary    = "Primary DB is stopping"
+	OperationStepIdSwitchoverCheckingPrimary    = "Checking that the Replica DB to promote replayed all WAL of the stopped Primary DB"
+	OperationStepIdSwitchoverPromotingReplica   = "Replica DB is promoting to Primary DB"
+	OperationStepIdSwitchoverReattachingPrimary = "Former Primary DB is re-attaching as a Replica DB"
+
+	OperationIdMajorVersionUpgrade                = "Major version upgrade of PostgreSql"
+	OperationStepIdMajorVersionUpgradeStopping    = "Primary DB is stopping before upgrading its major version"
+	OperationStepIdMajorVersionUpgradeRunningJob  = "Job upgrading the major version of the Primary DB with pg_upgrade is running"
+	OperationStepIdMajorVersionUpgradeStarting    = "Primary DB is starting with the new major version"
+	OperationStepIdMajorVersionUpgradeRollingBack = "Primary DB is restarting with the former major version"
+
 	OperationIdReplicaDbCountSpecEnforcement = "Replica DB count spec enforcement"
 	OperationStepIdReplicaDbDeploying        = "Replica DB is deploying"
 	OperationStepIdReplicaDbUndeploying      = "Replica DB is undeploying"
+	OperationStepIdReplicaDbRejoining        = "Failed Primary DB is rewinding to rejoin as a Replica DB"
 
 	OperationIdStatefulSetSpecEnforcing         = "Enforcing StatefulSet's Spec"
 	OperationStepIdStatefulSetSpecUpdating      = "StatefulSet's spec is updating"
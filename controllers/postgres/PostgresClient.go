@@ -0,0 +1,154 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "github.com/lib/pq"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"strings"
+	"sync"
+)
+
+const (
+	superUserName             = "postgres"
+	defaultDatabaseName       = "postgres"
+	connectionTimeOutInSecond = 3
+)
+
+// PostgresClient runs SQL queries against the PostgreSql server of a Pod deployed by Kubegres.
+// It connects with the super-user whose password is set in the env-var "POSTGRES_PASSWORD" of the Kubegres resource.
+// The same client can be used by several goroutines, for example to query all PostgreSql servers in parallel.
+type PostgresClient struct {
+	kubegresContext   ctx.KubegresContext
+	superUserPassword string
+	mutex             sync.Mutex
+}
+
+func CreatePostgresClient(kubegresContext ctx.KubegresContext) *PostgresClient {
+	return &PostgresClient{kubegresContext: kubegresContext}
+}
+
+func (r *PostgresClient) QueryRow(pod core.Pod, sqlQuery string, dest ...interface{}) error {
+
+	db, err := r.connect(pod)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.QueryRow(sqlQuery).Scan(dest...)
+}
+
+// Exec runs the given SQL statements one after the other, each of them outside of any transaction block.
+// It stops at the first statement which fails.
+func (r *PostgresClient) Exec(pod core.Pod, sqlStatements ...string) error {
+
+	db, err := r.connect(pod)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, sqlStatement := range sqlStatements {
+		if _, err = db.Exec(sqlStatement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresClient) connect(pod core.Pod) (*sql.DB, error) {
+
+	if pod.Status.PodIP == "" {
+		return nil, errors.New("Pod '" + pod.Name + "' does not have an IP address yet")
+	}
+
+	superUserPassword, err := r.getSuperUserPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	connectionInfo := fmt.Sprintf("host=%s port=%d user=%s password='%s' dbname=%s sslmode=disable connect_timeout=%d",
+		pod.Status.PodIP,
+		r.kubegresContext.Kubegres.Spec.Port,
+		superUserName,
+		r.escapeConnectionValue(superUserPassword),
+		defaultDatabaseName,
+		connectionTimeOutInSecond)
+
+	db, err := sql.Open("postgres", connectionInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (r *PostgresClient) escapeConnectionValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `'`, `\'`)
+}
+
+func (r *PostgresClient) getSuperUserPassword() (string, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.superUserPassword != "" {
+		return r.superUserPassword, nil
+	}
+
+	for _, envVar := range r.kubegresContext.Kubegres.Spec.Env {
+
+		if envVar.Name != ctx.EnvVarNameOfPostgresSuperUserPsw {
+			continue
+		}
+
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			r.superUserPassword = envVar.Value
+			return r.superUserPassword, nil
+		}
+
+		secretKeyRef := envVar.ValueFrom.SecretKeyRef
+		secret := &core.Secret{}
+		secretKey := types.NamespacedName{Namespace: r.kubegresContext.Kubegres.Namespace, Name: secretKeyRef.Name}
+
+		err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, secretKey, secret)
+		if err != nil {
+			return "", err
+		}
+
+		r.superUserPassword = string(secret.Data[secretKeyRef.Key])
+		return r.superUserPassword, nil
+	}
+
+	return "", errors.New("The env-var '" + ctx.EnvVarNameOfPostgresSuperUserPsw + "' is not set in the Kubegres resource")
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package checker
+
+import (
+	"errors"
+
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+type BackupSpecChecker struct {
+	kubegresBackupContext ctx.KubegresBackupContext
+	kubegresBackupStates  states.KubegresBackupStates
+}
+
+func CreateBackupSpecChecker(kubegresBackupContext ctx.KubegresBackupContext, kubegresBackupStates states.KubegresBackupStates) BackupSpecChecker {
+	return BackupSpecChecker{
+		kubegresBackupContext: kubegresBackupContext,
+		kubegresBackupStates:  kubegresBackupStates,
+	}
+}
+
+func (r *BackupSpecChecker) CheckSpec() SpecCheckResult {
+	specCheckResult := SpecCheckResult{}
+
+	// Once the backup Job is deployed, it runs to completion regardless of the changes in the Kubegres resource
+	if r.kubegresBackupStates.IsJobDeployed {
+		return specCheckResult
+	}
+
+	if r.kubegresBackupContext.KubegresBackup.Spec.ClusterName == "" {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.clusterName")
+		return specCheckResult
+	}
+
+	if !r.kubegresBackupStates.IsClusterDeployed {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			"'spec.clusterName' refers to a Kubegres resource which is not deployed. Please change this " +
+			"value to a deployed Kubegres resource, otherwise this operator cannot work correctly.")
+		return specCheckResult
+	}
+
+	if !r.kubegresBackupStates.IsCronJobDeployed {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			"'spec.clusterName' refers to a Kubegres resource without a backup CronJob. Please set the field " +
+			"'backup.schedule' of that Kubegres resource, otherwise this operator cannot work correctly.")
+	}
+
+	return specCheckResult
+}
+
+func (r *BackupSpecChecker) logSpecErrMsg(errorMsg string) string {
+	r.kubegresBackupContext.Log.ErrorEvent("SpecCheckErr", errors.New(errorMsg), "")
+	return errorMsg
+}
+
+func (r *BackupSpecChecker) createErrMsgSpecUndefined(specName string) string {
+	errorMsg := "In the Resources Spec the value of '" + specName + "' is undefined. Please set a value otherwise this operator cannot work correctly."
+	return r.logSpecErrMsg(errorMsg)
+}
@@ -17,6 +17,7 @@ package checker
 import (
 	"errors"
 	"reflect"
+	"strings"
 
 	core "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -29,6 +30,9 @@ import (
 type RestoreSpecChecker struct {
 	kubegresRestoreContext ctx.KubegresRestoreContext
 	restoreResourceStates  states.RestoreResourceStates
+
+	isAdmissionCheck       bool
+	admissionErrorMessages []string
 }
 
 func CreateRestoreSpecChecker(kubegresRestoreContext ctx.KubegresRestoreContext, restoreResourceStates states.RestoreResourceStates) RestoreSpecChecker {
@@ -38,29 +42,95 @@ func CreateRestoreSpecChecker(kubegresRestoreContext ctx.KubegresRestoreContext,
 	}
 }
 
+// CreateRestoreSpecCheckerForAdmission creates a RestoreSpecChecker run by the validating webhook. It does not log
+// any events.
+func CreateRestoreSpecCheckerForAdmission(kubegresRestoreContext ctx.KubegresRestoreContext, restoreResourceStates states.RestoreResourceStates) RestoreSpecChecker {
+	return RestoreSpecChecker{
+		kubegresRestoreContext: kubegresRestoreContext,
+		restoreResourceStates:  restoreResourceStates,
+		isAdmissionCheck:       true,
+	}
+}
+
+// CheckSpecForAdmission runs the same checks as CheckSpec and returns all the errors found, so that the validating
+// webhook can reject the request. The errors about resources which are not deployed or not ready yet (e.g. the PVC
+// or the KubegresBackup to restore) are not returned, since the restore waits for them.
+func (r *RestoreSpecChecker) CheckSpecForAdmission() ([]string, error) {
+	r.admissionErrorMessages = nil
+	_, err := r.CheckSpec()
+	return r.admissionErrorMessages, err
+}
+
 func (r *RestoreSpecChecker) CheckSpec() (SpecCheckResult, error) {
 	specCheckResult := SpecCheckResult{}
 
 	spec := &r.kubegresRestoreContext.KubegresRestore.Spec
 
-	if !r.isRestoreJobPvcDeployed() {
-		specCheckResult.HasSpecFatalError = true
-		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
-			"'spec.DataSource.File.PvcName' has a PersistentVolumeClaim name which is not deployed. Please deploy this " +
-			"PersistentVolumeClaim, otherwise this operator cannot work correctly.")
-	}
+	if r.kubegresRestoreContext.IsBackupSource() {
+		dataSourceBackup := r.kubegresRestoreContext.DataSourceBackup
 
-	if spec.DataSource.File.Mountpath == "" {
-		specCheckResult.HasSpecFatalError = true
-		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.File.Mountpath")
+		if dataSourceBackup == nil {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
+				"'spec.DataSource.Backup' refers to a KubegresBackup resource which is not deployed. Please change this " +
+				"value to a deployed KubegresBackup resource, otherwise this operator cannot work correctly.")
+			return specCheckResult, nil
+
+		} else if dataSourceBackup.Status.Phase != kubegresv1.KubegresBackupPhaseSucceeded {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
+				"'spec.DataSource.Backup' refers to a KubegresBackup resource which has not succeeded. " +
+				"The restore will start once the backup has succeeded.")
+			return specCheckResult, nil
+		}
 	}
 
-	if spec.DataSource.File.Snapshot == "" {
-		specCheckResult.HasSpecFatalError = true
-		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.File.Snapshot")
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		objectStore := spec.DataSource.ObjectStore
+
+		if spec.DataSource.File.PvcName != "" || spec.DataSource.File.Snapshot != "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the fields " +
+				"'spec.DataSource.File' and 'spec.DataSource.ObjectStore' cannot be used at the same time. Please unset one of them.")
+		}
+
+		if objectStore.S3.Bucket == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.ObjectStore.S3.Bucket")
+		}
+
+		if objectStore.S3.CredentialsSecret == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.ObjectStore.S3.CredentialsSecret")
+		}
+
+		if objectStore.Snapshot == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.ObjectStore.Snapshot")
+		}
+	} else {
+		if !r.isRestoreJobPvcDeployed() {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
+				"'spec.DataSource.File.PvcName' has a PersistentVolumeClaim name which is not deployed. Please deploy this " +
+				"PersistentVolumeClaim, otherwise this operator cannot work correctly.")
+		}
+
+		if spec.DataSource.File.Mountpath == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.File.Mountpath")
+		}
+
+		if spec.DataSource.File.Snapshot == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.DataSource.File.Snapshot")
+		}
 	}
 
-	if r.restoreResourceStates.Cluster.IsDeployed && !r.isDeployedClusterMangedByKubegresRestore() {
+	if r.kubegresRestoreContext.IsInPlaceRestore() {
+		r.checkInPlaceRestoreSpec(&specCheckResult)
+
+	} else if r.restoreResourceStates.Cluster.IsDeployed && !r.isDeployedClusterMangedByKubegresRestore() {
 		specCheckResult.HasSpecFatalError = true
 		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
 			"'spec.ClusterName' must not refer to an existing Kubegres resource. Please change this value, " +
@@ -82,7 +152,7 @@ func (r *RestoreSpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 		if !isDataSourceKubegresClusterDeployed {
 			specCheckResult.HasSpecFatalError = true
-			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
 				"'spec.DataSource.Cluster.ClusterName' refers to a Kubegres resource which is not deployed. Please change this " +
 				"value to a deployed Kubegres resource, otherwise this operator cannot work correctly.")
 		}
@@ -90,6 +160,43 @@ func (r *RestoreSpecChecker) CheckSpec() (SpecCheckResult, error) {
 		// TODO Check 'spec.DataSource.Cluster.ClusterSpec'
 	}
 
+	if r.kubegresRestoreContext.IsPointInTimeRecovery() {
+		if r.getNbreOfRecoveryTargets() != 1 {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec exactly one of the fields " +
+				"'spec.recoveryTarget.time', 'spec.recoveryTarget.lsn' and 'spec.recoveryTarget.name' must be set. " +
+				"Please set one of them, otherwise this operator cannot work correctly.")
+		}
+
+		if r.restoreResourceStates.FileChecker.ExitStatus == states.OkExitStatus &&
+			r.restoreResourceStates.FileChecker.SnapshotFormat != states.PhysicalSnapshotFormat {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the field 'spec.recoveryTarget' " +
+				"is set but the value of 'spec.dataSource.file.snapshot' refers to a logical backup. The archived WAL " +
+				"segments can only be replayed on top of a physical backup taken with 'spec.backup.type' set to 'physical'.")
+		}
+
+		if r.kubegresRestoreContext.GetWalArchiveS3() == nil && r.kubegresRestoreContext.GetWalArchiveFolder() == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the field 'spec.recoveryTarget' " +
+				"is set but the location of the archived WAL cannot be resolved. Please set either " +
+				"'spec.dataSource.walArchive.folder' or 'spec.dataSource.walArchive.s3', otherwise this operator cannot work correctly.")
+		}
+
+		if r.kubegresRestoreContext.SourceKubegresClusterSpec.Database.Size == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.dataSource.cluster.clusterSpec.database.size")
+		}
+	}
+
+	if r.kubegresRestoreContext.IsPartialRestore() {
+		r.checkPartialRestoreSpec(&specCheckResult)
+	}
+
+	if r.kubegresRestoreContext.IsVerificationEnabled() {
+		r.checkVerificationSpec(&specCheckResult)
+	}
+
 	if spec.CustomConfig != "" {
 		isCustomConfigDeployed, err := r.isCustomConfigDeployed()
 		if err != nil {
@@ -98,7 +205,7 @@ func (r *RestoreSpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 		if !isCustomConfigDeployed {
 			specCheckResult.HasSpecFatalError = true
-			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
 				"'spec.CustomConfig' refers to a ConfigMap which is not deployed. Please deploy this " +
 				"ConfigMap, otherwise this operator cannot work correctly.")
 		}
@@ -107,6 +214,134 @@ func (r *RestoreSpecChecker) CheckSpec() (SpecCheckResult, error) {
 	return specCheckResult, nil
 }
 
+// checkInPlaceRestoreSpec checks that the field 'inPlace' is confirmed and refers to an existing Kubegres resource
+// which is not restored by another KubegresRestore. Since the databases of the Primary are overwritten with a
+// logical restore, a physical backup cannot be restored in place.
+func (r *RestoreSpecChecker) checkInPlaceRestoreSpec(specCheckResult *SpecCheckResult) {
+
+	spec := r.kubegresRestoreContext.KubegresRestore.Spec
+	cluster := r.restoreResourceStates.Cluster
+
+	if spec.InPlace.ConfirmClusterName != spec.ClusterName {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			"'spec.InPlace.ConfirmClusterName' must be equal to the value of 'spec.ClusterName'. It confirms that the " +
+			"databases of that Kubegres resource are overwritten by the backup.")
+	}
+
+	if !cluster.IsDeployed {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the field 'spec.InPlace' is set " +
+			"but the value of 'spec.ClusterName' refers to a Kubegres resource which is not deployed. Please change this " +
+			"value to a deployed Kubegres resource, otherwise this operator cannot work correctly.")
+
+	} else if _, exists := cluster.Kubegres.Labels[ctx.ManagedByKubegresRestoreLabel]; exists && !cluster.IsManagedByKubegresRestore {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
+			"'spec.ClusterName' refers to a Kubegres resource which is restored by another KubegresRestore resource. " +
+			"Please wait until that restore has completed.")
+	}
+
+	if r.kubegresRestoreContext.IsPointInTimeRecovery() ||
+		(r.restoreResourceStates.FileChecker.ExitStatus == states.OkExitStatus &&
+			r.restoreResourceStates.FileChecker.SnapshotFormat == states.PhysicalSnapshotFormat) {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the field 'spec.InPlace' is set " +
+			"but the backup to restore is a physical backup. Only logical backups can be restored in place. Please " +
+			"unset the field 'spec.InPlace' to restore it into a new Kubegres resource.")
+	}
+}
+
+// checkPartialRestoreSpec checks the fields 'databases' and 'roles'. Their values are passed to the restore script
+// separated by spaces, so they cannot contain whitespaces, colons or double quotes.
+func (r *RestoreSpecChecker) checkPartialRestoreSpec(specCheckResult *SpecCheckResult) {
+
+	spec := r.kubegresRestoreContext.KubegresRestore.Spec
+
+	if r.kubegresRestoreContext.IsPointInTimeRecovery() ||
+		(r.restoreResourceStates.FileChecker.ExitStatus == states.OkExitStatus &&
+			r.restoreResourceStates.FileChecker.SnapshotFormat == states.PhysicalSnapshotFormat) {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the fields 'spec.Databases' " +
+			"and 'spec.Roles' can only be used to restore a logical backup. A physical backup is always restored entirely.")
+	}
+
+	restoredDatabaseNames := make(map[string]bool)
+	for _, database := range spec.Databases {
+		restoreAs := database.RestoreAs
+		if restoreAs == "" {
+			restoreAs = database.Name
+		}
+
+		if !r.isValidNameToRestore(database.Name) || !r.isValidNameToRestore(restoreAs) {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the database '" + database.Name +
+				"' in 'spec.Databases' has an invalid 'name' or 'restoreAs'. They must be set and must not contain " +
+				"whitespaces, colons or double quotes.")
+
+		} else if restoredDatabaseNames[restoreAs] {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the database '" + restoreAs +
+				"' is restored more than once in 'spec.Databases'. Please restore each database under a different name.")
+		}
+		restoredDatabaseNames[restoreAs] = true
+	}
+
+	for _, role := range spec.Roles {
+		if !r.isValidNameToRestore(role) {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the role '" + role +
+				"' in 'spec.Roles' is invalid. It must not be empty and must not contain whitespaces, colons or double quotes.")
+		}
+	}
+}
+
+// checkVerificationSpec checks the field 'verify'. The name of each query is written with its result in the
+// termination message of the verification job, one per line and separated by tabs.
+func (r *RestoreSpecChecker) checkVerificationSpec(specCheckResult *SpecCheckResult) {
+
+	verify := r.kubegresRestoreContext.KubegresRestore.Spec.Verify
+
+	queryNames := make(map[string]bool)
+	for _, query := range verify.Queries {
+		if query.Name == "" || strings.ContainsAny(query.Name, "\t\n") || query.Query == "" {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec a query in 'spec.Verify.Queries' " +
+				"has an invalid 'name' or 'query'. They must be set and the name must not contain tabs or new lines.")
+
+		} else if queryNames[query.Name] {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the name '" + query.Name +
+				"' is used by more than one query in 'spec.Verify.Queries'. Please give each query a different name.")
+		}
+		queryNames[query.Name] = true
+	}
+
+	for _, rowCount := range verify.RowCounts {
+		if rowCount.Table == "" || strings.ContainsAny(rowCount.Table, "\t\n") || rowCount.Count < 0 {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec a row count in " +
+				"'spec.Verify.RowCounts' has an invalid 'table' or 'count'. The table must be set without tabs or " +
+				"new lines and the count must be positive or zero.")
+		}
+	}
+}
+
+func (r *RestoreSpecChecker) isValidNameToRestore(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n:\"")
+}
+
+func (r *RestoreSpecChecker) getNbreOfRecoveryTargets() int {
+	recoveryTarget := r.kubegresRestoreContext.KubegresRestore.Spec.RecoveryTarget
+	nbreOfRecoveryTargets := 0
+	for _, target := range []string{recoveryTarget.Time, recoveryTarget.Lsn, recoveryTarget.Name} {
+		if target != "" {
+			nbreOfRecoveryTargets++
+		}
+	}
+	return nbreOfRecoveryTargets
+}
+
 func (r *RestoreSpecChecker) isRestoreJobPvcDeployed() bool {
 	return r.restoreResourceStates.Job.IsPvcDeployed
 }
@@ -164,10 +399,21 @@ func (r *RestoreSpecChecker) isCustomConfigDeployed() (bool, error) {
 }
 
 func (r *RestoreSpecChecker) logSpecErrMsg(errorMsg string) string {
+	if r.isAdmissionCheck {
+		r.admissionErrorMessages = append(r.admissionErrorMessages, errorMsg)
+		return errorMsg
+	}
 	r.kubegresRestoreContext.Log.ErrorEvent("SpecCheckErr", errors.New(errorMsg), "")
 	return errorMsg
 }
 
+func (r *RestoreSpecChecker) logUndeployedResourceErrMsg(errorMsg string) string {
+	if r.isAdmissionCheck {
+		return errorMsg
+	}
+	return r.logSpecErrMsg(errorMsg)
+}
+
 func (r *RestoreSpecChecker) createErrMsgSpecUndefined(specName string) string {
 	errorMsg := "In the Resources Spec the value of '" + specName + "' is undefined. Please set a value otherwise this operator cannot work correctly."
 	return r.logSpecErrMsg(errorMsg)
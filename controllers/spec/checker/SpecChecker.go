@@ -34,6 +34,9 @@ import (
 type SpecChecker struct {
 	kubegresContext ctx.KubegresContext
 	resourcesStates states.ResourcesStates
+
+	isAdmissionCheck       bool
+	admissionErrorMessages []string
 }
 
 type SpecCheckResult struct {
@@ -45,6 +48,21 @@ func CreateSpecChecker(kubegresContext ctx.KubegresContext, resourcesStates stat
 	return SpecChecker{kubegresContext: kubegresContext, resourcesStates: resourcesStates}
 }
 
+// CreateSpecCheckerForAdmission creates a SpecChecker run by the validating webhook. It does not log any events
+// and it does not roll back the spec of the Kubegres resource.
+func CreateSpecCheckerForAdmission(kubegresContext ctx.KubegresContext, resourcesStates states.ResourcesStates) SpecChecker {
+	return SpecChecker{kubegresContext: kubegresContext, resourcesStates: resourcesStates, isAdmissionCheck: true}
+}
+
+// CheckSpecForAdmission runs the same checks as CheckSpec and returns all the errors found, so that the validating
+// webhook can reject the request. The errors about resources which are not deployed yet (e.g. a ConfigMap or a PVC)
+// are not returned, since those resources may be deployed after the Kubegres resource.
+func (r *SpecChecker) CheckSpecForAdmission() ([]string, error) {
+	r.admissionErrorMessages = nil
+	_, err := r.CheckSpec()
+	return r.admissionErrorMessages, err
+}
+
 func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 	specCheckResult := SpecCheckResult{}
@@ -120,7 +138,7 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 	if !r.dbStorageClassDeployed() {
 		specCheckResult.HasSpecFatalError = true
-		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+		specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
 			"'spec.database.storageClassName' has a StorageClass name which is not deployed. Please deploy this StorageClass, " +
 			"otherwise this operator cannot work correctly.")
 	}
@@ -132,7 +150,7 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 	if r.isCustomConfigNotDeployed(spec) {
 		specCheckResult.HasSpecFatalError = true
-		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+		specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
 			"'spec.customConfig' has a configMap name which is not deployed. Please deploy this configMap otherwise this " +
 			"operator cannot work correctly.")
 	}
@@ -147,6 +165,11 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.env.POSTGRES_REPLICATION_PASSWORD")
 	}
 
+	if spec.Pooler.Enabled && !r.doesEnvVarExist(ctx.EnvVarNameOfPostgresPoolerUserPsw) {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.env.POSTGRES_POOLER_PASSWORD")
+	}
+
 	if *spec.Replicas <= 0 {
 		specCheckResult.HasSpecFatalError = true
 		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.replicas")
@@ -157,7 +180,7 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 		specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.image")
 	}
 
-	if r.isBackUpConfigured(spec) {
+	if (r.isBackUpConfigured(spec) && spec.Backup.Destination.S3 == nil) || r.isWalArchivedInBackUpPvc(spec) {
 
 		if spec.Backup.VolumeMount == emptyStr {
 			specCheckResult.HasSpecFatalError = true
@@ -171,12 +194,45 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 
 		if spec.Backup.PvcName != emptyStr && !r.isBackUpPvcDeployed() {
 			specCheckResult.HasSpecFatalError = true
-			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+			specCheckResult.FatalErrorMessage = r.logUndeployedResourceErrMsg("In the Resources Spec the value of " +
 				"'spec.Backup.PvcName' has a PersistentVolumeClaim name which is not deployed. Please deploy this " +
 				"PersistentVolumeClaim, otherwise this operator cannot work correctly.")
 		}
 	}
 
+	if r.isBackUpConfigured(spec) && spec.Backup.Destination.S3 != nil {
+
+		if spec.Backup.Destination.S3.Bucket == emptyStr {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.backup.destination.s3.bucket")
+		}
+
+		if spec.Backup.Destination.S3.CredentialsSecret == emptyStr {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.backup.destination.s3.credentialsSecret")
+		}
+	}
+
+	if spec.Backup.WalArchive.Enabled {
+
+		if spec.Backup.Type == postgresV1.BackUpTypeLogical {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of 'spec.backup.type' " +
+				"is 'logical' while 'spec.backup.walArchive.enabled' is true. The archived WAL segments can only be " +
+				"replayed on top of a physical backup. Please set 'spec.backup.type' to 'physical' in the YAML.")
+		}
+
+		if spec.Backup.WalArchive.S3 != nil && spec.Backup.WalArchive.S3.Bucket == emptyStr {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.backup.walArchive.s3.bucket")
+		}
+
+		if spec.Backup.WalArchive.S3 != nil && spec.Backup.WalArchive.S3.CredentialsSecret == emptyStr {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.createErrMsgSpecUndefined("spec.backup.walArchive.s3.credentialsSecret")
+		}
+	}
+
 	reservedVolumeName := r.doCustomVolumeClaimTemplatesHaveReservedName()
 	if reservedVolumeName != "" {
 		specCheckResult.HasSpecFatalError = true
@@ -208,10 +264,46 @@ func (r *SpecChecker) CheckSpec() (SpecCheckResult, error) {
 			"That value cannot be used and it is reserved for Kubegres internal usages. Please change that value in the YAML.")
 	}
 
+	reservedContainerName := r.doExtraContainersHaveReservedName()
+	if reservedContainerName != "" {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of 'spec.Sidecars' or " +
+			"'spec.InitContainers' has an entry with a container name which is a reserved name: " + reservedContainerName + " . " +
+			"That name cannot be used and it is reserved for Kubegres internal usages. Please change that name in the YAML.")
+	}
+
+	duplicateContainerName := r.doExtraContainersHaveDuplicateName()
+	if duplicateContainerName != "" {
+		specCheckResult.HasSpecFatalError = true
+		specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the container name '" + duplicateContainerName + "' " +
+			"is used more than once in 'spec.Sidecars' and 'spec.InitContainers'. Please give each container a different name in the YAML.")
+	}
+
+	for _, serviceSpecName := range []string{"primary", "replica"} {
+		serviceSpec := r.kubegresContext.GetServiceSpec(serviceSpecName == "primary")
+
+		if serviceSpec.ExternalTrafficPolicy != "" && serviceSpec.Type != v1.ServiceTypeNodePort && serviceSpec.Type != v1.ServiceTypeLoadBalancer {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+				"'spec.services." + serviceSpecName + ".externalTrafficPolicy' is set. It can only be set when the value of " +
+				"'spec.services." + serviceSpecName + ".type' is 'NodePort' or 'LoadBalancer'.")
+		}
+
+		if len(serviceSpec.LoadBalancerSourceRanges) > 0 && serviceSpec.Type != v1.ServiceTypeLoadBalancer {
+			specCheckResult.HasSpecFatalError = true
+			specCheckResult.FatalErrorMessage = r.logSpecErrMsg("In the Resources Spec the value of " +
+				"'spec.services." + serviceSpecName + ".loadBalancerSourceRanges' is set. It can only be set when the value of " +
+				"'spec.services." + serviceSpecName + ".type' is 'LoadBalancer'.")
+		}
+	}
+
 	return specCheckResult, nil
 }
 
 func (r *SpecChecker) updateKubegresSpec(specName string, specValue string) {
+	if r.isAdmissionCheck {
+		return
+	}
 	err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, r.kubegresContext.Kubegres)
 	if err != nil {
 		r.kubegresContext.Log.Error(err, "Unable to rollback the value of '"+specName+"' to '"+specValue+"'")
@@ -222,6 +314,10 @@ func (r *SpecChecker) isBackUpConfigured(spec *postgresV1.KubegresSpec) bool {
 	return spec.Backup.Schedule != ""
 }
 
+func (r *SpecChecker) isWalArchivedInBackUpPvc(spec *postgresV1.KubegresSpec) bool {
+	return spec.Backup.WalArchive.Enabled && spec.Backup.WalArchive.S3 == nil
+}
+
 func (r *SpecChecker) dbStorageClassDeployed() bool {
 	return r.resourcesStates.DbStorageClass.IsDeployed
 }
@@ -242,6 +338,11 @@ func (r *SpecChecker) createErrMsgSpecUndefined(specName string) string {
 }
 
 func (r *SpecChecker) createErrMsgSpecCannotBeChanged(specName, currentValue, newValue, reason string) string {
+	if r.isAdmissionCheck {
+		return r.logSpecErrMsg("In the Resources Spec the value of '" + specName + "' cannot be changed from '" + currentValue + "' to '" + newValue + "' after Pods were created. " +
+			reason)
+	}
+
 	errorMsg := "In the Resources Spec the value of '" + specName + "' cannot be changed from '" + currentValue + "' to '" + newValue + "' after Pods were created. " +
 		reason + " " +
 		"We roll-backed Kubegres spec to the currently working value '" + currentValue + "'. " +
@@ -250,10 +351,21 @@ func (r *SpecChecker) createErrMsgSpecCannotBeChanged(specName, currentValue, ne
 }
 
 func (r *SpecChecker) logSpecErrMsg(errorMsg string) string {
+	if r.isAdmissionCheck {
+		r.admissionErrorMessages = append(r.admissionErrorMessages, errorMsg)
+		return errorMsg
+	}
 	r.kubegresContext.Log.ErrorEvent("SpecCheckErr", errors.New(errorMsg), "")
 	return errorMsg
 }
 
+func (r *SpecChecker) logUndeployedResourceErrMsg(errorMsg string) string {
+	if r.isAdmissionCheck {
+		return errorMsg
+	}
+	return r.logSpecErrMsg(errorMsg)
+}
+
 func (r *SpecChecker) doesEnvVarExist(envName string) bool {
 	for _, envVar := range r.kubegresContext.Kubegres.Spec.Env {
 		if envVar.Name == envName {
@@ -302,6 +414,31 @@ func (r *SpecChecker) doCustomVolumeMountsHaveReservedName() string {
 	return ""
 }
 
+func (r *SpecChecker) doExtraContainersHaveReservedName() string {
+	for _, container := range r.getExtraContainers() {
+		if r.kubegresContext.IsReservedContainerName(container.Name) {
+			return container.Name
+		}
+	}
+	return ""
+}
+
+func (r *SpecChecker) doExtraContainersHaveDuplicateName() string {
+	containerNames := make(map[string]bool)
+	for _, container := range r.getExtraContainers() {
+		if containerNames[container.Name] {
+			return container.Name
+		}
+		containerNames[container.Name] = true
+	}
+	return ""
+}
+
+func (r *SpecChecker) getExtraContainers() []v1.Container {
+	spec := r.kubegresContext.Kubegres.Spec
+	return append(append([]v1.Container{}, spec.Sidecars...), spec.InitContainers...)
+}
+
 func (r *SpecChecker) doCustomVolumeMountsHaveReservedPath() bool {
 	for _, customVolumeMount := range r.kubegresContext.Kubegres.Spec.Volume.VolumeMounts {
 		if customVolumeMount.MountPath == r.kubegresContext.Kubegres.Spec.Database.VolumeMount {
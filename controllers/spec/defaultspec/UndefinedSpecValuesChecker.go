@@ -23,6 +23,7 @@ package defaultspec
 import (
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"strconv"
 )
@@ -30,8 +31,13 @@ import (
 type UndefinedSpecValuesChecker struct {
 	kubegresContext     ctx.KubegresContext
 	defaultStorageClass DefaultStorageClass
+	isAdmission         bool
 }
 
+// SetDefaultForUndefinedSpecValues sets the default values in the spec of a Kubegres resource reconciled by the
+// controller and updates it in Kubernetes. The defaulting webhook is opt-in (env variable ENABLE_WEBHOOKS), so the
+// controller keeps setting the default values. Once the webhook is enabled, the stored spec already has them and the
+// spec is not updated.
 func SetDefaultForUndefinedSpecValues(kubegresContext ctx.KubegresContext, defaultStorageClass DefaultStorageClass) error {
 	defaultSpec := UndefinedSpecValuesChecker{
 		kubegresContext:     kubegresContext,
@@ -41,9 +47,35 @@ func SetDefaultForUndefinedSpecValues(kubegresContext ctx.KubegresContext, defau
 	return defaultSpec.apply()
 }
 
+// SetDefaultForUndefinedSpecValuesInAdmission sets the default values in the spec of a Kubegres resource received by
+// the defaulting webhook. The spec is not updated in Kubernetes, since the webhook returns it to the API server.
+func SetDefaultForUndefinedSpecValuesInAdmission(kubegresContext ctx.KubegresContext, defaultStorageClass DefaultStorageClass) error {
+	defaultSpec := UndefinedSpecValuesChecker{
+		kubegresContext:     kubegresContext,
+		defaultStorageClass: defaultStorageClass,
+		isAdmission:         true,
+	}
+
+	_, err := defaultSpec.setDefaultValues()
+	return err
+}
+
 func (r *UndefinedSpecValuesChecker) apply() error {
 
-	wasSpecChanged := false
+	wasSpecChanged, err := r.setDefaultValues()
+	if err != nil {
+		return err
+	}
+
+	if wasSpecChanged {
+		return r.updateSpec()
+	}
+
+	return nil
+}
+
+func (r *UndefinedSpecValuesChecker) setDefaultValues() (wasSpecChanged bool, err error) {
+
 	kubegresSpec := &r.kubegresContext.Kubegres.Spec
 	const emptyStr = ""
 
@@ -69,27 +101,115 @@ func (r *UndefinedSpecValuesChecker) apply() error {
 		wasSpecChanged = true
 		defaultStorageClassName, err := r.defaultStorageClass.GetDefaultStorageClassName()
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		kubegresSpec.Database.StorageClassName = &defaultStorageClassName
 		r.createLog("spec.Database.StorageClassName", defaultStorageClassName)
 	}
 
+	if kubegresSpec.Backup.Schedule != emptyStr && kubegresSpec.Backup.Type == emptyStr {
+		wasSpecChanged = true
+		kubegresSpec.Backup.Type = v1.BackUpTypeLogical
+		if kubegresSpec.Backup.WalArchive.Enabled {
+			kubegresSpec.Backup.Type = v1.BackUpTypePhysical
+		}
+		r.createLog("spec.backup.type", kubegresSpec.Backup.Type)
+	}
+
 	if kubegresSpec.Scheduler.Affinity == nil {
 		kubegresSpec.Scheduler.Affinity = r.createDefaultAffinity()
 		wasSpecChanged = true
 		r.createLog("spec.Affinity", kubegresSpec.Scheduler.Affinity.String())
 	}
 
-	if wasSpecChanged {
-		return r.updateSpec()
+	if kubegresSpec.Pooler.Enabled && r.setDefaultForUndefinedPoolerValues() {
+		wasSpecChanged = true
 	}
 
-	return nil
+	if kubegresSpec.Backup.WalArchive.Enabled && r.setDefaultForUndefinedWalArchiveValues() {
+		wasSpecChanged = true
+	}
+
+	replicaReadySpec := &kubegresSpec.Services.ReplicaReady
+	if replicaReadySpec.Enabled && replicaReadySpec.MaxLagBytes == nil {
+		wasSpecChanged = true
+		maxLagBytes := int64(ctx.DefaultReplicaReadyMaxLagBytes)
+		replicaReadySpec.MaxLagBytes = &maxLagBytes
+		r.createLog("spec.services.replicaReady.maxLagBytes", strconv.FormatInt(maxLagBytes, 10))
+	}
+
+	return wasSpecChanged, nil
+}
+
+func (r *UndefinedSpecValuesChecker) setDefaultForUndefinedPoolerValues() (wasSpecChanged bool) {
+
+	poolerSpec := &r.kubegresContext.Kubegres.Spec.Pooler
+
+	if poolerSpec.Image == "" {
+		wasSpecChanged = true
+		poolerSpec.Image = ctx.DefaultPoolerImage
+		r.createLog("spec.pooler.image", poolerSpec.Image)
+	}
+
+	if poolerSpec.Port <= 0 {
+		wasSpecChanged = true
+		poolerSpec.Port = ctx.DefaultPoolerPortNumber
+		r.createLog("spec.pooler.port", strconv.Itoa(int(poolerSpec.Port)))
+	}
+
+	if poolerSpec.Replicas <= 0 {
+		wasSpecChanged = true
+		poolerSpec.Replicas = ctx.DefaultPoolerReplicas
+		r.createLog("spec.pooler.replicas", strconv.Itoa(int(poolerSpec.Replicas)))
+	}
+
+	if poolerSpec.PoolMode == "" {
+		wasSpecChanged = true
+		poolerSpec.PoolMode = v1.PoolerPoolModeSession
+		r.createLog("spec.pooler.poolMode", poolerSpec.PoolMode)
+	}
+
+	if poolerSpec.MaxClientConn <= 0 {
+		wasSpecChanged = true
+		poolerSpec.MaxClientConn = ctx.DefaultPoolerMaxClientConn
+		r.createLog("spec.pooler.maxClientConn", strconv.Itoa(int(poolerSpec.MaxClientConn)))
+	}
+
+	if poolerSpec.DefaultPoolSize <= 0 {
+		wasSpecChanged = true
+		poolerSpec.DefaultPoolSize = ctx.DefaultPoolerDefaultPoolSize
+		r.createLog("spec.pooler.defaultPoolSize", strconv.Itoa(int(poolerSpec.DefaultPoolSize)))
+	}
+
+	return wasSpecChanged
+}
+
+func (r *UndefinedSpecValuesChecker) setDefaultForUndefinedWalArchiveValues() (wasSpecChanged bool) {
+
+	walArchiveSpec := &r.kubegresContext.Kubegres.Spec.Backup.WalArchive
+
+	if walArchiveSpec.ArchiveTimeout <= 0 {
+		wasSpecChanged = true
+		walArchiveSpec.ArchiveTimeout = ctx.DefaultWalArchiveTimeout
+		r.createLog("spec.backup.walArchive.archiveTimeout", strconv.Itoa(int(walArchiveSpec.ArchiveTimeout)))
+	}
+
+	if walArchiveSpec.S3 != nil && walArchiveSpec.S3.Image == "" {
+		wasSpecChanged = true
+		walArchiveSpec.S3.Image = ctx.DefaultS3Image
+		r.createLog("spec.backup.walArchive.s3.image", walArchiveSpec.S3.Image)
+	}
+
+	return wasSpecChanged
 }
 
+// The webhook does not log any events, since the Kubegres resource may not be stored (e.g. with a dry-run request)
 func (r *UndefinedSpecValuesChecker) createLog(specName string, specValue string) {
+	if r.isAdmission {
+		r.kubegresContext.Log.Info("A default value was set for a field in Kubegres YAML spec.", specName, "New value: "+specValue)
+		return
+	}
 	r.kubegresContext.Log.InfoEvent("DefaultSpecValue", "A default value was set for a field in Kubegres YAML spec.", specName, "New value: "+specValue+"")
 }
 
@@ -98,6 +218,8 @@ func (r *UndefinedSpecValuesChecker) isStorageClassNameUndefinedInSpec() bool {
 	return storageClassName == nil || *storageClassName == ""
 }
 
+// updateSpec is only called by the controller. It is still needed when the webhooks are disabled, or for the Kubegres
+// resources stored before they were enabled.
 func (r *UndefinedSpecValuesChecker) updateSpec() error {
 	r.kubegresContext.Log.Info("Updating Kubegres Spec", "name", r.kubegresContext.Kubegres.Name)
 	return r.kubegresContext.Client.Update(r.kubegresContext.Ctx, r.kubegresContext.Kubegres)
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication_spec
+
+import (
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/postgres"
+	"reactive-tech.io/kubegres/controllers/states"
+	"strconv"
+	"strings"
+)
+
+// The standbys are identified by their 'application_name', which is set to the name of their Pod with the
+// PostgreSql parameter 'cluster_name' in the StatefulSet templates.
+const synchronousStandbysSqlQuery = "SELECT current_setting('synchronous_standby_names'), " +
+	"EXISTS (SELECT 1 FROM pg_file_settings WHERE name = 'synchronous_standby_names' AND sourcefile LIKE '%postgresql.auto.conf'), " +
+	"COALESCE((SELECT string_agg(application_name, ',') FROM pg_stat_replication WHERE state = 'streaming'), '')"
+
+const defaultNumSyncStandbys = 1
+
+// SynchronousStandbysSpecEnforcer keeps the parameter 'synchronous_standby_names' of the Primary in step with the
+// Replicas which are ready and streaming from it, so that transactions never wait on a Replica which is not deployed
+// or stuck anymore. The parameter is set with 'ALTER SYSTEM' and removed from 'postgresql.auto.conf' when the
+// replication mode is async.
+type SynchronousStandbysSpecEnforcer struct {
+	kubegresContext ctx.KubegresContext
+	resourcesStates states.ResourcesStates
+	postgresClient  *postgres.PostgresClient
+}
+
+func CreateSynchronousStandbysSpecEnforcer(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	postgresClient *postgres.PostgresClient) SynchronousStandbysSpecEnforcer {
+
+	return SynchronousStandbysSpecEnforcer{
+		kubegresContext: kubegresContext,
+		resourcesStates: resourcesStates,
+		postgresClient:  postgresClient,
+	}
+}
+
+func (r *SynchronousStandbysSpecEnforcer) EnforceSpec() error {
+
+	if !r.isPrimaryDbReady() {
+		return nil
+	}
+
+	currentStandbyNames, isSetBySystem, streamingStandbys, err := r.loadSynchronousStandbys()
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SynchronousStandbysLoadErr", err,
+			"Unable to load the synchronous standbys of the Primary PostgreSql.",
+			"Primary name", r.getPrimaryStatefulSetName())
+		return nil
+	}
+
+	if r.isAsyncMode() {
+		if isSetBySystem {
+			return r.resetSynchronousStandbyNames(currentStandbyNames)
+		}
+		return nil
+	}
+
+	expectedStandbyNames := r.getExpectedSynchronousStandbyNames(streamingStandbys)
+	if isSetBySystem && currentStandbyNames == expectedStandbyNames {
+		return nil
+	}
+
+	return r.setSynchronousStandbyNames(currentStandbyNames, expectedStandbyNames)
+}
+
+func (r *SynchronousStandbysSpecEnforcer) isPrimaryDbReady() bool {
+	primary := r.resourcesStates.Replication.Primary
+	return r.resourcesStates.StatefulSets.Primary.IsReady && primary.IsLoaded && !primary.IsInRecovery
+}
+
+func (r *SynchronousStandbysSpecEnforcer) isAsyncMode() bool {
+	mode := r.kubegresContext.Kubegres.Spec.Replication.Mode
+	return mode == "" || mode == v1.ReplicationModeAsync
+}
+
+func (r *SynchronousStandbysSpecEnforcer) getNumSyncStandbys() int {
+	numSyncStandbys := r.kubegresContext.Kubegres.Spec.Replication.NumSyncStandbys
+	if numSyncStandbys == nil || *numSyncStandbys <= 0 {
+		return defaultNumSyncStandbys
+	}
+	return int(*numSyncStandbys)
+}
+
+func (r *SynchronousStandbysSpecEnforcer) getPrimaryStatefulSetName() string {
+	return r.resourcesStates.StatefulSets.Primary.StatefulSet.Name
+}
+
+func (r *SynchronousStandbysSpecEnforcer) loadSynchronousStandbys() (string, bool, map[string]bool, error) {
+
+	var currentStandbyNames, streamingStandbysStr string
+	var isSetBySystem bool
+
+	err := r.postgresClient.QueryRow(r.resourcesStates.StatefulSets.Primary.Pod.Pod, synchronousStandbysSqlQuery,
+		&currentStandbyNames, &isSetBySystem, &streamingStandbysStr)
+	if err != nil {
+		return "", false, nil, err
+	}
+
+	streamingStandbys := make(map[string]bool)
+	for _, standbyName := range strings.Split(streamingStandbysStr, ",") {
+		if standbyName != "" {
+			streamingStandbys[standbyName] = true
+		}
+	}
+
+	return currentStandbyNames, isSetBySystem, streamingStandbys, nil
+}
+
+// The synchronous standbys are the Replicas which are ready, not stuck and streaming from the Primary, ordered by
+// instance index. If there is not any, 'synchronous_standby_names' is set to an empty value so that transactions
+// are not blocked.
+func (r *SynchronousStandbysSpecEnforcer) getExpectedSynchronousStandbyNames(streamingStandbys map[string]bool) string {
+
+	var standbyNames []string
+	for _, replica := range r.resourcesStates.StatefulSets.Replicas.All.GetAllSortedByInstanceIndex() {
+		podName := replica.Pod.Pod.Name
+		if replica.IsReady && !replica.Pod.IsStuck && streamingStandbys[podName] {
+			standbyNames = append(standbyNames, "\""+podName+"\"")
+		}
+	}
+
+	if len(standbyNames) == 0 {
+		return ""
+	}
+
+	numSyncStandbys := r.getNumSyncStandbys()
+	if numSyncStandbys > len(standbyNames) {
+		numSyncStandbys = len(standbyNames)
+	}
+
+	method := "FIRST"
+	if r.kubegresContext.Kubegres.Spec.Replication.Mode == v1.ReplicationModeQuorum {
+		method = "ANY"
+	}
+
+	return method + " " + strconv.Itoa(numSyncStandbys) + " (" + strings.Join(standbyNames, ", ") + ")"
+}
+
+func (r *SynchronousStandbysSpecEnforcer) setSynchronousStandbyNames(currentStandbyNames, expectedStandbyNames string) error {
+
+	err := r.postgresClient.Exec(r.resourcesStates.StatefulSets.Primary.Pod.Pod,
+		"ALTER SYSTEM SET synchronous_standby_names = '"+expectedStandbyNames+"'",
+		"SELECT pg_reload_conf()")
+
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SynchronousStandbysUpdateErr", err,
+			"Unable to update the synchronous standbys of the Primary PostgreSql.",
+			"Primary name", r.getPrimaryStatefulSetName(),
+			"Synchronous standbys", expectedStandbyNames)
+		return err
+	}
+
+	if expectedStandbyNames == "" {
+		r.kubegresContext.Log.WarningEvent("SynchronousStandbysUnavailable",
+			"The replication mode is '"+r.kubegresContext.Kubegres.Spec.Replication.Mode+"' but there is not any "+
+				"Replica ready and streaming from the Primary PostgreSql. Transactions are not replicated synchronously "+
+				"until a Replica is available.")
+	}
+
+	r.kubegresContext.Log.InfoEvent("SynchronousStandbysUpdated",
+		"Updated the synchronous standbys of the Primary PostgreSql.",
+		"Primary name", r.getPrimaryStatefulSetName(),
+		"Previous synchronous standbys", currentStandbyNames,
+		"New synchronous standbys", expectedStandbyNames)
+
+	return nil
+}
+
+func (r *SynchronousStandbysSpecEnforcer) resetSynchronousStandbyNames(currentStandbyNames string) error {
+
+	err := r.postgresClient.Exec(r.resourcesStates.StatefulSets.Primary.Pod.Pod,
+		"ALTER SYSTEM RESET synchronous_standby_names",
+		"SELECT pg_reload_conf()")
+
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SynchronousStandbysUpdateErr", err,
+			"Unable to reset the synchronous standbys of the Primary PostgreSql.",
+			"Primary name", r.getPrimaryStatefulSetName())
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("SynchronousStandbysUpdated",
+		"The replication mode is async. Reset the synchronous standbys of the Primary PostgreSql.",
+		"Primary name", r.getPrimaryStatefulSetName(),
+		"Previous synchronous standbys", currentStandbyNames)
+
+	return nil
+}
@@ -22,6 +22,7 @@ package resources_count_spec
 
 import (
 	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
@@ -73,7 +74,7 @@ func (r *BackUpCronJobCountSpecEnforcer) EnforceSpec() error {
 }
 
 func (r *BackUpCronJobCountSpecEnforcer) getConfigMapNameForBackUp(configStates states.ConfigStates) string {
-	if configStates.ConfigLocations.BackUpScript == ctx.BaseConfigMapVolumeName {
+	if configStates.ConfigLocations.BackUpScript == ctx.BaseConfigMapVolumeName || r.kubegresContext.IsPhysicalBackUp() {
 		return configStates.BaseConfigName
 	}
 	return configStates.CustomConfigName
@@ -106,6 +107,7 @@ func (r *BackUpCronJobCountSpecEnforcer) hasSpecChanged() (hasSpecChanged bool)
 	cronJobSpec := &cronJob.Spec
 	cronJobTemplateSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
 	kubegresBackUpSpec := r.kubegresContext.Kubegres.Spec.Backup
+	backUpContainer := r.getBackUpContainer(cronJobTemplateSpec)
 
 	currentSchedule := cronJobSpec.Schedule
 	expectedSchedule := kubegresBackUpSpec.Schedule
@@ -114,18 +116,29 @@ func (r *BackUpCronJobCountSpecEnforcer) hasSpecChanged() (hasSpecChanged bool)
 		r.logSpecChange("spec.backup.schedule")
 	}
 
-	currentVolumeMount := cronJobTemplateSpec.Containers[0].VolumeMounts[0].MountPath
-	expectedVolumeMount := kubegresBackUpSpec.VolumeMount
+	currentVolumeMount := backUpContainer.VolumeMounts[0].MountPath
+	expectedVolumeMount := r.kubegresContext.GetBackUpFolder()
 	if currentVolumeMount != expectedVolumeMount {
 		hasSpecChanged = true
 		r.logSpecChange("spec.backup.volumeMount")
 	}
 
-	currentPvcName := cronJobTemplateSpec.Volumes[0].PersistentVolumeClaim.ClaimName
-	expectedPvcName := kubegresBackUpSpec.PvcName
-	if currentPvcName != expectedPvcName {
+	currentDestination := cronJob.Annotations[template.BackUpDestinationAnnotationKey]
+	if currentDestination == "" && cronJobTemplateSpec.Volumes[0].PersistentVolumeClaim != nil {
+		// The CronJob was deployed by a version of Kubegres which did not set the annotation
+		currentDestination = "pvc name=" + cronJobTemplateSpec.Volumes[0].PersistentVolumeClaim.ClaimName
+	}
+	expectedDestination := r.resourcesCreator.GetBackUpDestinationConfig()
+	if currentDestination != expectedDestination {
+		hasSpecChanged = true
+		r.logSpecChange("spec.backup.destination")
+	}
+
+	currentRetention := cronJob.Annotations[template.BackUpRetentionAnnotationKey]
+	expectedRetention := r.resourcesCreator.GetBackUpRetentionConfig()
+	if currentRetention != expectedRetention {
 		hasSpecChanged = true
-		r.logSpecChange("spec.backup.pvcName")
+		r.logSpecChange("spec.backup.retention")
 	}
 
 	currentCustomConfig := cronJobTemplateSpec.Volumes[1].ConfigMap.Name
@@ -135,9 +148,28 @@ func (r *BackUpCronJobCountSpecEnforcer) hasSpecChanged() (hasSpecChanged bool)
 		r.logSpecChange("spec.backup.customConfig")
 	}
 
+	currentBackUpScript := backUpContainer.VolumeMounts[1].SubPath
+	expectedBackUpScript := states.ConfigMapDataKeyBackUpScript
+	if r.kubegresContext.IsPhysicalBackUp() {
+		expectedBackUpScript = states.ConfigMapDataKeyBaseBackUpScript
+	}
+	if currentBackUpScript != expectedBackUpScript {
+		hasSpecChanged = true
+		r.logSpecChange("spec.backup.type")
+	}
+
 	return hasSpecChanged
 }
 
+// getBackUpContainer returns the container taking the backup. It is an init container if the backup is uploaded
+// in S3 or if old backups are removed once it is taken.
+func (r *BackUpCronJobCountSpecEnforcer) getBackUpContainer(cronJobTemplateSpec core.PodSpec) core.Container {
+	if len(cronJobTemplateSpec.InitContainers) > 0 {
+		return cronJobTemplateSpec.InitContainers[0]
+	}
+	return cronJobTemplateSpec.Containers[0]
+}
+
 func (r *BackUpCronJobCountSpecEnforcer) deleteCronJob() error {
 
 	err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, r.resourcesStates.BackUp.DeployedCronJob)
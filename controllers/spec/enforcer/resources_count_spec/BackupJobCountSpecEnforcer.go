@@ -0,0 +1,110 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package resources_count_spec
+
+import (
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+type BackupJobCountSpecEnforcer struct {
+	kubegresBackupContext ctx.KubegresBackupContext
+	kubegresBackupStates  states.KubegresBackupStates
+	resourcesCreator      template.BackupResourcesCreatorFromTemplate
+}
+
+func CreateBackupJobCountSpecEnforcer(kubegresBackupContext ctx.KubegresBackupContext,
+	kubegresBackupStates states.KubegresBackupStates) BackupJobCountSpecEnforcer {
+
+	return BackupJobCountSpecEnforcer{
+		kubegresBackupContext: kubegresBackupContext,
+		kubegresBackupStates:  kubegresBackupStates,
+		resourcesCreator:      template.CreateBackupResourcesCreatorFromTemplate(kubegresBackupContext),
+	}
+}
+
+func (r *BackupJobCountSpecEnforcer) EnforceSpec() error {
+
+	if !r.kubegresBackupStates.IsJobDeployed {
+		return r.deployBackupJob()
+	}
+
+	job := r.kubegresBackupStates.Job
+	r.kubegresBackupContext.Status.SetJobName(job.Name)
+	r.kubegresBackupContext.Status.SetStartTime(job.Status.StartTime)
+
+	if r.kubegresBackupStates.IsJobSucceeded {
+		r.setBackupSucceeded()
+
+	} else if r.kubegresBackupStates.IsJobFailed {
+		r.kubegresBackupContext.Status.SetPhase(kubegresv1.KubegresBackupPhaseFailed)
+		r.kubegresBackupContext.Status.SetCompletionTime(&metav1.Time{Time: metav1.Now().Time})
+		r.kubegresBackupContext.Log.WarningEvent("BackupJobFailed", "The backup Job has failed. "+
+			"See the logs of its Pods for more details.", "Job name", job.Name)
+
+	} else {
+		r.kubegresBackupContext.Status.SetPhase(kubegresv1.KubegresBackupPhaseRunning)
+	}
+
+	return nil
+}
+
+func (r *BackupJobCountSpecEnforcer) deployBackupJob() error {
+
+	backupJob := r.resourcesCreator.CreateBackupJob(r.kubegresBackupStates.CronJob)
+
+	err := r.kubegresBackupContext.Client.Create(r.kubegresBackupContext.Ctx, &backupJob)
+	if err != nil {
+		r.kubegresBackupContext.Log.ErrorEvent("BackupJobDeploymentErr", err, "Unable to deploy backup Job.", "Job name", backupJob.Name)
+		return err
+	}
+
+	r.kubegresBackupContext.Log.InfoEvent("BackupJobDeployment", "Deployed backup Job.", "Job name", backupJob.Name)
+	r.kubegresBackupContext.Status.SetPhase(kubegresv1.KubegresBackupPhaseRunning)
+	r.kubegresBackupContext.Status.SetJobName(backupJob.Name)
+	return nil
+}
+
+// setBackupSucceeded sets in the status the location of the backup file, from the field 'backup' of the Kubegres
+// resource which was backed up, so that a KubegresRestore can restore it.
+func (r *BackupJobCountSpecEnforcer) setBackupSucceeded() {
+
+	backUpSpec := r.kubegresBackupStates.Cluster.Spec.Backup
+	fileName := r.kubegresBackupStates.FileName
+	status := r.kubegresBackupContext.Status
+
+	status.SetPhase(kubegresv1.KubegresBackupPhaseSucceeded)
+	status.SetCompletionTime(r.kubegresBackupStates.Job.Status.CompletionTime)
+	status.SetFileName(fileName)
+	status.SetSizeBytes(r.kubegresBackupStates.SizeBytes)
+
+	if fileName != "" {
+		if backUpSpec.Destination.S3 != nil {
+			status.SetObjectStore(&kubegresv1.ObjectStore{S3: backUpSpec.Destination.S3.DeepCopy(), Snapshot: fileName})
+		} else {
+			status.SetFile(&kubegresv1.File{PvcName: backUpSpec.PvcName, Mountpath: backUpSpec.VolumeMount, Snapshot: fileName})
+		}
+	}
+
+	r.kubegresBackupContext.Log.InfoEvent("BackupJobCompleted", "The backup Job has completed successfully.",
+		"File name", fileName, "Size in bytes", strconv.FormatInt(r.kubegresBackupStates.SizeBytes, 10))
+}
@@ -61,6 +61,16 @@ func (r *BaseConfigMapCountSpecEnforcer) CreateOperationConfig() operation.Block
 	}
 }
 
+func (r *BaseConfigMapCountSpecEnforcer) CreateOperationConfigForUpdating() operation.BlockingOperationConfig {
+
+	return operation.BlockingOperationConfig{
+		OperationId:       operation.OperationIdBaseConfigCountSpecEnforcement,
+		StepId:            operation.OperationStepIdBaseConfigUpdating,
+		TimeOutInSeconds:  10,
+		CompletionChecker: func(operation v1.KubegresBlockingOperation) bool { return r.isBaseConfigUpToDate() },
+	}
+}
+
 func (r *BaseConfigMapCountSpecEnforcer) EnforceSpec() error {
 
 	if r.blockingOperation.IsActiveOperationIdDifferentOf(operation.OperationIdBaseConfigCountSpecEnforcement) {
@@ -69,7 +79,7 @@ func (r *BaseConfigMapCountSpecEnforcer) EnforceSpec() error {
 
 	if r.hasLastAttemptTimedOut() {
 
-		if r.isBaseConfigDeployed() {
+		if r.isBaseConfigDeployed() && r.isBaseConfigUpToDate() {
 			r.blockingOperation.RemoveActiveOperation()
 			r.logKubegresFeaturesAreReEnabled()
 
@@ -80,7 +90,10 @@ func (r *BaseConfigMapCountSpecEnforcer) EnforceSpec() error {
 	}
 
 	if r.isBaseConfigDeployed() {
-		return nil
+		if r.isBaseConfigUpToDate() {
+			return nil
+		}
+		return r.updateBaseConfigMap()
 	}
 
 	baseConfigMap, err := r.resourcesCreator.CreateBaseConfigMap()
@@ -98,6 +111,18 @@ func (r *BaseConfigMapCountSpecEnforcer) isBaseConfigDeployed() bool {
 	return r.resourcesStates.Config.IsBaseConfigDeployed
 }
 
+// isBaseConfigUpToDate returns false when the deployed base ConfigMap was created by a previous version of Kubegres
+// and some of its scripts are either missing or outdated.
+func (r *BaseConfigMapCountSpecEnforcer) isBaseConfigUpToDate() bool {
+
+	expectedHash, err := r.resourcesCreator.GetBaseConfigHash()
+	if err != nil {
+		return false
+	}
+
+	return r.resourcesStates.Config.BaseConfigMap.Annotations[template.BaseConfigHashAnnotationKey] == expectedHash
+}
+
 func (r *BaseConfigMapCountSpecEnforcer) hasLastAttemptTimedOut() bool {
 	return r.blockingOperation.HasActiveOperationIdTimedOut(operation.OperationIdBaseConfigCountSpecEnforcement)
 }
@@ -111,10 +136,10 @@ func (r *BaseConfigMapCountSpecEnforcer) logTimedOut() {
 
 	operationTimeOutStr := strconv.FormatInt(r.CreateOperationConfig().TimeOutInSeconds, 10)
 
-	err := errors.New("Base ConfigMap deployment timed-out")
+	err := errors.New("Base ConfigMap deployment or update timed-out")
 	r.kubegresContext.Log.ErrorEvent("BaseConfigMapDeploymentTimedOutErr", err,
-		"Last Base ConfigMap deployment attempt has timed-out after "+operationTimeOutStr+" seconds. "+
-			"The new Base ConfigMap is still NOT ready. It must be fixed manually. "+
+		"Last Base ConfigMap deployment or update attempt has timed-out after "+operationTimeOutStr+" seconds. "+
+			"The Base ConfigMap is still NOT ready. It must be fixed manually. "+
 			"Until Base ConfigMap is ready, most of the features of Kubegres are disabled for safety reason. ",
 		"Based ConfigMap to fix", ctx.BaseConfigMapName)
 }
@@ -147,3 +172,40 @@ func (r *BaseConfigMapCountSpecEnforcer) activateBlockingOperationForDeployment(
 	return r.blockingOperation.ActivateOperation(operation.OperationIdBaseConfigCountSpecEnforcement,
 		operation.OperationStepIdBaseConfigDeploying)
 }
+
+// updateBaseConfigMap updates the scripts of a base ConfigMap deployed by a previous version of Kubegres. Once it is
+// updated, the enforcer 'BaseConfigSpecEnforcer' restarts the Pods since the data keys mounted with 'subPath' are
+// never refreshed in running containers.
+func (r *BaseConfigMapCountSpecEnforcer) updateBaseConfigMap() error {
+
+	baseConfigMap, err := r.resourcesCreator.CreateUpdatedBaseConfigMap(r.resourcesStates.Config.BaseConfigMap)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("BaseConfigMapTemplateErr", err,
+			"Unable to create a Base ConfigMap object from template.",
+			"Based ConfigMap name", ctx.BaseConfigMapName)
+		return err
+	}
+
+	r.kubegresContext.Log.Info("Updating Base ConfigMap", "name", baseConfigMap.Name)
+
+	if err := r.blockingOperation.ActivateOperation(operation.OperationIdBaseConfigCountSpecEnforcement,
+		operation.OperationStepIdBaseConfigUpdating); err != nil {
+		r.kubegresContext.Log.ErrorEvent("BaseConfigMapUpdateOperationActivationErr", err,
+			"Error while activating blocking operation for the update of a Base ConfigMap.",
+			"ConfigMap name", baseConfigMap.Name)
+		return err
+	}
+
+	if err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &baseConfigMap); err != nil {
+		r.kubegresContext.Log.ErrorEvent("BaseConfigMapUpdateErr", err,
+			"Unable to update Base ConfigMap.",
+			"ConfigMap name", baseConfigMap.Name)
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("BaseConfigMapUpdate", "Updated the scripts of the Base ConfigMap "+
+		"deployed by a previous version of Kubegres. The Pods will be restarted to use them.",
+		"ConfigMap name", baseConfigMap.Name)
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+const pvcRemovalCheckPeriodInSeconds = 5
+
+// FailedRestoreJobSpecEnforcer records the failures of the restore job and applies the fields 'retryPolicy' and
+// 'cleanupOnFailure' of KubegresRestore. Before a retry, the Kubegres resource deployed by the restore is deleted
+// with its PVCs, then the failed restore job is deleted so that the other enforcers deploy them again.
+type FailedRestoreJobSpecEnforcer struct {
+	kubegresRestoreContext     ctx.KubegresRestoreContext
+	restoreStates              states.RestoreResourceStates
+	nbreSecondsLeftBeforeRetry int64
+}
+
+func CreateFailedRestoreJobSpecEnforcer(kubegresRestoreContext ctx.KubegresRestoreContext,
+	restoreStates states.RestoreResourceStates) FailedRestoreJobSpecEnforcer {
+
+	return FailedRestoreJobSpecEnforcer{
+		kubegresRestoreContext: kubegresRestoreContext,
+		restoreStates:          restoreStates,
+	}
+}
+
+// GetNbreSecondsLeftBeforeRetry returns the number of seconds to wait before the failed restore job is rerun.
+// It returns 0 if the restore job is not waiting for a retry.
+func (r *FailedRestoreJobSpecEnforcer) GetNbreSecondsLeftBeforeRetry() int64 {
+	return r.nbreSecondsLeftBeforeRetry
+}
+
+func (r *FailedRestoreJobSpecEnforcer) EnforceSpec() error {
+	if r.restoreStates.Job.JobPhase != states.JobFailed {
+		return nil
+	}
+
+	r.recordFailureIfNew()
+	status := r.kubegresRestoreContext.Status
+
+	if status.GetFailedAttempts() >= r.kubegresRestoreContext.GetMaxAttempts() {
+		return r.stopRestore()
+	}
+
+	retryTime := status.GetLastFailureTime().Add(r.kubegresRestoreContext.GetRetryBackoff(status.GetFailedAttempts()))
+	if nbreSecondsLeft := int64(time.Until(retryTime).Seconds()); nbreSecondsLeft > 0 {
+		r.nbreSecondsLeftBeforeRetry = nbreSecondsLeft
+		status.SetCurrentStage(ctx.StageWaitingForRetry)
+		return nil
+	}
+
+	return r.cleanUpForRetry()
+}
+
+func (r *FailedRestoreJobSpecEnforcer) recordFailureIfNew() {
+	status := r.kubegresRestoreContext.Status
+	failureTime := r.restoreStates.Job.FailureTime
+
+	lastFailureTime := status.GetLastFailureTime()
+	if lastFailureTime != nil && !lastFailureTime.Before(&failureTime) {
+		return
+	}
+
+	status.SetFailedAttempts(status.GetFailedAttempts() + 1)
+	status.SetLastFailureTime(&failureTime)
+	status.SetFailureReason(r.restoreStates.Job.FailureReason)
+	status.SetIsCompleted(false)
+
+	r.kubegresRestoreContext.Log.WarningEvent("RestoreJobFailed", "Unable to complete restore job. "+
+		"See 'pod/"+r.restoreStates.Job.FailurePodName+"' for more details.",
+		"Name of job", r.restoreStates.Job.Job.Name,
+		"Reason", r.restoreStates.Job.FailureReason,
+		"Failed attempts", status.GetFailedAttempts(),
+		"Max attempts", r.kubegresRestoreContext.GetMaxAttempts())
+}
+
+// stopRestore is called once the last attempt of the restore job has failed. If the field 'cleanupOnFailure' is
+// true, the Kubegres resource deployed by the restore is deleted with its PVCs.
+func (r *FailedRestoreJobSpecEnforcer) stopRestore() error {
+	status := r.kubegresRestoreContext.Status
+	if status.GetCurrentStage() == ctx.StageRestoreJobFailed {
+		return nil
+	}
+
+	if r.kubegresRestoreContext.KubegresRestore.Spec.CleanupOnFailure && r.canDeleteCluster() {
+		if _, err := r.deleteClusterAndPvcs(); err != nil {
+			return err
+		}
+	}
+
+	metrics.IncRestores(types.NamespacedName{
+		Namespace: r.kubegresRestoreContext.KubegresRestore.Namespace,
+		Name:      r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName,
+	}, metrics.OutcomeFailed)
+
+	status.SetCurrentStage(ctx.StageRestoreJobFailed)
+	return nil
+}
+
+// cleanUpForRetry deletes the Kubegres resource deployed by the restore and its PVCs. Once they are removed, it
+// deletes the failed restore job so that the restore starts again from a clean database.
+func (r *FailedRestoreJobSpecEnforcer) cleanUpForRetry() error {
+	if r.canDeleteCluster() {
+		isClusterRemoved, err := r.deleteClusterAndPvcs()
+		if err != nil || !isClusterRemoved {
+			// The PVCs are not watched, we check again whether they are removed
+			r.nbreSecondsLeftBeforeRetry = pvcRemovalCheckPeriodInSeconds
+			return err
+		}
+	}
+
+	job := r.restoreStates.Job.Job
+	err := r.kubegresRestoreContext.Client.Delete(r.kubegresRestoreContext.Ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("RestoreJobDeletionErr", err, "Unable to delete failed restore job.", "Job name", job.Name)
+		return err
+	}
+
+	r.kubegresRestoreContext.Log.InfoEvent("RestoreJobRetry", "Deleted failed restore job. It will be deployed again.",
+		"Job name", job.Name,
+		"Attempt", r.kubegresRestoreContext.Status.GetFailedAttempts()+1,
+		"Max attempts", r.kubegresRestoreContext.GetMaxAttempts())
+	return nil
+}
+
+// canDeleteCluster returns true if the Kubegres resource to restore was deployed by this KubegresRestore.
+// The Kubegres resource of an in-place restore is never deleted.
+func (r *FailedRestoreJobSpecEnforcer) canDeleteCluster() bool {
+	return !r.kubegresRestoreContext.IsInPlaceRestore() &&
+		(!r.restoreStates.Cluster.IsDeployed || r.restoreStates.Cluster.IsManagedByKubegresRestore)
+}
+
+// deleteClusterAndPvcs deletes the Kubegres resource deployed by the restore and the PVCs of its databases. It
+// returns true once they are all removed.
+func (r *FailedRestoreJobSpecEnforcer) deleteClusterAndPvcs() (bool, error) {
+	if r.restoreStates.Cluster.IsDeployed {
+		kubegres := r.restoreStates.Cluster.Kubegres
+		err := r.kubegresRestoreContext.Client.Delete(r.kubegresRestoreContext.Ctx, kubegres)
+		if err != nil {
+			r.kubegresRestoreContext.Log.ErrorEvent("KubegresDeletionErr", err, "Unable to delete kubegres resource deployed by the failed restore.", "Kubegres name", kubegres.Name)
+			return false, err
+		}
+		r.kubegresRestoreContext.Log.InfoEvent("KubegresDeletion", "Deleted kubegres resource deployed by the failed restore.", "Kubegres name", kubegres.Name)
+		return false, nil
+	}
+
+	pvcList := &core.PersistentVolumeClaimList{}
+	clusterName := r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName
+	err := r.kubegresRestoreContext.Client.List(r.kubegresRestoreContext.Ctx, pvcList,
+		client.InNamespace(r.kubegresRestoreContext.KubegresRestore.Namespace),
+		client.MatchingLabels{"app": clusterName})
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("DatabasePvcLoadingErr", err, "Unable to load the PVCs of the kubegres resource deployed by the failed restore.")
+		return false, err
+	}
+
+	isClusterRemoved := true
+	for _, pvc := range pvcList.Items {
+		if !strings.HasPrefix(pvc.Name, ctx.DatabaseVolumeName+"-"+clusterName+"-") {
+			continue
+		}
+
+		isClusterRemoved = false
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+
+		if err := r.kubegresRestoreContext.Client.Delete(r.kubegresRestoreContext.Ctx, &pvc); err != nil {
+			r.kubegresRestoreContext.Log.ErrorEvent("DatabasePvcDeletionErr", err, "Unable to delete the PVC of the kubegres resource deployed by the failed restore.", "PVC name", pvc.Name)
+			return false, err
+		}
+		r.kubegresRestoreContext.Log.InfoEvent("DatabasePvcDeletion", "Deleted the PVC of the kubegres resource deployed by the failed restore.", "PVC name", pvc.Name)
+	}
+
+	return isClusterRemoved, nil
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"context"
+	batchv1 "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func TestFailedRestoreJobIsCountedOnceAndWaitsForBackoff(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(3, 60, false)
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now(), states.KubegresStates{})
+
+	for i := 0; i < 2; i++ {
+		if err := enforcer.EnforceSpec(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if kubegresRestore.Status.FailedAttempts != 1 {
+		t.Errorf("Expected the failure of the restore job to be counted once, got %d failed attempts", kubegresRestore.Status.FailedAttempts)
+	}
+	if kubegresRestore.Status.CurrentStage != ctx.StageWaitingForRetry {
+		t.Errorf("Expected the stage '%s', got '%s'", ctx.StageWaitingForRetry, kubegresRestore.Status.CurrentStage)
+	}
+	if nbreSecondsLeft := enforcer.GetNbreSecondsLeftBeforeRetry(); nbreSecondsLeft < 55 || nbreSecondsLeft > 60 {
+		t.Errorf("Expected the retry to be in 60 seconds, got %d seconds", nbreSecondsLeft)
+	}
+	thenRestoreJobIsDeployed(t, kubeClient, true)
+}
+
+func TestFailedRestoreJobBackoffDoublesAfterEachFailedAttempt(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(3, 60, false)
+	previousFailureTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	kubegresRestore.Status.FailedAttempts = 1
+	kubegresRestore.Status.LastFailureTime = &previousFailureTime
+	enforcer, _ := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now(), states.KubegresStates{})
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if kubegresRestore.Status.FailedAttempts != 2 {
+		t.Errorf("Expected 2 failed attempts, got %d", kubegresRestore.Status.FailedAttempts)
+	}
+	if nbreSecondsLeft := enforcer.GetNbreSecondsLeftBeforeRetry(); nbreSecondsLeft < 115 || nbreSecondsLeft > 120 {
+		t.Errorf("Expected the retry to be in 120 seconds, got %d seconds", nbreSecondsLeft)
+	}
+}
+
+func TestFailedRestoreJobIsDeletedOnceClusterAndPvcsDeployedByRestoreAreRemoved(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(3, 30, false)
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now().Add(-time.Minute),
+		states.KubegresStates{}, createPvcToTest("postgres-db-postgres-1-0"))
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenPvcIsDeployed(t, kubeClient, "postgres-db-postgres-1-0", false)
+	thenRestoreJobIsDeployed(t, kubeClient, true)
+	if enforcer.GetNbreSecondsLeftBeforeRetry() != pvcRemovalCheckPeriodInSeconds {
+		t.Errorf("Expected the removal of the PVCs to be checked again in %d seconds, got %d", pvcRemovalCheckPeriodInSeconds, enforcer.GetNbreSecondsLeftBeforeRetry())
+	}
+
+	enforcer.nbreSecondsLeftBeforeRetry = 0
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenRestoreJobIsDeployed(t, kubeClient, false)
+	if kubegresRestore.Status.FailedAttempts != 1 {
+		t.Errorf("Expected 1 failed attempt, got %d", kubegresRestore.Status.FailedAttempts)
+	}
+}
+
+func TestFailedRestoreJobDeletesClusterDeployedByRestoreBeforeItsPvcs(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(3, 30, false)
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now().Add(-time.Minute),
+		states.KubegresStates{IsDeployed: true, IsManagedByKubegresRestore: true, Kubegres: kubegres},
+		kubegres, createPvcToTest("postgres-db-postgres-1-0"))
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := kubeClient.Get(context.Background(), client.ObjectKeyFromObject(kubegres), &v1.Kubegres{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the kubegres resource deployed by the restore to be deleted, got: %v", err)
+	}
+	thenPvcIsDeployed(t, kubeClient, "postgres-db-postgres-1-0", true)
+	thenRestoreJobIsDeployed(t, kubeClient, true)
+}
+
+func TestFailedInPlaceRestoreJobIsDeletedWithoutRemovingClusterAndPvcs(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(3, 30, true)
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now().Add(-time.Minute),
+		states.KubegresStates{IsDeployed: true, IsManagedByKubegresRestore: true, Kubegres: kubegres},
+		kubegres, createPvcToTest("postgres-db-postgres-1-0"))
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenRestoreJobIsDeployed(t, kubeClient, false)
+	thenPvcIsDeployed(t, kubeClient, "postgres-db-postgres-1-0", true)
+	getKubegresToTest(t, kubeClient)
+}
+
+func TestFailedRestoreJobStopsRestoreAfterMaxAttempts(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(2, 30, false)
+	previousFailureTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	kubegresRestore.Status.FailedAttempts = 1
+	kubegresRestore.Status.LastFailureTime = &previousFailureTime
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now().Add(-time.Minute),
+		states.KubegresStates{}, createPvcToTest("postgres-db-postgres-1-0"))
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if kubegresRestore.Status.FailedAttempts != 2 || kubegresRestore.Status.CurrentStage != ctx.StageRestoreJobFailed {
+		t.Errorf("Expected the restore to stop after 2 failed attempts, got %d failed attempts and the stage '%s'",
+			kubegresRestore.Status.FailedAttempts, kubegresRestore.Status.CurrentStage)
+	}
+	if enforcer.GetNbreSecondsLeftBeforeRetry() != 0 {
+		t.Errorf("Expected no retry, got a retry in %d seconds", enforcer.GetNbreSecondsLeftBeforeRetry())
+	}
+	thenRestoreJobIsDeployed(t, kubeClient, true)
+	thenPvcIsDeployed(t, kubeClient, "postgres-db-postgres-1-0", true)
+}
+
+func TestFailedRestoreJobRemovesPvcsAfterMaxAttemptsWhenCleanupOnFailureIsSet(t *testing.T) {
+	kubegresRestore := createKubegresRestoreToTest(1, 30, false)
+	kubegresRestore.Spec.CleanupOnFailure = true
+	enforcer, kubeClient := createFailedRestoreJobSpecEnforcerToTest(kubegresRestore, time.Now(),
+		states.KubegresStates{}, createPvcToTest("postgres-db-postgres-1-0"), createPvcToTest("postgres-backup"))
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if kubegresRestore.Status.CurrentStage != ctx.StageRestoreJobFailed {
+		t.Errorf("Expected the stage '%s', got '%s'", ctx.StageRestoreJobFailed, kubegresRestore.Status.CurrentStage)
+	}
+	thenPvcIsDeployed(t, kubeClient, "postgres-db-postgres-1-0", false)
+	thenPvcIsDeployed(t, kubeClient, "postgres-backup", true)
+}
+
+func createKubegresRestoreToTest(maxAttempts, backoffSeconds int32, isInPlace bool) *v1.KubegresRestore {
+	kubegresRestore := &v1.KubegresRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec: v1.KubegresRestoreSpec{
+			ClusterName: "postgres",
+			RetryPolicy: &v1.RestoreRetryPolicy{MaxAttempts: maxAttempts, BackoffSeconds: backoffSeconds},
+		},
+	}
+	if isInPlace {
+		kubegresRestore.Spec.InPlace = &v1.InPlace{ConfirmClusterName: "postgres"}
+	}
+	return kubegresRestore
+}
+
+func createFailedRestoreJobSpecEnforcerToTest(kubegresRestore *v1.KubegresRestore, failureTime time.Time,
+	clusterStates states.KubegresStates, objects ...client.Object) (FailedRestoreJobSpecEnforcer, client.Client) {
+
+	restoreJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "restore-job", Namespace: "default"}}
+
+	kubegresRestoreContext := createKubegresRestoreContextToTest(kubegresRestore, append(objects, restoreJob)...)
+
+	restoreStates := states.RestoreResourceStates{Cluster: clusterStates}
+	restoreStates.Job = states.RestoreJobStates{
+		IsJobDeployed:  true,
+		JobPhase:       states.JobFailed,
+		FailureTime:    metav1.NewTime(failureTime.Truncate(time.Second)),
+		FailureReason:  "Container 'restore' exited with code 1",
+		FailurePodName: "restore-job-abcde",
+		Job:            restoreJob,
+	}
+
+	return CreateFailedRestoreJobSpecEnforcer(kubegresRestoreContext, restoreStates), kubegresRestoreContext.Client
+}
+
+func createPvcToTest(name string) *core.PersistentVolumeClaim {
+	return &core.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "postgres"}},
+	}
+}
+
+func thenRestoreJobIsDeployed(t *testing.T, kubeClient client.Client, isDeployed bool) {
+	err := kubeClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "restore-job"}, &batchv1.Job{})
+	if isDeployed && err != nil {
+		t.Errorf("Expected the restore job to be deployed, got: %v", err)
+	} else if !isDeployed && !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the restore job to be deleted, got: %v", err)
+	}
+}
+
+func thenPvcIsDeployed(t *testing.T, kubeClient client.Client, name string, isDeployed bool) {
+	pvcKey := client.ObjectKey{Namespace: "default", Name: name}
+	err := kubeClient.Get(context.Background(), pvcKey, &core.PersistentVolumeClaim{})
+	if isDeployed && err != nil {
+		t.Errorf("Expected the PVC '%s' to be deployed, got: %v", pvcKey.Name, err)
+	} else if !isDeployed && !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the PVC '%s' to be deleted, got: %v", pvcKey.Name, err)
+	}
+}
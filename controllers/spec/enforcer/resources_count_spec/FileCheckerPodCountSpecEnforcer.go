@@ -19,6 +19,7 @@ import (
 	"path"
 	"strings"
 
+	core "k8s.io/api/core/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
@@ -78,9 +79,35 @@ func (r *FileCheckerPodCountSpecEnforcer) deleteFileCheckerPod() error {
 }
 
 func (r *FileCheckerPodCountSpecEnforcer) isThereSpecDifference() bool {
-	return r.haveMountpathSpecChanged() || // Mountpath
-		r.haveSnapshotSpecChanged() || // Snapshot file
-		r.havePVCSpecChanged() // PVC name
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		return r.haveObjectStoreSpecChanged()
+	}
+
+	return r.havePVCSpecChanged() || // PVC name
+		r.haveMountpathSpecChanged() || // Mountpath
+		r.haveSnapshotSpecChanged() // Snapshot file
+}
+
+func (r *FileCheckerPodCountSpecEnforcer) haveObjectStoreSpecChanged() bool {
+	podSpec := r.restoreStates.FileChecker.Pod.Spec
+	if len(podSpec.Volumes) > 0 && podSpec.Volumes[0].PersistentVolumeClaim != nil {
+		return true
+	}
+
+	objectStoreS3 := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.ObjectStore.S3
+	container := podSpec.Containers[0]
+	return container.Env[0].Value != r.kubegresRestoreContext.GetObjectStoreSnapshotUrl() ||
+		container.EnvFrom[0].SecretRef.Name != objectStoreS3.CredentialsSecret ||
+		r.getEnvVarValue(container.Env, "S3_ENDPOINT") != objectStoreS3.Endpoint
+}
+
+func (r *FileCheckerPodCountSpecEnforcer) getEnvVarValue(envVars []core.EnvVar, envVarName string) string {
+	for _, envVar := range envVars {
+		if envVar.Name == envVarName {
+			return envVar.Value
+		}
+	}
+	return ""
 }
 
 func (r *FileCheckerPodCountSpecEnforcer) haveMountpathSpecChanged() bool {
@@ -97,7 +124,11 @@ func (r *FileCheckerPodCountSpecEnforcer) haveSnapshotSpecChanged() bool {
 }
 
 func (r *FileCheckerPodCountSpecEnforcer) havePVCSpecChanged() bool {
-	expected := r.restoreStates.FileChecker.Pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName
+	podSpec := r.restoreStates.FileChecker.Pod.Spec
+	if len(podSpec.Volumes) == 0 || podSpec.Volumes[0].PersistentVolumeClaim == nil {
+		return true
+	}
+	expected := podSpec.Volumes[0].PersistentVolumeClaim.ClaimName
 	actual := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.File.PvcName
 	return expected != actual
 }
@@ -111,8 +142,12 @@ func (r *FileCheckerPodCountSpecEnforcer) isSnapshotFileNotFoundInPvc() bool {
 }
 
 func (r *FileCheckerPodCountSpecEnforcer) logSnapshotNotFoundErrorEvent() {
+	snapshotSpecName := "spec.DataSource.File.Snapshot"
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		snapshotSpecName = "spec.DataSource.ObjectStore.Snapshot"
+	}
 	errorMsg := "In the Resources Spec the file specified by " +
-		"'spec.DataSource.File.Snapshot' is not found. Please make sure the filename is correct."
+		"'" + snapshotSpecName + "' is not found. Please make sure the filename is correct."
 	if len(r.restoreStates.FileChecker.MostRecentSnapshots) > 0 {
 		errorMsg += " The most recent snapshots found are " +
 			prettyStringFromFileArray(r.restoreStates.FileChecker.MostRecentSnapshots) +
@@ -46,12 +46,68 @@ func (r *JobCountSpecEnforcer) EnforceSpec() error {
 		return nil
 	}
 
+	if r.restoreStates.Job.IsPhysicalRestore {
+		return r.deployPhysicalRestoreJob()
+	}
+
+	if r.kubegresRestoreContext.IsInPlaceRestore() && !r.isClusterScaledDown() {
+		return nil
+	}
+
 	if r.isClusterReady() {
 		return r.deployRestoreJob()
 	}
 	return nil
 }
 
+// isClusterScaledDown returns true once the Kubegres cluster restored in place is scaled down to its Primary.
+func (r *JobCountSpecEnforcer) isClusterScaledDown() bool {
+	return r.restoreStates.Cluster.IsManagedByKubegresRestore && r.restoreStates.Cluster.NbreDeployedReplicas == 0
+}
+
+// For a physical restore, the restore job runs before the Kubegres cluster is deployed since it prepares the
+// database of the Primary in a PVC which is then claimed by the StatefulSet of the Primary.
+func (r *JobCountSpecEnforcer) deployPhysicalRestoreJob() error {
+	if r.isClusterDeployed() {
+		return nil
+	}
+
+	if !r.restoreStates.Job.IsTargetPrimaryDbPvcDeployed {
+		pvc, err := r.resourcesCreator.CreateTargetPrimaryDbPvc(r.kubegresSpec)
+		if err != nil {
+			r.kubegresRestoreContext.Log.ErrorEvent("TargetPvcTemplateErr", err, "Unable to create PVC object of the Primary to restore.")
+			return err
+		}
+
+		err = r.kubegresRestoreContext.Client.Create(r.kubegresRestoreContext.Ctx, &pvc)
+		if err != nil {
+			r.kubegresRestoreContext.Log.ErrorEvent("TargetPvcDeploymentErr", err, "Unable to deploy PVC of the Primary to restore.", "PVC name", pvc.Name)
+			return err
+		}
+
+		r.kubegresRestoreContext.Log.InfoEvent("TargetPvcDeployment", "Deployed PVC of the Primary to restore.", "PVC name", pvc.Name)
+	}
+
+	restoreJobTemplate, err := r.resourcesCreator.CreatePhysicalRestoreJob(r.kubegresSpec)
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("JobTemplateErr", err, "Unable to create physical restore job object from template.")
+		return err
+	}
+
+	err = r.kubegresRestoreContext.Client.Create(r.kubegresRestoreContext.Ctx, &restoreJobTemplate)
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("JobDeploymentErr", err, "Unable to deploy physical restore job.")
+		return err
+	}
+
+	r.kubegresRestoreContext.Log.InfoEvent("JobDeployment", "Deployed physical restore job.", "Job name", restoreJobTemplate.Name)
+	return nil
+}
+
+func (r *JobCountSpecEnforcer) isClusterDeployed() bool {
+	return r.restoreStates.Cluster.IsDeployed
+}
+
 func (r *JobCountSpecEnforcer) deployRestoreJob() error {
 	restoreJobTemplate, err := r.resourcesCreator.CreateRestoreJob(r.kubegresSpec)
 	if err != nil {
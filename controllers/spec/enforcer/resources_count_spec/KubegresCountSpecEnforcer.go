@@ -15,10 +15,16 @@ limitations under the License.
 package resources_count_spec
 
 import (
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	kubegresv1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
 	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type KubegresCountSpecEnforcer struct {
@@ -46,18 +52,181 @@ func (r *KubegresCountSpecEnforcer) EnforceSpec() error {
 		return nil
 	}
 
+	// The retries of a failed restore job are handled by FailedRestoreJobSpecEnforcer
+	if r.restoreStates.Job.JobPhase == states.JobFailed {
+		if r.kubegresRestoreContext.IsInPlaceRestore() && r.isRestoreStopped() {
+			return r.releaseInPlaceRestoredKubegres()
+		}
+		return nil
+	}
+
+	if r.kubegresRestoreContext.IsInPlaceRestore() {
+		return r.enforceInPlaceRestoreSpec()
+	}
+
+	if r.restoreStates.Job.IsPhysicalRestore {
+		return r.enforcePhysicalRestoreSpec()
+	}
+
 	if !r.isClusterDeployed() {
 		return r.deployKubegres()
 	}
 
-	if r.isJobCompleted() {
-		r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageRestoreJobIsCompleted)
+	if r.isJobCompleted() && r.isRestoreVerified() {
+		r.completeRestore()
 		return r.finalizeKubegres()
 	}
 
 	return nil
 }
 
+// For a physical restore, the Kubegres cluster is deployed once the restore job has unpacked the base backup in the
+// database of its Primary. The restore is completed once the Primary has recovered and is ready.
+func (r *KubegresCountSpecEnforcer) enforcePhysicalRestoreSpec() error {
+	if !r.isJobCompleted() {
+		return nil
+	}
+
+	if !r.isClusterDeployed() {
+		return r.deployKubegres()
+	}
+
+	if !r.restoreStates.Cluster.IsReady || !r.isRestoreVerified() {
+		return nil
+	}
+
+	r.completeRestore()
+	return r.finalizeKubegres()
+}
+
+// For an in-place restore, the existing Kubegres cluster is scaled down to its Primary before the restore job runs.
+// Once the job has completed, the cluster is scaled back up and its Replicas are re-created from a copy of the
+// restored Primary.
+func (r *KubegresCountSpecEnforcer) enforceInPlaceRestoreSpec() error {
+	if !r.isJobCompleted() {
+		if !r.restoreStates.Cluster.IsManagedByKubegresRestore {
+			return r.scaleDownKubegres()
+		}
+		if r.restoreStates.Cluster.NbreDeployedReplicas > 0 {
+			r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageScalingDownCluster)
+		}
+		return nil
+	}
+
+	if !r.isRestoreVerified() {
+		if r.isRestoreStopped() {
+			return r.releaseInPlaceRestoredKubegres()
+		}
+		return nil
+	}
+
+	if err := r.disableReuseOfReplicaPvcs(); err != nil {
+		return err
+	}
+
+	if err := r.finalizeKubegres(); err != nil {
+		return err
+	}
+
+	r.completeRestore()
+	return nil
+}
+
+// releaseInPlaceRestoredKubegres is called once an in-place restore has stopped without completing, either because
+// the last attempt of the restore job has failed or because the verification has failed. The Kubegres cluster is
+// scaled back up and released so that it is not left with its Primary only. The PVCs of the removed Replicas are not
+// reused: they keep the databases as they were before the restore.
+func (r *KubegresCountSpecEnforcer) releaseInPlaceRestoredKubegres() error {
+	if !r.restoreStates.Cluster.IsManagedByKubegresRestore {
+		return nil
+	}
+
+	if err := r.disableReuseOfReplicaPvcs(); err != nil {
+		return err
+	}
+
+	kubegres := r.restoreStates.Cluster.Kubegres
+	if err := r.finalizeKubegres(); err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("KubegresScaleUpErr", err, "Unable to scale back up kubegres resource after the in-place restore has failed.", "Kubegres name", kubegres.Name)
+		return err
+	}
+
+	r.kubegresRestoreContext.Log.WarningEvent("InPlaceRestoreAborted", "The in-place restore has failed. Scaled back up kubegres resource without completing the restore.", "Kubegres name", kubegres.Name)
+	return nil
+}
+
+// completeRestore marks the restore as completed once the backup is restored and, if the field 'verify' is set,
+// all the checks have passed.
+func (r *KubegresCountSpecEnforcer) completeRestore() {
+	if !r.kubegresRestoreContext.Status.GetIsCompleted() {
+		r.kubegresRestoreContext.Log.InfoEvent("RestoreJobCompleted", "Restorejob has completed succesfully.")
+		metrics.IncRestores(types.NamespacedName{
+			Namespace: r.kubegresRestoreContext.KubegresRestore.Namespace,
+			Name:      r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName,
+		}, metrics.OutcomeSucceeded)
+	}
+
+	r.kubegresRestoreContext.Status.SetIsCompleted(true)
+	r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageRestoreJobIsCompleted)
+}
+
+// scaleDownKubegres scales the existing Kubegres cluster down to its Primary and labels it as managed by this
+// KubegresRestore. The number of instances to scale back up to is kept in an annotation of the Kubegres resource.
+func (r *KubegresCountSpecEnforcer) scaleDownKubegres() error {
+	var replicas int32 = 1
+	kubegres := r.restoreStates.Cluster.Kubegres
+
+	if kubegres.Labels == nil {
+		kubegres.Labels = map[string]string{}
+	}
+	if kubegres.Annotations == nil {
+		kubegres.Annotations = map[string]string{}
+	}
+	kubegres.Labels[ctx.ManagedByKubegresRestoreLabel] = r.kubegresRestoreContext.KubegresRestore.Name
+	if kubegres.Spec.Replicas != nil {
+		kubegres.Annotations[ctx.InPlaceRestoreReplicasAnnotationKey] = strconv.Itoa(int(*kubegres.Spec.Replicas))
+	}
+	kubegres.Spec.Replicas = &replicas
+
+	err := r.kubegresRestoreContext.Client.Update(r.kubegresRestoreContext.Ctx, kubegres)
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("KubegresScaleDownErr", err, "Unable to scale down kubegres resource to its Primary.", "Kubegres name", kubegres.Name)
+		return err
+	}
+
+	r.kubegresRestoreContext.Log.InfoEvent("KubegresScaledDown", "Scaled down kubegres resource to its Primary before restoring the backup in place.", "Kubegres name", kubegres.Name)
+	r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageScalingDownCluster)
+	return nil
+}
+
+// disableReuseOfReplicaPvcs prevents the PVCs of the Replicas removed by the scale down from being reused when the
+// Kubegres cluster is scaled back up, since their databases were not restored. New Replicas copy the restored Primary.
+func (r *KubegresCountSpecEnforcer) disableReuseOfReplicaPvcs() error {
+	pvcList := &core.PersistentVolumeClaimList{}
+	err := r.kubegresRestoreContext.Client.List(r.kubegresRestoreContext.Ctx, pvcList,
+		client.InNamespace(r.kubegresRestoreContext.KubegresRestore.Namespace),
+		client.MatchingLabels{"app": r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName})
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("DatabasePvcLoadingErr", err, "Unable to load the PVCs of the kubegres resource restored in place.")
+		return err
+	}
+
+	for _, pvc := range pvcList.Items {
+		if pvc.Annotations[ctx.ReusablePvcAnnotationKey] != "true" {
+			continue
+		}
+
+		pvc.Annotations[ctx.ReusablePvcAnnotationKey] = "false"
+		pvc.Annotations[ctx.FailedPrimaryPvcAnnotationKey] = "false"
+		if err := r.kubegresRestoreContext.Client.Update(r.kubegresRestoreContext.Ctx, &pvc); err != nil {
+			r.kubegresRestoreContext.Log.ErrorEvent("DatabasePvcUpdateErr", err, "Unable to mark the PVC of a removed Replica as not reusable.", "PVC name", pvc.Name)
+			return err
+		}
+		r.kubegresRestoreContext.Log.InfoEvent("DatabasePvcNotReusable", "The PVC of a Replica removed before the restore will not be reused. It can be deleted manually.", "PVC name", pvc.Name)
+	}
+	return nil
+}
+
 func (r *KubegresCountSpecEnforcer) deployKubegres() error {
 	kubegresTemplate := r.resourcesCreator.CreateKubegresResource(r.targetKubegresSpec)
 	err := r.kubegresRestoreContext.Client.Create(r.kubegresRestoreContext.Ctx, &kubegresTemplate)
@@ -74,12 +243,18 @@ func (r *KubegresCountSpecEnforcer) deployKubegres() error {
 func (r *KubegresCountSpecEnforcer) finalizeKubegres() error {
 	kubegresIsChanged := false
 	kubegres := r.restoreStates.Cluster.Kubegres
-	if r.kubegresHasReplicas() {
+	if r.kubegresRestoreContext.IsInPlaceRestore() {
+		if replicas, exists := kubegres.Annotations[ctx.InPlaceRestoreReplicasAnnotationKey]; exists {
+			kubegresIsChanged = true
+			kubegres.Spec.Replicas = r.parseReplicas(replicas)
+			delete(kubegres.Annotations, ctx.InPlaceRestoreReplicasAnnotationKey)
+		}
+	} else if r.kubegresHasReplicas() {
 		kubegresIsChanged = true
 		kubegres.Spec.Replicas = r.kubegresRestoreContext.SourceKubegresClusterSpec.Replicas
 	}
 
-	if r.kubegresRestoreContext.AreResourcesSpecifiedForRestoreJob() {
+	if r.kubegresRestoreContext.AreResourcesSpecifiedForRestoreJob() && !r.kubegresRestoreContext.IsInPlaceRestore() {
 		kubegresIsChanged = true
 		kubegres.Spec.Resources = r.kubegresRestoreContext.SourceKubegresClusterSpec.Resources
 	}
@@ -98,6 +273,16 @@ func (r *KubegresCountSpecEnforcer) finalizeKubegres() error {
 	return nil
 }
 
+func (r *KubegresCountSpecEnforcer) parseReplicas(value string) *int32 {
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		r.kubegresRestoreContext.Log.Error(err, "Unable to parse the number of instances to scale back up to.", "Value", value)
+		replicas = 1
+	}
+	replicas32 := int32(replicas)
+	return &replicas32
+}
+
 func (r *KubegresCountSpecEnforcer) isClusterDeployed() bool {
 	return r.restoreStates.Cluster.IsDeployed
 }
@@ -106,6 +291,17 @@ func (r *KubegresCountSpecEnforcer) isJobCompleted() bool {
 	return r.restoreStates.Job.JobPhase == states.JobSucceded
 }
 
+func (r *KubegresCountSpecEnforcer) isRestoreVerified() bool {
+	return !r.kubegresRestoreContext.IsVerificationEnabled() || r.restoreStates.Verification.JobPhase == states.JobSucceded
+}
+
+// isRestoreStopped returns true once FailedRestoreJobSpecEnforcer or VerificationJobCountSpecEnforcer has stopped the
+// restore.
+func (r *KubegresCountSpecEnforcer) isRestoreStopped() bool {
+	currentStage := r.kubegresRestoreContext.Status.GetCurrentStage()
+	return currentStage == ctx.StageRestoreJobFailed || currentStage == ctx.StageVerificationFailed
+}
+
 func (r *KubegresCountSpecEnforcer) kubegresHasReplicas() bool {
 	return r.kubegresRestoreContext.SourceKubegresClusterSpec.Replicas != nil
 }
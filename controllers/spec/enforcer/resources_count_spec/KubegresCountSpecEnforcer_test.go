@@ -0,0 +1,227 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestInPlaceRestoreScalesDownKubegresBeforeRestoreJob(t *testing.T) {
+	kubegres := createInPlaceRestoredKubegresToTest(false)
+	enforcer, kubeClient, kubegresRestore := createKubegresCountSpecEnforcerToTest(kubegres, states.JobPending, "")
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	deployedKubegres := getKubegresToTest(t, kubeClient)
+	if *deployedKubegres.Spec.Replicas != 1 {
+		t.Errorf("Expected the kubegres resource to be scaled down to its Primary, got %d instances", *deployedKubegres.Spec.Replicas)
+	}
+	if deployedKubegres.Annotations[ctx.InPlaceRestoreReplicasAnnotationKey] != "3" {
+		t.Errorf("Expected the number of instances before the restore to be kept, got %v", deployedKubegres.Annotations)
+	}
+	if deployedKubegres.Labels[ctx.ManagedByKubegresRestoreLabel] != kubegresRestore.Name {
+		t.Errorf("Expected the kubegres resource to be managed by the restore, got %v", deployedKubegres.Labels)
+	}
+	if kubegresRestore.Status.CurrentStage != ctx.StageScalingDownCluster {
+		t.Errorf("Expected the stage '%s', got '%s'", ctx.StageScalingDownCluster, kubegresRestore.Status.CurrentStage)
+	}
+}
+
+func TestInPlaceRestoreScalesUpKubegresOnceRestoreJobHasSucceeded(t *testing.T) {
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient, kubegresRestore := createKubegresCountSpecEnforcerToTest(kubegres, states.JobSucceded, ctx.StageRestoreJobIsRunning)
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenKubegresIsScaledBackUpAndReleased(t, kubeClient)
+	thenReplicaPvcIsNotReusable(t, kubeClient)
+	if !kubegresRestore.Status.IsCompleted || kubegresRestore.Status.CurrentStage != ctx.StageRestoreJobIsCompleted {
+		t.Errorf("Expected the restore to be completed, got stage '%s'", kubegresRestore.Status.CurrentStage)
+	}
+}
+
+func TestFailedInPlaceRestoreKeepsKubegresScaledDownWhileWaitingForRetry(t *testing.T) {
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient, _ := createKubegresCountSpecEnforcerToTest(kubegres, states.JobFailed, ctx.StageWaitingForRetry)
+	resourceVersion := getKubegresToTest(t, kubeClient).ResourceVersion
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if getKubegresToTest(t, kubeClient).ResourceVersion != resourceVersion {
+		t.Error("Expected the kubegres resource to not be updated before the last attempt of the restore job")
+	}
+}
+
+func TestFailedInPlaceRestoreScalesUpKubegresOnceRestoreIsStopped(t *testing.T) {
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient, kubegresRestore := createKubegresCountSpecEnforcerToTest(kubegres, states.JobFailed, ctx.StageRestoreJobFailed)
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenKubegresIsScaledBackUpAndReleased(t, kubeClient)
+	thenReplicaPvcIsNotReusable(t, kubeClient)
+	if kubegresRestore.Status.IsCompleted || kubegresRestore.Status.CurrentStage != ctx.StageRestoreJobFailed {
+		t.Errorf("Expected the restore to stay failed, got stage '%s'", kubegresRestore.Status.CurrentStage)
+	}
+}
+
+func TestFailedVerificationOfInPlaceRestoreScalesUpKubegres(t *testing.T) {
+	kubegres := createInPlaceRestoredKubegresToTest(true)
+	enforcer, kubeClient, kubegresRestore := createKubegresCountSpecEnforcerToTest(kubegres, states.JobSucceded, ctx.StageVerificationFailed)
+	kubegresRestore.Spec.Verify = &v1.RestoreVerification{}
+	enforcer.restoreStates.Verification.JobPhase = states.JobFailed
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	thenKubegresIsScaledBackUpAndReleased(t, kubeClient)
+	if kubegresRestore.Status.IsCompleted {
+		t.Error("Expected the restore to not be completed")
+	}
+}
+
+func createKubegresCountSpecEnforcerToTest(kubegres *v1.Kubegres, jobPhase states.JobPhase, currentStage string) (KubegresCountSpecEnforcer, client.Client, *v1.KubegresRestore) {
+
+	kubegresRestore := &v1.KubegresRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec: v1.KubegresRestoreSpec{
+			ClusterName: kubegres.Name,
+			InPlace:     &v1.InPlace{ConfirmClusterName: kubegres.Name},
+		},
+		Status: v1.KubegresRestoreStatus{CurrentStage: currentStage},
+	}
+
+	replicaPvc := &core.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ctx.DatabaseVolumeName + "-" + kubegres.Name + "-2-0",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": kubegres.Name},
+			Annotations: map[string]string{ctx.ReusablePvcAnnotationKey: "true"},
+		},
+	}
+
+	kubegresRestoreContext := createKubegresRestoreContextToTest(kubegresRestore, kubegres, replicaPvc)
+	kubeClient := kubegresRestoreContext.Client
+
+	deployedKubegres := &v1.Kubegres{}
+	_ = kubeClient.Get(context.Background(), client.ObjectKeyFromObject(kubegres), deployedKubegres)
+
+	restoreStates := states.RestoreResourceStates{}
+	restoreStates.FileChecker.ExitStatus = states.OkExitStatus
+	restoreStates.Job.JobPhase = jobPhase
+	restoreStates.Cluster = states.KubegresStates{
+		IsDeployed:                 true,
+		IsReady:                    true,
+		IsManagedByKubegresRestore: deployedKubegres.Labels[ctx.ManagedByKubegresRestoreLabel] != "",
+		Kubegres:                   deployedKubegres,
+	}
+
+	return CreateKubegresCountSpecEnforcer(kubegresRestoreContext, restoreStates, v1.KubegresSpec{}), kubeClient, kubegresRestore
+}
+
+// createKubegresRestoreContextToTest creates the context of the given KubegresRestore with a fake client in which
+// the given objects are deployed. Its events are recorded by a FakeRecorder.
+func createKubegresRestoreContextToTest(kubegresRestore *v1.KubegresRestore, objects ...client.Object) ctx.KubegresRestoreContext {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	restoreLog := log.LogWrapper[*v1.KubegresRestore]{Resource: kubegresRestore, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	return ctx.KubegresRestoreContext{
+		KubegresRestore: kubegresRestore,
+		Status:          &status.RestoreStatusWrapper{KubegresRestore: kubegresRestore, Log: restoreLog},
+		Ctx:             context.Background(),
+		Log:             restoreLog,
+		Client:          kubeClient,
+	}
+}
+
+func createInPlaceRestoredKubegresToTest(isScaledDown bool) *v1.Kubegres {
+	var replicas int32 = 3
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec:       v1.KubegresSpec{Replicas: &replicas},
+	}
+
+	if isScaledDown {
+		var scaledDownReplicas int32 = 1
+		kubegres.Spec.Replicas = &scaledDownReplicas
+		kubegres.Labels = map[string]string{ctx.ManagedByKubegresRestoreLabel: "restore"}
+		kubegres.Annotations = map[string]string{ctx.InPlaceRestoreReplicasAnnotationKey: "3"}
+	}
+	return kubegres
+}
+
+func thenKubegresIsScaledBackUpAndReleased(t *testing.T, kubeClient client.Client) {
+	deployedKubegres := getKubegresToTest(t, kubeClient)
+	if deployedKubegres.Spec.Replicas == nil || *deployedKubegres.Spec.Replicas != 3 {
+		t.Errorf("Expected the kubegres resource to be scaled back up to 3 instances, got %v", deployedKubegres.Spec.Replicas)
+	}
+	if _, exists := deployedKubegres.Annotations[ctx.InPlaceRestoreReplicasAnnotationKey]; exists {
+		t.Errorf("Expected the annotation '%s' to be removed", ctx.InPlaceRestoreReplicasAnnotationKey)
+	}
+	if _, exists := deployedKubegres.Labels[ctx.ManagedByKubegresRestoreLabel]; exists {
+		t.Errorf("Expected the label '%s' to be removed", ctx.ManagedByKubegresRestoreLabel)
+	}
+}
+
+func thenReplicaPvcIsNotReusable(t *testing.T, kubeClient client.Client) {
+	pvc := core.PersistentVolumeClaim{}
+	pvcKey := client.ObjectKey{Namespace: "default", Name: ctx.DatabaseVolumeName + "-postgres-2-0"}
+	if err := kubeClient.Get(context.Background(), pvcKey, &pvc); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if pvc.Annotations[ctx.ReusablePvcAnnotationKey] != "false" {
+		t.Errorf("Expected the PVC of the removed Replica to not be reusable, got %v", pvc.Annotations)
+	}
+}
+
+func getKubegresToTest(t *testing.T, kubeClient client.Client) v1.Kubegres {
+	kubegres := v1.Kubegres{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "postgres"}, &kubegres); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return kubegres
+}
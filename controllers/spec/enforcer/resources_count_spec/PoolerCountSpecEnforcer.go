@@ -0,0 +1,383 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/postgres"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PoolerCountSpecEnforcer deploys a PgBouncer Deployment and Service in front of the Primary and, optionally, in
+// front of the Replicas. The Pods of the Primary Pooler are re-created when the Primary changes (e.g. after a
+// failover), so that PgBouncer does not keep any connections to the former Primary.
+//
+// PgBouncer looks up the passwords of the users with a dedicated auth user, which is not a superuser. Its password is
+// set in the env-var "POSTGRES_POOLER_PASSWORD" of the Kubegres resource. It can only execute a SECURITY DEFINER
+// function which returns the password of a given user, so that neither the superuser nor its password is used.
+type PoolerCountSpecEnforcer struct {
+	kubegresContext  ctx.KubegresContext
+	resourcesStates  states.ResourcesStates
+	resourcesCreator template.ResourcesCreatorFromTemplate
+	postgresClient   *postgres.PostgresClient
+}
+
+func CreatePoolerCountSpecEnforcer(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	resourcesCreator template.ResourcesCreatorFromTemplate,
+	postgresClient *postgres.PostgresClient) PoolerCountSpecEnforcer {
+
+	return PoolerCountSpecEnforcer{
+		kubegresContext:  kubegresContext,
+		resourcesStates:  resourcesStates,
+		resourcesCreator: resourcesCreator,
+		postgresClient:   postgresClient,
+	}
+}
+
+func (r *PoolerCountSpecEnforcer) EnforceSpec() error {
+
+	if !r.isPoolerEnabled() {
+		r.undeployPooler(r.resourcesStates.Pooler.Primary, true)
+		r.undeployPooler(r.resourcesStates.Pooler.Replica, false)
+		r.undeployConfigMap()
+		return nil
+	}
+
+	if !r.isPrimaryDbReady() {
+		return nil
+	}
+
+	poolerUserPassword, err := r.getPoolerUserPassword()
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerUserPasswordErr", err,
+			"Unable to get the password of the auth user of the Pooler from the environment variable '"+
+				ctx.EnvVarNameOfPostgresPoolerUserPsw+"'. The Pooler cannot be deployed.")
+		return err
+	}
+
+	configHash, err := r.enforceConfigMap(poolerUserPassword)
+	if err != nil {
+		return err
+	}
+
+	if err = r.enforceAuthUser(poolerUserPassword, configHash); err != nil {
+		return err
+	}
+
+	err = r.enforcePooler(r.resourcesStates.Pooler.Primary, true, configHash)
+	if err != nil {
+		return err
+	}
+
+	if !r.isPoolerEnabledForReplicas() {
+		r.undeployPooler(r.resourcesStates.Pooler.Replica, false)
+		return nil
+	}
+
+	if r.isThereReadyReplica() {
+		return r.enforcePooler(r.resourcesStates.Pooler.Replica, false, configHash)
+	}
+
+	return nil
+}
+
+func (r *PoolerCountSpecEnforcer) isPoolerEnabled() bool {
+	return r.kubegresContext.Kubegres.Spec.Pooler.Enabled
+}
+
+func (r *PoolerCountSpecEnforcer) isPoolerEnabledForReplicas() bool {
+	return r.kubegresContext.Kubegres.Spec.Pooler.EnabledForReplicas
+}
+
+func (r *PoolerCountSpecEnforcer) isPrimaryDbReady() bool {
+	return r.resourcesStates.StatefulSets.Primary.IsReady
+}
+
+func (r *PoolerCountSpecEnforcer) isThereReadyReplica() bool {
+	return r.resourcesStates.StatefulSets.Replicas.NbreReady > 0
+}
+
+// The hash of the configuration and of the password of the auth user is returned, so that the Pods of the Poolers are
+// re-created when one of them changes
+func (r *PoolerCountSpecEnforcer) enforceConfigMap(poolerUserPassword string) (string, error) {
+
+	expectedConfigMap := r.resourcesCreator.CreatePoolerConfigMap()
+	configHash := r.computeConfigHash(expectedConfigMap.Data, poolerUserPassword)
+	deployedConfigMap := r.resourcesStates.Pooler.ConfigMap
+
+	if !deployedConfigMap.IsDeployed {
+		if err := r.kubegresContext.Client.Create(r.kubegresContext.Ctx, &expectedConfigMap); err != nil {
+			r.kubegresContext.Log.ErrorEvent("PoolerConfigDeploymentErr", err, "Unable to deploy the config ConfigMap of the Pooler.", "ConfigMap name", expectedConfigMap.Name)
+			return "", err
+		}
+		r.kubegresContext.Log.InfoEvent("PoolerConfigDeployment", "Deployed the config ConfigMap of the Pooler.", "ConfigMap name", expectedConfigMap.Name)
+		return configHash, nil
+	}
+
+	if reflect.DeepEqual(deployedConfigMap.ConfigMap.Data, expectedConfigMap.Data) {
+		return configHash, nil
+	}
+
+	configMapToUpdate := deployedConfigMap.ConfigMap
+	configMapToUpdate.Data = expectedConfigMap.Data
+
+	if err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &configMapToUpdate); err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerConfigUpdateErr", err, "Unable to update the config ConfigMap of the Pooler.", "ConfigMap name", configMapToUpdate.Name)
+		return "", err
+	}
+
+	r.kubegresContext.Log.InfoEvent("PoolerConfigUpdate", "Updated the config ConfigMap of the Pooler.", "ConfigMap name", configMapToUpdate.Name)
+	return configHash, nil
+}
+
+// The auth user and its lookup function are created in the Primary before the Pods of the Primary Pooler are deployed
+// and each time the configuration or the password changes. The Replicas receive them by replication.
+func (r *PoolerCountSpecEnforcer) enforceAuthUser(poolerUserPassword, configHash string) error {
+
+	primaryPooler := r.resourcesStates.Pooler.Primary
+	if primaryPooler.IsDeploymentDeployed &&
+		primaryPooler.Deployment.Spec.Template.Annotations[ctx.PoolerConfigHashAnnotationKey] == configHash {
+		return nil
+	}
+
+	err := r.postgresClient.Exec(r.resourcesStates.StatefulSets.Primary.Pod.Pod, r.createAuthUserSqlStatements(poolerUserPassword)...)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerAuthUserErr", err, "Unable to create the auth user of the Pooler in the Primary.", "User name", ctx.PoolerAuthUserName)
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("PoolerAuthUser", "Created the auth user of the Pooler in the Primary.", "User name", ctx.PoolerAuthUserName)
+	return nil
+}
+
+// The lookup function is owned by the superuser and runs with its privileges. It does not return the passwords of the
+// superusers, so that they cannot connect through PgBouncer. Its schema is re-owned by the superuser in case another
+// user created it beforehand.
+func (r *PoolerCountSpecEnforcer) createAuthUserSqlStatements(poolerUserPassword string) []string {
+
+	authUser := ctx.PoolerAuthUserName
+	schema := ctx.PoolerAuthSchemaName
+	function := ctx.PoolerAuthLookupFunction + "(text)"
+	escapedPassword := strings.ReplaceAll(poolerUserPassword, "'", "''")
+
+	return []string{
+		"DO $kubegres$ BEGIN " +
+			"IF NOT EXISTS (SELECT FROM pg_catalog.pg_roles WHERE rolname = '" + authUser + "') THEN " +
+			"CREATE ROLE " + authUser + "; " +
+			"END IF; " +
+			"END $kubegres$",
+		"ALTER ROLE " + authUser + " WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT NOREPLICATION NOBYPASSRLS " +
+			"PASSWORD '" + escapedPassword + "'",
+		"CREATE SCHEMA IF NOT EXISTS " + schema,
+		"ALTER SCHEMA " + schema + " OWNER TO CURRENT_USER",
+		"REVOKE ALL ON SCHEMA " + schema + " FROM PUBLIC",
+		"GRANT USAGE ON SCHEMA " + schema + " TO " + authUser,
+		"CREATE OR REPLACE FUNCTION " + ctx.PoolerAuthLookupFunction + "(username text) " +
+			"RETURNS TABLE (usename name, passwd text) " +
+			"LANGUAGE sql STABLE SECURITY DEFINER SET search_path = pg_catalog, pg_temp AS $kubegres$ " +
+			"SELECT s.usename, s.passwd FROM pg_catalog.pg_shadow s WHERE s.usename = username AND NOT s.usesuper " +
+			"$kubegres$",
+		"ALTER FUNCTION " + function + " OWNER TO CURRENT_USER",
+		"REVOKE ALL ON FUNCTION " + function + " FROM PUBLIC",
+		"GRANT EXECUTE ON FUNCTION " + function + " TO " + authUser,
+	}
+}
+
+func (r *PoolerCountSpecEnforcer) enforcePooler(pooler states.PoolerWrapper, isPrimary bool, configHash string) error {
+
+	primaryOrReplicaTxt := r.createLogLabel(isPrimary)
+
+	podAnnotations := map[string]string{ctx.PoolerConfigHashAnnotationKey: configHash}
+	if isPrimary {
+		podAnnotations[ctx.PoolerPrimaryAnnotationKey] = r.resourcesStates.StatefulSets.Primary.StatefulSet.Name
+	}
+
+	expectedDeployment, err := r.resourcesCreator.CreatePoolerDeployment(isPrimary, podAnnotations)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerTemplateErr", err, "Unable to create "+primaryOrReplicaTxt+" Pooler Deployment object from template.")
+		return err
+	}
+
+	if !pooler.IsDeploymentDeployed {
+		if err = r.createResource(&expectedDeployment, primaryOrReplicaTxt+" Pooler Deployment"); err != nil {
+			return err
+		}
+
+	} else if r.hasDeploymentChanged(pooler, expectedDeployment.Spec.Replicas, expectedDeployment.Spec.Template) {
+
+		deploymentToUpdate := pooler.Deployment
+		deploymentToUpdate.Spec.Replicas = expectedDeployment.Spec.Replicas
+		deploymentToUpdate.Spec.Template = expectedDeployment.Spec.Template
+
+		if pooler.Deployment.Spec.Template.Annotations[ctx.PoolerPrimaryAnnotationKey] != podAnnotations[ctx.PoolerPrimaryAnnotationKey] {
+			r.kubegresContext.Log.InfoEvent("PoolerFollowingPrimary", "The Primary changed. Re-creating the Pods of the Pooler.",
+				"Primary name", podAnnotations[ctx.PoolerPrimaryAnnotationKey])
+		}
+
+		if err = r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &deploymentToUpdate); err != nil {
+			r.kubegresContext.Log.ErrorEvent("PoolerUpdateErr", err, "Unable to update "+primaryOrReplicaTxt+" Pooler Deployment.", "Deployment name", deploymentToUpdate.Name)
+			return err
+		}
+		r.kubegresContext.Log.InfoEvent("PoolerUpdate", "Updated "+primaryOrReplicaTxt+" Pooler Deployment.", "Deployment name", deploymentToUpdate.Name)
+	}
+
+	expectedService, err := r.resourcesCreator.CreatePoolerService(isPrimary)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerTemplateErr", err, "Unable to create "+primaryOrReplicaTxt+" Pooler Service object from template.")
+		return err
+	}
+
+	if !pooler.IsServiceDeployed {
+		return r.createResource(&expectedService, primaryOrReplicaTxt+" Pooler Service")
+	}
+
+	if pooler.Service.Spec.Ports[0].Port != expectedService.Spec.Ports[0].Port {
+		serviceToUpdate := pooler.Service
+		serviceToUpdate.Spec.Ports[0].Port = expectedService.Spec.Ports[0].Port
+
+		if err = r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &serviceToUpdate); err != nil {
+			r.kubegresContext.Log.ErrorEvent("PoolerUpdateErr", err, "Unable to update "+primaryOrReplicaTxt+" Pooler Service.", "Service name", serviceToUpdate.Name)
+			return err
+		}
+		r.kubegresContext.Log.InfoEvent("PoolerUpdate", "Updated "+primaryOrReplicaTxt+" Pooler Service.", "Service name", serviceToUpdate.Name)
+	}
+
+	return nil
+}
+
+// Only the fields set by Kubegres are compared, since Kubernetes sets default values in the other fields
+func (r *PoolerCountSpecEnforcer) hasDeploymentChanged(pooler states.PoolerWrapper, expectedReplicas *int32, expectedTemplate core.PodTemplateSpec) bool {
+
+	current := pooler.Deployment.Spec
+	currentContainer := current.Template.Spec.Containers[0]
+	expectedContainer := expectedTemplate.Spec.Containers[0]
+
+	return *current.Replicas != *expectedReplicas ||
+		!reflect.DeepEqual(current.Template.Annotations, expectedTemplate.Annotations) ||
+		currentContainer.Image != expectedContainer.Image ||
+		currentContainer.Ports[0].ContainerPort != expectedContainer.Ports[0].ContainerPort ||
+		!reflect.DeepEqual(currentContainer.Resources, expectedContainer.Resources) ||
+		!reflect.DeepEqual(currentContainer.Env, expectedContainer.Env) ||
+		current.Template.Spec.Volumes[0].ConfigMap.Items[0].Key != expectedTemplate.Spec.Volumes[0].ConfigMap.Items[0].Key
+}
+
+func (r *PoolerCountSpecEnforcer) createResource(resource client.Object, resourceLabel string) error {
+
+	if err := r.kubegresContext.Client.Create(r.kubegresContext.Ctx, resource); err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerDeploymentErr", err, "Unable to deploy "+resourceLabel+".", "Name", resource.GetName())
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("PoolerDeployment", "Deployed "+resourceLabel+".", "Name", resource.GetName())
+	return nil
+}
+
+func (r *PoolerCountSpecEnforcer) undeployPooler(pooler states.PoolerWrapper, isPrimary bool) {
+
+	primaryOrReplicaTxt := r.createLogLabel(isPrimary)
+
+	if pooler.IsDeploymentDeployed {
+		r.deleteResource(&pooler.Deployment, primaryOrReplicaTxt+" Pooler Deployment")
+	}
+
+	if pooler.IsServiceDeployed {
+		r.deleteResource(&pooler.Service, primaryOrReplicaTxt+" Pooler Service")
+	}
+}
+
+func (r *PoolerCountSpecEnforcer) undeployConfigMap() {
+	if r.resourcesStates.Pooler.ConfigMap.IsDeployed {
+		r.deleteResource(&r.resourcesStates.Pooler.ConfigMap.ConfigMap, "config ConfigMap of the Pooler")
+	}
+}
+
+func (r *PoolerCountSpecEnforcer) deleteResource(resource client.Object, resourceLabel string) {
+
+	if err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, resource); err != nil {
+		r.kubegresContext.Log.ErrorEvent("PoolerUndeploymentErr", err, "Unable to undeploy "+resourceLabel+".", "Name", resource.GetName())
+		return
+	}
+
+	r.kubegresContext.Log.InfoEvent("PoolerUndeployment", "Undeployed "+resourceLabel+".", "Name", resource.GetName())
+}
+
+func (r *PoolerCountSpecEnforcer) getPoolerUserPassword() (string, error) {
+
+	for _, envVar := range r.kubegresContext.Kubegres.Spec.Env {
+		if envVar.Name != ctx.EnvVarNameOfPostgresPoolerUserPsw {
+			continue
+		}
+
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			return envVar.Value, nil
+		}
+
+		secretKeyRef := envVar.ValueFrom.SecretKeyRef
+		secret := &core.Secret{}
+		secretKey := client.ObjectKey{Namespace: r.kubegresContext.Kubegres.Namespace, Name: secretKeyRef.Name}
+		if err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, secretKey, secret); err != nil {
+			return "", err
+		}
+
+		password, exists := secret.Data[secretKeyRef.Key]
+		if !exists {
+			return "", errors.New("The key '" + secretKeyRef.Key + "' does not exist in the Secret '" + secretKeyRef.Name + "'")
+		}
+		return string(password), nil
+	}
+
+	return "", errors.New("The environment variable '" + ctx.EnvVarNameOfPostgresPoolerUserPsw + "' is not set")
+}
+
+func (r *PoolerCountSpecEnforcer) computeConfigHash(config map[string]string, poolerUserPassword string) string {
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key + "=" + config[key] + "\n"))
+	}
+	hash.Write([]byte(poolerUserPassword))
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func (r *PoolerCountSpecEnforcer) createLogLabel(isPrimary bool) string {
+	if isPrimary {
+		return "Primary"
+	} else {
+		return "Replica"
+	}
+}
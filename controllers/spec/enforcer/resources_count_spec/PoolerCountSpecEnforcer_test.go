@@ -0,0 +1,203 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func TestPoolerConfigMapDoesNotContainAnyPassword(t *testing.T) {
+	enforcer, kubeClient := createPoolerCountSpecEnforcerToTest(states.ResourcesStates{})
+
+	if _, err := enforcer.enforceConfigMap("poolerPsw"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	configMap := core.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: "default", Name: "postgres" + ctx.PoolerNameSuffix + "-config"}
+	if err := kubeClient.Get(context.Background(), configMapKey, &configMap); err != nil {
+		t.Fatalf("Expected the config ConfigMap of the Pooler to be deployed, got: %v", err)
+	}
+
+	if len(configMap.Data) != 2 {
+		t.Errorf("Expected the ConfigMap to only contain the configuration of PgBouncer, got the keys %v", configMap.Data)
+	}
+
+	for key, ini := range configMap.Data {
+		if strings.Contains(ini, "poolerPsw") || strings.Contains(ini, "pg_shadow") {
+			t.Errorf("Expected '%s' to neither contain a password nor query pg_shadow, got:\n%s", key, ini)
+		}
+		if !strings.Contains(ini, "auth_user = "+ctx.PoolerAuthUserName+"\n") ||
+			!strings.Contains(ini, "auth_query = SELECT usename, passwd FROM "+ctx.PoolerAuthLookupFunction+"($1)\n") {
+			t.Errorf("Expected '%s' to look up the passwords with the auth user, got:\n%s", key, ini)
+		}
+	}
+}
+
+func TestPoolerConfigHashChangesWithThePasswordOfTheAuthUser(t *testing.T) {
+	enforcer, _ := createPoolerCountSpecEnforcerToTest(states.ResourcesStates{})
+
+	configHash, err := enforcer.enforceConfigMap("poolerPsw")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	enforcer.resourcesStates.Pooler.ConfigMap.IsDeployed = true
+	enforcer.resourcesStates.Pooler.ConfigMap.ConfigMap = enforcer.resourcesCreator.CreatePoolerConfigMap()
+
+	newConfigHash, err := enforcer.enforceConfigMap("newPoolerPsw")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if newConfigHash == configHash {
+		t.Error("Expected the config hash to change when the password of the auth user changes, so that the Pods of PgBouncer are re-created")
+	}
+}
+
+func TestPoolerAuthUserIsNotCreatedAgainWhenTheConfigDidNotChange(t *testing.T) {
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.Pooler.Primary.IsDeploymentDeployed = true
+	resourcesStates.Pooler.Primary.Deployment.Spec.Template.Annotations = map[string]string{ctx.PoolerConfigHashAnnotationKey: "configHash"}
+	enforcer, _ := createPoolerCountSpecEnforcerToTest(resourcesStates)
+
+	// The enforcer has no Postgres client: it would panic if it connected to the Primary.
+	if err := enforcer.enforceAuthUser("poolerPsw", "configHash"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestPoolerAuthUserCanOnlyExecuteTheLookupFunction(t *testing.T) {
+	enforcer, _ := createPoolerCountSpecEnforcerToTest(states.ResourcesStates{})
+
+	sqlStatements := strings.Join(enforcer.createAuthUserSqlStatements("pooler'Psw"), ";\n")
+
+	expectedStatements := []string{
+		"ALTER ROLE " + ctx.PoolerAuthUserName + " WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT NOREPLICATION NOBYPASSRLS PASSWORD 'pooler''Psw'",
+		"SECURITY DEFINER SET search_path = pg_catalog, pg_temp",
+		"AND NOT s.usesuper",
+		"REVOKE ALL ON SCHEMA " + ctx.PoolerAuthSchemaName + " FROM PUBLIC",
+		"REVOKE ALL ON FUNCTION " + ctx.PoolerAuthLookupFunction + "(text) FROM PUBLIC",
+		"GRANT EXECUTE ON FUNCTION " + ctx.PoolerAuthLookupFunction + "(text) TO " + ctx.PoolerAuthUserName,
+	}
+	for _, expectedStatement := range expectedStatements {
+		if !strings.Contains(sqlStatements, expectedStatement) {
+			t.Errorf("Expected the SQL statements to contain '%s', got:\n%s", expectedStatement, sqlStatements)
+		}
+	}
+}
+
+func TestPoolerUserPasswordIsReadFromTheSecretOfTheEnvVar(t *testing.T) {
+	enforcer, _ := createPoolerCountSpecEnforcerToTest(states.ResourcesStates{})
+
+	password, err := enforcer.getPoolerUserPassword()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if password != "poolerPsw" {
+		t.Errorf("Expected the password of the auth user to be 'poolerPsw', got '%s'", password)
+	}
+
+	enforcer.kubegresContext.Kubegres.Spec.Env = nil
+	if _, err = enforcer.getPoolerUserPassword(); err == nil {
+		t.Error("Expected an error when the env-var '" + ctx.EnvVarNameOfPostgresPoolerUserPsw + "' is not set")
+	}
+}
+
+func TestPoolerPodsGetThePasswordOfTheAuthUserFromTheEnvVar(t *testing.T) {
+	enforcer, _ := createPoolerCountSpecEnforcerToTest(states.ResourcesStates{})
+
+	deployment, err := enforcer.resourcesCreator.CreatePoolerDeployment(false, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	podSpec := deployment.Spec.Template.Spec
+	envVar := podSpec.Containers[0].Env[0]
+	if envVar.Name != ctx.EnvVarNameOfPostgresPoolerUserPsw || envVar.ValueFrom == nil ||
+		envVar.ValueFrom.SecretKeyRef.Name != "postgres-secret" || envVar.ValueFrom.SecretKeyRef.Key != "poolerUserPassword" {
+		t.Errorf("Expected the env-var '%s' to be copied from the Kubegres resource, got: %v", ctx.EnvVarNameOfPostgresPoolerUserPsw, envVar)
+	}
+
+	configVolume := podSpec.Volumes[0]
+	if configVolume.ConfigMap == nil || configVolume.ConfigMap.Name != "postgres"+ctx.PoolerNameSuffix+"-config" ||
+		configVolume.ConfigMap.Items[0].Key != template.PoolerConfigMapKeyReplicaIni {
+		t.Errorf("Expected the configuration of the Replica Pooler to be mounted from the config ConfigMap, got: %v", configVolume)
+	}
+}
+
+func createPoolerCountSpecEnforcerToTest(resourcesStates states.ResourcesStates) (PoolerCountSpecEnforcer, client.Client) {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: v1.KubegresSpec{
+			Port: 5432,
+			Env: []core.EnvVar{{
+				Name: ctx.EnvVarNameOfPostgresPoolerUserPsw,
+				ValueFrom: &core.EnvVarSource{SecretKeyRef: &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{Name: "postgres-secret"},
+					Key:                  "poolerUserPassword",
+				}},
+			}},
+			Pooler: v1.KubegresPooler{
+				Enabled:         true,
+				Image:           ctx.DefaultPoolerImage,
+				Port:            ctx.DefaultPoolerPortNumber,
+				Replicas:        ctx.DefaultPoolerReplicas,
+				PoolMode:        v1.PoolerPoolModeSession,
+				MaxClientConn:   ctx.DefaultPoolerMaxClientConn,
+				DefaultPoolSize: ctx.DefaultPoolerDefaultPoolSize,
+			},
+		},
+	}
+
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres-secret", Namespace: "default"},
+		Data:       map[string][]byte{"poolerUserPassword": []byte("poolerPsw")},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Client:   kubeClient,
+		Ctx:      context.Background(),
+		Log:      log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10)},
+	}
+
+	resourcesCreator := template.CreateResourcesCreatorFromTemplate(kubegresContext, template.CustomConfigSpecHelper{},
+		template.WalArchiveSpecHelper{}, template.ExtraContainersSpecHelper{}, template.ResourceTemplateLoader{})
+
+	return CreatePoolerCountSpecEnforcer(kubegresContext, resourcesStates, resourcesCreator, nil), kubeClient
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"strconv"
+
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReplicaReadyServiceCountSpecEnforcer deploys a Service in front of the Replicas whose replication lag is below
+// 'spec.services.replicaReady.maxLagBytes'. The Service selects the Pods having the label
+// 'kubegres.reactive-tech.io/replica-ready' which is added to or removed from the Pods at each reconciliation,
+// depending on the replication lag measured by Kubegres.
+type ReplicaReadyServiceCountSpecEnforcer struct {
+	kubegresContext  ctx.KubegresContext
+	resourcesStates  states.ResourcesStates
+	resourcesCreator template.ResourcesCreatorFromTemplate
+}
+
+func CreateReplicaReadyServiceCountSpecEnforcer(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	resourcesCreator template.ResourcesCreatorFromTemplate) ReplicaReadyServiceCountSpecEnforcer {
+
+	return ReplicaReadyServiceCountSpecEnforcer{
+		kubegresContext:  kubegresContext,
+		resourcesStates:  resourcesStates,
+		resourcesCreator: resourcesCreator,
+	}
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) EnforceSpec() error {
+
+	if !r.isReplicaReadyServiceEnabled() {
+		r.undeployService()
+		return r.enforcePodLabels(map[string]bool{})
+	}
+
+	if !r.isServiceDeployed() {
+		err := r.deployService()
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.enforcePodLabels(r.getPodsToSelect())
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) isReplicaReadyServiceEnabled() bool {
+	return r.kubegresContext.Kubegres.Spec.Services.ReplicaReady.Enabled
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) isServiceDeployed() bool {
+	return r.resourcesStates.Services.ReplicaReady.IsDeployed
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) deployService() error {
+
+	service, err := r.resourcesCreator.CreateReplicaReadyService()
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceTemplateErr", err, "Unable to create Replica-ready Service object from template.")
+		return err
+	}
+
+	if err = r.kubegresContext.Client.Create(r.kubegresContext.Ctx, &service); err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceDeploymentErr", err, "Unable to deploy Replica-ready Service.", "Service name", service.Name)
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("ServiceDeployment", "Deployed Replica-ready Service.", "Service name", service.Name)
+	return nil
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) undeployService() {
+
+	if !r.isServiceDeployed() {
+		return
+	}
+
+	service := r.resourcesStates.Services.ReplicaReady.Service
+	if err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, &service); err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceUndeploymentErr", err, "Unable to undeploy Replica-ready Service.", "Service name", service.Name)
+		return
+	}
+
+	r.kubegresContext.Log.InfoEvent("ServiceUndeployment", "Undeployed Replica-ready Service.", "Service name", service.Name)
+}
+
+// A Replica is selected if it is ready and if its replication lag is known and below the maximum. When the lag
+// cannot be measured (e.g. the Primary is not reachable or the Replica is still copying the database of the Primary),
+// the Replica is not selected, since it may serve stale data.
+func (r *ReplicaReadyServiceCountSpecEnforcer) getPodsToSelect() map[string]bool {
+
+	podsToSelect := make(map[string]bool)
+	maxLagBytes := r.getMaxLagBytes()
+
+	for _, replica := range r.resourcesStates.StatefulSets.Replicas.All.GetAllSortedByInstanceIndex() {
+
+		if !replica.Pod.IsReady {
+			continue
+		}
+
+		lagInBytes, isKnown := r.resourcesStates.Replication.GetReplicaLagInBytes(replica.InstanceIndex)
+		if isKnown && lagInBytes <= maxLagBytes {
+			podsToSelect[replica.Pod.Pod.Name] = true
+		}
+	}
+
+	primary := r.resourcesStates.StatefulSets.Primary
+	if len(podsToSelect) == 0 && r.kubegresContext.Kubegres.Spec.Services.ReplicaReady.IncludePrimaryWhenNoReplicaReady && primary.Pod.IsReady {
+		podsToSelect[primary.Pod.Pod.Name] = true
+	}
+
+	return podsToSelect
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) getMaxLagBytes() int64 {
+	maxLagBytes := r.kubegresContext.Kubegres.Spec.Services.ReplicaReady.MaxLagBytes
+	if maxLagBytes == nil {
+		return ctx.DefaultReplicaReadyMaxLagBytes
+	}
+	return *maxLagBytes
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) enforcePodLabels(podsToSelect map[string]bool) error {
+
+	for _, statefulSetWrapper := range r.resourcesStates.StatefulSets.All.GetAllSortedByInstanceIndex() {
+
+		if !statefulSetWrapper.Pod.IsDeployed {
+			continue
+		}
+
+		podName := statefulSetWrapper.Pod.Pod.Name
+		if r.isPodSelected(statefulSetWrapper) == podsToSelect[podName] {
+			continue
+		}
+
+		if err := r.updatePodLabel(statefulSetWrapper, podsToSelect[podName]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) isPodSelected(statefulSetWrapper statefulset.StatefulSetWrapper) bool {
+	return statefulSetWrapper.Pod.Pod.Labels[ctx.ReplicaReadyLabelKey] == "true"
+}
+
+func (r *ReplicaReadyServiceCountSpecEnforcer) updatePodLabel(statefulSetWrapper statefulset.StatefulSetWrapper, isSelected bool) error {
+
+	pod := statefulSetWrapper.Pod.Pod.DeepCopy()
+	patch := client.MergeFrom(statefulSetWrapper.Pod.Pod.DeepCopy())
+
+	if isSelected {
+		if pod.Labels == nil {
+			pod.Labels = make(map[string]string)
+		}
+		pod.Labels[ctx.ReplicaReadyLabelKey] = "true"
+	} else {
+		delete(pod.Labels, ctx.ReplicaReadyLabelKey)
+	}
+
+	if err := r.kubegresContext.Client.Patch(r.kubegresContext.Ctx, pod, patch); err != nil {
+		r.kubegresContext.Log.ErrorEvent("ReplicaReadyLabelErr", err, "Unable to update the label '"+ctx.ReplicaReadyLabelKey+"' of a Pod.",
+			"Pod name", pod.Name, "Selected", strconv.FormatBool(isSelected))
+		return err
+	}
+
+	r.kubegresContext.Log.Info("Updated the label '"+ctx.ReplicaReadyLabelKey+"' of a Pod.",
+		"Pod name", pod.Name, "Selected", isSelected)
+	return nil
+}
@@ -21,6 +21,8 @@ limitations under the License.
 package resources_count_spec
 
 import (
+	"reflect"
+
 	core "k8s.io/api/core/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"reactive-tech.io/kubegres/controllers/spec/template"
@@ -51,6 +53,12 @@ func (r *ServicesCountSpecEnforcer) EnforceSpec() error {
 		if err != nil {
 			return err
 		}
+
+	} else if r.isPrimaryServiceDeployed() {
+		err := r.enforceDeployedService(r.resourcesStates.Services.Primary.Service, true)
+		if err != nil {
+			return err
+		}
 	}
 
 	if !r.isReplicaServiceDeployed() && r.isThereReadyReplica() {
@@ -58,6 +66,12 @@ func (r *ServicesCountSpecEnforcer) EnforceSpec() error {
 		if err != nil {
 			return err
 		}
+
+	} else if r.isReplicaServiceDeployed() {
+		err := r.enforceDeployedService(r.resourcesStates.Services.Replica.Service, false)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -106,6 +120,144 @@ func (r *ServicesCountSpecEnforcer) deployService(isPrimary bool) error {
 	return nil
 }
 
+// The fields of 'spec.services' are applied to a deployed Service. Since a headless Service cannot be changed into
+// a Service with a virtual IP (and inversely), such a Service is deleted and it is re-created by the next reconciliation.
+func (r *ServicesCountSpecEnforcer) enforceDeployedService(deployedService core.Service, isPrimary bool) error {
+
+	primaryOrReplicaTxt := r.createLogLabel(isPrimary)
+
+	expectedService, err := r.createServiceResource(isPrimary)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceTemplateErr", err, "Unable to create "+primaryOrReplicaTxt+" Service object from template.")
+		return err
+	}
+
+	if r.isHeadless(deployedService) != r.isHeadless(expectedService) {
+		return r.undeployServiceToRecreateIt(deployedService, primaryOrReplicaTxt)
+	}
+
+	if !r.hasServiceChanged(deployedService, expectedService) {
+		return nil
+	}
+
+	appliedKeys := template.GetAppliedServiceKeys(deployedService)
+
+	serviceToUpdate := deployedService
+	serviceToUpdate.Labels = r.mergeMaps(deployedService.Labels, expectedService.Labels, appliedKeys.Labels)
+	serviceToUpdate.Annotations = r.mergeMaps(deployedService.Annotations, expectedService.Annotations, appliedKeys.Annotations)
+	serviceToUpdate.Spec.Type = r.getServiceType(expectedService)
+	serviceToUpdate.Spec.LoadBalancerSourceRanges = expectedService.Spec.LoadBalancerSourceRanges
+	serviceToUpdate.Spec.ExternalTrafficPolicy = expectedService.Spec.ExternalTrafficPolicy
+	serviceToUpdate.Spec.Ports[0].Port = expectedService.Spec.Ports[0].Port
+
+	// The node ports are only allowed for the types "NodePort" and "LoadBalancer"
+	if serviceToUpdate.Spec.Type == core.ServiceTypeClusterIP {
+		serviceToUpdate.Spec.HealthCheckNodePort = 0
+		for i := range serviceToUpdate.Spec.Ports {
+			serviceToUpdate.Spec.Ports[i].NodePort = 0
+		}
+	}
+
+	if err = r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &serviceToUpdate); err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceUpdateErr", err, "Unable to update "+primaryOrReplicaTxt+" Service.", "Service name", serviceToUpdate.Name)
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("ServiceUpdate", "Updated "+primaryOrReplicaTxt+" Service.", "Service name", serviceToUpdate.Name)
+	return nil
+}
+
+func (r *ServicesCountSpecEnforcer) undeployServiceToRecreateIt(service core.Service, primaryOrReplicaTxt string) error {
+
+	if err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, &service); err != nil {
+		r.kubegresContext.Log.ErrorEvent("ServiceUndeploymentErr", err, "Unable to undeploy "+primaryOrReplicaTxt+" Service to re-create it with a different type.", "Service name", service.Name)
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("ServiceUndeployment", "Undeployed "+primaryOrReplicaTxt+" Service to re-create it with a different type.", "Service name", service.Name)
+	return nil
+}
+
+// Only the fields set by Kubegres are compared, since Kubernetes sets default values in the other fields. Likewise,
+// only the labels and the annotations set by Kubegres are compared, since other controllers or the users may add
+// their own ones to the Service.
+func (r *ServicesCountSpecEnforcer) hasServiceChanged(deployedService, expectedService core.Service) bool {
+	appliedKeys := template.GetAppliedServiceKeys(deployedService)
+
+	return r.getServiceType(deployedService) != r.getServiceType(expectedService) ||
+		!r.containsMap(deployedService.Labels, expectedService.Labels) ||
+		!r.containsMap(deployedService.Annotations, expectedService.Annotations) ||
+		r.hasKeyRemovedFromSpec(deployedService.Labels, expectedService.Labels, appliedKeys.Labels) ||
+		r.hasKeyRemovedFromSpec(deployedService.Annotations, expectedService.Annotations, appliedKeys.Annotations) ||
+		!r.areSourceRangesEqual(deployedService.Spec.LoadBalancerSourceRanges, expectedService.Spec.LoadBalancerSourceRanges) ||
+		r.getExternalTrafficPolicy(deployedService) != r.getExternalTrafficPolicy(expectedService) ||
+		deployedService.Spec.Ports[0].Port != expectedService.Spec.Ports[0].Port
+}
+
+func (r *ServicesCountSpecEnforcer) isHeadless(service core.Service) bool {
+	return service.Spec.ClusterIP == core.ClusterIPNone
+}
+
+func (r *ServicesCountSpecEnforcer) getServiceType(service core.Service) core.ServiceType {
+	if service.Spec.Type == "" {
+		return core.ServiceTypeClusterIP
+	}
+	return service.Spec.Type
+}
+
+// Kubernetes sets the policy "Cluster" by default for the types "NodePort" and "LoadBalancer"
+func (r *ServicesCountSpecEnforcer) getExternalTrafficPolicy(service core.Service) core.ServiceExternalTrafficPolicyType {
+	if service.Spec.ExternalTrafficPolicy == "" && r.getServiceType(service) != core.ServiceTypeClusterIP {
+		return core.ServiceExternalTrafficPolicyTypeCluster
+	}
+	return service.Spec.ExternalTrafficPolicy
+}
+
+func (r *ServicesCountSpecEnforcer) containsMap(current, expected map[string]string) bool {
+	for key, expectedValue := range expected {
+		if currentValue, exists := current[key]; !exists || currentValue != expectedValue {
+			return false
+		}
+	}
+	return true
+}
+
+// A key applied by Kubegres from 'spec.services' and which is no longer in the spec is still in the deployed Service
+func (r *ServicesCountSpecEnforcer) hasKeyRemovedFromSpec(current, expected map[string]string, appliedKeys []string) bool {
+	for _, appliedKey := range appliedKeys {
+		_, isExpected := expected[appliedKey]
+		if _, isDeployed := current[appliedKey]; isDeployed && !isExpected {
+			return true
+		}
+	}
+	return false
+}
+
+// The keys of the deployed Service which are not set by Kubegres are kept. The keys which Kubegres applied from
+// 'spec.services' and which are no longer in the spec are removed.
+func (r *ServicesCountSpecEnforcer) mergeMaps(current, expected map[string]string, appliedKeys []string) map[string]string {
+	merged := make(map[string]string)
+	for key, value := range current {
+		merged[key] = value
+	}
+	for _, appliedKey := range appliedKeys {
+		if _, isExpected := expected[appliedKey]; !isExpected {
+			delete(merged, appliedKey)
+		}
+	}
+	for key, value := range expected {
+		merged[key] = value
+	}
+	return merged
+}
+
+func (r *ServicesCountSpecEnforcer) areSourceRangesEqual(current, expected []string) bool {
+	if len(current) == 0 && len(expected) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(current, expected)
+}
+
 func (r *ServicesCountSpecEnforcer) createLogLabel(isPrimary bool) string {
 	if isPrimary {
 		return "Primary"
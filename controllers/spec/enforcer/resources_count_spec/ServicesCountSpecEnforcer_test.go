@@ -0,0 +1,195 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sort"
+	"testing"
+)
+
+func TestServiceKeepsLabelsAndAnnotationsNotSetByKubegres(t *testing.T) {
+	deployedService := createDeployedPrimaryServiceToTest(map[string]string{"team": "payments"})
+	enforcer, kubeClient := createServicesCountSpecEnforcerToTest(deployedService, map[string]string{"team": "databases"})
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	service := getServiceToTest(t, kubeClient)
+	if service.Labels["team"] != "databases" || service.Annotations["team"] != "databases" {
+		t.Errorf("Expected the labels and the annotations of 'spec.services' to be set, got %v and %v", service.Labels, service.Annotations)
+	}
+	if service.Labels["added-by"] != "other-controller" || service.Annotations["added-by"] != "other-controller" {
+		t.Errorf("Expected the labels and the annotations not set by Kubegres to be kept, got %v and %v", service.Labels, service.Annotations)
+	}
+	if service.Labels["replicationRole"] != "primary" {
+		t.Errorf("Expected the labels set by Kubegres to be kept, got %v", service.Labels)
+	}
+}
+
+func TestServiceIsNotUpdatedWhenOnlyKeysNotSetByKubegresDiffer(t *testing.T) {
+	deployedService := createDeployedPrimaryServiceToTest(map[string]string{"team": "databases"})
+	enforcer, kubeClient := createServicesCountSpecEnforcerToTest(deployedService, map[string]string{"team": "databases"})
+	resourceVersion := getServiceToTest(t, kubeClient).ResourceVersion
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if getServiceToTest(t, kubeClient).ResourceVersion != resourceVersion {
+		t.Error("Expected the Service to not be updated")
+	}
+}
+
+func TestServiceAnnotationRemovedFromSpecIsRemovedFromDeployedService(t *testing.T) {
+	deployedService := createDeployedPrimaryServiceToTest(map[string]string{"team": "databases", "tier": "gold"})
+	enforcer, kubeClient := createServicesCountSpecEnforcerToTest(deployedService, map[string]string{"team": "databases", "tier": "gold"})
+	enforcer.kubegresContext.Kubegres.Spec.Services.Primary.Annotations = map[string]string{"team": "databases"}
+
+	if !enforcer.hasServiceChanged(*deployedService, createExpectedPrimaryServiceToTest(t, enforcer)) {
+		t.Fatal("Expected the removal of an annotation from 'spec.services' to be detected")
+	}
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	service := getServiceToTest(t, kubeClient)
+	if _, exists := service.Annotations["tier"]; exists {
+		t.Errorf("Expected the annotation removed from 'spec.services' to be removed, got %v", service.Annotations)
+	}
+	if service.Annotations["team"] != "databases" || service.Labels["tier"] != "gold" {
+		t.Errorf("Expected the labels and the annotations still in 'spec.services' to be kept, got %v and %v", service.Labels, service.Annotations)
+	}
+	if service.Annotations["added-by"] != "other-controller" {
+		t.Errorf("Expected the annotations not set by Kubegres to be kept, got %v", service.Annotations)
+	}
+	if appliedKeys := template.GetAppliedServiceKeys(service); !reflect.DeepEqual(appliedKeys.Annotations, []string{"team"}) {
+		t.Errorf("Expected the applied annotations to be recorded as [team], got %v", appliedKeys.Annotations)
+	}
+
+	if enforcer.hasServiceChanged(service, createExpectedPrimaryServiceToTest(t, enforcer)) {
+		t.Error("Expected the updated Service to match 'spec.services'")
+	}
+}
+
+func TestServiceLabelRemovedFromSpecIsRemovedFromDeployedService(t *testing.T) {
+	deployedService := createDeployedPrimaryServiceToTest(map[string]string{"team": "databases"})
+	enforcer, kubeClient := createServicesCountSpecEnforcerToTest(deployedService, nil)
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	service := getServiceToTest(t, kubeClient)
+	if _, exists := service.Labels["team"]; exists {
+		t.Errorf("Expected the label removed from 'spec.services' to be removed, got %v", service.Labels)
+	}
+	if service.Labels["added-by"] != "other-controller" || service.Annotations["added-by"] != "other-controller" {
+		t.Errorf("Expected the labels and the annotations not set by Kubegres to be kept, got %v and %v", service.Labels, service.Annotations)
+	}
+}
+
+func createExpectedPrimaryServiceToTest(t *testing.T, enforcer ServicesCountSpecEnforcer) core.Service {
+	expectedService, err := enforcer.resourcesCreator.CreatePrimaryService()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return expectedService
+}
+
+func createServicesCountSpecEnforcerToTest(deployedService *core.Service, servicesSpecKeys map[string]string) (ServicesCountSpecEnforcer, client.Client) {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: v1.KubegresSpec{
+			Port: 5432,
+			Services: v1.KubegresServices{
+				Primary: v1.KubegresService{Labels: servicesSpecKeys, Annotations: servicesSpecKeys},
+			},
+		},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(deployedService).Build()
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Client:   kubeClient,
+		Ctx:      context.Background(),
+		Log:      log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10)},
+	}
+
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.Services.Primary = states.ServiceWrapper{Name: deployedService.Name, IsDeployed: true, Service: *deployedService.DeepCopy()}
+	resourcesStates.Services.Replica = states.ServiceWrapper{IsDeployed: false}
+
+	resourcesCreator := template.CreateResourcesCreatorFromTemplate(kubegresContext, template.CustomConfigSpecHelper{},
+		template.WalArchiveSpecHelper{}, template.ExtraContainersSpecHelper{}, template.ResourceTemplateLoader{})
+
+	return CreateServicesCountSpecEnforcer(kubegresContext, resourcesStates, resourcesCreator), kubeClient
+}
+
+func createDeployedPrimaryServiceToTest(servicesSpecKeys map[string]string) *core.Service {
+
+	labels := map[string]string{"app": "postgres", "replicationRole": "primary", "added-by": "other-controller"}
+	annotations := map[string]string{"added-by": "other-controller"}
+	var appliedKeys []string
+	for key, value := range servicesSpecKeys {
+		labels[key] = value
+		annotations[key] = value
+		appliedKeys = append(appliedKeys, key)
+	}
+
+	sort.Strings(appliedKeys)
+	appliedKeysJson, _ := json.Marshal(template.AppliedServiceKeys{Labels: appliedKeys, Annotations: appliedKeys})
+	annotations[ctx.AppliedServiceKeysAnnotationKey] = string(appliedKeysJson)
+
+	return &core.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default", Labels: labels, Annotations: annotations},
+		Spec: core.ServiceSpec{
+			ClusterIP: core.ClusterIPNone,
+			Ports:     []core.ServicePort{{Protocol: core.ProtocolTCP, Port: 5432}},
+			Selector:  map[string]string{"app": "postgres", "replicationRole": "primary"},
+		},
+	}
+}
+
+func getServiceToTest(t *testing.T, kubeClient client.Client) core.Service {
+	service := core.Service{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "postgres"}, &service); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return service
+}
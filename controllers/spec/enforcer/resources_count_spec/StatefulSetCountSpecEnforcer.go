@@ -22,30 +22,51 @@ package resources_count_spec
 
 import (
 	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec/statefulset"
+	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec/statefulset/failover"
 )
 
 type StatefulSetCountSpecEnforcer struct {
-	primaryDbCountSpecEnforcer statefulset.PrimaryDbCountSpecEnforcer
-	replicaDbCountSpecEnforcer statefulset.ReplicaDbCountSpecEnforcer
+	majorVersionUpgradeEnforcer statefulset.MajorVersionUpgradeEnforcer
+	primaryToReplicaSwitchover  failover.PrimaryToReplicaSwitchover
+	primaryDbCountSpecEnforcer  statefulset.PrimaryDbCountSpecEnforcer
+	replicaDbCountSpecEnforcer  statefulset.ReplicaDbCountSpecEnforcer
 }
 
-func CreateStatefulSetCountSpecEnforcer(primaryDbCountSpecEnforcer statefulset.PrimaryDbCountSpecEnforcer,
+func CreateStatefulSetCountSpecEnforcer(majorVersionUpgradeEnforcer statefulset.MajorVersionUpgradeEnforcer,
+	primaryToReplicaSwitchover failover.PrimaryToReplicaSwitchover,
+	primaryDbCountSpecEnforcer statefulset.PrimaryDbCountSpecEnforcer,
 	replicaDbCountSpecEnforcer statefulset.ReplicaDbCountSpecEnforcer) StatefulSetCountSpecEnforcer {
 
 	return StatefulSetCountSpecEnforcer{
-		primaryDbCountSpecEnforcer: primaryDbCountSpecEnforcer,
-		replicaDbCountSpecEnforcer: replicaDbCountSpecEnforcer,
+		majorVersionUpgradeEnforcer: majorVersionUpgradeEnforcer,
+		primaryToReplicaSwitchover:  primaryToReplicaSwitchover,
+		primaryDbCountSpecEnforcer:  primaryDbCountSpecEnforcer,
+		replicaDbCountSpecEnforcer:  replicaDbCountSpecEnforcer,
 	}
 }
 
 func (r *StatefulSetCountSpecEnforcer) EnforceSpec() error {
 
+	if err := r.enforceMajorVersionUpgrade(); err != nil {
+		return err
+	}
+	if err := r.enforceSwitchover(); err != nil {
+		return err
+	}
 	if err := r.enforcePrimaryDbInstance(); err != nil {
 		return err
 	}
 	return r.enforceReplicaDbInstances()
 }
 
+func (r *StatefulSetCountSpecEnforcer) enforceMajorVersionUpgrade() error {
+	return r.majorVersionUpgradeEnforcer.Enforce()
+}
+
+func (r *StatefulSetCountSpecEnforcer) enforceSwitchover() error {
+	return r.primaryToReplicaSwitchover.Enforce()
+}
+
 func (r *StatefulSetCountSpecEnforcer) enforcePrimaryDbInstance() error {
 	return r.primaryDbCountSpecEnforcer.Enforce()
 }
@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+// VerificationJobCountSpecEnforcer deploys the Job which runs the checks of the field 'verify' once the backup is
+// restored and the restored cluster is ready. The restore is completed by KubegresCountSpecEnforcer only if all the
+// checks pass.
+type VerificationJobCountSpecEnforcer struct {
+	kubegresRestoreContext ctx.KubegresRestoreContext
+	restoreStates          states.RestoreResourceStates
+	resourcesCreator       template.RestoreJobResourcesCreatorTemplate
+	kubegresSpec           kubegresv1.KubegresSpec
+}
+
+func CreateVerificationJobCountSpecEnforcer(kubegresRestoreContext ctx.KubegresRestoreContext,
+	restoreStates states.RestoreResourceStates,
+	kubegresSpec kubegresv1.KubegresSpec) VerificationJobCountSpecEnforcer {
+
+	resourcesCreator := template.CreateRestoreJobCreator(kubegresRestoreContext)
+	return VerificationJobCountSpecEnforcer{
+		kubegresRestoreContext: kubegresRestoreContext,
+		restoreStates:          restoreStates,
+		resourcesCreator:       resourcesCreator,
+		kubegresSpec:           kubegresSpec,
+	}
+}
+
+func (r *VerificationJobCountSpecEnforcer) EnforceSpec() error {
+	if !r.kubegresRestoreContext.IsVerificationEnabled() || !r.isRestoreJobCompleted() {
+		return nil
+	}
+
+	switch r.restoreStates.Verification.JobPhase {
+	case states.JobPending:
+		if !r.restoreStates.Verification.IsJobDeployed && r.restoreStates.Cluster.IsReady {
+			return r.deployVerificationJob()
+		}
+	case states.JobSucceded:
+		r.kubegresRestoreContext.Status.SetVerificationResults(r.restoreStates.Verification.Results)
+	case states.JobFailed:
+		r.kubegresRestoreContext.Status.SetVerificationResults(r.restoreStates.Verification.Results)
+		r.stopRestore()
+	}
+	return nil
+}
+
+func (r *VerificationJobCountSpecEnforcer) deployVerificationJob() error {
+	verificationJob, err := r.resourcesCreator.CreateVerificationJob(r.kubegresSpec)
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("VerificationJobTemplateErr", err, "Unable to create verification job object from template.")
+		return err
+	}
+
+	err = r.kubegresRestoreContext.Client.Create(r.kubegresRestoreContext.Ctx, &verificationJob)
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("VerificationJobDeploymentErr", err, "Unable to deploy verification job.")
+		return err
+	}
+
+	r.kubegresRestoreContext.Log.InfoEvent("VerificationJobDeployment", "Deployed verification job.", "Job name", verificationJob.Name)
+	r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageVerifyingRestore)
+	return nil
+}
+
+// stopRestore is called once the verification job has failed. The restored databases are kept as they are so that
+// the failed checks can be investigated.
+func (r *VerificationJobCountSpecEnforcer) stopRestore() {
+	status := r.kubegresRestoreContext.Status
+	if status.GetCurrentStage() == ctx.StageVerificationFailed {
+		return
+	}
+
+	var failedChecks []string
+	for _, result := range r.restoreStates.Verification.Results {
+		if !result.Passed {
+			failedChecks = append(failedChecks, result.Name)
+		}
+	}
+
+	r.kubegresRestoreContext.Log.WarningEvent("RestoreVerificationFailed", "The verification of the restored databases has failed. "+
+		"See 'job/"+r.kubegresRestoreContext.GetVerificationJobName()+"' for more details.",
+		"Failed checks", failedChecks)
+
+	metrics.IncRestores(types.NamespacedName{
+		Namespace: r.kubegresRestoreContext.KubegresRestore.Namespace,
+		Name:      r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName,
+	}, metrics.OutcomeFailed)
+
+	status.SetIsCompleted(false)
+	status.SetCurrentStage(ctx.StageVerificationFailed)
+}
+
+func (r *VerificationJobCountSpecEnforcer) isRestoreJobCompleted() bool {
+	return r.restoreStates.Job.JobPhase == states.JobSucceded
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources_count_spec
+
+import (
+	"context"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+func TestVerificationJobIsDeployedOnceRestoreJobHasSucceededAndClusterIsReady(t *testing.T) {
+	enforcer, kubegresRestore := createVerificationJobCountSpecEnforcerToTest(states.JobSucceded, states.JobPending)
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	verificationJob := batchv1.Job{}
+	jobKey := client.ObjectKey{Namespace: "default", Name: "restore" + ctx.VerificationJobSuffix}
+	if err := enforcer.kubegresRestoreContext.Client.Get(context.Background(), jobKey, &verificationJob); err != nil {
+		t.Fatalf("Expected the verification job to be deployed, got: %v", err)
+	}
+	if kubegresRestore.Status.CurrentStage != ctx.StageVerifyingRestore {
+		t.Errorf("Expected the stage '%s', got '%s'", ctx.StageVerifyingRestore, kubegresRestore.Status.CurrentStage)
+	}
+}
+
+func TestVerificationJobIsNotDeployedBeforeRestoreJobHasSucceeded(t *testing.T) {
+	for _, restoreJobPhase := range []states.JobPhase{states.JobPending, states.JobRunning, states.JobFailed} {
+		enforcer, _ := createVerificationJobCountSpecEnforcerToTest(restoreJobPhase, states.JobPending)
+
+		if err := enforcer.EnforceSpec(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		jobList := batchv1.JobList{}
+		if err := enforcer.kubegresRestoreContext.Client.List(context.Background(), &jobList); err != nil || len(jobList.Items) != 0 {
+			t.Errorf("Expected no verification job while the restore job is '%s', got %d jobs", restoreJobPhase, len(jobList.Items))
+		}
+	}
+}
+
+func TestSucceededVerificationRecordsResults(t *testing.T) {
+	enforcer, kubegresRestore := createVerificationJobCountSpecEnforcerToTest(states.JobSucceded, states.JobSucceded)
+	enforcer.restoreStates.Verification.Results = []v1.RestoreVerificationResult{
+		{Name: "row-count/app/public.orders", Passed: true},
+	}
+
+	if err := enforcer.EnforceSpec(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(kubegresRestore.Status.VerificationResults) != 1 || !kubegresRestore.Status.VerificationResults[0].Passed {
+		t.Errorf("Expected the passed check to be recorded in the status, got %v", kubegresRestore.Status.VerificationResults)
+	}
+	if kubegresRestore.Status.CurrentStage == ctx.StageVerificationFailed {
+		t.Error("Expected the restore to not be stopped")
+	}
+}
+
+func TestFailedVerificationStopsRestoreOnce(t *testing.T) {
+	enforcer, kubegresRestore := createVerificationJobCountSpecEnforcerToTest(states.JobSucceded, states.JobFailed)
+	kubegresRestore.Status.IsCompleted = true
+	enforcer.restoreStates.Verification.Results = []v1.RestoreVerificationResult{
+		{Name: "database-exists/app", Passed: true},
+		{Name: "row-count/app/public.orders", Passed: false, Message: "Expected '42' but got '41'"},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := enforcer.EnforceSpec(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if kubegresRestore.Status.CurrentStage != ctx.StageVerificationFailed || kubegresRestore.Status.IsCompleted {
+		t.Errorf("Expected the restore to be stopped and not completed, got the stage '%s'", kubegresRestore.Status.CurrentStage)
+	}
+	results := kubegresRestore.Status.VerificationResults
+	if len(results) != 2 || results[1].Passed || results[1].Message != "Expected '42' but got '41'" {
+		t.Errorf("Expected the failed check to be recorded in the status, got %v", results)
+	}
+
+	recorder := enforcer.kubegresRestoreContext.Log.Recorder.(*record.FakeRecorder)
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected one event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "RestoreVerificationFailed") || !strings.Contains(event, "row-count/app/public.orders") {
+		t.Errorf("Expected the failed check to be reported in an event, got: %s", event)
+	}
+}
+
+func createVerificationJobCountSpecEnforcerToTest(restoreJobPhase, verificationJobPhase states.JobPhase) (VerificationJobCountSpecEnforcer, *v1.KubegresRestore) {
+
+	kubegresRestore := &v1.KubegresRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec: v1.KubegresRestoreSpec{
+			ClusterName: "postgres",
+			Verify: &v1.RestoreVerification{
+				RowCounts: []v1.VerificationRowCount{{Database: "app", Table: "public.orders", Count: 42}},
+			},
+		},
+	}
+	kubegresRestoreContext := createKubegresRestoreContextToTest(kubegresRestore)
+
+	restoreStates := states.RestoreResourceStates{}
+	restoreStates.Cluster.IsReady = true
+	restoreStates.Job.JobPhase = restoreJobPhase
+	restoreStates.Verification.JobPhase = verificationJobPhase
+	restoreStates.Verification.IsJobDeployed = verificationJobPhase != states.JobPending
+
+	kubegresSpec := v1.KubegresSpec{Image: "postgres:16"}
+	return CreateVerificationJobCountSpecEnforcer(kubegresRestoreContext, restoreStates, kubegresSpec), kubegresRestore
+}
@@ -0,0 +1,476 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset
+
+import (
+	"errors"
+	postgresV1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+)
+
+// MajorVersionUpgradeEnforcer upgrades the major version of PostgreSql when it changes in the spec. The Primary is
+// stopped, its data is upgraded by a Job running 'pg_upgrade --link' and it is started with the new image. The data of
+// the Replicas cannot be upgraded, so they are removed and re-seeded from the upgraded Primary. If the Job fails, the
+// Primary is restarted with the former image. All steps of an upgrade are active on the instance index of the Primary.
+type MajorVersionUpgradeEnforcer struct {
+	kubegresContext   ctx.KubegresContext
+	resourcesStates   states.ResourcesStates
+	resourcesCreator  template.ResourcesCreatorFromTemplate
+	blockingOperation *operation.BlockingOperation
+}
+
+func CreateMajorVersionUpgradeEnforcer(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	resourcesCreator template.ResourcesCreatorFromTemplate,
+	blockingOperation *operation.BlockingOperation) MajorVersionUpgradeEnforcer {
+
+	return MajorVersionUpgradeEnforcer{
+		kubegresContext:   kubegresContext,
+		resourcesStates:   resourcesStates,
+		resourcesCreator:  resourcesCreator,
+		blockingOperation: blockingOperation,
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) CreateOperationConfigForStoppingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdMajorVersionUpgrade,
+		StepId:                              operation.OperationStepIdMajorVersionUpgradeStopping,
+		TimeOutInSeconds:                    300,
+		CompletionChecker:                   r.isPrimaryStopped,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) CreateOperationConfigForRunningJob() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdMajorVersionUpgrade,
+		StepId:                              operation.OperationStepIdMajorVersionUpgradeRunningJob,
+		TimeOutInSeconds:                    3600,
+		CompletionChecker:                   r.isUpgradeJobCompleted,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) CreateOperationConfigForStartingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdMajorVersionUpgrade,
+		StepId:                              operation.OperationStepIdMajorVersionUpgradeStarting,
+		TimeOutInSeconds:                    600,
+		CompletionChecker:                   r.isPrimaryReady,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) CreateOperationConfigForRollingBack() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:       operation.OperationIdMajorVersionUpgrade,
+		StepId:            operation.OperationStepIdMajorVersionUpgradeRollingBack,
+		TimeOutInSeconds:  600,
+		CompletionChecker: r.isPrimaryReady,
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) Enforce() error {
+
+	if r.blockingOperation.IsActiveOperationIdDifferentOf(operation.OperationIdMajorVersionUpgrade) {
+		return nil
+	}
+
+	if r.hasLastUpgradeAttemptTimedOut() {
+		return r.onUpgradeTimedOut()
+	}
+
+	if r.blockingOperation.IsActiveOperationInTransition(operation.OperationIdMajorVersionUpgrade) {
+		return r.startNextStep()
+	}
+
+	if r.isUpgradeInProgress() {
+		return nil
+	}
+
+	r.updateStatusWithRunningMajorVersion()
+
+	if !r.kubegresContext.IsPostgresMajorVersionChanged() || !r.canUpgradeStart() {
+		return nil
+	}
+
+	return r.stopPrimary()
+}
+
+func (r *MajorVersionUpgradeEnforcer) startNextStep() error {
+
+	switch r.blockingOperation.GetPreviouslyActiveOperation().StepId {
+	case operation.OperationStepIdMajorVersionUpgradeStopping:
+		return r.runUpgradeJob()
+	case operation.OperationStepIdMajorVersionUpgradeRunningJob:
+		if r.resourcesStates.MajorVersionUpgrade.IsJobSucceeded {
+			return r.startPrimaryWithNewMajorVersion()
+		}
+		return r.rollBack("The Job upgrading the data of the Primary DB with pg_upgrade failed.")
+	case operation.OperationStepIdMajorVersionUpgradeStarting:
+		return r.completeUpgrade()
+	}
+
+	return nil
+}
+
+// The major version running on the Primary is saved in the status, so that it is known when the image changes.
+// If the Primary cannot be queried and the major version is not known yet, it is parsed from the image deployed in
+// the Primary StatefulSet. While it stays unknown, the image of the StatefulSets is not updated.
+func (r *MajorVersionUpgradeEnforcer) updateStatusWithRunningMajorVersion() {
+
+	primary := r.resourcesStates.Replication.Primary
+	if primary.IsLoaded && !primary.IsInRecovery && primary.MajorVersion > 0 {
+		r.kubegresContext.Status.SetPostgresMajorVersion(primary.MajorVersion)
+		return
+	}
+
+	primaryStatefulSet := r.resourcesStates.StatefulSets.Primary
+	if r.kubegresContext.Status.GetPostgresMajorVersion() > 0 || !primaryStatefulSet.IsDeployed {
+		return
+	}
+
+	deployedImage := primaryStatefulSet.StatefulSet.Spec.Template.Spec.Containers[0].Image
+	if majorVersion, isKnown := ctx.ParsePostgresMajorVersionFromImage(deployedImage); isKnown {
+		r.kubegresContext.Status.SetPostgresMajorVersion(majorVersion)
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) canUpgradeStart() bool {
+
+	fromMajorVersion := r.kubegresContext.Status.GetPostgresMajorVersion()
+	toMajorVersion, _ := r.kubegresContext.GetExpectedPostgresMajorVersion()
+
+	if toMajorVersion < fromMajorVersion {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionDowngradeErr", errors.New(""),
+			"The major version "+strconv.Itoa(int(toMajorVersion))+" of PostgreSql in the spec is lower than the "+
+				"major version "+strconv.Itoa(int(fromMajorVersion))+" running on the Primary. Downgrading is not supported. "+
+				"The image of the Primary and of the Replicas is not updated.")
+		return false
+	}
+
+	lastUpgrade := r.kubegresContext.Status.GetMajorVersionUpgrade()
+	if lastUpgrade.Phase == postgresV1.MajorVersionUpgradePhaseFailed && lastUpgrade.ToImage == r.kubegresContext.Kubegres.Spec.Image {
+		r.kubegresContext.Log.Info("The last upgrade of the major version of PostgreSql with this image failed. "+
+			"It is not retried until the field 'image' changes.",
+			"Image", lastUpgrade.ToImage)
+		return false
+	}
+
+	if r.resourcesStates.MajorVersionUpgrade.IsJobDeployed {
+		r.deleteUpgradeJob()
+		return false
+	}
+
+	return r.resourcesStates.StatefulSets.Primary.IsReady
+}
+
+func (r *MajorVersionUpgradeEnforcer) stopPrimary() error {
+
+	primary := r.resourcesStates.StatefulSets.Primary
+	fromMajorVersion := r.kubegresContext.Status.GetPostgresMajorVersion()
+	toMajorVersion, _ := r.kubegresContext.GetExpectedPostgresMajorVersion()
+
+	err := r.activateOperation(operation.OperationStepIdMajorVersionUpgradeStopping)
+	if err != nil {
+		return err
+	}
+
+	r.kubegresContext.Status.SetMajorVersionUpgrade(postgresV1.KubegresMajorVersionUpgrade{
+		Phase:            postgresV1.MajorVersionUpgradePhaseRunning,
+		FromMajorVersion: fromMajorVersion,
+		ToMajorVersion:   toMajorVersion,
+		FromImage:        primary.StatefulSet.Spec.Template.Spec.Containers[0].Image,
+		ToImage:          r.kubegresContext.Kubegres.Spec.Image,
+	})
+
+	if err = r.scalePrimaryStatefulSet(0, ""); err != nil {
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("MajorVersionUpgradeStoppingPrimary",
+		"Major version upgrade: Stopping the Primary DB before upgrading its data with pg_upgrade.",
+		"Primary name", primary.StatefulSet.Name,
+		"From major version", fromMajorVersion,
+		"To major version", toMajorVersion)
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) runUpgradeJob() error {
+
+	upgrade := r.kubegresContext.Status.GetMajorVersionUpgrade()
+	primaryStatefulSetName := r.resourcesStates.StatefulSets.Primary.StatefulSet.Name
+
+	err := r.activateOperation(operation.OperationStepIdMajorVersionUpgradeRunningJob)
+	if err != nil {
+		return err
+	}
+
+	upgradeJob, err := r.resourcesCreator.CreateMajorVersionUpgradeJob(primaryStatefulSetName,
+		upgrade.FromImage,
+		upgrade.FromMajorVersion,
+		upgrade.ToMajorVersion)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeJobTemplateErr", err,
+			"Unable to create a Job object from template to upgrade the major version of PostgreSql.")
+		return r.rollBack("Unable to create the Job upgrading the data of the Primary DB.")
+	}
+
+	err = r.kubegresContext.Client.Create(r.kubegresContext.Ctx, &upgradeJob)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeJobDeploymentErr", err,
+			"Unable to deploy the Job upgrading the major version of PostgreSql.",
+			"Job name", upgradeJob.Name)
+		return r.rollBack("Unable to deploy the Job upgrading the data of the Primary DB.")
+	}
+
+	r.kubegresContext.Log.InfoEvent("MajorVersionUpgradeJobDeployment",
+		"Major version upgrade: Deployed a Job upgrading the data of the Primary DB with pg_upgrade.",
+		"Job name", upgradeJob.Name)
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) startPrimaryWithNewMajorVersion() error {
+
+	upgrade := r.kubegresContext.Status.GetMajorVersionUpgrade()
+	upgrade.RollbackDataDirectory = r.kubegresContext.Kubegres.Spec.Database.VolumeMount + "/" +
+		ctx.DefaultDatabaseFolder + "_" + strconv.Itoa(int(upgrade.FromMajorVersion))
+	r.kubegresContext.Status.SetMajorVersionUpgrade(upgrade)
+
+	err := r.activateOperation(operation.OperationStepIdMajorVersionUpgradeStarting)
+	if err != nil {
+		return err
+	}
+
+	if err = r.scalePrimaryStatefulSet(1, upgrade.ToImage); err != nil {
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("MajorVersionUpgradeStartingPrimary",
+		"Major version upgrade: The data of the Primary DB was upgraded. Starting the Primary DB with the new image. "+
+			"The former data folder is moved to '"+upgrade.RollbackDataDirectory+"'. Since pg_upgrade ran with '--link', "+
+			"it shares its data files with the upgraded data and it cannot be used once the Primary DB has started. "+
+			"It can be removed manually once the upgrade is checked.",
+		"Image", upgrade.ToImage)
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) completeUpgrade() error {
+
+	upgrade := r.kubegresContext.Status.GetMajorVersionUpgrade()
+	primaryInstanceIndex := r.resourcesStates.StatefulSets.Primary.InstanceIndex
+
+	for _, replica := range r.resourcesStates.StatefulSets.Replicas.All.GetAllSortedByInstanceIndex() {
+		if err := r.deleteResource(&replica.StatefulSet, "Replica StatefulSet"); err != nil {
+			return err
+		}
+	}
+
+	for _, dbPvc := range r.resourcesStates.DbPvcs.All {
+		if dbPvc.InstanceIndex == primaryInstanceIndex || dbPvc.IsDeleting {
+			continue
+		}
+		if err := r.deleteResource(&dbPvc.Pvc, "Replica PVC"); err != nil {
+			return err
+		}
+	}
+
+	r.deleteUpgradeJob()
+	r.blockingOperation.RemoveActiveOperation()
+
+	upgrade.Phase = postgresV1.MajorVersionUpgradePhaseSucceeded
+	r.kubegresContext.Status.SetMajorVersionUpgrade(upgrade)
+	r.kubegresContext.Status.SetPostgresMajorVersion(upgrade.ToMajorVersion)
+	r.kubegresContext.Status.SetEnforcedReplicas(1)
+
+	r.kubegresContext.Log.InfoEvent("MajorVersionUpgradeCompleted",
+		"Major version upgrade: The Primary DB is ready with the new major version. "+
+			"The Replica DBs were removed and they will be re-deployed from the upgraded Primary DB.",
+		"From major version", upgrade.FromMajorVersion,
+		"To major version", upgrade.ToMajorVersion)
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) rollBack(reason string) error {
+
+	upgrade := r.kubegresContext.Status.GetMajorVersionUpgrade()
+	upgrade.Phase = postgresV1.MajorVersionUpgradePhaseFailed
+	r.kubegresContext.Status.SetMajorVersionUpgrade(upgrade)
+
+	err := errors.New("Major version upgrade failed")
+	r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeErr", err,
+		"Major version upgrade: "+reason+" Rolling back by restarting the Primary DB with the former image. "+
+			"The upgrade is not retried until the field 'image' changes.",
+		"Former image", upgrade.FromImage,
+		"Job name", r.resourcesStates.MajorVersionUpgrade.JobName)
+
+	if err = r.activateOperation(operation.OperationStepIdMajorVersionUpgradeRollingBack); err != nil {
+		return err
+	}
+
+	return r.scalePrimaryStatefulSet(1, upgrade.FromImage)
+}
+
+func (r *MajorVersionUpgradeEnforcer) onUpgradeTimedOut() error {
+
+	activeOperation := r.blockingOperation.GetActiveOperation()
+
+	switch activeOperation.StepId {
+
+	case operation.OperationStepIdMajorVersionUpgradeStopping:
+		r.logUpgradeTimedOut(r.CreateOperationConfigForStoppingPrimary(), "The Primary DB did not stop.")
+		return r.rollBack("The Primary DB did not stop.")
+
+	case operation.OperationStepIdMajorVersionUpgradeRunningJob:
+		if r.resourcesStates.MajorVersionUpgrade.IsJobSucceeded {
+			return r.startPrimaryWithNewMajorVersion()
+		} else if r.resourcesStates.MajorVersionUpgrade.IsJobFailed {
+			return r.rollBack("The Job upgrading the data of the Primary DB with pg_upgrade failed.")
+		}
+		r.logUpgradeTimedOut(r.CreateOperationConfigForRunningJob(),
+			"The Job upgrading the data of the Primary DB is still running. Once it succeeds or fails, "+
+				"Kubegres will resume the upgrade. If the Job is stuck, it must be fixed manually.")
+
+	case operation.OperationStepIdMajorVersionUpgradeStarting:
+		if r.isPrimaryReady(activeOperation) {
+			return r.completeUpgrade()
+		}
+		r.logUpgradeTimedOut(r.CreateOperationConfigForStartingPrimary(),
+			"The Primary DB is still NOT ready with the new major version. It must be fixed manually. "+
+				"Since pg_upgrade ran with '--link', the former data folder '"+
+				r.kubegresContext.Status.GetMajorVersionUpgrade().RollbackDataDirectory+"' of its PVC cannot be "+
+				"started once the new major version has started: restore a backup if the upgraded data is not usable. "+
+				"Until the PrimaryDB is ready, most of the features of Kubegres are disabled for safety reason.")
+
+	case operation.OperationStepIdMajorVersionUpgradeRollingBack:
+		if r.isPrimaryReady(activeOperation) {
+			r.blockingOperation.RemoveActiveOperation()
+			r.kubegresContext.Log.InfoEvent("KubegresReEnabled", "Primary DB which caused the major version "+
+				"upgrade to time-out is ready again. We can safely re-enable all features of Kubegres.")
+			return nil
+		}
+		r.logUpgradeTimedOut(r.CreateOperationConfigForRollingBack(),
+			"The Primary DB is still NOT ready with the former major version. It must be fixed manually. "+
+				"Until the PrimaryDB is ready, most of the features of Kubegres are disabled for safety reason.")
+	}
+
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) logUpgradeTimedOut(operationConfig operation.BlockingOperationConfig, details string) {
+	operationTimeOutStr := strconv.FormatInt(operationConfig.TimeOutInSeconds, 10)
+	err := errors.New("Major version upgrade timed-out")
+	r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeTimedOutErr", err,
+		"The step '"+operationConfig.StepId+"' of the major version upgrade has timed-out after "+operationTimeOutStr+" seconds. "+details)
+}
+
+func (r *MajorVersionUpgradeEnforcer) activateOperation(stepId string) error {
+
+	primaryInstanceIndex := r.resourcesStates.StatefulSets.Primary.InstanceIndex
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdMajorVersionUpgrade, stepId, primaryInstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeOperationActivationErr", err,
+			"Error while activating a blocking operation for the major version upgrade of PostgreSql.",
+			"StepId", stepId,
+			"InstanceIndex", primaryInstanceIndex)
+	}
+	return err
+}
+
+// If an image is given, it is set in the containers of the Primary StatefulSet
+func (r *MajorVersionUpgradeEnforcer) scalePrimaryStatefulSet(nbreReplicas int32, image string) error {
+
+	primaryStatefulSet := r.resourcesStates.StatefulSets.Primary.StatefulSet
+	primaryStatefulSet.Spec.Replicas = &nbreReplicas
+
+	if image != "" {
+		primaryStatefulSet.Spec.Template.Spec.Containers[0].Image = image
+		if initContainer := template.GetReplicaInitContainer(&primaryStatefulSet.Spec.Template.Spec); initContainer != nil {
+			initContainer.Image = image
+		}
+	}
+
+	err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &primaryStatefulSet)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradePrimaryUpdateErr", err,
+			"Major version upgrade: Unable to update the Primary StatefulSet.",
+			"Primary name", primaryStatefulSet.Name,
+			"Nbre replicas", nbreReplicas)
+	}
+	return err
+}
+
+func (r *MajorVersionUpgradeEnforcer) deleteResource(obj client.Object, resourceType string) error {
+	err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, obj)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeDeletionErr", err,
+			"Major version upgrade: Unable to delete a "+resourceType+" containing data of the former major version.",
+			"Name", obj.GetName())
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("MajorVersionUpgradeDeletion",
+		"Major version upgrade: Deleted a "+resourceType+" containing data of the former major version.",
+		"Name", obj.GetName())
+	return nil
+}
+
+func (r *MajorVersionUpgradeEnforcer) deleteUpgradeJob() {
+
+	upgradeJob := r.resourcesStates.MajorVersionUpgrade.DeployedJob
+	if upgradeJob == nil || upgradeJob.Name == "" {
+		return
+	}
+
+	err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, upgradeJob, client.PropagationPolicy("Background"))
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeJobDeletionErr", err,
+			"Unable to delete the Job upgrading the major version of PostgreSql.",
+			"Job name", upgradeJob.Name)
+	}
+}
+
+func (r *MajorVersionUpgradeEnforcer) hasLastUpgradeAttemptTimedOut() bool {
+	return r.blockingOperation.HasActiveOperationIdTimedOut(operation.OperationIdMajorVersionUpgrade)
+}
+
+func (r *MajorVersionUpgradeEnforcer) isUpgradeInProgress() bool {
+	return r.blockingOperation.GetActiveOperation().OperationId == operation.OperationIdMajorVersionUpgrade
+}
+
+func (r *MajorVersionUpgradeEnforcer) isPrimaryStopped(operation postgresV1.KubegresBlockingOperation) bool {
+	return !r.resourcesStates.StatefulSets.Primary.Pod.IsDeployed
+}
+
+func (r *MajorVersionUpgradeEnforcer) isUpgradeJobCompleted(operation postgresV1.KubegresBlockingOperation) bool {
+	return r.resourcesStates.MajorVersionUpgrade.IsJobSucceeded || r.resourcesStates.MajorVersionUpgrade.IsJobFailed
+}
+
+func (r *MajorVersionUpgradeEnforcer) isPrimaryReady(operation postgresV1.KubegresBlockingOperation) bool {
+	return r.resourcesStates.StatefulSets.Primary.IsReady
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset
+
+import (
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"testing"
+)
+
+func TestRunningMajorVersionIsQueriedFromPrimary(t *testing.T) {
+	resourcesStates := createResourcesStatesWithPrimaryImage("postgres:14.2")
+	resourcesStates.Replication.Primary = states.WalLocationWrapper{IsLoaded: true, MajorVersion: 15}
+	enforcer, kubegres := createMajorVersionUpgradeEnforcerToTest(0, resourcesStates)
+
+	enforcer.updateStatusWithRunningMajorVersion()
+
+	if kubegres.Status.PostgresMajorVersion != 15 {
+		t.Errorf("Expected the major version queried from the Primary, got: %d", kubegres.Status.PostgresMajorVersion)
+	}
+}
+
+func TestRunningMajorVersionIsParsedFromImageWhenPrimaryCannotBeQueried(t *testing.T) {
+	enforcer, kubegres := createMajorVersionUpgradeEnforcerToTest(0, createResourcesStatesWithPrimaryImage("postgres:14.2"))
+
+	enforcer.updateStatusWithRunningMajorVersion()
+
+	if kubegres.Status.PostgresMajorVersion != 14 {
+		t.Errorf("Expected the major version parsed from the deployed image, got: %d", kubegres.Status.PostgresMajorVersion)
+	}
+}
+
+func TestRunningMajorVersionIsKeptWhenPrimaryCannotBeQueried(t *testing.T) {
+	enforcer, kubegres := createMajorVersionUpgradeEnforcerToTest(13, createResourcesStatesWithPrimaryImage("postgres:14.2"))
+
+	enforcer.updateStatusWithRunningMajorVersion()
+
+	if kubegres.Status.PostgresMajorVersion != 13 {
+		t.Errorf("Expected the known major version to be kept, got: %d", kubegres.Status.PostgresMajorVersion)
+	}
+}
+
+func TestRunningMajorVersionStaysUnknownWithUnparsableImage(t *testing.T) {
+	enforcer, kubegres := createMajorVersionUpgradeEnforcerToTest(0, createResourcesStatesWithPrimaryImage("postgres:latest"))
+
+	enforcer.updateStatusWithRunningMajorVersion()
+
+	if kubegres.Status.PostgresMajorVersion != 0 {
+		t.Errorf("Expected the major version to stay unknown, got: %d", kubegres.Status.PostgresMajorVersion)
+	}
+}
+
+func createMajorVersionUpgradeEnforcerToTest(runningMajorVersion int32,
+	resourcesStates states.ResourcesStates) (MajorVersionUpgradeEnforcer, *v1.Kubegres) {
+
+	kubegres := &v1.Kubegres{}
+	kubegres.Status.PostgresMajorVersion = runningMajorVersion
+
+	logWrapper := log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard()}
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Status:   &status.KubegresStatusWrapper{Kubegres: kubegres, Log: logWrapper},
+		Log:      logWrapper,
+	}
+
+	return CreateMajorVersionUpgradeEnforcer(kubegresContext, resourcesStates, template.ResourcesCreatorFromTemplate{}, nil), kubegres
+}
+
+func createResourcesStatesWithPrimaryImage(image string) states.ResourcesStates {
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.StatefulSets.Primary.IsDeployed = true
+	resourcesStates.StatefulSets.Primary.StatefulSet.Spec.Template.Spec.Containers = []core.Container{{Name: "postgres", Image: image}}
+	return resourcesStates
+}
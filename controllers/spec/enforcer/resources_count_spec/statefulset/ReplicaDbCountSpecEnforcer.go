@@ -26,6 +26,7 @@ import (
 	postgresV1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/spec/enforcer/resources_count_spec/statefulset/failover"
 	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
 	"reactive-tech.io/kubegres/controllers/states/statefulset"
@@ -37,19 +38,22 @@ type ReplicaDbCountSpecEnforcer struct {
 	resourcesStates   states.ResourcesStates
 	resourcesCreator  template.ResourcesCreatorFromTemplate
 	blockingOperation *operation.BlockingOperation
+	dbPvcPolicy       failover.DbPvcPolicy
 }
 
 func CreateReplicaDbCountSpecEnforcer(
 	kubegresContext ctx.KubegresContext,
 	resourcesStates states.ResourcesStates,
 	resourcesCreator template.ResourcesCreatorFromTemplate,
-	blockingOperation *operation.BlockingOperation) ReplicaDbCountSpecEnforcer {
+	blockingOperation *operation.BlockingOperation,
+	dbPvcPolicy failover.DbPvcPolicy) ReplicaDbCountSpecEnforcer {
 
 	return ReplicaDbCountSpecEnforcer{
 		kubegresContext:   kubegresContext,
 		resourcesStates:   resourcesStates,
 		resourcesCreator:  resourcesCreator,
 		blockingOperation: blockingOperation,
+		dbPvcPolicy:       dbPvcPolicy,
 	}
 }
 
@@ -73,6 +77,16 @@ func (r *ReplicaDbCountSpecEnforcer) CreateOperationConfigForReplicaDbUndeployin
 	}
 }
 
+func (r *ReplicaDbCountSpecEnforcer) CreateOperationConfigForReplicaDbRejoining() operation.BlockingOperationConfig {
+
+	return operation.BlockingOperationConfig{
+		OperationId:       operation.OperationIdReplicaDbCountSpecEnforcement,
+		StepId:            operation.OperationStepIdReplicaDbRejoining,
+		TimeOutInSeconds:  600,
+		CompletionChecker: r.isReplicaDbReady,
+	}
+}
+
 func (r *ReplicaDbCountSpecEnforcer) Enforce() error {
 
 	if r.blockingOperation.IsActiveOperationIdDifferentOf(operation.OperationIdReplicaDbCountSpecEnforcement) {
@@ -178,7 +192,17 @@ func (r *ReplicaDbCountSpecEnforcer) logTimedOut() {
 	operationTimeOutStr := strconv.FormatInt(r.CreateOperationConfigForReplicaDbDeploying().TimeOutInSeconds, 10)
 	replicaStatefulSetName := activeOperation.StatefulSetOperation.Name
 
-	if activeOperation.StepId == operation.OperationStepIdReplicaDbDeploying {
+	if activeOperation.StepId == operation.OperationStepIdReplicaDbRejoining {
+
+		operationTimeOutStr = strconv.FormatInt(r.CreateOperationConfigForReplicaDbRejoining().TimeOutInSeconds, 10)
+		err := errors.New("Replica DB rejoining with the data of a reused PVC timed-out")
+		r.kubegresContext.Log.ErrorEvent("ReplicaStatefulSetRejoiningTimedOutErr", err,
+			"Last attempt to rewind the data of a reused PVC to rejoin as a Replica DB has timed-out after "+operationTimeOutStr+" seconds. "+
+				"The Replica DB is still NOT ready. It must be fixed manually, for example by deleting its StatefulSet and its PVC. "+
+				"Until the ReplicaDB is ready, most of the features of Kubegres are disabled for safety reason. ",
+			"Replica DB StatefulSet to fix", replicaStatefulSetName)
+
+	} else if activeOperation.StepId == operation.OperationStepIdReplicaDbDeploying {
 
 		err := errors.New("Replica DB StatefulSet deployment timed-out")
 		r.kubegresContext.Log.ErrorEvent("ReplicaStatefulSetDeploymentTimedOutErr", err,
@@ -242,14 +266,21 @@ func (r *ReplicaDbCountSpecEnforcer) isReplicaDbUndeployed(operation postgresV1.
 func (r *ReplicaDbCountSpecEnforcer) deployReplicaStatefulSet() error {
 
 	instanceIndex := r.kubegresContext.Status.GetLastCreatedInstanceIndex() + 1
+	dbPvcToReuse, isPvcReused := r.dbPvcPolicy.GetDbPvcToReuse()
+	if isPvcReused && !r.dbPvcPolicy.CanRewindReusedPvc(dbPvcToReuse) {
+		isPvcReused = false
+	}
+	if isPvcReused {
+		instanceIndex = dbPvcToReuse.InstanceIndex
+	}
 
-	err := r.activateBlockingOperationForDeployment(instanceIndex)
+	err := r.activateBlockingOperationForDeployment(instanceIndex, isPvcReused)
 	if err != nil {
 		r.kubegresContext.Log.ErrorEvent("ReplicaStatefulSetOperationActivationErr", err, "Error while activating blocking operation for the deployment of a Replica StatefulSet.", "InstanceIndex", instanceIndex)
 		return err
 	}
 
-	replicaStatefulSet, err := r.resourcesCreator.CreateReplicaStatefulSet(instanceIndex)
+	replicaStatefulSet, err := r.createReplicaStatefulSet(instanceIndex, isPvcReused)
 	if err != nil {
 		r.kubegresContext.Log.ErrorEvent("ReplicaStatefulSetTemplateErr", err, "Error while creating a Replica StatefulSet object from template.", "InstanceIndex", instanceIndex)
 		r.blockingOperation.RemoveActiveOperation()
@@ -266,14 +297,42 @@ func (r *ReplicaDbCountSpecEnforcer) deployReplicaStatefulSet() error {
 
 	r.kubegresContext.Status.SetEnforcedReplicas(r.kubegresContext.Kubegres.Status.EnforcedReplicas + 1)
 
-	r.kubegresContext.Status.SetLastCreatedInstanceIndex(instanceIndex)
-	r.kubegresContext.Log.InfoEvent("ReplicaStatefulSetDeployment", "Deployed Replica StatefulSet.", "Replica name", replicaStatefulSet.Name)
+	if instanceIndex > r.kubegresContext.Status.GetLastCreatedInstanceIndex() {
+		r.kubegresContext.Status.SetLastCreatedInstanceIndex(instanceIndex)
+	}
+
+	if isPvcReused && dbPvcToReuse.IsFailedPrimary {
+		r.dbPvcPolicy.ApplyOnPvcReuse(dbPvcToReuse)
+		r.kubegresContext.Log.InfoEvent("ReplicaStatefulSetRejoining", "Deployed Replica StatefulSet rewinding the data of the failed Primary to rejoin the cluster.",
+			"Replica name", replicaStatefulSet.Name,
+			"PVC name", dbPvcToReuse.Pvc.Name)
+	} else if isPvcReused {
+		r.dbPvcPolicy.ApplyOnPvcReuse(dbPvcToReuse)
+		r.kubegresContext.Log.InfoEvent("ReplicaStatefulSetDeployment", "Deployed Replica StatefulSet rewinding the data of the PVC of a removed StatefulSet to reuse it.",
+			"Replica name", replicaStatefulSet.Name,
+			"PVC name", dbPvcToReuse.Pvc.Name)
+	} else {
+		r.kubegresContext.Log.InfoEvent("ReplicaStatefulSetDeployment", "Deployed Replica StatefulSet.", "Replica name", replicaStatefulSet.Name)
+	}
 	return nil
 }
 
-func (r *ReplicaDbCountSpecEnforcer) activateBlockingOperationForDeployment(statefulSetInstanceIndex int32) error {
+func (r *ReplicaDbCountSpecEnforcer) createReplicaStatefulSet(instanceIndex int32, isPvcReused bool) (v1.StatefulSet, error) {
+	if isPvcReused {
+		return r.resourcesCreator.CreateRewindingReplicaStatefulSet(instanceIndex)
+	}
+	return r.resourcesCreator.CreateReplicaStatefulSet(instanceIndex)
+}
+
+func (r *ReplicaDbCountSpecEnforcer) activateBlockingOperationForDeployment(statefulSetInstanceIndex int32, isPvcReused bool) error {
+
+	stepId := operation.OperationStepIdReplicaDbDeploying
+	if isPvcReused {
+		stepId = operation.OperationStepIdReplicaDbRejoining
+	}
+
 	return r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdReplicaDbCountSpecEnforcement,
-		operation.OperationStepIdReplicaDbDeploying,
+		stepId,
 		statefulSetInstanceIndex)
 }
 
@@ -303,6 +362,8 @@ func (r *ReplicaDbCountSpecEnforcer) undeployReplicaStatefulSets(replicaToUndepl
 		return err
 	}
 
+	r.dbPvcPolicy.ApplyOnStatefulSetRemoval(replicaToUndeploy, replicaToUndeploy.IsReady)
+
 	r.kubegresContext.Status.SetEnforcedReplicas(r.kubegresContext.Kubegres.Status.EnforcedReplicas - 1)
 
 	return nil
@@ -0,0 +1,207 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"strconv"
+)
+
+// DbPvcPolicy applies the policy set in the field 'failover.pvc' to the database PVC of a Primary or a Replica
+// whose StatefulSet is removed by Kubegres, and selects the PVC to reuse when a new Replica is deployed.
+type DbPvcPolicy struct {
+	kubegresContext ctx.KubegresContext
+	resourcesStates states.ResourcesStates
+}
+
+func CreateDbPvcPolicy(kubegresContext ctx.KubegresContext, resourcesStates states.ResourcesStates) DbPvcPolicy {
+	return DbPvcPolicy{
+		kubegresContext: kubegresContext,
+		resourcesStates: resourcesStates,
+	}
+}
+
+// ApplyOnStatefulSetRemoval is called once the StatefulSet of a Primary or a Replica was deleted.
+// The parameter 'isDataReusable' tells whether the database of that StatefulSet can safely be reused by a new Replica.
+func (r *DbPvcPolicy) ApplyOnStatefulSetRemoval(statefulSetWrapper statefulset.StatefulSetWrapper, isDataReusable bool) {
+
+	dbPvc, exists := r.resourcesStates.DbPvcs.GetByInstanceIndex(statefulSetWrapper.InstanceIndex)
+	if !exists || dbPvc.IsDeleting {
+		return
+	}
+
+	switch r.getPolicy() {
+	case v1.FailoverPvcDelete:
+		r.deletePvc(dbPvc)
+	case v1.FailoverPvcReuse:
+		r.markPvcAsReusable(dbPvc, isDataReusable)
+	}
+}
+
+// ApplyOnFailedPrimaryRemoval is called once the StatefulSet of a failed Primary was deleted during a failover.
+// If the field 'failover.rewindFailedPrimary' is true, the PVC is retained so that the failed Primary rejoins the
+// cluster as a Replica. Otherwise, the policy set in the field 'failover.pvc' applies.
+func (r *DbPvcPolicy) ApplyOnFailedPrimaryRemoval(primaryStatefulSetWrapper statefulset.StatefulSetWrapper) {
+
+	if !r.isRewindFailedPrimaryEnabled() {
+		r.ApplyOnStatefulSetRemoval(primaryStatefulSetWrapper, true)
+		return
+	}
+
+	dbPvc, exists := r.resourcesStates.DbPvcs.GetByInstanceIndex(primaryStatefulSetWrapper.InstanceIndex)
+	if !exists || dbPvc.IsDeleting {
+		return
+	}
+
+	r.markPvcAsFailedPrimary(dbPvc)
+}
+
+// GetDbPvcToReuse returns a PVC which can be reused by a new Replica. The PVC of a failed Primary to rewind is
+// returned first. The returned boolean is false if there is not any PVC to reuse.
+func (r *DbPvcPolicy) GetDbPvcToReuse() (states.DbPvcWrapper, bool) {
+
+	reusableDbPvcs := r.resourcesStates.DbPvcs.GetReusable()
+
+	if r.isRewindFailedPrimaryEnabled() {
+		for _, dbPvc := range reusableDbPvcs {
+			if dbPvc.IsFailedPrimary {
+				return dbPvc, true
+			}
+		}
+	}
+
+	if r.getPolicy() != v1.FailoverPvcReuse || len(reusableDbPvcs) == 0 {
+		return states.DbPvcWrapper{}, false
+	}
+
+	return reusableDbPvcs[0], true
+}
+
+// CanRewindReusedPvc returns true if the data of the given PVC can be rewound with pg_rewind against the Primary
+// before a new Replica reuses it. The data of a removed Replica may have diverged from the Primary, for example if
+// it replicated a former Primary, so a PVC is only reused if that check can be made.
+func (r *DbPvcPolicy) CanRewindReusedPvc(dbPvc states.DbPvcWrapper) bool {
+
+	if r.resourcesStates.Config.IsRewindScriptDeployed {
+		return true
+	}
+
+	r.kubegresContext.Log.WarningEvent("ReusablePvcRewindUnavailable",
+		"The PVC of a removed StatefulSet cannot be reused yet because the base ConfigMap does not contain the script '"+
+			states.ConfigMapDataKeyRewindFailedPrimaryScript+"'. It was deployed by a previous version of Kubegres "+
+			"and it is updated by Kubegres. A new PVC will be created for the Replica instead.",
+		"ConfigMap name", r.resourcesStates.Config.BaseConfigName,
+		"PVC name", dbPvc.Pvc.Name)
+	return false
+}
+
+// ApplyOnPvcReuse is called once a new Replica was deployed reusing the given PVC. It removes the annotations set
+// when its StatefulSet was removed, so that the PVC is not reused again if a policy does not require it.
+func (r *DbPvcPolicy) ApplyOnPvcReuse(dbPvc states.DbPvcWrapper) {
+
+	pvc := dbPvc.Pvc
+	delete(pvc.Annotations, ctx.ReusablePvcAnnotationKey)
+	delete(pvc.Annotations, ctx.FailedPrimaryPvcAnnotationKey)
+
+	err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &pvc)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("DatabasePvcUpdateErr", err,
+			"Unable to remove the reusable annotations of a PVC reused by a new Replica.",
+			"PVC name", pvc.Name)
+	}
+}
+
+func (r *DbPvcPolicy) isRewindFailedPrimaryEnabled() bool {
+	return r.kubegresContext.Kubegres.Spec.Failover.RewindFailedPrimary
+}
+
+func (r *DbPvcPolicy) getPolicy() string {
+	return r.kubegresContext.Kubegres.Spec.Failover.Pvc
+}
+
+func (r *DbPvcPolicy) deletePvc(dbPvc states.DbPvcWrapper) {
+
+	err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, &dbPvc.Pvc)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("DatabasePvcDeletionErr", err,
+			"Unable to delete the PVC of a removed StatefulSet as requested by the field 'failover.pvc'.",
+			"PVC name", dbPvc.Pvc.Name)
+		return
+	}
+
+	r.kubegresContext.Log.InfoEvent("DatabasePvcDeletion",
+		"Deleted the PVC of a removed StatefulSet as requested by the field 'failover.pvc'.",
+		"PVC name", dbPvc.Pvc.Name)
+}
+
+func (r *DbPvcPolicy) markPvcAsReusable(dbPvc states.DbPvcWrapper, isReusable bool) {
+
+	pvc := dbPvc.Pvc
+	if pvc.Annotations == nil {
+		pvc.Annotations = make(map[string]string)
+	}
+	pvc.Annotations[ctx.ReusablePvcAnnotationKey] = strconv.FormatBool(isReusable)
+	pvc.Annotations[ctx.FailedPrimaryPvcAnnotationKey] = "false"
+
+	err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &pvc)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("DatabasePvcUpdateErr", err,
+			"Unable to mark the PVC of a removed StatefulSet as reusable.",
+			"PVC name", pvc.Name)
+		return
+	}
+
+	if isReusable {
+		r.kubegresContext.Log.InfoEvent("DatabasePvcReusable",
+			"The PVC of a removed StatefulSet will be reused by the next Replica to deploy.",
+			"PVC name", pvc.Name)
+	} else {
+		r.kubegresContext.Log.InfoEvent("DatabasePvcNotReusable",
+			"The PVC of a removed StatefulSet will not be reused because its Pod was not ready. It is retained "+
+				"for investigation and it can be deleted manually.",
+			"PVC name", pvc.Name)
+	}
+}
+
+func (r *DbPvcPolicy) markPvcAsFailedPrimary(dbPvc states.DbPvcWrapper) {
+
+	pvc := dbPvc.Pvc
+	if pvc.Annotations == nil {
+		pvc.Annotations = make(map[string]string)
+	}
+	pvc.Annotations[ctx.ReusablePvcAnnotationKey] = "true"
+	pvc.Annotations[ctx.FailedPrimaryPvcAnnotationKey] = "true"
+
+	err := r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &pvc)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("DatabasePvcUpdateErr", err,
+			"Unable to mark the PVC of a failed Primary as to rewind.",
+			"PVC name", pvc.Name)
+		return
+	}
+
+	r.kubegresContext.Log.InfoEvent("DatabasePvcToRewind",
+		"The PVC of the failed Primary is retained. Its data will be rewound so that it rejoins the cluster as a Replica.",
+		"PVC name", pvc.Name)
+}
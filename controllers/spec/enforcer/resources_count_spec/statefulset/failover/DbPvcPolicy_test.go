@@ -0,0 +1,87 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/states"
+	"testing"
+)
+
+func TestReusedPvcOfRemovedReplicaIsRewound(t *testing.T) {
+	dbPvcPolicy, recorder := createDbPvcPolicyToTest(true)
+
+	dbPvc, isReused := dbPvcPolicy.GetDbPvcToReuse()
+	if !isReused {
+		t.Fatal("Expected the PVC of the removed Replica to be reused")
+	}
+
+	if !dbPvcPolicy.CanRewindReusedPvc(dbPvc) {
+		t.Error("Expected the data of the reused PVC to be rewound before the Replica starts")
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no event, got: %s", <-recorder.Events)
+	}
+}
+
+func TestReusedPvcIsNotReusedWithoutRewindScript(t *testing.T) {
+	dbPvcPolicy, recorder := createDbPvcPolicyToTest(false)
+
+	dbPvc, _ := dbPvcPolicy.GetDbPvcToReuse()
+
+	if dbPvcPolicy.CanRewindReusedPvc(dbPvc) {
+		t.Error("Expected the PVC to not be reused as its data cannot be rewound")
+	}
+	if len(recorder.Events) != 1 {
+		t.Error("Expected a warning event as the PVC cannot be reused")
+	}
+}
+
+func createDbPvcPolicyToTest(isRewindScriptDeployed bool) (DbPvcPolicy, *record.FakeRecorder) {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec:       v1.KubegresSpec{Failover: v1.KubegresFailover{Pvc: v1.FailoverPvcReuse}},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Log:      log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard(), Recorder: recorder},
+	}
+
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.Config.IsRewindScriptDeployed = isRewindScriptDeployed
+	resourcesStates.DbPvcs.All = []states.DbPvcWrapper{{
+		InstanceIndex: 2,
+		IsBound:       true,
+		IsReusable:    true,
+		Pvc:           core.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "postgres-db-postgres-2-0"}},
+	}}
+
+	return CreateDbPvcPolicy(kubegresContext, resourcesStates), recorder
+}
@@ -22,29 +22,37 @@ package failover
 
 import (
 	"errors"
+	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	v1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
 	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/spec/template"
 	"reactive-tech.io/kubegres/controllers/states"
 	"reactive-tech.io/kubegres/controllers/states/statefulset"
 	"strconv"
+	"strings"
 )
 
 type PrimaryToReplicaFailOver struct {
 	kubegresContext   ctx.KubegresContext
 	resourcesStates   states.ResourcesStates
 	blockingOperation *operation.BlockingOperation
+	dbPvcPolicy       DbPvcPolicy
 }
 
 func CreatePrimaryToReplicaFailOver(kubegresContext ctx.KubegresContext,
 	resourcesStates states.ResourcesStates,
-	blockingOperation *operation.BlockingOperation) PrimaryToReplicaFailOver {
+	blockingOperation *operation.BlockingOperation,
+	dbPvcPolicy DbPvcPolicy) PrimaryToReplicaFailOver {
 
 	return PrimaryToReplicaFailOver{
 		kubegresContext:   kubegresContext,
 		resourcesStates:   resourcesStates,
 		blockingOperation: blockingOperation,
+		dbPvcPolicy:       dbPvcPolicy,
 	}
 }
 
@@ -214,14 +222,104 @@ func (r *PrimaryToReplicaFailOver) selectReplicaToPromote() (statefulset.Statefu
 		return r.manuallySelectReplicaToPromote()
 	}
 
+	var readyReplicas []statefulset.StatefulSetWrapper
 	for _, statefulSetWrapper := range r.resourcesStates.StatefulSets.Replicas.All.GetAllSortedByInstanceIndex() {
 		if statefulSetWrapper.IsReady {
-			return statefulSetWrapper, nil
+			readyReplicas = append(readyReplicas, statefulSetWrapper)
 		}
 	}
 
-	errorMsg := r.logFailoverCannotHappenAsNoHealthyReplica()
-	return statefulset.StatefulSetWrapper{}, errors.New(errorMsg)
+	if len(readyReplicas) == 0 {
+		errorMsg := r.logFailoverCannotHappenAsNoHealthyReplica()
+		return statefulset.StatefulSetWrapper{}, errors.New(errorMsg)
+	}
+
+	newPrimary, newPrimaryWalLocation, isWalLocationKnown := r.selectMostAdvancedReplica(readyReplicas)
+	if !isWalLocationKnown {
+		return newPrimary, r.checkReplicaCanBePromotedWithUnknownLag(newPrimary,
+			"the WAL locations of the ready Replicas are unknown")
+	}
+
+	r.logFailOverCandidateSelection(newPrimary, readyReplicas)
+
+	if r.isMaxLagBytesSet() && !r.resourcesStates.Replication.LastKnownPrimary.IsLoaded {
+		return newPrimary, r.checkReplicaCanBePromotedWithUnknownLag(newPrimary,
+			"the last WAL location of the Primary is unknown")
+	}
+
+	if r.isReplicaLagAboveMax(newPrimaryWalLocation) {
+		errorMsg := r.logFailoverCannotHappenAsReplicaLagAboveMax(newPrimary, newPrimaryWalLocation)
+		return statefulset.StatefulSetWrapper{}, errors.New(errorMsg)
+	}
+
+	return newPrimary, nil
+}
+
+// The most advanced Replica is the one which received the most WAL from the Primary. If 2 Replicas received the
+// same WAL, the one which replayed the most WAL is selected. Replicas with unknown WAL locations are only selected
+// if the WAL locations of all ready Replicas are unknown.
+func (r *PrimaryToReplicaFailOver) selectMostAdvancedReplica(readyReplicas []statefulset.StatefulSetWrapper) (statefulset.StatefulSetWrapper, states.WalLocationWrapper, bool) {
+
+	selectedReplica := readyReplicas[0]
+	selectedWalLocation := states.WalLocationWrapper{}
+
+	for _, replica := range readyReplicas {
+
+		walLocation, exists := r.resourcesStates.Replication.GetReplica(replica.InstanceIndex)
+		if !exists || !walLocation.IsLoaded {
+			continue
+		}
+
+		if !selectedWalLocation.IsLoaded ||
+			walLocation.ReceivedLocation > selectedWalLocation.ReceivedLocation ||
+			(walLocation.ReceivedLocation == selectedWalLocation.ReceivedLocation &&
+				walLocation.ReplayedLocation > selectedWalLocation.ReplayedLocation) {
+
+			selectedReplica = replica
+			selectedWalLocation = walLocation
+		}
+	}
+
+	return selectedReplica, selectedWalLocation, selectedWalLocation.IsLoaded
+}
+
+// checkReplicaCanBePromotedWithUnknownLag returns an error if the field 'failover.maxLagBytes' is set, unless the
+// field 'failover.promoteWhenLagUnknown' allows to promote a Replica whose lag cannot be checked.
+func (r *PrimaryToReplicaFailOver) checkReplicaCanBePromotedWithUnknownLag(newPrimary statefulset.StatefulSetWrapper, reason string) error {
+
+	failoverSpec := r.kubegresContext.Kubegres.Spec.Failover
+
+	if r.isMaxLagBytesSet() && !failoverSpec.PromoteWhenLagUnknown {
+		errorMsg := r.logFailoverCannotHappenAsReplicaLagUnknown(reason)
+		return errors.New(errorMsg)
+	}
+
+	r.kubegresContext.Log.WarningEvent("FailOverReplicaLagUnknown",
+		"FailOver: The lag of the Replica to promote cannot be checked because "+reason+". "+
+			"Selecting the first ready Replica to promote.",
+		"Replica to promote", newPrimary.StatefulSet.Name)
+	return nil
+}
+
+func (r *PrimaryToReplicaFailOver) isMaxLagBytesSet() bool {
+	return r.kubegresContext.Kubegres.Spec.Failover.MaxLagBytes != nil
+}
+
+func (r *PrimaryToReplicaFailOver) isReplicaLagAboveMax(replicaWalLocation states.WalLocationWrapper) bool {
+
+	if !r.isMaxLagBytesSet() {
+		return false
+	}
+
+	return r.getReplicaLagInBytes(replicaWalLocation) > *r.kubegresContext.Kubegres.Spec.Failover.MaxLagBytes
+}
+
+func (r *PrimaryToReplicaFailOver) getReplicaLagInBytes(replicaWalLocation states.WalLocationWrapper) int64 {
+	lastKnownPrimaryLocation := r.resourcesStates.Replication.LastKnownPrimary.ReplayedLocation
+	if replicaWalLocation.ReceivedLocation >= lastKnownPrimaryLocation {
+		return 0
+	}
+	return int64(lastKnownPrimaryLocation - replicaWalLocation.ReceivedLocation)
 }
 
 func (r *PrimaryToReplicaFailOver) manuallySelectReplicaToPromote() (statefulset.StatefulSetWrapper, error) {
@@ -241,17 +339,7 @@ func (r *PrimaryToReplicaFailOver) manuallySelectReplicaToPromote() (statefulset
 
 func (r *PrimaryToReplicaFailOver) promoteReplicaToPrimary(newPrimary statefulset.StatefulSetWrapper) error {
 
-	newPrimary.StatefulSet.Labels["replicationRole"] = ctx.PrimaryRoleName
-	newPrimary.StatefulSet.Spec.Template.Labels["replicationRole"] = ctx.PrimaryRoleName
-	volumeMount := core.VolumeMount{
-		Name:      "base-config",
-		MountPath: "/tmp/promote_replica_to_primary.sh",
-		SubPath:   "promote_replica_to_primary.sh",
-	}
-
-	initContainer := &newPrimary.StatefulSet.Spec.Template.Spec.InitContainers[0]
-	initContainer.VolumeMounts = append(initContainer.VolumeMounts, volumeMount)
-	initContainer.Command = []string{"sh", "-c", "/tmp/promote_replica_to_primary.sh"}
+	configureReplicaStatefulSetToPromote(&newPrimary.StatefulSet)
 
 	err := r.activateOperationFailingOver(newPrimary)
 	if err != nil {
@@ -273,6 +361,8 @@ func (r *PrimaryToReplicaFailOver) promoteReplicaToPrimary(newPrimary statefulse
 		return err
 	}
 
+	metrics.IncFailOvers(types.NamespacedName{Namespace: r.kubegresContext.Kubegres.Namespace, Name: r.kubegresContext.Kubegres.Name})
+
 	return nil
 }
 
@@ -316,6 +406,8 @@ func (r *PrimaryToReplicaFailOver) deletePrimaryStatefulSet() {
 		r.kubegresContext.Log.InfoEvent("FailOverPrimaryDeleted",
 			"Deleted the failing Primary StatefulSet.",
 			"Primary name", statefulSetToDelete.Name)
+
+		r.dbPvcPolicy.ApplyOnFailedPrimaryRemoval(r.resourcesStates.StatefulSets.Primary)
 	}
 }
 
@@ -358,6 +450,56 @@ func (r *PrimaryToReplicaFailOver) logFailoverCannotHappenAsNoHealthyReplica() s
 	return errorMsg
 }
 
+func (r *PrimaryToReplicaFailOver) logFailOverCandidateSelection(newPrimary statefulset.StatefulSetWrapper,
+	readyReplicas []statefulset.StatefulSetWrapper) {
+
+	var walLocations []string
+	for _, replica := range readyReplicas {
+		walLocation, exists := r.resourcesStates.Replication.GetReplica(replica.InstanceIndex)
+		if !exists || !walLocation.IsLoaded {
+			walLocations = append(walLocations, replica.StatefulSet.Name+": unknown")
+			continue
+		}
+		walLocations = append(walLocations, replica.StatefulSet.Name+": received "+
+			states.FormatWalLocation(walLocation.ReceivedLocation)+", replayed "+
+			states.FormatWalLocation(walLocation.ReplayedLocation))
+	}
+
+	lastKnownPrimaryLocation := "unknown"
+	if r.resourcesStates.Replication.LastKnownPrimary.IsLoaded {
+		lastKnownPrimaryLocation = states.FormatWalLocation(r.resourcesStates.Replication.LastKnownPrimary.ReplayedLocation)
+	}
+
+	r.kubegresContext.Log.InfoEvent("FailOverCandidateSelection",
+		"FailOver: Selected the most advanced Replica to promote by comparing the WAL locations of the ready Replicas.",
+		"Replica to promote", newPrimary.StatefulSet.Name,
+		"Last known Primary WAL location", lastKnownPrimaryLocation,
+		"Replicas WAL locations", strings.Join(walLocations, "; "))
+}
+
+func (r *PrimaryToReplicaFailOver) logFailoverCannotHappenAsReplicaLagAboveMax(newPrimary statefulset.StatefulSetWrapper,
+	replicaWalLocation states.WalLocationWrapper) string {
+
+	errorReason := "FailoverCannotHappenAsReplicaLagAboveMaxErr"
+	errorMsg := "We cannot Failover to the Replica '" + newPrimary.StatefulSet.Name + "' because it is " +
+		strconv.FormatInt(r.getReplicaLagInBytes(replicaWalLocation), 10) + " bytes behind the last known WAL location " +
+		"of the Primary, which is more than the value of the field 'failover.maxLagBytes' (" +
+		strconv.FormatInt(*r.kubegresContext.Kubegres.Spec.Failover.MaxLagBytes, 10) + "). " +
+		"It is the most advanced ready Replica. Primary has to be fixed manually."
+	r.kubegresContext.Log.ErrorEvent(errorReason, errors.New(""), errorMsg)
+	return errorMsg
+}
+
+func (r *PrimaryToReplicaFailOver) logFailoverCannotHappenAsReplicaLagUnknown(reason string) string {
+	errorReason := "FailoverCannotHappenAsReplicaLagUnknownErr"
+	errorMsg := "We cannot Failover to a Replica because " + reason + ". The lag of the Replicas cannot be checked " +
+		"against the value of the field 'failover.maxLagBytes' (" +
+		strconv.FormatInt(*r.kubegresContext.Kubegres.Spec.Failover.MaxLagBytes, 10) + "). " +
+		"Set the field 'failover.promoteWhenLagUnknown' to true to allow it. Primary has to be fixed manually."
+	r.kubegresContext.Log.ErrorEvent(errorReason, errors.New(""), errorMsg)
+	return errorMsg
+}
+
 func (r *PrimaryToReplicaFailOver) logManualFailoverCannotHappenAsConfigErr() string {
 	errorReason := "ManualFailoverCannotHappenAsConfigErr"
 	errorMsg := "The value of the field 'failover.promotePod' is set to '" + r.getPodToManuallyPromote() + "'. " +
@@ -366,3 +508,21 @@ func (r *PrimaryToReplicaFailOver) logManualFailoverCannotHappenAsConfigErr() st
 	r.kubegresContext.Log.WarningEvent(errorReason, errorMsg)
 	return errorMsg
 }
+
+// configureReplicaStatefulSetToPromote sets the role of the given Replica StatefulSet to Primary and replaces the
+// command of its init container so that the Replica is promoted when its Pod restarts.
+func configureReplicaStatefulSetToPromote(replicaStatefulSet *apps.StatefulSet) {
+
+	replicaStatefulSet.Labels["replicationRole"] = ctx.PrimaryRoleName
+	replicaStatefulSet.Spec.Template.Labels["replicationRole"] = ctx.PrimaryRoleName
+	volumeMount := core.VolumeMount{
+		Name:      "base-config",
+		MountPath: "/tmp/promote_replica_to_primary.sh",
+		SubPath:   "promote_replica_to_primary.sh",
+	}
+
+	if initContainer := template.GetReplicaInitContainer(&replicaStatefulSet.Spec.Template.Spec); initContainer != nil {
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, volumeMount)
+		initContainer.Command = []string{"sh", "-c", "/tmp/promote_replica_to_primary.sh"}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"strings"
+	"testing"
+)
+
+func TestSelectReplicaToPromoteSelectsTheMostAdvancedReplica(t *testing.T) {
+	failOver, recorder := createFailOverToTest(int64Ptr(1000), false, loadedPrimary(5000),
+		loadedReplica(2, 3000), loadedReplica(3, 4500))
+
+	newPrimary, err := failOver.selectReplicaToPromote()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if newPrimary.InstanceIndex != 3 {
+		t.Errorf("Expected the Replica 3 to be promoted, got the Replica %d", newPrimary.InstanceIndex)
+	}
+	assertEventRecorded(t, recorder, "FailOverCandidateSelection")
+}
+
+func TestSelectReplicaToPromoteRefusesWhenTheLagIsAboveMax(t *testing.T) {
+	failOver, recorder := createFailOverToTest(int64Ptr(1000), false, loadedPrimary(5000),
+		loadedReplica(2, 3000), loadedReplica(3, 3500))
+
+	_, err := failOver.selectReplicaToPromote()
+
+	if err == nil {
+		t.Fatal("Expected an error as the lag of the most advanced Replica is above 'maxLagBytes'")
+	}
+	assertEventRecorded(t, recorder, "FailoverCannotHappenAsReplicaLagAboveMaxErr")
+}
+
+func TestSelectReplicaToPromoteRefusesWhenTheWalLocationsOfReplicasAreUnknown(t *testing.T) {
+	failOver, recorder := createFailOverToTest(int64Ptr(1000), false, loadedPrimary(5000),
+		unknownReplica(2), unknownReplica(3))
+
+	_, err := failOver.selectReplicaToPromote()
+
+	if err == nil {
+		t.Fatal("Expected an error as the lag of the Replicas cannot be checked against 'maxLagBytes'")
+	}
+	assertEventRecorded(t, recorder, "FailoverCannotHappenAsReplicaLagUnknownErr")
+}
+
+func TestSelectReplicaToPromoteRefusesWhenTheWalLocationOfPrimaryIsUnknown(t *testing.T) {
+	failOver, recorder := createFailOverToTest(int64Ptr(1000), false, states.WalLocationWrapper{},
+		loadedReplica(2, 3000))
+
+	_, err := failOver.selectReplicaToPromote()
+
+	if err == nil {
+		t.Fatal("Expected an error as the lag of the Replicas cannot be checked against 'maxLagBytes'")
+	}
+	assertEventRecorded(t, recorder, "FailoverCannotHappenAsReplicaLagUnknownErr")
+}
+
+func TestSelectReplicaToPromotePromotesWhenTheLagIsUnknownAndPromotionIsAllowed(t *testing.T) {
+	failOver, recorder := createFailOverToTest(int64Ptr(1000), true, loadedPrimary(5000),
+		unknownReplica(2), unknownReplica(3))
+
+	newPrimary, err := failOver.selectReplicaToPromote()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if newPrimary.InstanceIndex != 2 {
+		t.Errorf("Expected the first ready Replica to be promoted, got the Replica %d", newPrimary.InstanceIndex)
+	}
+	assertEventRecorded(t, recorder, "FailOverReplicaLagUnknown")
+}
+
+func TestSelectReplicaToPromotePromotesWhenTheLagIsUnknownAndMaxLagIsNotSet(t *testing.T) {
+	failOver, recorder := createFailOverToTest(nil, false, states.WalLocationWrapper{},
+		unknownReplica(2))
+
+	newPrimary, err := failOver.selectReplicaToPromote()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if newPrimary.InstanceIndex != 2 {
+		t.Errorf("Expected the Replica 2 to be promoted, got the Replica %d", newPrimary.InstanceIndex)
+	}
+	assertEventRecorded(t, recorder, "FailOverReplicaLagUnknown")
+}
+
+type replicaToTest struct {
+	instanceIndex int32
+	walLocation   states.WalLocationWrapper
+}
+
+func createFailOverToTest(maxLagBytes *int64,
+	promoteWhenLagUnknown bool,
+	lastKnownPrimary states.WalLocationWrapper,
+	replicas ...replicaToTest) (PrimaryToReplicaFailOver, *record.FakeRecorder) {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: v1.KubegresSpec{
+			Failover: v1.KubegresFailover{MaxLagBytes: maxLagBytes, PromoteWhenLagUnknown: promoteWhenLagUnknown},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Log:      log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard(), Recorder: recorder},
+	}
+
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.Replication.LastKnownPrimary = lastKnownPrimary
+
+	for _, replica := range replicas {
+		statefulSetName := kubegresContext.GetStatefulSetResourceName(replica.instanceIndex)
+		resourcesStates.StatefulSets.Replicas.All.Add(statefulset.StatefulSetWrapper{
+			IsDeployed:    true,
+			IsReady:       true,
+			InstanceIndex: replica.instanceIndex,
+			StatefulSet:   apps.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: statefulSetName}},
+		})
+		resourcesStates.StatefulSets.Replicas.NbreReady++
+
+		replica.walLocation.InstanceIndex = replica.instanceIndex
+		replica.walLocation.StatefulSetName = statefulSetName
+		resourcesStates.Replication.Replicas = append(resourcesStates.Replication.Replicas, replica.walLocation)
+	}
+
+	return CreatePrimaryToReplicaFailOver(kubegresContext, resourcesStates, nil, DbPvcPolicy{}), recorder
+}
+
+func loadedPrimary(walLocation uint64) states.WalLocationWrapper {
+	return states.WalLocationWrapper{IsLoaded: true, ReceivedLocation: walLocation, ReplayedLocation: walLocation}
+}
+
+func loadedReplica(instanceIndex int32, walLocation uint64) replicaToTest {
+	return replicaToTest{
+		instanceIndex: instanceIndex,
+		walLocation: states.WalLocationWrapper{
+			IsLoaded:         true,
+			IsInRecovery:     true,
+			ReceivedLocation: walLocation,
+			ReplayedLocation: walLocation,
+		},
+	}
+}
+
+func unknownReplica(instanceIndex int32) replicaToTest {
+	return replicaToTest{instanceIndex: instanceIndex}
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func assertEventRecorded(t *testing.T, recorder *record.FakeRecorder, expectedReason string) {
+	t.Helper()
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+expectedReason+" ") {
+				return
+			}
+		default:
+			t.Errorf("Expected an event with the reason '%s'", expectedReason)
+			return
+		}
+	}
+}
@@ -0,0 +1,696 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"errors"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/postgres"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+)
+
+// Writes are fenced on a best effort basis by making all new transactions read-only and by terminating the client
+// connections, so that the Replica to promote catches up quickly. A client can still override the setting, so it does
+// not prevent data loss: the Replica is only promoted once the Primary is stopped and the Replica has replayed the
+// shutdown checkpoint of the Primary, which is the last WAL record it wrote.
+// The setting is persisted in the file 'postgresql.auto.conf' of the Primary, so that it is still fenced if it
+// restarts. It is removed when the former Primary is rewound to re-attach as a Replica.
+var (
+	fencePrimarySqlStatements = []string{
+		"ALTER SYSTEM SET default_transaction_read_only = on",
+		"SELECT pg_reload_conf()",
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE backend_type = 'client backend' AND pid <> pg_backend_pid()",
+	}
+	unfencePrimarySqlStatements = []string{
+		"ALTER SYSTEM RESET default_transaction_read_only",
+		"SELECT pg_reload_conf()",
+	}
+)
+
+const formerPrimaryCleanShutdownState = "shut down"
+
+// PrimaryToReplicaSwitchover promotes the Replica set in the field 'switchover.targetPod' to Primary without data
+// loss. Writes are fenced on the Primary until the Replica has replayed all its WAL. Then the Primary is stopped and
+// a Pod reads, from its PVC, whether it was shut down cleanly and the location of its shutdown checkpoint. Once the
+// Replica has replayed that checkpoint, it is promoted and the former Primary is re-attached as a Replica using its
+// existing data. Otherwise, the Switchover is aborted and Kubegres fails over as when a Primary fails.
+type PrimaryToReplicaSwitchover struct {
+	kubegresContext   ctx.KubegresContext
+	resourcesStates   states.ResourcesStates
+	resourcesCreator  template.ResourcesCreatorFromTemplate
+	blockingOperation *operation.BlockingOperation
+	postgresClient    *postgres.PostgresClient
+}
+
+func CreatePrimaryToReplicaSwitchover(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	resourcesCreator template.ResourcesCreatorFromTemplate,
+	blockingOperation *operation.BlockingOperation,
+	postgresClient *postgres.PostgresClient) PrimaryToReplicaSwitchover {
+
+	return PrimaryToReplicaSwitchover{
+		kubegresContext:   kubegresContext,
+		resourcesStates:   resourcesStates,
+		resourcesCreator:  resourcesCreator,
+		blockingOperation: blockingOperation,
+		postgresClient:    postgresClient,
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) CreateOperationConfigForFencingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdSwitchover,
+		StepId:                              operation.OperationStepIdSwitchoverFencingPrimary,
+		TimeOutInSeconds:                    120,
+		CompletionChecker:                   r.hasTargetReplicaCaughtUp,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) CreateOperationConfigForStoppingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdSwitchover,
+		StepId:                              operation.OperationStepIdSwitchoverStoppingPrimary,
+		TimeOutInSeconds:                    120,
+		CompletionChecker:                   r.isFormerPrimaryStopped,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) CreateOperationConfigForCheckingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdSwitchover,
+		StepId:                              operation.OperationStepIdSwitchoverCheckingPrimary,
+		TimeOutInSeconds:                    300,
+		CompletionChecker:                   r.isFormerPrimaryChecked,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) CreateOperationConfigForPromotingReplica() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:                         operation.OperationIdSwitchover,
+		StepId:                              operation.OperationStepIdSwitchoverPromotingReplica,
+		TimeOutInSeconds:                    300,
+		CompletionChecker:                   r.isTargetReplicaPromoted,
+		AfterCompletionMoveToTransitionStep: true,
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) CreateOperationConfigForReattachingPrimary() operation.BlockingOperationConfig {
+	return operation.BlockingOperationConfig{
+		OperationId:       operation.OperationIdSwitchover,
+		StepId:            operation.OperationStepIdSwitchoverReattachingPrimary,
+		TimeOutInSeconds:  600,
+		CompletionChecker: r.isFormerPrimaryReattached,
+	}
+}
+
+// Enforce starts a switchover when the field 'switchover.targetPod' is set and moves it to its next step once the
+// previous step is completed. All steps of a switchover are active on the instance index of the former Primary.
+func (r *PrimaryToReplicaSwitchover) Enforce() error {
+
+	if r.blockingOperation.IsActiveOperationIdDifferentOf(operation.OperationIdSwitchover) {
+		return nil
+	}
+
+	if r.hasLastSwitchoverAttemptTimedOut() {
+		return r.onSwitchoverTimedOut()
+	}
+
+	if r.blockingOperation.IsActiveOperationInTransition(operation.OperationIdSwitchover) {
+		return r.startNextStep()
+	}
+
+	if r.isSwitchoverInProgress() || !r.isSwitchoverRequested() {
+		return nil
+	}
+
+	if r.isTargetPodPrimary() {
+		r.kubegresContext.Log.InfoEvent("SwitchoverCompleted",
+			"The Pod set in the field 'switchover.targetPod' is the Primary.",
+			"Primary Pod", r.getTargetPod())
+		return r.resetInSpecSwitchover()
+	}
+
+	return r.fencePrimary()
+}
+
+func (r *PrimaryToReplicaSwitchover) startNextStep() error {
+
+	previouslyActiveOperation := r.blockingOperation.GetPreviouslyActiveOperation()
+	formerPrimaryInstanceIndex := previouslyActiveOperation.StatefulSetOperation.InstanceIndex
+
+	switch previouslyActiveOperation.StepId {
+	case operation.OperationStepIdSwitchoverFencingPrimary:
+		return r.stopPrimary()
+	case operation.OperationStepIdSwitchoverStoppingPrimary:
+		return r.checkFormerPrimary(formerPrimaryInstanceIndex)
+	case operation.OperationStepIdSwitchoverCheckingPrimary:
+		return r.promoteTargetReplicaIfCaughtUp(formerPrimaryInstanceIndex)
+	case operation.OperationStepIdSwitchoverPromotingReplica:
+		return r.reattachFormerPrimary(formerPrimaryInstanceIndex)
+	}
+
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) fencePrimary() error {
+
+	if !r.resourcesStates.StatefulSets.Primary.IsReady {
+		return r.logSwitchoverCannotHappen("The Primary is not ready.")
+	}
+
+	targetReplica, exists := r.getTargetReplica()
+	if !exists || !targetReplica.IsReady {
+		return r.logSwitchoverCannotHappen("The Pod '" + r.getTargetPod() + "' is not a ready Replica.")
+	}
+
+	if _, isLagKnown := r.resourcesStates.Replication.GetReplicaLagInBytes(targetReplica.InstanceIndex); !isLagKnown {
+		return r.logSwitchoverCannotHappen("The WAL locations of the Primary and of the Replica '" + r.getTargetPod() +
+			"' are unknown.")
+	}
+
+	// A checker Pod left by a previous Switchover would return the state of another Primary
+	r.deleteFormerPrimaryCheckerPod()
+
+	primary := r.resourcesStates.StatefulSets.Primary
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdSwitchover,
+		operation.OperationStepIdSwitchoverFencingPrimary,
+		primary.InstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverOperationActivationErr", err,
+			"Error while activating a blocking operation for the Switchover of a Primary DB.",
+			"InstanceIndex", primary.InstanceIndex)
+		return err
+	}
+
+	err = r.postgresClient.Exec(primary.Pod.Pod, fencePrimarySqlStatements...)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverFencingErr", err,
+			"Switchover: Unable to fence writes on the Primary. It will be retried.",
+			"Primary name", primary.StatefulSet.Name)
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("SwitchoverFencingPrimary",
+		"Switchover: Fenced writes on the Primary. Waiting for the Replica to promote to replay all WAL of the Primary.",
+		"Primary name", primary.StatefulSet.Name,
+		"Replica to promote", targetReplica.StatefulSet.Name)
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) stopPrimary() error {
+
+	primary := r.resourcesStates.StatefulSets.Primary
+
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdSwitchover,
+		operation.OperationStepIdSwitchoverStoppingPrimary,
+		primary.InstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverOperationActivationErr", err,
+			"Error while activating a blocking operation to stop the Primary DB during a Switchover.",
+			"InstanceIndex", primary.InstanceIndex)
+		return err
+	}
+
+	// Once stopped, the Primary sends its remaining WAL to the Replicas. Its PVC is kept to re-attach it as a Replica.
+	err = r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, &primary.StatefulSet)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverPrimaryDeletionErr", err,
+			"Switchover: Unable to delete the Primary StatefulSet.",
+			"Primary name", primary.StatefulSet.Name)
+		r.abortSwitchover(primary)
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("SwitchoverPrimaryDeleted",
+		"Switchover: Deleted the Primary StatefulSet. Waiting for the Primary to stop.",
+		"Primary name", primary.StatefulSet.Name)
+	return nil
+}
+
+// checkFormerPrimary deploys a Pod reading, from the PVC of the stopped Primary, whether it was shut down cleanly and
+// the location of its shutdown checkpoint.
+func (r *PrimaryToReplicaSwitchover) checkFormerPrimary(formerPrimaryInstanceIndex int32) error {
+
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdSwitchover,
+		operation.OperationStepIdSwitchoverCheckingPrimary,
+		formerPrimaryInstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverOperationActivationErr", err,
+			"Error while activating a blocking operation to check the stopped Primary DB during a Switchover.",
+			"InstanceIndex", formerPrimaryInstanceIndex)
+		return err
+	}
+
+	formerPrimaryName := r.kubegresContext.GetStatefulSetResourceName(formerPrimaryInstanceIndex)
+	checkerPod, err := r.resourcesCreator.CreateFormerPrimaryCheckerPod(formerPrimaryName)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverCheckerPodTemplateErr", err,
+			"Unable to create a Pod object from template to check the stopped Primary DB.")
+		return r.failOverInsteadOfSwitchover("Unable to check the stopped Primary DB.")
+	}
+
+	err = r.kubegresContext.Client.Create(r.kubegresContext.Ctx, &checkerPod)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverCheckerPodDeploymentErr", err,
+			"Switchover: Unable to deploy the Pod checking the stopped Primary DB.",
+			"Pod name", checkerPod.Name)
+		return r.failOverInsteadOfSwitchover("Unable to check the stopped Primary DB.")
+	}
+
+	r.kubegresContext.Log.InfoEvent("SwitchoverCheckingPrimary",
+		"Switchover: The Primary is stopped. Checking that the Replica to promote replayed all its WAL.",
+		"Former Primary name", formerPrimaryName,
+		"Replica to promote", r.getTargetPod())
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) promoteTargetReplicaIfCaughtUp(formerPrimaryInstanceIndex int32) error {
+
+	primaryState, isChecked := r.getFormerPrimaryState()
+	r.deleteFormerPrimaryCheckerPod()
+
+	if !isChecked {
+		return r.failOverInsteadOfSwitchover("The state of the stopped Primary DB could not be read from its PVC.")
+	}
+
+	if primaryState.state != formerPrimaryCleanShutdownState {
+		return r.failOverInsteadOfSwitchover("The Primary DB did not shut down cleanly (state: '" +
+			primaryState.state + "'), so the Replica to promote may not have received all its WAL.")
+	}
+
+	if !r.hasTargetReplicaReplayed(primaryState.checkpointLocation) {
+		return r.failOverInsteadOfSwitchover("The Replica to promote did not replay the shutdown checkpoint '" +
+			states.FormatWalLocation(primaryState.checkpointLocation) + "' of the stopped Primary DB.")
+	}
+
+	return r.promoteTargetReplica(formerPrimaryInstanceIndex)
+}
+
+func (r *PrimaryToReplicaSwitchover) promoteTargetReplica(formerPrimaryInstanceIndex int32) error {
+
+	targetReplica, exists := r.getTargetReplica()
+	if !exists || !targetReplica.IsReady {
+		err := errors.New("Replica to promote is not ready")
+		r.kubegresContext.Log.ErrorEvent("SwitchoverPromotionErr", err,
+			"Switchover: The Replica to promote is not ready anymore. The Primary was stopped. "+
+				"Kubegres will failover to the most advanced ready Replica.",
+			"Replica to promote", r.getTargetPod())
+		r.blockingOperation.RemoveActiveOperation()
+		return r.resetInSpecSwitchover()
+	}
+
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdSwitchover,
+		operation.OperationStepIdSwitchoverPromotingReplica,
+		formerPrimaryInstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverOperationActivationErr", err,
+			"Error while activating a blocking operation to promote a Replica DB during a Switchover.",
+			"InstanceIndex", formerPrimaryInstanceIndex)
+		return err
+	}
+
+	configureReplicaStatefulSetToPromote(&targetReplica.StatefulSet)
+
+	r.kubegresContext.Log.InfoEvent("SwitchoverPromotingReplica", "Switchover: Promoting Replica to Primary.",
+		"Replica to promote", targetReplica.StatefulSet.Name)
+
+	err = r.kubegresContext.Client.Update(r.kubegresContext.Ctx, &targetReplica.StatefulSet)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverPromotionErr", err,
+			"Switchover: Unable to promote Replica to Primary. It will be retried.",
+			"Replica to promote", targetReplica.StatefulSet.Name)
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) reattachFormerPrimary(formerPrimaryInstanceIndex int32) error {
+
+	err := r.blockingOperation.ActivateOperationOnStatefulSet(operation.OperationIdSwitchover,
+		operation.OperationStepIdSwitchoverReattachingPrimary,
+		formerPrimaryInstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverOperationActivationErr", err,
+			"Error while activating a blocking operation to re-attach the former Primary DB during a Switchover.",
+			"InstanceIndex", formerPrimaryInstanceIndex)
+		return err
+	}
+
+	replicaStatefulSet, err := r.createReplicaStatefulSet(formerPrimaryInstanceIndex)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("ReplicaStatefulSetTemplateErr", err,
+			"Error while creating a Replica StatefulSet object from template.",
+			"InstanceIndex", formerPrimaryInstanceIndex)
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	err = r.kubegresContext.Client.Create(r.kubegresContext.Ctx, &replicaStatefulSet)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverReattachingErr", err,
+			"Switchover: Unable to re-attach the former Primary as a Replica. Kubegres will deploy a new Replica.",
+			"Replica name", replicaStatefulSet.Name)
+		r.blockingOperation.RemoveActiveOperation()
+		return err
+	}
+
+	r.kubegresContext.Log.InfoEvent("SwitchoverReattachingPrimary",
+		"Switchover: Re-attaching the former Primary as a Replica.",
+		"Replica name", replicaStatefulSet.Name)
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) createReplicaStatefulSet(instanceIndex int32) (apps.StatefulSet, error) {
+
+	if r.resourcesStates.Config.IsRewindScriptDeployed {
+		return r.resourcesCreator.CreateRewindingReplicaStatefulSet(instanceIndex)
+	}
+
+	r.kubegresContext.Log.WarningEvent("SwitchoverRewindUnavailable",
+		"The former Primary cannot be rewound because the base ConfigMap does not contain the script '"+
+			states.ConfigMapDataKeyRewindFailedPrimaryScript+"'. It was deployed by a previous version of Kubegres. "+
+			"A full copy of the Primary DB will be made instead. To enable rewinding, delete the base ConfigMap "+
+			"so that Kubegres re-creates it.",
+		"ConfigMap name", r.resourcesStates.Config.BaseConfigName)
+
+	return r.resourcesCreator.CreateReplicaStatefulSet(instanceIndex)
+}
+
+func (r *PrimaryToReplicaSwitchover) onSwitchoverTimedOut() error {
+
+	activeOperation := r.blockingOperation.GetActiveOperation()
+	formerPrimaryInstanceIndex := activeOperation.StatefulSetOperation.InstanceIndex
+
+	switch activeOperation.StepId {
+
+	case operation.OperationStepIdSwitchoverFencingPrimary:
+		r.logSwitchoverTimedOut(r.CreateOperationConfigForFencingPrimary(),
+			"The Replica to promote did not replay all WAL of the Primary. The Switchover is aborted and "+
+				"writes are re-enabled on the Primary.")
+		r.abortSwitchover(r.resourcesStates.StatefulSets.Primary)
+		return r.resetInSpecSwitchover()
+
+	case operation.OperationStepIdSwitchoverStoppingPrimary:
+		if r.isFormerPrimaryStopped(activeOperation) {
+			return r.checkFormerPrimary(formerPrimaryInstanceIndex)
+		}
+		r.logSwitchoverTimedOut(r.CreateOperationConfigForStoppingPrimary(),
+			"The Primary DB is still NOT stopped. It must be fixed manually. "+
+				"Until the Primary DB is stopped, most of the features of Kubegres are disabled for safety reason.")
+
+	case operation.OperationStepIdSwitchoverCheckingPrimary:
+		r.logSwitchoverTimedOut(r.CreateOperationConfigForCheckingPrimary(),
+			"Either the state of the stopped Primary DB could not be read or the Replica to promote did not "+
+				"replay all its WAL.")
+		return r.promoteTargetReplicaIfCaughtUp(formerPrimaryInstanceIndex)
+
+	case operation.OperationStepIdSwitchoverPromotingReplica:
+		if r.isTargetReplicaPromoted(activeOperation) {
+			return r.reattachFormerPrimary(formerPrimaryInstanceIndex)
+		}
+		r.logSwitchoverTimedOut(r.CreateOperationConfigForPromotingReplica(),
+			"The new Primary DB is still NOT ready. It must be fixed manually. "+
+				"Until the PrimaryDB is ready, most of the features of Kubegres are disabled for safety reason.")
+
+	case operation.OperationStepIdSwitchoverReattachingPrimary:
+		_, err := r.resourcesStates.StatefulSets.Replicas.All.GetByInstanceIndex(formerPrimaryInstanceIndex)
+		if err != nil || r.isFormerPrimaryReattached(activeOperation) {
+			r.blockingOperation.RemoveActiveOperation()
+			r.kubegresContext.Log.InfoEvent("KubegresReEnabled", "Former Primary DB which caused the Switchover to "+
+				"time-out is either set to ready again or it was removed. We can safely re-enable all features of Kubegres.")
+			return nil
+		}
+		r.logSwitchoverTimedOut(r.CreateOperationConfigForReattachingPrimary(),
+			"The former Primary DB is still NOT ready as a Replica DB. It must be fixed manually, for example by "+
+				"deleting its StatefulSet and its PVC. "+
+				"Until the ReplicaDB is ready, most of the features of Kubegres are disabled for safety reason.")
+	}
+
+	return nil
+}
+
+func (r *PrimaryToReplicaSwitchover) abortSwitchover(primary statefulset.StatefulSetWrapper) {
+
+	r.blockingOperation.RemoveActiveOperation()
+
+	err := r.postgresClient.Exec(primary.Pod.Pod, unfencePrimarySqlStatements...)
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverUnfencingErr", err,
+			"Switchover: Unable to re-enable writes on the Primary. Run the SQL statement "+
+				"'ALTER SYSTEM RESET default_transaction_read_only' followed by 'SELECT pg_reload_conf()' on the Primary.",
+			"Primary name", primary.StatefulSet.Name)
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) hasTargetReplicaCaughtUp(operation v1.KubegresBlockingOperation) bool {
+
+	targetReplica, exists := r.getTargetReplica()
+	if !exists {
+		return false
+	}
+
+	lagInBytes, isLagKnown := r.resourcesStates.Replication.GetReplicaLagInBytes(targetReplica.InstanceIndex)
+	if isLagKnown && lagInBytes > 0 {
+		r.kubegresContext.Log.Info("Switchover: Waiting for the Replica to promote to replay all WAL of the Primary.",
+			"Replica to promote", targetReplica.StatefulSet.Name,
+			"Lag in bytes", lagInBytes)
+	}
+
+	return isLagKnown && lagInBytes == 0
+}
+
+// failOverInsteadOfSwitchover is called once the Primary is stopped, when it cannot be checked that the Replica to
+// promote has all its WAL. The Switchover is aborted, so that Kubegres fails over as when a Primary fails, which
+// applies the field 'failover.maxLagBytes'.
+func (r *PrimaryToReplicaSwitchover) failOverInsteadOfSwitchover(reason string) error {
+
+	err := errors.New("Switchover aborted")
+	r.kubegresContext.Log.ErrorEvent("SwitchoverAbortedErr", err,
+		"Switchover: "+reason+" The Primary was stopped. The Switchover is aborted and Kubegres will failover "+
+			"to the most advanced ready Replica.",
+		"Replica to promote", r.getTargetPod())
+
+	r.blockingOperation.RemoveActiveOperation()
+	return r.resetInSpecSwitchover()
+}
+
+func (r *PrimaryToReplicaSwitchover) isFormerPrimaryStopped(operation v1.KubegresBlockingOperation) bool {
+
+	formerPrimaryPodName := operation.StatefulSetOperation.Name + "-0"
+	podKey := client.ObjectKey{Namespace: r.kubegresContext.Kubegres.Namespace, Name: formerPrimaryPodName}
+
+	err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, podKey, &core.Pod{})
+	return apierrors.IsNotFound(err)
+}
+
+func (r *PrimaryToReplicaSwitchover) isFormerPrimaryChecked(operation v1.KubegresBlockingOperation) bool {
+
+	primaryState, isChecked := r.getFormerPrimaryState()
+	if !isChecked {
+		return false
+	}
+
+	// Once the Primary is known to not have stopped cleanly, there is no need to wait for the Replica
+	return primaryState.state != formerPrimaryCleanShutdownState ||
+		r.hasTargetReplicaReplayed(primaryState.checkpointLocation)
+}
+
+// hasTargetReplicaReplayed returns true if the Replica to promote has replayed the WAL record at the given location.
+// PostgreSql returns the end location of the last replayed record, so it is greater than the location of the record.
+func (r *PrimaryToReplicaSwitchover) hasTargetReplicaReplayed(walLocation uint64) bool {
+
+	targetReplica, exists := r.getTargetReplica()
+	if !exists {
+		return false
+	}
+
+	replica, exists := r.resourcesStates.Replication.GetReplica(targetReplica.InstanceIndex)
+	if !exists || !replica.IsLoaded {
+		return false
+	}
+
+	if replica.ReplayedLocation <= walLocation {
+		r.kubegresContext.Log.Info("Switchover: Waiting for the Replica to promote to replay the shutdown "+
+			"checkpoint of the stopped Primary.",
+			"Replica to promote", targetReplica.StatefulSet.Name,
+			"Replayed WAL location", states.FormatWalLocation(replica.ReplayedLocation),
+			"Shutdown checkpoint location", states.FormatWalLocation(walLocation))
+		return false
+	}
+
+	return true
+}
+
+// getFormerPrimaryState returns the state of the stopped Primary read by the checker Pod. The returned boolean is
+// false until the Pod has terminated with a valid message.
+func (r *PrimaryToReplicaSwitchover) getFormerPrimaryState() (formerPrimaryState, bool) {
+
+	checkerPod := &core.Pod{}
+	podKey := client.ObjectKey{Namespace: r.kubegresContext.Kubegres.Namespace, Name: r.kubegresContext.GetFormerPrimaryCheckerPodName()}
+	if err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, podKey, checkerPod); err != nil {
+		return formerPrimaryState{}, false
+	}
+
+	for _, containerStatus := range checkerPod.Status.ContainerStatuses {
+		terminated := containerStatus.State.Terminated
+		if terminated == nil || terminated.ExitCode != 0 {
+			continue
+		}
+
+		primaryState, err := parseFormerPrimaryState(terminated.Message)
+		if err != nil {
+			r.kubegresContext.Log.Error(err, "Unable to parse the state of the stopped Primary.",
+				"Pod name", checkerPod.Name)
+			return formerPrimaryState{}, false
+		}
+		return primaryState, true
+	}
+
+	return formerPrimaryState{}, false
+}
+
+func (r *PrimaryToReplicaSwitchover) deleteFormerPrimaryCheckerPod() {
+
+	checkerPod := &core.Pod{}
+	checkerPod.Name = r.kubegresContext.GetFormerPrimaryCheckerPodName()
+	checkerPod.Namespace = r.kubegresContext.Kubegres.Namespace
+
+	err := r.kubegresContext.Client.Delete(r.kubegresContext.Ctx, checkerPod)
+	if err != nil && !apierrors.IsNotFound(err) {
+		r.kubegresContext.Log.ErrorEvent("SwitchoverCheckerPodDeletionErr", err,
+			"Unable to delete the Pod checking the stopped Primary DB.",
+			"Pod name", checkerPod.Name)
+	}
+}
+
+func (r *PrimaryToReplicaSwitchover) isTargetReplicaPromoted(operation v1.KubegresBlockingOperation) bool {
+	return r.resourcesStates.StatefulSets.Primary.IsReady && r.isTargetPodPrimary()
+}
+
+func (r *PrimaryToReplicaSwitchover) isFormerPrimaryReattached(operation v1.KubegresBlockingOperation) bool {
+	replica, err := r.resourcesStates.StatefulSets.Replicas.All.GetByInstanceIndex(operation.StatefulSetOperation.InstanceIndex)
+	return err == nil && replica.IsReady
+}
+
+func (r *PrimaryToReplicaSwitchover) getTargetReplica() (statefulset.StatefulSetWrapper, bool) {
+	for _, statefulSetWrapper := range r.resourcesStates.StatefulSets.Replicas.All.GetAllSortedByInstanceIndex() {
+		if statefulSetWrapper.Pod.Pod.Name == r.getTargetPod() {
+			return statefulSetWrapper, true
+		}
+	}
+	return statefulset.StatefulSetWrapper{}, false
+}
+
+func (r *PrimaryToReplicaSwitchover) isTargetPodPrimary() bool {
+	primary := r.resourcesStates.StatefulSets.Primary
+	return primary.IsDeployed && primary.Pod.Pod.Name == r.getTargetPod()
+}
+
+func (r *PrimaryToReplicaSwitchover) getTargetPod() string {
+	return r.kubegresContext.Kubegres.Spec.Switchover.TargetPod
+}
+
+func (r *PrimaryToReplicaSwitchover) isSwitchoverRequested() bool {
+	return r.getTargetPod() != ""
+}
+
+func (r *PrimaryToReplicaSwitchover) isSwitchoverInProgress() bool {
+	return r.blockingOperation.GetActiveOperation().OperationId == operation.OperationIdSwitchover
+}
+
+func (r *PrimaryToReplicaSwitchover) hasLastSwitchoverAttemptTimedOut() bool {
+	return r.blockingOperation.HasActiveOperationIdTimedOut(operation.OperationIdSwitchover)
+}
+
+func (r *PrimaryToReplicaSwitchover) resetInSpecSwitchover() error {
+	r.kubegresContext.Log.Info("Resetting the field 'switchover.targetPod' in spec.")
+	r.kubegresContext.Kubegres.Spec.Switchover.TargetPod = ""
+	return r.kubegresContext.Client.Update(r.kubegresContext.Ctx, r.kubegresContext.Kubegres)
+}
+
+func (r *PrimaryToReplicaSwitchover) logSwitchoverCannotHappen(reason string) error {
+	errorMsg := "A Switchover to promote the Pod '" + r.getTargetPod() + "' as Primary was requested. " +
+		"However, it cannot happen. " + reason + " Resetting the field 'switchover.targetPod'."
+	r.kubegresContext.Log.ErrorEvent("SwitchoverCannotHappenErr", errors.New(""), errorMsg)
+	return r.resetInSpecSwitchover()
+}
+
+func (r *PrimaryToReplicaSwitchover) logSwitchoverTimedOut(operationConfig operation.BlockingOperationConfig, message string) {
+	operationTimeOutStr := strconv.FormatInt(operationConfig.TimeOutInSeconds, 10)
+	err := errors.New("Switchover timed-out")
+	r.kubegresContext.Log.ErrorEvent("SwitchoverTimedOutErr", err,
+		"Last Switchover step '"+operationConfig.StepId+"' has timed-out after "+operationTimeOutStr+" seconds. "+message,
+		"Replica to promote", r.getTargetPod())
+}
+
+type formerPrimaryState struct {
+	state              string
+	checkpointLocation uint64
+}
+
+// parseFormerPrimaryState parses the termination message of the checker Pod, e.g. "state=shut down\ncheckpoint=0/3000028".
+func parseFormerPrimaryState(message string) (formerPrimaryState, error) {
+
+	var primaryState formerPrimaryState
+	var checkpointLocation string
+
+	for _, line := range strings.Split(message, "\n") {
+		if value, found := cutPrefix(line, "state="); found {
+			primaryState.state = value
+		} else if value, found := cutPrefix(line, "checkpoint="); found {
+			checkpointLocation = value
+		}
+	}
+
+	if primaryState.state == "" {
+		return formerPrimaryState{}, errors.New("The state of the stopped Primary is missing in: '" + message + "'")
+	}
+
+	var err error
+	if primaryState.checkpointLocation, err = states.ParseWalLocation(checkpointLocation); err != nil {
+		return formerPrimaryState{}, err
+	}
+
+	return primaryState, nil
+}
+
+func cutPrefix(value, prefix string) (string, bool) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(value, prefix)), true
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+const (
+	targetReplicaInstanceIndex = 2
+	shutdownCheckpointLocation = "0/3000028"
+)
+
+func TestFormerPrimaryStateIsParsed(t *testing.T) {
+	primaryState, err := parseFormerPrimaryState("state=shut down\ncheckpoint=16/B374D848\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if primaryState.state != formerPrimaryCleanShutdownState || primaryState.checkpointLocation != 0x16B374D848 {
+		t.Errorf("Unexpected state of the former Primary: %+v", primaryState)
+	}
+}
+
+func TestFormerPrimaryStateWithoutCheckpointIsRejected(t *testing.T) {
+	if _, err := parseFormerPrimaryState("state=in production\ncheckpoint=\n"); err == nil {
+		t.Error("Expected an error as the checkpoint location is missing")
+	}
+}
+
+func TestFormerPrimaryIsNotCheckedUntilCheckerPodTerminates(t *testing.T) {
+	switchover := createSwitchoverToTest(t, nil, 0x3000100)
+
+	if switchover.isFormerPrimaryChecked(v1.KubegresBlockingOperation{}) {
+		t.Error("Expected the former Primary to not be checked without the checker Pod")
+	}
+}
+
+func TestFormerPrimaryIsCheckedOnceReplicaReplayedShutdownCheckpoint(t *testing.T) {
+	switchover := createSwitchoverToTest(t, createTerminatedCheckerPod("shut down"), 0x3000100)
+
+	if !switchover.isFormerPrimaryChecked(v1.KubegresBlockingOperation{}) {
+		t.Error("Expected the former Primary to be checked as the Replica replayed its shutdown checkpoint")
+	}
+}
+
+func TestFormerPrimaryIsNotCheckedWhileReplicaDidNotReplayShutdownCheckpoint(t *testing.T) {
+	switchover := createSwitchoverToTest(t, createTerminatedCheckerPod("shut down"), 0x3000028)
+
+	if switchover.isFormerPrimaryChecked(v1.KubegresBlockingOperation{}) {
+		t.Error("Expected to wait as the Replica replayed up to the start of the shutdown checkpoint only")
+	}
+}
+
+func TestFormerPrimaryIsCheckedWhenItDidNotShutDownCleanly(t *testing.T) {
+	switchover := createSwitchoverToTest(t, createTerminatedCheckerPod("in production"), 0x1000000)
+
+	if !switchover.isFormerPrimaryChecked(v1.KubegresBlockingOperation{}) {
+		t.Error("Expected the check to complete, so that the Switchover is aborted without waiting for the Replica")
+	}
+}
+
+func TestFormerPrimaryIsStoppedOnceItsPodIsDeleted(t *testing.T) {
+	formerPrimaryPod := &core.Pod{ObjectMeta: metav1.ObjectMeta{Name: "postgres-1-0", Namespace: "default"}}
+	switchover := createSwitchoverToTest(t, formerPrimaryPod, 0)
+	operation := v1.KubegresBlockingOperation{StatefulSetOperation: v1.KubegresStatefulSetOperation{Name: "postgres-1"}}
+
+	if switchover.isFormerPrimaryStopped(operation) {
+		t.Fatal("Expected the former Primary to not be stopped while its Pod exists")
+	}
+
+	if err := switchover.kubegresContext.Client.Delete(context.Background(), formerPrimaryPod); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !switchover.isFormerPrimaryStopped(operation) {
+		t.Error("Expected the former Primary to be stopped once its Pod is deleted")
+	}
+}
+
+func TestOnlyReplicaInitContainerIsConfiguredToPromote(t *testing.T) {
+	userInitContainer := core.Container{Name: "wait-for-vault", Command: []string{"sh", "-c", "/vault/wait.sh"}}
+	replicaInitContainer := core.Container{Name: ctx.ReplicaInitContainerName, Command: []string{"sh", "-c", "/tmp/copy_primary_data_to_replica.sh"}}
+	replicaStatefulSet := apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"replicationRole": ctx.ReplicaRoleName}},
+		Spec: apps.StatefulSetSpec{Template: core.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"replicationRole": ctx.ReplicaRoleName}},
+			Spec:       core.PodSpec{InitContainers: []core.Container{userInitContainer, replicaInitContainer}},
+		}},
+	}
+
+	configureReplicaStatefulSetToPromote(&replicaStatefulSet)
+
+	initContainers := replicaStatefulSet.Spec.Template.Spec.InitContainers
+	if initContainers[0].Command[2] != "/vault/wait.sh" || len(initContainers[0].VolumeMounts) != 0 {
+		t.Errorf("Expected the init container of 'spec.initContainers' to be unchanged, got: %v", initContainers[0])
+	}
+	if initContainers[1].Command[2] != "/tmp/promote_replica_to_primary.sh" {
+		t.Errorf("Expected the init container of the Replica to promote it, got: %v", initContainers[1].Command)
+	}
+	if replicaStatefulSet.Spec.Template.Labels["replicationRole"] != ctx.PrimaryRoleName {
+		t.Errorf("Expected the Pods to be labelled as Primary, got: %v", replicaStatefulSet.Spec.Template.Labels)
+	}
+}
+
+func createSwitchoverToTest(t *testing.T, pod *core.Pod, targetReplicaReplayedLocation uint64) PrimaryToReplicaSwitchover {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec:       v1.KubegresSpec{Switchover: v1.KubegresSwitchover{TargetPod: "postgres-2-0"}},
+	}
+
+	clientBuilder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	if pod != nil {
+		clientBuilder = clientBuilder.WithObjects(pod)
+	}
+
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Client:   clientBuilder.Build(),
+		Ctx:      context.Background(),
+		Log:      log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard()},
+	}
+
+	targetReplica := statefulset.StatefulSetWrapper{
+		IsDeployed:    true,
+		IsReady:       true,
+		InstanceIndex: targetReplicaInstanceIndex,
+		StatefulSet:   apps.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "postgres-2"}},
+	}
+	targetReplica.Pod.Pod = core.Pod{ObjectMeta: metav1.ObjectMeta{Name: "postgres-2-0"}}
+
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.StatefulSets.Replicas.All.Add(targetReplica)
+	resourcesStates.Replication.Replicas = []states.WalLocationWrapper{{
+		IsLoaded:         true,
+		InstanceIndex:    targetReplicaInstanceIndex,
+		IsInRecovery:     true,
+		ReplayedLocation: targetReplicaReplayedLocation,
+	}}
+
+	return CreatePrimaryToReplicaSwitchover(kubegresContext, resourcesStates, template.ResourcesCreatorFromTemplate{}, nil, nil)
+}
+
+func createTerminatedCheckerPod(formerPrimaryState string) *core.Pod {
+	checkerPod := &core.Pod{ObjectMeta: metav1.ObjectMeta{Name: "postgres" + ctx.FormerPrimaryCheckerPodNameSuffix, Namespace: "default"}}
+	checkerPod.Status.ContainerStatuses = []core.ContainerStatus{{
+		State: core.ContainerState{Terminated: &core.ContainerStateTerminated{
+			Message: "state=" + formerPrimaryState + "\ncheckpoint=" + shutdownCheckpointLocation + "\n",
+		}},
+	}}
+	return checkerPod
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	apps "k8s.io/api/apps/v1"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+// BaseConfigSpecEnforcer restarts the Pods of a StatefulSet once the base ConfigMap deployed by a previous version of
+// Kubegres is updated. Its data keys are mounted with 'subPath' and are never refreshed in running containers, so
+// changing the annotation 'BaseConfigHashAnnotationKey' in the Pod template is the only way to use the new scripts.
+type BaseConfigSpecEnforcer struct {
+	resourcesStates  states.ResourcesStates
+	resourcesCreator template.ResourcesCreatorFromTemplate
+}
+
+func CreateBaseConfigSpecEnforcer(resourcesStates states.ResourcesStates,
+	resourcesCreator template.ResourcesCreatorFromTemplate) BaseConfigSpecEnforcer {
+
+	return BaseConfigSpecEnforcer{
+		resourcesStates:  resourcesStates,
+		resourcesCreator: resourcesCreator,
+	}
+}
+
+func (r *BaseConfigSpecEnforcer) GetSpecName() string {
+	return "BaseConfig"
+}
+
+func (r *BaseConfigSpecEnforcer) CheckForSpecDifference(statefulSet *apps.StatefulSet) StatefulSetSpecDifference {
+
+	expected, err := r.resourcesCreator.GetBaseConfigHash()
+	if err != nil || !r.isDeployedBaseConfigMapUpToDate(expected) {
+		// The Pods are restarted only once the base ConfigMap is updated, otherwise they would use the outdated scripts
+		return StatefulSetSpecDifference{}
+	}
+
+	current := statefulSet.Spec.Template.Annotations[template.BaseConfigHashAnnotationKey]
+	if current != expected {
+		return StatefulSetSpecDifference{
+			SpecName: r.GetSpecName(),
+			Current:  current,
+			Expected: expected,
+		}
+	}
+
+	return StatefulSetSpecDifference{}
+}
+
+func (r *BaseConfigSpecEnforcer) EnforceSpec(statefulSet *apps.StatefulSet) (wasSpecUpdated bool, err error) {
+
+	expected, err := r.resourcesCreator.GetBaseConfigHash()
+	if err != nil {
+		return false, err
+	}
+
+	if statefulSet.Spec.Template.Annotations == nil {
+		statefulSet.Spec.Template.Annotations = make(map[string]string)
+	}
+	statefulSet.Spec.Template.Annotations[template.BaseConfigHashAnnotationKey] = expected
+
+	return true, nil
+}
+
+func (r *BaseConfigSpecEnforcer) OnSpecEnforcedSuccessfully(statefulSet *apps.StatefulSet) error {
+	return nil
+}
+
+func (r *BaseConfigSpecEnforcer) isDeployedBaseConfigMapUpToDate(expectedHash string) bool {
+	deployedBaseConfigMap := r.resourcesStates.Config.BaseConfigMap
+	return deployedBaseConfigMap.Annotations[template.BaseConfigHashAnnotationKey] == expectedHash
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	apps "k8s.io/api/apps/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/controllers/states"
+	"testing"
+)
+
+func TestPodsAreNotRestartedUntilBaseConfigMapIsUpdated(t *testing.T) {
+	enforcer := createBaseConfigSpecEnforcer(t, "outdated")
+	statefulSet := apps.StatefulSet{}
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference as the base ConfigMap is not updated yet")
+	}
+}
+
+func TestPodsAreRestartedOnceBaseConfigMapIsUpdated(t *testing.T) {
+	enforcer := createBaseConfigSpecEnforcer(t, "")
+	statefulSet := apps.StatefulSet{}
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if !specDifference.IsThereDifference() {
+		t.Fatal("Expected a difference as the Pods were started with the outdated base ConfigMap")
+	}
+
+	if _, err := enforcer.EnforceSpec(&statefulSet); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	specDifference = enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference once the spec is enforced")
+	}
+}
+
+// createBaseConfigSpecEnforcer creates an enforcer with a deployed base ConfigMap annotated with the given hash, or
+// with the expected hash if it is empty.
+func createBaseConfigSpecEnforcer(t *testing.T, deployedBaseConfigHash string) BaseConfigSpecEnforcer {
+	resourcesCreator := template.CreateResourcesCreatorFromTemplate(ctx.KubegresContext{},
+		template.CustomConfigSpecHelper{}, template.WalArchiveSpecHelper{}, template.ExtraContainersSpecHelper{},
+		template.ResourceTemplateLoader{})
+
+	if deployedBaseConfigHash == "" {
+		expectedHash, err := resourcesCreator.GetBaseConfigHash()
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		deployedBaseConfigHash = expectedHash
+	}
+
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.Config.BaseConfigMap.Annotations = map[string]string{template.BaseConfigHashAnnotationKey: deployedBaseConfigHash}
+
+	return CreateBaseConfigSpecEnforcer(resourcesStates, resourcesCreator)
+}
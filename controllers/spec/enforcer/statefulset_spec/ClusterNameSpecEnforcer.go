@@ -0,0 +1,104 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+const (
+	podNameEnvVarName  = "POD_NAME"
+	clusterNameArgName = "cluster_name=$(" + podNameEnvVarName + ")"
+)
+
+// ClusterNameSpecEnforcer sets the PostgreSql parameter 'cluster_name' to the name of the Pod in the StatefulSets which
+// were created before 'spec.replication' existed. The synchronous Replicas are listed by their 'cluster_name' in
+// 'synchronous_standby_names', so without it the synchronous replication would silently stay asynchronous.
+type ClusterNameSpecEnforcer struct {
+	kubegresContext ctx.KubegresContext
+}
+
+func CreateClusterNameSpecEnforcer(kubegresContext ctx.KubegresContext) ClusterNameSpecEnforcer {
+	return ClusterNameSpecEnforcer{kubegresContext: kubegresContext}
+}
+
+func (r *ClusterNameSpecEnforcer) GetSpecName() string {
+	return "ClusterName"
+}
+
+func (r *ClusterNameSpecEnforcer) CheckForSpecDifference(statefulSet *apps.StatefulSet) StatefulSetSpecDifference {
+
+	container := statefulSet.Spec.Template.Spec.Containers[0]
+
+	if !r.hasPodNameEnvVar(container) || !r.hasClusterNameArg(container) {
+		return StatefulSetSpecDifference{
+			SpecName: r.GetSpecName(),
+			Current:  "undefined",
+			Expected: clusterNameArgName,
+		}
+	}
+
+	return StatefulSetSpecDifference{}
+}
+
+func (r *ClusterNameSpecEnforcer) EnforceSpec(statefulSet *apps.StatefulSet) (wasSpecUpdated bool, err error) {
+
+	container := &statefulSet.Spec.Template.Spec.Containers[0]
+
+	if !r.hasPodNameEnvVar(*container) {
+		container.Env = append(container.Env, core.EnvVar{
+			Name: podNameEnvVarName,
+			ValueFrom: &core.EnvVarSource{
+				FieldRef: &core.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.name"},
+			},
+		})
+	}
+
+	if !r.hasClusterNameArg(*container) {
+		container.Args = append(container.Args, "-c", clusterNameArgName)
+	}
+
+	return true, nil
+}
+
+func (r *ClusterNameSpecEnforcer) OnSpecEnforcedSuccessfully(statefulSet *apps.StatefulSet) error {
+	return nil
+}
+
+func (r *ClusterNameSpecEnforcer) hasPodNameEnvVar(container core.Container) bool {
+	for _, envVar := range container.Env {
+		if envVar.Name == podNameEnvVarName {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ClusterNameSpecEnforcer) hasClusterNameArg(container core.Container) bool {
+	for _, arg := range container.Args {
+		if arg == clusterNameArgName {
+			return true
+		}
+	}
+	return false
+}
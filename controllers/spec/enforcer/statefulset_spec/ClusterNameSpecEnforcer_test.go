@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"testing"
+)
+
+func TestClusterNameIsAddedToStatefulSetCreatedWithoutIt(t *testing.T) {
+	enforcer := CreateClusterNameSpecEnforcer(ctx.KubegresContext{})
+	statefulSet := createStatefulSetWithArgs("-c", "config_file=/etc/postgres.conf")
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if !specDifference.IsThereDifference() {
+		t.Fatal("Expected a difference as the StatefulSet does not set 'cluster_name'")
+	}
+
+	if _, err := enforcer.EnforceSpec(&statefulSet); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	specDifference = enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference once the spec is enforced")
+	}
+
+	args := statefulSet.Spec.Template.Spec.Containers[0].Args
+	if len(args) != 4 || args[2] != "-c" || args[3] != clusterNameArgName {
+		t.Errorf("Expected 'cluster_name' to be appended to the existing args, got: %v", args)
+	}
+}
+
+func TestClusterNameIsNotAddedTwice(t *testing.T) {
+	enforcer := CreateClusterNameSpecEnforcer(ctx.KubegresContext{})
+	statefulSet := createStatefulSetWithArgs("-c", clusterNameArgName)
+	statefulSet.Spec.Template.Spec.Containers[0].Env = []core.EnvVar{{Name: podNameEnvVarName}}
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference as the StatefulSet already sets 'cluster_name'")
+	}
+}
+
+func createStatefulSetWithArgs(args ...string) apps.StatefulSet {
+	statefulSet := apps.StatefulSet{}
+	statefulSet.Spec.Template.Spec.Containers = []core.Container{{Name: "postgres", Args: args}}
+	return statefulSet
+}
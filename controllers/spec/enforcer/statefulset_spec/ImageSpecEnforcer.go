@@ -23,6 +23,7 @@ package statefulset_spec
 import (
 	apps "k8s.io/api/apps/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
 )
 
 type ImageSpecEnforcer struct {
@@ -42,6 +43,22 @@ func (r *ImageSpecEnforcer) CheckForSpecDifference(statefulSet *apps.StatefulSet
 	current := statefulSet.Spec.Template.Spec.Containers[0].Image
 	expected := r.kubegresContext.Kubegres.Spec.Image
 
+	// An image with a different major version of PostgreSql cannot run the existing data.
+	// It is set by the major version upgrade once the data is upgraded with pg_upgrade.
+	if r.kubegresContext.IsPostgresMajorVersionChanged() {
+		return StatefulSetSpecDifference{}
+	}
+
+	// Without knowing the running major version, it cannot be checked that the new image runs the existing data
+	if current != expected && r.kubegresContext.IsPostgresMajorVersionUnknown() {
+		r.kubegresContext.Log.Info("The image of the StatefulSet is not updated until the major version of "+
+			"PostgreSql running on the Primary is known.",
+			"StatefulSet", statefulSet.Name,
+			"Current image", current,
+			"Expected image", expected)
+		return StatefulSetSpecDifference{}
+	}
+
 	if current != expected {
 		return StatefulSetSpecDifference{
 			SpecName: r.GetSpecName(),
@@ -56,8 +73,8 @@ func (r *ImageSpecEnforcer) CheckForSpecDifference(statefulSet *apps.StatefulSet
 func (r *ImageSpecEnforcer) EnforceSpec(statefulSet *apps.StatefulSet) (wasSpecUpdated bool, err error) {
 	statefulSet.Spec.Template.Spec.Containers[0].Image = r.kubegresContext.Kubegres.Spec.Image
 
-	if len(statefulSet.Spec.Template.Spec.InitContainers) > 0 {
-		statefulSet.Spec.Template.Spec.InitContainers[0].Image = r.kubegresContext.Kubegres.Spec.Image
+	if initContainer := template.GetReplicaInitContainer(&statefulSet.Spec.Template.Spec); initContainer != nil {
+		initContainer.Image = r.kubegresContext.Kubegres.Spec.Image
 	}
 
 	return true, nil
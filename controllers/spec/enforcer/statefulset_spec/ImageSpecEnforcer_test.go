@@ -0,0 +1,88 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"testing"
+)
+
+func TestImageIsNotUpdatedWhileRunningMajorVersionIsUnknown(t *testing.T) {
+	enforcer := createImageSpecEnforcer("postgres:16.1", 0)
+	statefulSet := createStatefulSetWithImage("postgres:15.4")
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference as the running major version is unknown")
+	}
+}
+
+func TestImageIsNotUpdatedWhenMajorVersionChanges(t *testing.T) {
+	enforcer := createImageSpecEnforcer("postgres:16.1", 15)
+	statefulSet := createStatefulSetWithImage("postgres:15.4")
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if specDifference.IsThereDifference() {
+		t.Error("Expected no difference as the major version upgrade sets the image")
+	}
+}
+
+func TestImageIsUpdatedWithinSameMajorVersion(t *testing.T) {
+	enforcer := createImageSpecEnforcer("postgres:15.5", 15)
+	statefulSet := createStatefulSetWithImage("postgres:15.4")
+
+	specDifference := enforcer.CheckForSpecDifference(&statefulSet)
+	if !specDifference.IsThereDifference() {
+		t.Fatal("Expected a difference as the minor version changed")
+	}
+
+	if _, err := enforcer.EnforceSpec(&statefulSet); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if image := statefulSet.Spec.Template.Spec.Containers[0].Image; image != "postgres:15.5" {
+		t.Errorf("Expected the image to be updated, got: %s", image)
+	}
+}
+
+func createImageSpecEnforcer(image string, runningMajorVersion int32) ImageSpecEnforcer {
+	kubegres := &v1.Kubegres{Spec: v1.KubegresSpec{Image: image}}
+	kubegres.Status.PostgresMajorVersion = runningMajorVersion
+
+	logWrapper := log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard()}
+	return CreateImageSpecEnforcer(ctx.KubegresContext{
+		Kubegres: kubegres,
+		Status:   &status.KubegresStatusWrapper{Kubegres: kubegres, Log: logWrapper},
+		Log:      logWrapper,
+	})
+}
+
+func createStatefulSetWithImage(image string) apps.StatefulSet {
+	statefulSet := apps.StatefulSet{}
+	statefulSet.Spec.Template.Spec.Containers = []core.Container{{Name: "postgres", Image: image}}
+	return statefulSet
+}
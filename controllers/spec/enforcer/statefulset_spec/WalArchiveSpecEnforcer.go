@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset_spec
+
+import (
+	apps "k8s.io/api/apps/v1"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+)
+
+type WalArchiveSpecEnforcer struct {
+	walArchiveSpecHelper template.WalArchiveSpecHelper
+}
+
+func CreateWalArchiveSpecEnforcer(walArchiveSpecHelper template.WalArchiveSpecHelper) WalArchiveSpecEnforcer {
+	return WalArchiveSpecEnforcer{walArchiveSpecHelper: walArchiveSpecHelper}
+}
+
+func (r *WalArchiveSpecEnforcer) GetSpecName() string {
+	return "WalArchive"
+}
+
+func (r *WalArchiveSpecEnforcer) CheckForSpecDifference(statefulSet *apps.StatefulSet) StatefulSetSpecDifference {
+
+	current := statefulSet.Spec.Template.Annotations[template.WalArchiveConfigAnnotationKey]
+	expected := r.walArchiveSpecHelper.GetExpectedConfig()
+
+	if current != expected {
+		return StatefulSetSpecDifference{
+			SpecName: r.GetSpecName(),
+			Current:  current,
+			Expected: expected,
+		}
+	}
+
+	return StatefulSetSpecDifference{}
+}
+
+func (r *WalArchiveSpecEnforcer) EnforceSpec(statefulSet *apps.StatefulSet) (wasSpecUpdated bool, err error) {
+	wasSpecUpdated, _ = r.walArchiveSpecHelper.ConfigureStatefulSet(statefulSet)
+	return wasSpecUpdated, nil
+}
+
+func (r *WalArchiveSpecEnforcer) OnSpecEnforcedSuccessfully(statefulSet *apps.StatefulSet) error {
+	return nil
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+// BackUpRetentionAnnotationKey is set in the backup CronJob with the retention policy applied to the backups, so that
+// the CronJob is re-created when the field 'backup.retention' changes.
+const BackUpRetentionAnnotationKey = "kubegres.reactive-tech.io/backup-retention"
+
+// backUpPruneScript removes the backups which are not retained by the field 'backup.retention', either from the
+// folder $BACKUP_DESTINATION_FOLDER or, if set, from $BACKUP_S3_URL. The backups are sorted by the date in their
+// file name, and the last backup of a day, a week or a month is the most recent one taken during that period.
+// The names of the retained backups are written in the termination message of the container, from the most recent
+// to the oldest, until its size limit is reached. If $BACKUP_RETENTION_FOLDER is set, the date of the oldest
+// retained base backup is written in it for the script 'walArchivePruneScript'.
+const backUpPruneScript = awsOptionsScript + `
+listBackUps() {
+    if [ -n "$BACKUP_S3_URL" ]; then
+        aws s3 ls $awsOptions $BACKUP_S3_URL/ | awk '{print $4}'
+    else
+        ls -1 $BACKUP_DESTINATION_FOLDER
+    fi
+}
+
+deleteBackUp() {
+    echo "$(date '+%d/%m/%Y %H:%M:%S') - Removing the backup '$1' as it is not retained";
+    if [ -n "$BACKUP_S3_URL" ]; then
+        aws s3 rm $awsOptions --only-show-errors $BACKUP_S3_URL/$1
+    else
+        rm -f $BACKUP_DESTINATION_FOLDER/$1
+    fi
+}
+
+backUpFileNameRegex="^$KUBEGRES_RESOURCE_NAME-(base)?backup-([0-9]{2})_([0-9]{2})_([0-9]{4})_([0-9]{2})_([0-9]{2})_([0-9]{2})[.]"
+backUps=""
+for backUpFileName in $(listBackUps); do
+    if [[ "$backUpFileName" =~ $backUpFileNameRegex ]]; then
+        m=("${BASH_REMATCH[@]}")
+        timestamp=$(date -u -d "${m[4]}-${m[3]}-${m[2]} ${m[5]}:${m[6]}:${m[7]}" +%s)
+        backUps="$backUps$timestamp $backUpFileName"$'\n'
+    fi
+done
+
+now=$(date -u +%s)
+index=0
+nbreDays=0
+nbreWeeks=0
+nbreMonths=0
+lastDay=""
+lastWeek=""
+lastMonth=""
+retainedBackUps=""
+oldestRetainedBaseBackUp=""
+
+while read -r timestamp backUpFileName; do
+
+    if [ -z "$backUpFileName" ]; then
+        continue
+    fi
+
+    day=$(date -u -d "@$timestamp" +%Y-%m-%d)
+    week=$(date -u -d "@$timestamp" +%G-%V)
+    month=$(date -u -d "@$timestamp" +%Y-%m)
+
+    isRetained=false
+    if [ $((KEEP_LAST + KEEP_DAILY + KEEP_WEEKLY + KEEP_MONTHLY)) -eq 0 ] || [ $index -lt $KEEP_LAST ]; then
+        isRetained=true
+    fi
+    if [ "$day" != "$lastDay" ]; then
+        lastDay=$day
+        nbreDays=$((nbreDays + 1))
+        if [ $nbreDays -le $KEEP_DAILY ]; then isRetained=true; fi
+    fi
+    if [ "$week" != "$lastWeek" ]; then
+        lastWeek=$week
+        nbreWeeks=$((nbreWeeks + 1))
+        if [ $nbreWeeks -le $KEEP_WEEKLY ]; then isRetained=true; fi
+    fi
+    if [ "$month" != "$lastMonth" ]; then
+        lastMonth=$month
+        nbreMonths=$((nbreMonths + 1))
+        if [ $nbreMonths -le $KEEP_MONTHLY ]; then isRetained=true; fi
+    fi
+    if [ $MAX_AGE_DAYS -gt 0 ] && [ $index -gt 0 ] && [ $((now - timestamp)) -gt $((MAX_AGE_DAYS * 86400)) ]; then
+        isRetained=false
+    fi
+
+    if [ "$isRetained" == "true" ]; then
+        retainedBackUps="$retainedBackUps$backUpFileName"$'\n'
+        if [[ "$backUpFileName" == "$KUBEGRES_RESOURCE_NAME-basebackup-"* ]]; then
+            oldestRetainedBaseBackUp=$timestamp
+        fi
+    else
+        deleteBackUp $backUpFileName
+    fi
+
+    index=$((index + 1))
+
+done <<< "$(echo -n "$backUps" | sort -rn)"
+
+if [ -n "$BACKUP_RETENTION_FOLDER" ]; then
+    echo -n "$oldestRetainedBaseBackUp" > $BACKUP_RETENTION_FOLDER/oldest_retained_base_backup
+fi
+
+echo "$(date '+%d/%m/%Y %H:%M:%S') - Retained backups:"
+echo -n "$retainedBackUps"
+echo -n "$retainedBackUps" | awk 'length(retained) + length($0) < 4000 { retained = retained $0 "\n" } END { printf "%s", retained }' > /dev/termination-log
+`
+
+// walArchivePruneScript removes the archived WAL segments which cannot be replayed on top of any retained base
+// backup, either from the folder $WAL_ARCHIVE_FOLDER or, if set, from $WAL_ARCHIVE_S3_URL. A segment is removed if it
+// was archived more than one hour before the oldest retained base backup was taken, which allows for the difference
+// of clocks between the Pods. The timeline history files are never removed, since they are required to recover a
+// base backup taken before a failover. If no base backup is retained, no segment is removed.
+const walArchivePruneScript = awsOptionsScript + `
+oldestRetainedBaseBackUp=$(cat $BACKUP_RETENTION_FOLDER/oldest_retained_base_backup 2>/dev/null || true)
+if [ -z "$oldestRetainedBaseBackUp" ]; then
+    echo "$(date '+%d/%m/%Y %H:%M:%S') - Skipping the removal of the archived WAL segments as no base backup is retained"
+    exit 0
+fi
+
+listArchivedWal() {
+    if [ -n "$WAL_ARCHIVE_S3_URL" ]; then
+        aws s3 ls $awsOptions $WAL_ARCHIVE_S3_URL/ | grep -v " PRE " | while read -r day time size walFileName; do
+            echo "$(date -u -d "$day $time" +%s) $walFileName"
+        done
+    else
+        for walFilePath in $WAL_ARCHIVE_FOLDER/*; do
+            if [ -f "$walFilePath" ]; then
+                echo "$(stat -c %Y $walFilePath) $(basename $walFilePath)"
+            fi
+        done
+    fi
+}
+
+deleteArchivedWal() {
+    if [ -n "$WAL_ARCHIVE_S3_URL" ]; then
+        aws s3 rm $awsOptions --only-show-errors $WAL_ARCHIVE_S3_URL/$1
+    else
+        rm -f $WAL_ARCHIVE_FOLDER/$1
+    fi
+}
+
+limit=$((oldestRetainedBaseBackUp - 3600))
+nbreRemoved=0
+walFileNameRegex="^[0-9A-F]{24}"
+
+while read -r timestamp walFileName; do
+    if [[ "$walFileName" =~ $walFileNameRegex ]] && [[ "$walFileName" != *.history ]] && [ "$timestamp" -lt "$limit" ]; then
+        deleteArchivedWal $walFileName
+        nbreRemoved=$((nbreRemoved + 1))
+    fi
+done <<< "$(listArchivedWal)"
+
+echo "$(date '+%d/%m/%Y %H:%M:%S') - Removed $nbreRemoved archived WAL segments older than the oldest retained base backup"
+`
+
+// GetBackUpRetentionConfig returns a description of the field 'backup.retention' and of the location of the archived
+// WAL segments, or an empty string if the backups are never removed.
+func (r *ResourcesCreatorFromTemplate) GetBackUpRetentionConfig() string {
+	if !r.kubegresContext.IsBackUpRetentionEnabled() {
+		return ""
+	}
+
+	retention := r.kubegresContext.Kubegres.Spec.Backup.Retention
+	retentionConfig := "keepLast=" + strconv.Itoa(int(retention.KeepLast)) +
+		" keepDaily=" + strconv.Itoa(int(retention.KeepDaily)) +
+		" keepWeekly=" + strconv.Itoa(int(retention.KeepWeekly)) +
+		" keepMonthly=" + strconv.Itoa(int(retention.KeepMonthly)) +
+		" maxAgeDays=" + strconv.Itoa(int(retention.MaxAgeDays))
+
+	// The archived WAL segments are removed with the base backups
+	if r.kubegresContext.IsWalArchiveEnabled() {
+		retentionConfig += " walArchive: " + r.walArchiveSpecHelper.GetExpectedConfig()
+	}
+	return retentionConfig
+}
+
+// configureBackUpRetention changes the Pod of the backup CronJob so that the containers taking and uploading the
+// backup run as init containers, followed by a container removing the backups which are not retained by the
+// field 'backup.retention'. If the WAL segments are archived, that container runs as an init container too, followed
+// by a container removing the segments which are older than the oldest retained base backup.
+func (r *ResourcesCreatorFromTemplate) configureBackUpRetention(backUpCronJobSpec *core.PodSpec) {
+
+	postgres := r.kubegresContext.Kubegres
+	retention := postgres.Spec.Backup.Retention
+
+	prunerContainer := core.Container{
+		Name:            ctx.BackUpPrunerContainerName,
+		Image:           postgres.Spec.Image,
+		ImagePullPolicy: core.PullIfNotPresent,
+		Command:         []string{"bash", "-c", backUpPruneScript},
+		Env: []core.EnvVar{
+			{Name: "KUBEGRES_RESOURCE_NAME", Value: postgres.Name},
+			{Name: "KEEP_LAST", Value: strconv.Itoa(int(retention.KeepLast))},
+			{Name: "KEEP_DAILY", Value: strconv.Itoa(int(retention.KeepDaily))},
+			{Name: "KEEP_WEEKLY", Value: strconv.Itoa(int(retention.KeepWeekly))},
+			{Name: "KEEP_MONTHLY", Value: strconv.Itoa(int(retention.KeepMonthly))},
+			{Name: "MAX_AGE_DAYS", Value: strconv.Itoa(int(retention.MaxAgeDays))},
+		},
+	}
+
+	if r.kubegresContext.IsBackUpInS3() {
+		s3 := *postgres.Spec.Backup.Destination.S3
+		prunerContainer.Env = append(prunerContainer.Env, core.EnvVar{Name: "BACKUP_S3_URL", Value: ctx.GetS3Url(s3)})
+		configureS3Container(&prunerContainer, s3)
+
+	} else {
+		backUpVolumeName := backUpCronJobSpec.Volumes[0].Name
+		prunerContainer.Env = append(prunerContainer.Env, core.EnvVar{Name: "BACKUP_DESTINATION_FOLDER", Value: r.kubegresContext.GetBackUpFolder()})
+		prunerContainer.VolumeMounts = []core.VolumeMount{{Name: backUpVolumeName, MountPath: r.kubegresContext.GetBackUpFolder()}}
+	}
+
+	backUpCronJobSpec.InitContainers = append(backUpCronJobSpec.InitContainers, backUpCronJobSpec.Containers...)
+	backUpCronJobSpec.Containers = []core.Container{prunerContainer}
+
+	if r.kubegresContext.IsWalArchiveEnabled() {
+		r.configureWalArchiveRetention(backUpCronJobSpec)
+	}
+}
+
+func (r *ResourcesCreatorFromTemplate) configureWalArchiveRetention(backUpCronJobSpec *core.PodSpec) {
+
+	postgres := r.kubegresContext.Kubegres
+	retentionVolumeMount := core.VolumeMount{Name: ctx.BackUpRetentionVolumeName, MountPath: ctx.BackUpRetentionVolumeMount}
+
+	backUpCronJobSpec.Volumes = append(backUpCronJobSpec.Volumes, core.Volume{
+		Name:         ctx.BackUpRetentionVolumeName,
+		VolumeSource: core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}},
+	})
+
+	prunerContainer := backUpCronJobSpec.Containers[0]
+	prunerContainer.Env = append(prunerContainer.Env, core.EnvVar{Name: "BACKUP_RETENTION_FOLDER", Value: ctx.BackUpRetentionVolumeMount})
+	prunerContainer.VolumeMounts = append(prunerContainer.VolumeMounts, retentionVolumeMount)
+
+	walPrunerContainer := core.Container{
+		Name:            ctx.WalArchivePrunerContainerName,
+		Image:           postgres.Spec.Image,
+		ImagePullPolicy: core.PullIfNotPresent,
+		Command:         []string{"bash", "-c", walArchivePruneScript},
+		Env:             []core.EnvVar{{Name: "BACKUP_RETENTION_FOLDER", Value: ctx.BackUpRetentionVolumeMount}},
+		VolumeMounts:    []core.VolumeMount{retentionVolumeMount},
+	}
+
+	if r.kubegresContext.IsWalArchivedInS3() {
+		s3 := *postgres.Spec.Backup.WalArchive.S3
+		walPrunerContainer.Env = append(walPrunerContainer.Env, core.EnvVar{Name: "WAL_ARCHIVE_S3_URL", Value: ctx.GetWalArchiveS3Url(s3, postgres.Name)})
+		configureS3Container(&walPrunerContainer, s3)
+		if !hasVolume(backUpCronJobSpec, ctx.S3CaVolumeName) {
+			addS3CaVolume(backUpCronJobSpec, s3)
+		}
+
+	} else {
+		// The WAL segments are archived in the PVC 'backup.pvcName', which is only mounted if the backups are stored in it
+		walArchiveVolumeName := backUpCronJobSpec.Volumes[0].Name
+		if r.kubegresContext.IsBackUpInS3() {
+			walArchiveVolumeName = ctx.WalArchiveVolumeName
+			backUpCronJobSpec.Volumes = append(backUpCronJobSpec.Volumes, core.Volume{
+				Name: walArchiveVolumeName,
+				VolumeSource: core.VolumeSource{
+					PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{ClaimName: postgres.Spec.Backup.PvcName},
+				},
+			})
+		}
+		walPrunerContainer.Env = append(walPrunerContainer.Env, core.EnvVar{Name: ctx.EnvVarNameWalArchiveFolder, Value: r.kubegresContext.GetWalArchiveFolder()})
+		walPrunerContainer.VolumeMounts = append(walPrunerContainer.VolumeMounts, core.VolumeMount{Name: walArchiveVolumeName, MountPath: postgres.Spec.Backup.VolumeMount})
+	}
+
+	backUpCronJobSpec.InitContainers = append(backUpCronJobSpec.InitContainers, prunerContainer)
+	backUpCronJobSpec.Containers = []core.Container{walPrunerContainer}
+}
+
+func hasVolume(podSpec *core.PodSpec, volumeName string) bool {
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == volumeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"testing"
+)
+
+func TestBackUpCronJobPrunesArchivedWalAfterBackUps(t *testing.T) {
+	backUpCronJob := createBackUpCronJobWithRetentionForTest(t, nil)
+
+	podSpec := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec
+	lastInitContainer := podSpec.InitContainers[len(podSpec.InitContainers)-1]
+	if lastInitContainer.Name != ctx.BackUpPrunerContainerName || !hasEnvVar(lastInitContainer, "BACKUP_RETENTION_FOLDER") {
+		t.Errorf("Expected the backup pruner to run before the WAL pruner, got %v", lastInitContainer)
+	}
+
+	walPrunerContainer := podSpec.Containers[0]
+	if walPrunerContainer.Name != ctx.WalArchivePrunerContainerName {
+		t.Fatalf("Expected the WAL pruner as the container of the Pod, got %v", podSpec.Containers)
+	}
+	if getEnvVarValue(walPrunerContainer, ctx.EnvVarNameWalArchiveFolder) != "/var/lib/backup/postgres-wal" {
+		t.Errorf("Expected the WAL pruner to prune the WAL archived in the backup PVC, got %v", walPrunerContainer.Env)
+	}
+	if !hasVolumeMount(walPrunerContainer, podSpec.Volumes[0].Name, "/var/lib/backup") {
+		t.Errorf("Expected the backup PVC to be mounted in the WAL pruner, got %v", walPrunerContainer.VolumeMounts)
+	}
+}
+
+func TestBackUpCronJobPrunesArchivedWalInS3(t *testing.T) {
+	walArchiveS3 := &v1.KubegresS3{Bucket: "wal-bucket", CredentialsSecret: "wal-credentials"}
+	backUpCronJob := createBackUpCronJobWithRetentionForTest(t, walArchiveS3)
+
+	walPrunerContainer := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	if getEnvVarValue(walPrunerContainer, "WAL_ARCHIVE_S3_URL") != ctx.GetWalArchiveS3Url(*walArchiveS3, "postgres") {
+		t.Errorf("Expected the WAL pruner to prune the WAL archived in S3, got %v", walPrunerContainer.Env)
+	}
+	if walPrunerContainer.EnvFrom[0].SecretRef.Name != "wal-credentials" {
+		t.Error("Expected the WAL pruner to use the credentials of 'backup.walArchive.s3'")
+	}
+}
+
+func createBackUpCronJobWithRetentionForTest(t *testing.T, walArchiveS3 *v1.KubegresS3) batch.CronJob {
+
+	replicas := int32(3)
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: v1.KubegresSpec{
+			Image:    "postgres:16",
+			Replicas: &replicas,
+			Backup: v1.KubegresBackUp{
+				Schedule:    "0 */1 * * *",
+				Type:        v1.BackUpTypePhysical,
+				PvcName:     "backup-pvc",
+				VolumeMount: "/var/lib/backup",
+				Retention:   v1.KubegresBackUpRetention{KeepLast: 2},
+				WalArchive:  v1.KubegresWalArchive{Enabled: true, S3: walArchiveS3},
+			},
+		},
+	}
+
+	kubegresContext := ctx.KubegresContext{Kubegres: kubegres}
+	resourcesCreator := CreateResourcesCreatorFromTemplate(kubegresContext, CustomConfigSpecHelper{},
+		CreateWalArchiveSpecHelper(kubegresContext), ExtraContainersSpecHelper{}, ResourceTemplateLoader{})
+
+	backUpCronJob, err := resourcesCreator.CreateBackUpCronJob(ctx.BaseConfigMapName)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return backUpCronJob
+}
+
+func hasEnvVar(container core.Container, envVarName string) bool {
+	return getEnvVarValue(container, envVarName) != ""
+}
+
+func getEnvVarValue(container core.Container, envVarName string) string {
+	for _, envVar := range container.Env {
+		if envVar.Name == envVarName {
+			return envVar.Value
+		}
+	}
+	return ""
+}
+
+func hasVolumeMount(container core.Container, volumeName, mountPath string) bool {
+	for _, volumeMount := range container.VolumeMounts {
+		if volumeMount.Name == volumeName && volumeMount.MountPath == mountPath {
+			return true
+		}
+	}
+	return false
+}
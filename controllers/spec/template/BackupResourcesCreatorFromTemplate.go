@@ -0,0 +1,124 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package template
+
+import (
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubegresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+type BackupResourcesCreatorFromTemplate struct {
+	kubegresBackupContext ctx.KubegresBackupContext
+}
+
+func CreateBackupResourcesCreatorFromTemplate(kubegresBackupContext ctx.KubegresBackupContext) BackupResourcesCreatorFromTemplate {
+	return BackupResourcesCreatorFromTemplate{kubegresBackupContext: kubegresBackupContext}
+}
+
+// CreateBackupJob creates a Job from the template of the given backup CronJob, in the same way as the Jobs created
+// on schedule, so that the backup is taken with the same script and stored in the same destination.
+// The container removing the backups which are not retained by the field 'backup.retention' is not run, and the
+// backup file is named so that it is never removed by the retention of the scheduled backups.
+func (r *BackupResourcesCreatorFromTemplate) CreateBackupJob(backUpCronJob *batch.CronJob) batch.Job {
+
+	kubegresBackup := r.kubegresBackupContext.KubegresBackup
+
+	backupJob := batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.kubegresBackupContext.GetBackupJobName(),
+			Namespace: kubegresBackup.Namespace,
+			Labels: map[string]string{
+				"app":                            kubegresBackup.Spec.ClusterName,
+				ctx.ManagedByKubegresBackupLabel: kubegresBackup.Name,
+			},
+			Annotations: map[string]string{
+				"cronjob.kubernetes.io/instantiate": "manual",
+			},
+			OwnerReferences: r.getOwnerReference(),
+		},
+		Spec: *backUpCronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+
+	for key, value := range backUpCronJob.Spec.JobTemplate.Labels {
+		backupJob.Labels[key] = value
+	}
+
+	backupJobSpec := &backupJob.Spec.Template.Spec
+	r.removeBackUpPrunerContainers(backupJobSpec)
+	r.setOnDemandBackUpFileName(backupJobSpec)
+
+	return backupJob
+}
+
+// removeBackUpPrunerContainers removes the containers added by 'backup.retention'. As the containers taking and
+// uploading the backup are then init containers, the last of them becomes the container of the Pod.
+func (r *BackupResourcesCreatorFromTemplate) removeBackUpPrunerContainers(backupJobSpec *core.PodSpec) {
+
+	initContainers := removePrunerContainers(backupJobSpec.InitContainers)
+	containers := removePrunerContainers(backupJobSpec.Containers)
+
+	nbreInitContainers := len(initContainers)
+	if len(containers) == 0 && nbreInitContainers > 0 {
+		containers = []core.Container{initContainers[nbreInitContainers-1]}
+		initContainers = initContainers[:nbreInitContainers-1]
+	}
+
+	backupJobSpec.InitContainers = initContainers
+	backupJobSpec.Containers = containers
+}
+
+func removePrunerContainers(containers []core.Container) []core.Container {
+	var keptContainers []core.Container
+	for _, container := range containers {
+		if container.Name != ctx.BackUpPrunerContainerName && container.Name != ctx.WalArchivePrunerContainerName {
+			keptContainers = append(keptContainers, container)
+		}
+	}
+	return keptContainers
+}
+
+// setOnDemandBackUpFileName changes the name of the Kubegres resource given to the backup script, which prefixes the
+// name of the backup file with it.
+func (r *BackupResourcesCreatorFromTemplate) setOnDemandBackUpFileName(backupJobSpec *core.PodSpec) {
+
+	for i := range backupJobSpec.InitContainers {
+		setOnDemandBackUpFileNameInContainer(&backupJobSpec.InitContainers[i])
+	}
+	for i := range backupJobSpec.Containers {
+		setOnDemandBackUpFileNameInContainer(&backupJobSpec.Containers[i])
+	}
+}
+
+func setOnDemandBackUpFileNameInContainer(container *core.Container) {
+
+	if container.Name != ctx.BackUpContainerName {
+		return
+	}
+
+	for i := range container.Env {
+		if container.Env[i].Name == "KUBEGRES_RESOURCE_NAME" {
+			container.Env[i].Value += ctx.OnDemandBackUpFileNameSuffix
+		}
+	}
+}
+
+func (r *BackupResourcesCreatorFromTemplate) getOwnerReference() []metav1.OwnerReference {
+	return []metav1.OwnerReference{*metav1.NewControllerRef(r.kubegresBackupContext.KubegresBackup, kubegresv1.GroupVersion.WithKind(ctx.KindKubegresBackup))}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"testing"
+)
+
+func TestCreateBackupJobRemovesBackUpPrunerContainer(t *testing.T) {
+	backUpContainer := createBackUpContainerForTest()
+	uploaderContainer := core.Container{Name: ctx.BackUpUploaderContainerName}
+	prunerContainer := core.Container{Name: ctx.BackUpPrunerContainerName}
+	backUpCronJob := createBackUpCronJobForTest(core.PodSpec{
+		InitContainers: []core.Container{backUpContainer, uploaderContainer},
+		Containers:     []core.Container{prunerContainer},
+	})
+
+	backupJob := createBackupResourcesCreatorForTest().CreateBackupJob(backUpCronJob)
+
+	podSpec := backupJob.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 1 || podSpec.InitContainers[0].Name != ctx.BackUpContainerName {
+		t.Errorf("Expected the backup container as the only init container, got %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Containers) != 1 || podSpec.Containers[0].Name != ctx.BackUpUploaderContainerName {
+		t.Errorf("Expected the uploader container as the only container, got %v", podSpec.Containers)
+	}
+	if len(backUpCronJob.Spec.JobTemplate.Spec.Template.Spec.Containers) != 1 {
+		t.Error("Expected the template of the backup CronJob to be unchanged")
+	}
+}
+
+func TestCreateBackupJobMovesBackUpContainerBackWhenNoUploader(t *testing.T) {
+	backUpCronJob := createBackUpCronJobForTest(core.PodSpec{
+		InitContainers: []core.Container{createBackUpContainerForTest()},
+		Containers:     []core.Container{{Name: ctx.BackUpPrunerContainerName}},
+	})
+
+	backupJob := createBackupResourcesCreatorForTest().CreateBackupJob(backUpCronJob)
+
+	podSpec := backupJob.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 0 {
+		t.Errorf("Expected no init containers, got %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Containers) != 1 || podSpec.Containers[0].Name != ctx.BackUpContainerName {
+		t.Errorf("Expected the backup container as the only container, got %v", podSpec.Containers)
+	}
+}
+
+func TestCreateBackupJobRemovesWalArchivePrunerContainer(t *testing.T) {
+	backUpCronJob := createBackUpCronJobForTest(core.PodSpec{
+		InitContainers: []core.Container{createBackUpContainerForTest(), {Name: ctx.BackUpPrunerContainerName}},
+		Containers:     []core.Container{{Name: ctx.WalArchivePrunerContainerName}},
+	})
+
+	backupJob := createBackupResourcesCreatorForTest().CreateBackupJob(backUpCronJob)
+
+	podSpec := backupJob.Spec.Template.Spec
+	if len(podSpec.InitContainers) != 0 {
+		t.Errorf("Expected no init containers, got %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Containers) != 1 || podSpec.Containers[0].Name != ctx.BackUpContainerName {
+		t.Errorf("Expected the backup container as the only container, got %v", podSpec.Containers)
+	}
+}
+
+func TestCreateBackupJobNamesBackUpFileOutsideOfRetention(t *testing.T) {
+	backUpCronJob := createBackUpCronJobForTest(core.PodSpec{
+		Containers: []core.Container{createBackUpContainerForTest()},
+	})
+
+	backupJob := createBackupResourcesCreatorForTest().CreateBackupJob(backUpCronJob)
+
+	env := backupJob.Spec.Template.Spec.Containers[0].Env
+	if env[1].Value != "mypostgres"+ctx.OnDemandBackUpFileNameSuffix {
+		t.Errorf("Expected the name of the backup file to be prefixed with 'mypostgres%s', got '%s'", ctx.OnDemandBackUpFileNameSuffix, env[1].Value)
+	}
+	if env[0].Value != "/var/lib/backup" {
+		t.Error("Expected the other environment variables to be unchanged")
+	}
+}
+
+func createBackupResourcesCreatorForTest() *BackupResourcesCreatorFromTemplate {
+	kubegresBackup := &v1.KubegresBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "mybackup", Namespace: "default"},
+		Spec:       v1.KubegresBackupSpec{ClusterName: "mypostgres"},
+	}
+	resourcesCreator := CreateBackupResourcesCreatorFromTemplate(ctx.KubegresBackupContext{KubegresBackup: kubegresBackup})
+	return &resourcesCreator
+}
+
+func createBackUpContainerForTest() core.Container {
+	return core.Container{
+		Name: ctx.BackUpContainerName,
+		Env: []core.EnvVar{
+			{Name: "BACKUP_DESTINATION_FOLDER", Value: "/var/lib/backup"},
+			{Name: "KUBEGRES_RESOURCE_NAME", Value: "mypostgres"},
+		},
+	}
+}
+
+func createBackUpCronJobForTest(podSpec core.PodSpec) *batch.CronJob {
+	backUpCronJob := &batch.CronJob{}
+	backUpCronJob.Spec.JobTemplate.Spec.Template.Spec = podSpec
+	return backUpCronJob
+}
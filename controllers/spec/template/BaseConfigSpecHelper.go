@@ -0,0 +1,86 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+// BaseConfigHashAnnotationKey is set in the base ConfigMap and in the Pod template of each StatefulSet with a hash of
+// the data keys of the base ConfigMap managed by Kubegres. It allows detecting when a base ConfigMap deployed by a
+// previous version of Kubegres must be updated and, since its data keys are mounted with 'subPath' and are never
+// refreshed in running containers, when the Pods must be restarted.
+const BaseConfigHashAnnotationKey = "kubegres.reactive-tech.io/base-config-hash"
+
+// userEditableBaseConfigKeys are the data keys of the base ConfigMap which users may edit to change the configuration
+// of all Kubegres resources in a namespace. When the base ConfigMap is updated, they are only added if missing.
+var userEditableBaseConfigKeys = map[string]bool{
+	states.ConfigMapDataKeyPostgresConf:      true,
+	states.ConfigMapDataKeyPrimaryInitScript: true,
+	states.ConfigMapDataKeyPgHbaConf:         true,
+}
+
+// GetBaseConfigHash returns the value of the annotation 'BaseConfigHashAnnotationKey' for the given template of the
+// base ConfigMap. The content of the user editable data keys is not part of the hash, only their names.
+func GetBaseConfigHash(baseConfigMapTemplate core.ConfigMap) string {
+
+	keys := make([]string, 0, len(baseConfigMapTemplate.Data))
+	for key := range baseConfigMapTemplate.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key + "\n"))
+		if !userEditableBaseConfigKeys[key] {
+			hash.Write([]byte(baseConfigMapTemplate.Data[key] + "\n"))
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// UpdateBaseConfigMap updates the data of a deployed base ConfigMap from its template. The data keys managed by
+// Kubegres are overwritten, the user editable ones are only added if missing and any other data key is kept.
+func UpdateBaseConfigMap(deployedBaseConfigMap *core.ConfigMap, baseConfigMapTemplate core.ConfigMap) {
+
+	if deployedBaseConfigMap.Data == nil {
+		deployedBaseConfigMap.Data = make(map[string]string)
+	}
+
+	for key, value := range baseConfigMapTemplate.Data {
+		if _, exists := deployedBaseConfigMap.Data[key]; exists && userEditableBaseConfigKeys[key] {
+			continue
+		}
+		deployedBaseConfigMap.Data[key] = value
+	}
+
+	if deployedBaseConfigMap.Annotations == nil {
+		deployedBaseConfigMap.Annotations = make(map[string]string)
+	}
+	deployedBaseConfigMap.Annotations[BaseConfigHashAnnotationKey] = GetBaseConfigHash(baseConfigMapTemplate)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/states"
+	"testing"
+)
+
+func TestBaseConfigHashIgnoresContentOfUserEditableKeys(t *testing.T) {
+	baseConfigMap := createBaseConfigMapWithData(map[string]string{
+		states.ConfigMapDataKeyPostgresConf:     "max_connections = 100",
+		states.ConfigMapDataKeyArchiveWalScript: "#!/bin/bash",
+	})
+	hash := GetBaseConfigHash(baseConfigMap)
+
+	baseConfigMap.Data[states.ConfigMapDataKeyPostgresConf] = "max_connections = 200"
+	if GetBaseConfigHash(baseConfigMap) != hash {
+		t.Error("Expected the hash to ignore the content of 'postgres.conf'")
+	}
+
+	baseConfigMap.Data[states.ConfigMapDataKeyArchiveWalScript] = "#!/bin/bash\nset -e"
+	if GetBaseConfigHash(baseConfigMap) == hash {
+		t.Error("Expected the hash to change when a script managed by Kubegres changes")
+	}
+}
+
+func TestUpdateBaseConfigMapKeepsUserChangesAndReplacesScripts(t *testing.T) {
+	deployedBaseConfigMap := createBaseConfigMapWithData(map[string]string{
+		states.ConfigMapDataKeyPostgresConf: "max_connections = 200",
+		states.ConfigMapDataKeyBackUpScript: "outdated",
+		"my_script.sh":                      "kept",
+	})
+	baseConfigMapTemplate := createBaseConfigMapWithData(map[string]string{
+		states.ConfigMapDataKeyPostgresConf:     "max_connections = 100",
+		states.ConfigMapDataKeyPgHbaConf:        "host all all all md5",
+		states.ConfigMapDataKeyBackUpScript:     "new",
+		states.ConfigMapDataKeyArchiveWalScript: "archive",
+	})
+
+	UpdateBaseConfigMap(&deployedBaseConfigMap, baseConfigMapTemplate)
+
+	expectedData := map[string]string{
+		states.ConfigMapDataKeyPostgresConf:     "max_connections = 200",
+		states.ConfigMapDataKeyPgHbaConf:        "host all all all md5",
+		states.ConfigMapDataKeyBackUpScript:     "new",
+		states.ConfigMapDataKeyArchiveWalScript: "archive",
+		"my_script.sh":                          "kept",
+	}
+	for key, expectedValue := range expectedData {
+		if deployedBaseConfigMap.Data[key] != expectedValue {
+			t.Errorf("Expected the data key '%s' to be '%s', got: '%s'", key, expectedValue, deployedBaseConfigMap.Data[key])
+		}
+	}
+
+	if deployedBaseConfigMap.Annotations[BaseConfigHashAnnotationKey] != GetBaseConfigHash(baseConfigMapTemplate) {
+		t.Error("Expected the annotation of the updated base ConfigMap to be the hash of its template")
+	}
+}
+
+func TestUpdatedBaseConfigMapFromPreviousVersionIsUpToDate(t *testing.T) {
+	resourcesCreator := ResourcesCreatorFromTemplate{templateFromFiles: ResourceTemplateLoader{}}
+	deployedBaseConfigMap := createBaseConfigMapWithData(map[string]string{
+		states.ConfigMapDataKeyPostgresConf: "max_connections = 200",
+	})
+
+	updatedBaseConfigMap, err := resourcesCreator.CreateUpdatedBaseConfigMap(deployedBaseConfigMap)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expectedHash, err := resourcesCreator.GetBaseConfigHash()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if updatedBaseConfigMap.Annotations[BaseConfigHashAnnotationKey] != expectedHash {
+		t.Error("Expected the updated base ConfigMap to be up-to-date")
+	}
+	if updatedBaseConfigMap.Data[states.ConfigMapDataKeyArchiveWalScript] == "" {
+		t.Error("Expected the missing script to archive WAL segments to be added")
+	}
+	if deployedBaseConfigMap.Data[states.ConfigMapDataKeyArchiveWalScript] != "" {
+		t.Error("Expected the deployed base ConfigMap to be left unchanged")
+	}
+}
+
+func createBaseConfigMapWithData(data map[string]string) core.ConfigMap {
+	baseConfigMap := core.ConfigMap{}
+	baseConfigMap.Data = data
+	return baseConfigMap
+}
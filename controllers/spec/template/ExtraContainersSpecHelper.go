@@ -0,0 +1,151 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+const (
+	// ExtraContainersConfigAnnotationKey is set in the Pod template of a StatefulSet with the names and a hash of
+	// the containers of the fields 'sidecars' and 'initContainers'. It allows detecting when those fields have changed
+	// and knowing which containers to replace.
+	ExtraContainersConfigAnnotationKey = "kubegres.reactive-tech.io/extra-containers-config"
+	sidecarsConfigPrefix               = "sidecars="
+	initContainersConfigPrefix         = "initContainers="
+)
+
+// ExtraContainersSpecHelper adds the containers of the fields 'sidecars' and 'initContainers' to the Pods of a
+// StatefulSet. They are appended after the containers set by Kubegres, so that the PostgreSql container stays first.
+type ExtraContainersSpecHelper struct {
+	kubegresContext ctx.KubegresContext
+}
+
+func CreateExtraContainersSpecHelper(kubegresContext ctx.KubegresContext) ExtraContainersSpecHelper {
+	return ExtraContainersSpecHelper{kubegresContext: kubegresContext}
+}
+
+func (r *ExtraContainersSpecHelper) ConfigureStatefulSet(statefulSet *v1.StatefulSet) (hasStatefulSetChanged bool) {
+
+	currentConfig := statefulSet.Spec.Template.Annotations[ExtraContainersConfigAnnotationKey]
+	expectedConfig := r.GetExpectedConfig()
+	if currentConfig == expectedConfig {
+		return false
+	}
+
+	r.removeExtraContainers(statefulSet, currentConfig)
+
+	if expectedConfig != "" {
+		postgresSpec := r.kubegresContext.Kubegres.Spec
+		podSpec := &statefulSet.Spec.Template.Spec
+		podSpec.Containers = append(podSpec.Containers, postgresSpec.Sidecars...)
+		podSpec.InitContainers = append(podSpec.InitContainers, postgresSpec.InitContainers...)
+
+		if statefulSet.Spec.Template.Annotations == nil {
+			statefulSet.Spec.Template.Annotations = make(map[string]string)
+		}
+		statefulSet.Spec.Template.Annotations[ExtraContainersConfigAnnotationKey] = expectedConfig
+	}
+
+	return true
+}
+
+// GetExpectedConfig returns the value of the annotation 'ExtraContainersConfigAnnotationKey' for the fields
+// 'sidecars' and 'initContainers'. Since Kubernetes sets default values in the containers of a StatefulSet, they are
+// compared with a hash of those fields rather than with the deployed containers.
+func (r *ExtraContainersSpecHelper) GetExpectedConfig() string {
+
+	postgresSpec := r.kubegresContext.Kubegres.Spec
+	if len(postgresSpec.Sidecars) == 0 && len(postgresSpec.InitContainers) == 0 {
+		return ""
+	}
+
+	containersJson, _ := json.Marshal([][]core.Container{postgresSpec.Sidecars, postgresSpec.InitContainers})
+	hash := sha256.Sum256(containersJson)
+
+	return sidecarsConfigPrefix + strings.Join(r.getContainerNames(postgresSpec.Sidecars), ",") +
+		" " + initContainersConfigPrefix + strings.Join(r.getContainerNames(postgresSpec.InitContainers), ",") +
+		" hash=" + hex.EncodeToString(hash[:])[:16]
+}
+
+// GetReplicaInitContainer returns the init container copying the database of the Primary in the Pod of a Replica,
+// or nil if the Pod does not have it.
+func GetReplicaInitContainer(podSpec *core.PodSpec) *core.Container {
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name == ctx.ReplicaInitContainerName {
+			return &podSpec.InitContainers[i]
+		}
+	}
+	return nil
+}
+
+// removeExtraContainers removes the containers whose names are listed in the given configuration.
+func (r *ExtraContainersSpecHelper) removeExtraContainers(statefulSet *v1.StatefulSet, currentConfig string) {
+
+	delete(statefulSet.Spec.Template.Annotations, ExtraContainersConfigAnnotationKey)
+
+	sidecarNames := make(map[string]bool)
+	initContainerNames := make(map[string]bool)
+	for _, field := range strings.Fields(currentConfig) {
+		if strings.HasPrefix(field, sidecarsConfigPrefix) {
+			r.addNames(sidecarNames, strings.TrimPrefix(field, sidecarsConfigPrefix))
+		} else if strings.HasPrefix(field, initContainersConfigPrefix) {
+			r.addNames(initContainerNames, strings.TrimPrefix(field, initContainersConfigPrefix))
+		}
+	}
+
+	podSpec := &statefulSet.Spec.Template.Spec
+	podSpec.Containers = r.filterContainers(podSpec.Containers, sidecarNames)
+	podSpec.InitContainers = r.filterContainers(podSpec.InitContainers, initContainerNames)
+}
+
+func (r *ExtraContainersSpecHelper) addNames(names map[string]bool, commaSeparatedNames string) {
+	for _, name := range strings.Split(commaSeparatedNames, ",") {
+		if name != "" {
+			names[name] = true
+		}
+	}
+}
+
+func (r *ExtraContainersSpecHelper) filterContainers(containers []core.Container, namesToRemove map[string]bool) []core.Container {
+	var filteredContainers []core.Container
+	for _, container := range containers {
+		if !namesToRemove[container.Name] {
+			filteredContainers = append(filteredContainers, container)
+		}
+	}
+	return filteredContainers
+}
+
+func (r *ExtraContainersSpecHelper) getContainerNames(containers []core.Container) []string {
+	var names []string
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names
+}
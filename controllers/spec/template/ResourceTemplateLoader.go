@@ -56,6 +56,10 @@ func (r *ResourceTemplateLoader) LoadReplicaService() (serviceTemplate core.Serv
 	return r.loadService(yaml.ReplicaServiceTemplate)
 }
 
+func (r *ResourceTemplateLoader) LoadReplicaReadyService() (serviceTemplate core.Service, err error) {
+	return r.loadService(yaml.ReplicaReadyServiceTemplate)
+}
+
 func (r *ResourceTemplateLoader) LoadPrimaryStatefulSet() (statefulSetTemplate apps.StatefulSet, err error) {
 	return r.loadStatefulSet(yaml.PrimaryStatefulSetTemplate)
 }
@@ -64,6 +68,21 @@ func (r *ResourceTemplateLoader) LoadReplicaStatefulSet() (statefulSetTemplate a
 	return r.loadStatefulSet(yaml.ReplicaStatefulSetTemplate)
 }
 
+func (r *ResourceTemplateLoader) LoadPoolerService() (serviceTemplate core.Service, err error) {
+	return r.loadService(yaml.PoolerServiceTemplate)
+}
+
+func (r *ResourceTemplateLoader) LoadPoolerDeployment() (deployment apps.Deployment, err error) {
+	obj, err := r.decodeYaml(yaml.PoolerDeploymentTemplate)
+
+	if err != nil {
+		r.log.Error(err, "Unable to load Kubegres Pooler Deployment. Given error:")
+		return apps.Deployment{}, err
+	}
+
+	return *obj.(*apps.Deployment), nil
+}
+
 func (r *ResourceTemplateLoader) LoadBackUpCronJob() (cronJob batch.CronJob, err error) {
 	obj, err := r.decodeYaml(yaml.BackUpCronJobTemplate)
 
@@ -75,6 +94,28 @@ func (r *ResourceTemplateLoader) LoadBackUpCronJob() (cronJob batch.CronJob, err
 	return *obj.(*batch.CronJob), nil
 }
 
+func (r *ResourceTemplateLoader) LoadMajorVersionUpgradeJob() (job batch.Job, err error) {
+	obj, err := r.decodeYaml(yaml.MajorVersionUpgradeJobTemplate)
+
+	if err != nil {
+		r.log.Error(err, "Unable to load Kubegres Major Version Upgrade Job. Given error:")
+		return batch.Job{}, err
+	}
+
+	return *obj.(*batch.Job), nil
+}
+
+func (r *ResourceTemplateLoader) LoadFormerPrimaryCheckerPod() (pod core.Pod, err error) {
+	obj, err := r.decodeYaml(yaml.FormerPrimaryCheckerPodTemplate)
+
+	if err != nil {
+		r.log.Error(err, "Unable to load Kubegres Switchover Checker Pod. Given error:")
+		return core.Pod{}, err
+	}
+
+	return *obj.(*core.Pod), nil
+}
+
 func (r *ResourceTemplateLoader) loadService(yamlContents string) (serviceTemplate core.Service, err error) {
 
 	obj, err := r.decodeYaml(yamlContents)
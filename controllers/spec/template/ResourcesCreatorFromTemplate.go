@@ -21,35 +21,53 @@ limitations under the License.
 package template
 
 import (
+	"encoding/json"
+	"sort"
 	"strconv"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	postgresV1 "reactive-tech.io/kubegres/api/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
 )
 
 type ResourcesCreatorFromTemplate struct {
-	kubegresContext        ctx.KubegresContext
-	customConfigSpecHelper CustomConfigSpecHelper
-	templateFromFiles      ResourceTemplateLoader
+	kubegresContext           ctx.KubegresContext
+	customConfigSpecHelper    CustomConfigSpecHelper
+	walArchiveSpecHelper      WalArchiveSpecHelper
+	extraContainersSpecHelper ExtraContainersSpecHelper
+	templateFromFiles         ResourceTemplateLoader
 }
 
 const (
 	KubegresInternalAnnotationKey = "kubectl.kubernetes.io/last-applied-configuration"
+
+	PoolerConfigMapKeyPrimaryIni = "pgbouncer.ini"
+	PoolerConfigMapKeyReplicaIni = "pgbouncer-replica.ini"
 )
 
+// BackUpDestinationAnnotationKey is set in the backup CronJob with a description of the location where the backups
+// are stored. It allows detecting when the fields 'backup.pvcName' or 'backup.destination' have changed.
+const BackUpDestinationAnnotationKey = "kubegres.reactive-tech.io/backup-destination"
+
 func CreateResourcesCreatorFromTemplate(kubegresContext ctx.KubegresContext,
 	customConfigSpecHelper CustomConfigSpecHelper,
+	walArchiveSpecHelper WalArchiveSpecHelper,
+	extraContainersSpecHelper ExtraContainersSpecHelper,
 	resourceTemplateLoader ResourceTemplateLoader) ResourcesCreatorFromTemplate {
 
 	return ResourcesCreatorFromTemplate{
-		kubegresContext:        kubegresContext,
-		customConfigSpecHelper: customConfigSpecHelper,
-		templateFromFiles:      resourceTemplateLoader,
+		kubegresContext:           kubegresContext,
+		customConfigSpecHelper:    customConfigSpecHelper,
+		walArchiveSpecHelper:      walArchiveSpecHelper,
+		extraContainersSpecHelper: extraContainersSpecHelper,
+		templateFromFiles:         resourceTemplateLoader,
 	}
 }
 
@@ -62,10 +80,36 @@ func (r *ResourcesCreatorFromTemplate) CreateBaseConfigMap() (core.ConfigMap, er
 
 	baseConfigMap.Namespace = r.kubegresContext.Kubegres.Namespace
 	//baseConfigMap.OwnerReferences = r.getOwnerReference()
+	baseConfigMap.Annotations = map[string]string{BaseConfigHashAnnotationKey: GetBaseConfigHash(baseConfigMap)}
 
 	return baseConfigMap, nil
 }
 
+// CreateUpdatedBaseConfigMap returns a copy of the deployed base ConfigMap updated from its template.
+func (r *ResourcesCreatorFromTemplate) CreateUpdatedBaseConfigMap(deployedBaseConfigMap core.ConfigMap) (core.ConfigMap, error) {
+
+	baseConfigMap, err := r.templateFromFiles.LoadBaseConfigMap()
+	if err != nil {
+		return core.ConfigMap{}, err
+	}
+
+	updatedBaseConfigMap := *deployedBaseConfigMap.DeepCopy()
+	UpdateBaseConfigMap(&updatedBaseConfigMap, baseConfigMap)
+
+	return updatedBaseConfigMap, nil
+}
+
+// GetBaseConfigHash returns the expected value of the annotation 'BaseConfigHashAnnotationKey'.
+func (r *ResourcesCreatorFromTemplate) GetBaseConfigHash() (string, error) {
+
+	baseConfigMap, err := r.templateFromFiles.LoadBaseConfigMap()
+	if err != nil {
+		return "", err
+	}
+
+	return GetBaseConfigHash(baseConfigMap), nil
+}
+
 func (r *ResourcesCreatorFromTemplate) CreatePrimaryService() (core.Service, error) {
 
 	primaryService, err := r.templateFromFiles.LoadPrimaryService()
@@ -73,7 +117,7 @@ func (r *ResourcesCreatorFromTemplate) CreatePrimaryService() (core.Service, err
 		return core.Service{}, err
 	}
 
-	r.initService(&primaryService)
+	r.initService(&primaryService, r.kubegresContext.GetServiceSpec(true))
 
 	primaryService.Name = r.kubegresContext.GetServiceResourceName(true)
 
@@ -87,13 +131,28 @@ func (r *ResourcesCreatorFromTemplate) CreateReplicaService() (core.Service, err
 		return core.Service{}, err
 	}
 
-	r.initService(&replicaService)
+	r.initService(&replicaService, r.kubegresContext.GetServiceSpec(false))
 
 	replicaService.Name = r.kubegresContext.GetServiceResourceName(false)
 
 	return replicaService, nil
 }
 
+// The Service selects the Pods labelled by Kubegres with the label 'kubegres.reactive-tech.io/replica-ready'
+func (r *ResourcesCreatorFromTemplate) CreateReplicaReadyService() (core.Service, error) {
+
+	replicaReadyService, err := r.templateFromFiles.LoadReplicaReadyService()
+	if err != nil {
+		return core.Service{}, err
+	}
+
+	r.initService(&replicaReadyService, postgresV1.KubegresService{})
+
+	replicaReadyService.Name = r.kubegresContext.GetReplicaReadyServiceResourceName()
+
+	return replicaReadyService, nil
+}
+
 func (r *ResourcesCreatorFromTemplate) CreatePrimaryStatefulSet(statefulSetInstanceIndex int32) (apps.StatefulSet, error) {
 
 	statefulSetTemplate, err := r.templateFromFiles.LoadPrimaryStatefulSet()
@@ -104,6 +163,7 @@ func (r *ResourcesCreatorFromTemplate) CreatePrimaryStatefulSet(statefulSetInsta
 	primaryServiceName := r.kubegresContext.GetServiceResourceName(true)
 	r.initStatefulSet(primaryServiceName, &statefulSetTemplate, statefulSetInstanceIndex)
 	r.customConfigSpecHelper.ConfigureStatefulSet(&statefulSetTemplate)
+	r.walArchiveSpecHelper.ConfigureStatefulSet(&statefulSetTemplate)
 	return statefulSetTemplate, nil
 }
 
@@ -119,8 +179,9 @@ func (r *ResourcesCreatorFromTemplate) CreateReplicaStatefulSet(statefulSetInsta
 
 	r.initStatefulSet(replicaServiceName, &statefulSetTemplate, statefulSetInstanceIndex)
 	r.customConfigSpecHelper.ConfigureStatefulSet(&statefulSetTemplate)
+	r.walArchiveSpecHelper.ConfigureStatefulSet(&statefulSetTemplate)
 
-	initContainer := &statefulSetTemplate.Spec.Template.Spec.InitContainers[0]
+	initContainer := GetReplicaInitContainer(&statefulSetTemplate.Spec.Template.Spec)
 	postgresSpec := r.kubegresContext.Kubegres.Spec
 	initContainer.Image = postgresSpec.Image
 	initContainer.Env[0].Value = primaryServiceName
@@ -131,6 +192,32 @@ func (r *ResourcesCreatorFromTemplate) CreateReplicaStatefulSet(statefulSetInsta
 	return statefulSetTemplate, nil
 }
 
+// CreateRewindingReplicaStatefulSet creates a Replica StatefulSet whose init container rewinds the data of a reused
+// PVC with pg_rewind against the current Primary, so that a former Primary or the data of a removed Replica rejoins
+// the cluster as a Replica. The data is only rewound by the first Pod of the StatefulSet, which is identified by
+// the environment variable 'KUBEGRES_REWIND_ID'.
+func (r *ResourcesCreatorFromTemplate) CreateRewindingReplicaStatefulSet(statefulSetInstanceIndex int32) (apps.StatefulSet, error) {
+
+	statefulSetTemplate, err := r.CreateReplicaStatefulSet(statefulSetInstanceIndex)
+	if err != nil {
+		return apps.StatefulSet{}, err
+	}
+
+	volumeMount := core.VolumeMount{
+		Name:      "base-config",
+		MountPath: "/tmp/" + states.ConfigMapDataKeyRewindFailedPrimaryScript,
+		SubPath:   states.ConfigMapDataKeyRewindFailedPrimaryScript,
+	}
+
+	initContainer := GetReplicaInitContainer(&statefulSetTemplate.Spec.Template.Spec)
+	initContainer.VolumeMounts = append(initContainer.VolumeMounts, volumeMount)
+	initContainer.Env = append(initContainer.Env, r.getEnvVar(ctx.EnvVarNameOfPostgresSuperUserPsw))
+	initContainer.Env = append(initContainer.Env, core.EnvVar{Name: "KUBEGRES_REWIND_ID", Value: strconv.FormatInt(time.Now().UnixNano(), 10)})
+	initContainer.Command = []string{"sh", "-c", "/tmp/" + states.ConfigMapDataKeyRewindFailedPrimaryScript}
+
+	return statefulSetTemplate, nil
+}
+
 func (r *ResourcesCreatorFromTemplate) CreateBackUpCronJob(configMapNameForBackUp string) (batch.CronJob, error) {
 
 	backUpCronJob, err := r.templateFromFiles.LoadBackUpCronJob()
@@ -152,15 +239,21 @@ func (r *ResourcesCreatorFromTemplate) CreateBackUpCronJob(configMapNameForBackU
 
 	backUpCronJobSpec := &backUpCronJob.Spec.JobTemplate.Spec.Template.Spec
 
+	if backUpCronJob.Annotations == nil {
+		backUpCronJob.Annotations = make(map[string]string)
+	}
+	backUpCronJob.Annotations[BackUpDestinationAnnotationKey] = r.GetBackUpDestinationConfig()
+	backUpCronJob.Annotations[BackUpRetentionAnnotationKey] = r.GetBackUpRetentionConfig()
+
 	backUpCronJobSpec.Volumes[0].PersistentVolumeClaim.ClaimName = backupSpec.PvcName
 	backUpCronJobSpec.Volumes[1].ConfigMap.Name = configMapNameForBackUp
 
 	backUpCronJobContainer := &backUpCronJobSpec.Containers[0]
 	backUpCronJobContainer.Image = postgres.Spec.Image
-	backUpCronJobContainer.VolumeMounts[0].MountPath = backupSpec.VolumeMount
+	backUpCronJobContainer.VolumeMounts[0].MountPath = r.kubegresContext.GetBackUpFolder()
 	backUpCronJobContainer.Env[0].ValueFrom = r.getEnvVar(ctx.EnvVarNameOfPostgresSuperUserPsw).ValueFrom
 	backUpCronJobContainer.Env[1].Value = postgres.Name
-	backUpCronJobContainer.Env[2].Value = backupSpec.VolumeMount
+	backUpCronJobContainer.Env[2].Value = r.kubegresContext.GetBackUpFolder()
 	backUpCronJobContainer.Env = append(backUpCronJobContainer.Env, r.kubegresContext.Kubegres.Spec.Env...)
 
 	backSourceDbHostName := r.kubegresContext.GetServiceResourceName(false)
@@ -169,10 +262,273 @@ func (r *ResourcesCreatorFromTemplate) CreateBackUpCronJob(configMapNameForBackU
 	}
 	backUpCronJobContainer.Env[3].Value = backSourceDbHostName
 
+	if r.kubegresContext.IsPhysicalBackUp() {
+		backUpScript := "/tmp/" + states.ConfigMapDataKeyBaseBackUpScript
+		backUpCronJobContainer.Args[len(backUpCronJobContainer.Args)-1] = backUpScript
+		backUpCronJobContainer.VolumeMounts[1].MountPath = backUpScript
+		backUpCronJobContainer.VolumeMounts[1].SubPath = states.ConfigMapDataKeyBaseBackUpScript
+	}
+
+	if r.kubegresContext.IsBackUpInS3() {
+		r.configureBackUpUploadToS3(backUpCronJobSpec)
+	}
+
+	if r.kubegresContext.IsBackUpRetentionEnabled() {
+		r.configureBackUpRetention(backUpCronJobSpec)
+	}
+
 	return backUpCronJob, nil
 }
 
-func (r *ResourcesCreatorFromTemplate) initService(service *core.Service) {
+// GetBackUpDestinationConfig returns a description of the location where the backup CronJob stores the backups.
+func (r *ResourcesCreatorFromTemplate) GetBackUpDestinationConfig() string {
+	backUpSpec := r.kubegresContext.Kubegres.Spec.Backup
+	if r.kubegresContext.IsBackUpInS3() {
+		return describeS3(*backUpSpec.Destination.S3)
+	}
+	return "pvc name=" + backUpSpec.PvcName
+}
+
+// configureBackUpUploadToS3 changes the Pod of the backup CronJob so that the backup is taken in a temporary folder
+// by an init container, and then uploaded in the bucket 'backup.destination.s3' by the container of the Pod.
+func (r *ResourcesCreatorFromTemplate) configureBackUpUploadToS3(backUpCronJobSpec *core.PodSpec) {
+
+	s3 := *r.kubegresContext.Kubegres.Spec.Backup.Destination.S3
+	backUpVolume := &backUpCronJobSpec.Volumes[0]
+	backUpVolume.VolumeSource = core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}}
+
+	uploaderContainer := core.Container{
+		Name:            ctx.BackUpUploaderContainerName,
+		ImagePullPolicy: core.PullIfNotPresent,
+		Command:         []string{"bash", "-c", backUpUploadScript},
+		Env: []core.EnvVar{
+			{Name: "BACKUP_DESTINATION_FOLDER", Value: r.kubegresContext.GetBackUpFolder()},
+			{Name: "BACKUP_S3_URL", Value: ctx.GetS3Url(s3)},
+		},
+		VolumeMounts: []core.VolumeMount{
+			{Name: backUpVolume.Name, MountPath: r.kubegresContext.GetBackUpFolder()},
+		},
+	}
+	configureS3Container(&uploaderContainer, s3)
+	addS3CaVolume(backUpCronJobSpec, s3)
+
+	backUpCronJobSpec.InitContainers = []core.Container{backUpCronJobSpec.Containers[0]}
+	backUpCronJobSpec.Containers = []core.Container{uploaderContainer}
+}
+
+// CreateMajorVersionUpgradeJob creates a Job which upgrades with pg_upgrade the data in the PVC of the given Primary
+// StatefulSet, from the major version of PostgreSql of the former image to the major version of the image in the spec.
+func (r *ResourcesCreatorFromTemplate) CreateMajorVersionUpgradeJob(primaryStatefulSetName string,
+	fromImage string,
+	fromMajorVersion int32,
+	toMajorVersion int32) (batch.Job, error) {
+
+	upgradeJob, err := r.templateFromFiles.LoadMajorVersionUpgradeJob()
+	if err != nil {
+		return batch.Job{}, err
+	}
+
+	postgres := r.kubegresContext.Kubegres
+	postgresSpec := postgres.Spec
+	fromMajorVersionStr := strconv.Itoa(int(fromMajorVersion))
+
+	upgradeJob.Name = r.kubegresContext.GetMajorVersionUpgradeJobName()
+	upgradeJob.Namespace = postgres.Namespace
+	upgradeJob.Labels["app"] = postgres.Name + ctx.MajorVersionUpgradeJobNameSuffix
+	upgradeJob.OwnerReferences = r.getOwnerReference()
+	upgradeJob.Spec.Template.Annotations = r.getCustomAnnotations()
+
+	upgradeJobSpec := &upgradeJob.Spec.Template.Spec
+	upgradeJobSpec.Volumes[0].PersistentVolumeClaim.ClaimName = r.kubegresContext.GetDatabasePvcResourceName(primaryStatefulSetName)
+
+	if postgresSpec.ImagePullSecrets != nil {
+		upgradeJobSpec.ImagePullSecrets = append(upgradeJobSpec.ImagePullSecrets, postgresSpec.ImagePullSecrets...)
+	}
+	if postgresSpec.Scheduler.Affinity != nil {
+		upgradeJobSpec.Affinity = postgresSpec.Scheduler.Affinity
+	}
+	if len(postgresSpec.Scheduler.Tolerations) > 0 {
+		upgradeJobSpec.Tolerations = postgresSpec.Scheduler.Tolerations
+	}
+	if postgresSpec.SecurityContext != nil {
+		upgradeJobSpec.SecurityContext = postgresSpec.SecurityContext
+	}
+
+	initContainer := &upgradeJobSpec.InitContainers[0]
+	initContainer.Image = fromImage
+	initContainer.Env[0].Value = fromMajorVersionStr
+
+	container := &upgradeJobSpec.Containers[0]
+	container.Image = postgresSpec.Image
+	container.Env[0].Value = postgresSpec.Database.VolumeMount + "/" + ctx.DefaultDatabaseFolder
+	container.Env[1].Value = fromMajorVersionStr
+	container.Env[2].Value = strconv.Itoa(int(toMajorVersion))
+	container.Env = append(container.Env, postgresSpec.Env...)
+	container.VolumeMounts[0].MountPath = postgresSpec.Database.VolumeMount
+	container.VolumeMounts[1].MountPath = "/usr/lib/postgresql/" + fromMajorVersionStr
+	container.VolumeMounts[2].MountPath = "/usr/share/postgresql/" + fromMajorVersionStr
+
+	return upgradeJob, nil
+}
+
+// CreateFormerPrimaryCheckerPod creates a Pod reading the state and the last checkpoint location of a Primary
+// stopped during a Switchover from its PVC.
+func (r *ResourcesCreatorFromTemplate) CreateFormerPrimaryCheckerPod(formerPrimaryStatefulSetName string) (core.Pod, error) {
+
+	checkerPod, err := r.templateFromFiles.LoadFormerPrimaryCheckerPod()
+	if err != nil {
+		return core.Pod{}, err
+	}
+
+	postgres := r.kubegresContext.Kubegres
+	postgresSpec := postgres.Spec
+
+	checkerPod.Name = r.kubegresContext.GetFormerPrimaryCheckerPodName()
+	checkerPod.Namespace = postgres.Namespace
+	checkerPod.Labels["app"] = postgres.Name + ctx.FormerPrimaryCheckerPodNameSuffix
+	checkerPod.OwnerReferences = r.getOwnerReference()
+
+	checkerPodSpec := &checkerPod.Spec
+	checkerPodSpec.Volumes[0].PersistentVolumeClaim.ClaimName = r.kubegresContext.GetDatabasePvcResourceName(formerPrimaryStatefulSetName)
+
+	if postgresSpec.ImagePullSecrets != nil {
+		checkerPodSpec.ImagePullSecrets = append(checkerPodSpec.ImagePullSecrets, postgresSpec.ImagePullSecrets...)
+	}
+	if postgresSpec.Scheduler.Affinity != nil {
+		checkerPodSpec.Affinity = postgresSpec.Scheduler.Affinity
+	}
+	if len(postgresSpec.Scheduler.Tolerations) > 0 {
+		checkerPodSpec.Tolerations = postgresSpec.Scheduler.Tolerations
+	}
+	if postgresSpec.SecurityContext != nil {
+		checkerPodSpec.SecurityContext = postgresSpec.SecurityContext
+	}
+
+	container := &checkerPodSpec.Containers[0]
+	container.Image = postgresSpec.Image
+	container.Env[0].Value = postgresSpec.Database.VolumeMount + "/" + ctx.DefaultDatabaseFolder
+	container.VolumeMounts[0].MountPath = postgresSpec.Database.VolumeMount
+
+	return checkerPod, nil
+}
+
+// CreatePoolerConfigMap creates a ConfigMap containing the configuration files of PgBouncer. It does not contain any
+// password: the one of the auth user is given to the Pods of PgBouncer with the env-var "POSTGRES_POOLER_PASSWORD".
+func (r *ResourcesCreatorFromTemplate) CreatePoolerConfigMap() core.ConfigMap {
+
+	postgres := r.kubegresContext.Kubegres
+
+	return core.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            r.kubegresContext.GetPoolerConfigMapName(),
+			Namespace:       postgres.Namespace,
+			Labels:          map[string]string{"app": r.kubegresContext.GetPoolerResourceName(true)},
+			OwnerReferences: r.getOwnerReference(),
+		},
+		Data: map[string]string{
+			PoolerConfigMapKeyPrimaryIni: r.createPoolerIni(r.kubegresContext.GetServiceResourceName(true)),
+			PoolerConfigMapKeyReplicaIni: r.createPoolerIni(r.kubegresContext.GetServiceResourceName(false)),
+		},
+	}
+}
+
+// CreatePoolerDeployment creates a PgBouncer Deployment in front of the Primary or of the Replicas. The given
+// annotations are set in its Pod template, so that its Pods are re-created when one of them changes.
+func (r *ResourcesCreatorFromTemplate) CreatePoolerDeployment(isPrimary bool, podAnnotations map[string]string) (apps.Deployment, error) {
+
+	deployment, err := r.templateFromFiles.LoadPoolerDeployment()
+	if err != nil {
+		return apps.Deployment{}, err
+	}
+
+	postgres := r.kubegresContext.Kubegres
+	poolerSpec := postgres.Spec.Pooler
+	resourceName := r.kubegresContext.GetPoolerResourceName(isPrimary)
+
+	deployment.Name = resourceName
+	deployment.Namespace = postgres.Namespace
+	deployment.Annotations = r.getCustomAnnotations()
+	deployment.Labels["app"] = resourceName
+	deployment.OwnerReferences = r.getOwnerReference()
+
+	deployment.Spec.Replicas = &poolerSpec.Replicas
+	deployment.Spec.Selector.MatchLabels["app"] = resourceName
+	deployment.Spec.Template.Labels["app"] = resourceName
+	deployment.Spec.Template.Annotations = r.getCustomAnnotations()
+	for key, value := range podAnnotations {
+		deployment.Spec.Template.Annotations[key] = value
+	}
+
+	deploymentSpec := &deployment.Spec.Template.Spec
+	if postgres.Spec.ImagePullSecrets != nil {
+		deploymentSpec.ImagePullSecrets = append(deploymentSpec.ImagePullSecrets, postgres.Spec.ImagePullSecrets...)
+	}
+
+	deploymentSpec.Volumes[0].ConfigMap.Name = r.kubegresContext.GetPoolerConfigMapName()
+	deploymentSpec.Volumes[0].ConfigMap.Items[0].Key = PoolerConfigMapKeyPrimaryIni
+	if !isPrimary {
+		deploymentSpec.Volumes[0].ConfigMap.Items[0].Key = PoolerConfigMapKeyReplicaIni
+	}
+
+	container := &deploymentSpec.Containers[0]
+	container.Image = poolerSpec.Image
+	container.Env[0] = r.getEnvVar(ctx.EnvVarNameOfPostgresPoolerUserPsw)
+	container.Env[1].Value = ctx.PoolerAuthUserName
+	container.Ports[0].ContainerPort = poolerSpec.Port
+	container.LivenessProbe.TCPSocket.Port = intstr.FromInt(int(poolerSpec.Port))
+	container.ReadinessProbe.TCPSocket.Port = intstr.FromInt(int(poolerSpec.Port))
+	if poolerSpec.Resources.Requests != nil || poolerSpec.Resources.Limits != nil {
+		container.Resources = poolerSpec.Resources
+	}
+
+	return deployment, nil
+}
+
+func (r *ResourcesCreatorFromTemplate) CreatePoolerService(isPrimary bool) (core.Service, error) {
+
+	service, err := r.templateFromFiles.LoadPoolerService()
+	if err != nil {
+		return core.Service{}, err
+	}
+
+	resourceName := r.kubegresContext.GetPoolerResourceName(isPrimary)
+
+	service.Name = resourceName
+	service.Namespace = r.kubegresContext.Kubegres.Namespace
+	service.OwnerReferences = r.getOwnerReference()
+	service.Labels["app"] = resourceName
+	service.Spec.Selector["app"] = resourceName
+	service.Spec.Ports[0].Port = r.kubegresContext.Kubegres.Spec.Pooler.Port
+
+	return service, nil
+}
+
+// With the auth type "md5", PgBouncer authenticates with SCRAM the users whose password is stored as a SCRAM secret.
+// It looks up the passwords with the auth user, which can only execute the SECURITY DEFINER function created by
+// Kubegres in the database "postgres". Superusers cannot connect through PgBouncer.
+func (r *ResourcesCreatorFromTemplate) createPoolerIni(dbHostName string) string {
+
+	postgresSpec := r.kubegresContext.Kubegres.Spec
+	poolerSpec := postgresSpec.Pooler
+
+	return "[databases]\n" +
+		"* = host=" + dbHostName + " port=" + strconv.Itoa(int(postgresSpec.Port)) + "\n" +
+		"\n" +
+		"[pgbouncer]\n" +
+		"listen_addr = 0.0.0.0\n" +
+		"listen_port = " + strconv.Itoa(int(poolerSpec.Port)) + "\n" +
+		"auth_type = md5\n" +
+		"auth_file = /var/run/pgbouncer-kubegres/userlist.txt\n" +
+		"auth_user = " + ctx.PoolerAuthUserName + "\n" +
+		"auth_dbname = postgres\n" +
+		"auth_query = SELECT usename, passwd FROM " + ctx.PoolerAuthLookupFunction + "($1)\n" +
+		"pool_mode = " + poolerSpec.PoolMode + "\n" +
+		"max_client_conn = " + strconv.Itoa(int(poolerSpec.MaxClientConn)) + "\n" +
+		"default_pool_size = " + strconv.Itoa(int(poolerSpec.DefaultPoolSize)) + "\n" +
+		"ignore_startup_parameters = extra_float_digits\n"
+}
+
+func (r *ResourcesCreatorFromTemplate) initService(service *core.Service, serviceSpec postgresV1.KubegresService) {
 
 	resourceName := r.kubegresContext.Kubegres.Name
 	service.Namespace = r.kubegresContext.Kubegres.Namespace
@@ -180,6 +536,59 @@ func (r *ResourcesCreatorFromTemplate) initService(service *core.Service) {
 	service.Labels["app"] = resourceName
 	service.Spec.Selector["app"] = resourceName
 	service.Spec.Ports[0].Port = r.kubegresContext.Kubegres.Spec.Port
+
+	appliedKeys := AppliedServiceKeys{}
+	for labelKey, labelValue := range serviceSpec.Labels {
+		if _, exists := service.Labels[labelKey]; !exists {
+			service.Labels[labelKey] = labelValue
+			appliedKeys.Labels = append(appliedKeys.Labels, labelKey)
+		}
+	}
+
+	service.Annotations = make(map[string]string)
+	for annotationKey, annotationValue := range serviceSpec.Annotations {
+		if annotationKey != ctx.AppliedServiceKeysAnnotationKey {
+			service.Annotations[annotationKey] = annotationValue
+			appliedKeys.Annotations = append(appliedKeys.Annotations, annotationKey)
+		}
+	}
+	service.Annotations[ctx.AppliedServiceKeysAnnotationKey] = appliedKeys.toJson()
+
+	// Without type, the Service stays headless as defined in the template
+	if serviceSpec.Type != "" {
+		service.Spec.Type = serviceSpec.Type
+		service.Spec.ClusterIP = ""
+	}
+
+	service.Spec.LoadBalancerSourceRanges = serviceSpec.LoadBalancerSourceRanges
+	service.Spec.ExternalTrafficPolicy = serviceSpec.ExternalTrafficPolicy
+}
+
+// AppliedServiceKeys are the keys of the labels and of the annotations of 'spec.services' which Kubegres applied to a
+// Service. They are recorded in an annotation of the Service, so that a key removed from 'spec.services' can be
+// removed from the Service without removing the keys added by other controllers or by the users.
+type AppliedServiceKeys struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// GetAppliedServiceKeys returns the keys recorded in the given Service. A Service deployed before they were recorded,
+// or whose annotation cannot be parsed, has no recorded keys.
+func GetAppliedServiceKeys(service core.Service) AppliedServiceKeys {
+	appliedKeys := AppliedServiceKeys{}
+	if value, exists := service.Annotations[ctx.AppliedServiceKeysAnnotationKey]; exists {
+		if err := json.Unmarshal([]byte(value), &appliedKeys); err != nil {
+			return AppliedServiceKeys{}
+		}
+	}
+	return appliedKeys
+}
+
+func (r AppliedServiceKeys) toJson() string {
+	sort.Strings(r.Labels)
+	sort.Strings(r.Annotations)
+	value, _ := json.Marshal(r)
+	return string(value)
 }
 
 func (r *ResourcesCreatorFromTemplate) initStatefulSet(
@@ -206,6 +615,11 @@ func (r *ResourcesCreatorFromTemplate) initStatefulSet(
 	statefulSetTemplate.Spec.Template.Labels["index"] = instanceIndex
 	statefulSetTemplate.Spec.Template.Annotations = r.getCustomAnnotations()
 
+	// The base ConfigMap template is embedded in Kubegres, so loading it cannot fail here once it was deployed
+	if baseConfigHash, err := r.GetBaseConfigHash(); err == nil {
+		statefulSetTemplate.Spec.Template.Annotations[BaseConfigHashAnnotationKey] = baseConfigHash
+	}
+
 	statefulSetTemplateSpec := &statefulSetTemplate.Spec.Template.Spec
 
 	if postgresSpec.ImagePullSecrets != nil {
@@ -264,6 +678,8 @@ func (r *ResourcesCreatorFromTemplate) initStatefulSet(
 	if postgresSpec.Probe.ReadinessProbe != nil {
 		statefulSetTemplate.Spec.Template.Spec.Containers[0].ReadinessProbe = postgresSpec.Probe.ReadinessProbe
 	}
+
+	r.extraContainersSpecHelper.ConfigureStatefulSet(statefulSetTemplate)
 }
 
 // Extract annotations set in Kubegres YAML by
@@ -0,0 +1,230 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const pgDumpAllOutputToTest = `--
+-- PostgreSQL database cluster dump
+--
+
+SET default_transaction_read_only = off;
+
+--
+-- Drop databases (except postgres and template1)
+--
+
+DROP DATABASE app;
+DROP DATABASE "sales data";
+
+--
+-- Drop roles
+--
+
+DROP ROLE app_user;
+DROP ROLE postgres;
+DROP ROLE sales;
+
+--
+-- Roles
+--
+
+CREATE ROLE app_user;
+ALTER ROLE app_user WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN;
+CREATE ROLE postgres;
+ALTER ROLE postgres WITH SUPERUSER INHERIT CREATEROLE CREATEDB LOGIN REPLICATION BYPASSRLS;
+CREATE ROLE sales;
+ALTER ROLE sales WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN;
+
+GRANT app_user TO sales GRANTED BY postgres;
+
+--
+-- Database "app" dump
+--
+
+CREATE DATABASE app WITH TEMPLATE = template0 ENCODING = 'UTF8';
+ALTER DATABASE app OWNER TO app_user;
+\connect app
+CREATE TABLE public.items (id integer);
+GRANT CONNECT ON DATABASE app TO sales;
+
+--
+-- Database "sales data" dump
+--
+
+CREATE DATABASE "sales data" WITH TEMPLATE = template0 ENCODING = 'UTF8';
+\connect "sales data"
+CREATE TABLE public.orders (id integer);
+`
+
+func TestRestoreScriptRestoresAllDatabasesAndRolesWithoutFailingOnExistingRoles(t *testing.T) {
+	restoredSql, exitCode, _ := runRestoreScriptToTest(t, "", "", 0)
+
+	if exitCode != 0 {
+		t.Fatalf("Expected the exit code 0, got %d", exitCode)
+	}
+
+	expectedLines := []string{
+		`DROP DATABASE IF EXISTS app;`,
+		`DROP DATABASE IF EXISTS "sales data";`,
+		`DO $kubegres$ BEGIN DROP ROLE IF EXISTS postgres; EXCEPTION WHEN dependent_objects_still_exist OR object_in_use THEN RAISE NOTICE USING MESSAGE = SQLERRM; END $kubegres$;`,
+		`DO $kubegres$ BEGIN CREATE ROLE app_user; EXCEPTION WHEN duplicate_object THEN RAISE NOTICE USING MESSAGE = SQLERRM; END $kubegres$;`,
+		`ALTER ROLE app_user WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN;`,
+		`GRANT app_user TO sales GRANTED BY postgres;`,
+		`CREATE DATABASE app WITH TEMPLATE = template0 ENCODING = 'UTF8';`,
+		`CREATE TABLE public.items (id integer);`,
+		`\connect "sales data"`,
+		`CREATE TABLE public.orders (id integer);`,
+	}
+	thenSqlContainsLines(t, restoredSql, expectedLines)
+}
+
+func TestRestoreScriptRestoresOnlyTheGivenDatabasesAndRoles(t *testing.T) {
+	restoredSql, exitCode, _ := runRestoreScriptToTest(t, "app:app_copy", "app_user", 0)
+
+	if exitCode != 0 {
+		t.Fatalf("Expected the exit code 0, got %d", exitCode)
+	}
+
+	expectedLines := []string{
+		`DROP DATABASE IF EXISTS "app_copy";`,
+		`DO $kubegres$ BEGIN CREATE ROLE app_user; EXCEPTION WHEN duplicate_object THEN RAISE NOTICE USING MESSAGE = SQLERRM; END $kubegres$;`,
+		`-- Database "app_copy" dump`,
+		`CREATE DATABASE "app_copy" WITH TEMPLATE = template0 ENCODING = 'UTF8';`,
+		`ALTER DATABASE "app_copy" OWNER TO app_user;`,
+		`\connect "app_copy"`,
+		`CREATE TABLE public.items (id integer);`,
+		`GRANT CONNECT ON DATABASE "app_copy" TO sales;`,
+	}
+	thenSqlContainsLines(t, restoredSql, expectedLines)
+
+	for _, unexpected := range []string{"sales data", "public.orders", "ROLE sales", "GRANT app_user TO sales", "DATABASE app "} {
+		if strings.Contains(restoredSql, unexpected) {
+			t.Errorf("Expected the restored SQL to not contain '%s', got:\n%s", unexpected, restoredSql)
+		}
+	}
+}
+
+func TestRestoreScriptFailsWhenDatabaseIsNotInBackup(t *testing.T) {
+	_, exitCode, output := runRestoreScriptToTest(t, "missing", "", 0)
+
+	if exitCode != 3 {
+		t.Errorf("Expected the exit code 3, got %d", exitCode)
+	}
+	if !strings.Contains(output, `The database "missing" is not found in the backup`) {
+		t.Errorf("Expected the missing database to be logged, got:\n%s", output)
+	}
+}
+
+func TestRestoreScriptFailsWhenPsqlFails(t *testing.T) {
+	for _, restoreDatabases := range []string{"", "app"} {
+		_, exitCode, output := runRestoreScriptToTest(t, restoreDatabases, "", 3)
+
+		if exitCode != 1 {
+			t.Errorf("Expected the exit code 1 with RESTORE_DATABASES='%s', got %d", restoreDatabases, exitCode)
+		}
+		if !strings.Contains(output, "Unable to restore the database") || strings.Contains(output, "successfully") {
+			t.Errorf("Expected the failure of psql to be logged with RESTORE_DATABASES='%s', got:\n%s", restoreDatabases, output)
+		}
+	}
+}
+
+// runRestoreScriptToTest runs the script 'restore_database.sh' of the base ConfigMap with a fake psql which writes
+// the SQL it receives in a file and exits with the given exit code. It returns that SQL, the exit code of the script
+// and its output.
+func runRestoreScriptToTest(t *testing.T, restoreDatabases, restoreRoles string, psqlExitCode int) (string, int, string) {
+	for _, command := range []string{"bash", "gzip", "awk"} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("The command '%s' is required to run the restore script", command)
+		}
+	}
+
+	resourceTemplateLoader := ResourceTemplateLoader{}
+	baseConfigMap, err := resourceTemplateLoader.LoadBaseConfigMap()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	folder := t.TempDir()
+	scriptPath := filepath.Join(folder, "restore_database.sh")
+	snapshotPath := filepath.Join(folder, "snapshot.gz")
+	restoredSqlPath := filepath.Join(folder, "restored.sql")
+	fakePsql := "#!/bin/bash\n" +
+		"[[ \" $* \" == *\" -v ON_ERROR_STOP=1 \"* ]] || exit 9\n" +
+		"cat > " + restoredSqlPath + "\n" +
+		"exit " + strconv.Itoa(psqlExitCode) + "\n"
+
+	var snapshot bytes.Buffer
+	gzipWriter := gzip.NewWriter(&snapshot)
+	_, _ = gzipWriter.Write([]byte(pgDumpAllOutputToTest))
+	_ = gzipWriter.Close()
+
+	writeFileToTest(t, scriptPath, baseConfigMap.Data["restore_database.sh"])
+	writeFileToTest(t, filepath.Join(folder, "psql"), fakePsql)
+	writeFileToTest(t, snapshotPath, snapshot.String())
+
+	cmd := exec.Command("bash", scriptPath)
+	cmd.Env = append(os.Environ(),
+		"PATH="+folder+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"RESTOREPOINT_FILEPATH="+snapshotPath,
+		"BACKUP_TARGET_DB_HOST_NAME=postgres",
+		"KUBEGRES_RESOURCE_NAME=postgres",
+		"RESTORE_DATABASES="+restoreDatabases,
+		"RESTORE_ROLES="+restoreRoles)
+	output, err := cmd.CombinedOutput()
+
+	exitCode := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	restoredSql, _ := os.ReadFile(restoredSqlPath)
+	return string(restoredSql), exitCode, string(output)
+}
+
+func writeFileToTest(t *testing.T, path, contents string) {
+	if err := os.WriteFile(path, []byte(contents), 0755); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func thenSqlContainsLines(t *testing.T, sql string, expectedLines []string) {
+	lines := map[string]bool{}
+	for _, line := range strings.Split(sql, "\n") {
+		lines[line] = true
+	}
+	for _, expectedLine := range expectedLines {
+		if !lines[expectedLine] {
+			t.Errorf("Expected the restored SQL to contain the line '%s', got:\n%s", expectedLine, sql)
+		}
+	}
+}
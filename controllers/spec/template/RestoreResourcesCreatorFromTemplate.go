@@ -2,9 +2,12 @@ package template
 
 import (
 	"path"
+	"strconv"
+	"strings"
 
 	batchv1 "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -43,12 +46,24 @@ func (r *RestoreJobResourcesCreatorTemplate) CreateRestoreJob(kubegresSpec kubeg
 	}
 
 	container := &restoreJobTemplate.Spec.Template.Spec.Containers[0]
-	container.VolumeMounts[0].MountPath = restoreSpec.DataSource.File.Mountpath
+	container.VolumeMounts[0].MountPath = r.kubegresRestoreContext.GetSnapshotFolder()
 	container.Env[0].ValueFrom = r.getKubegresEnvVar(ctx.EnvVarNameOfPostgresSuperUserPsw, kubegresSpec).ValueFrom
 	container.Env[1].Value = restoreSpec.ClusterName
-	container.Env[2].Value = path.Join(restoreSpec.DataSource.File.Mountpath, restoreSpec.DataSource.File.Snapshot)
+	container.Env[2].Value = r.kubegresRestoreContext.GetSnapshotFilePath()
+	if r.kubegresRestoreContext.IsInPlaceRestore() {
+		container.Env = append(container.Env, core.EnvVar{Name: "TERMINATE_DB_CONNECTIONS", Value: "true"})
+	}
+	if r.kubegresRestoreContext.IsPartialRestore() {
+		container.Env = append(container.Env,
+			core.EnvVar{Name: "RESTORE_DATABASES", Value: r.kubegresRestoreContext.GetDatabasesToRestore()},
+			core.EnvVar{Name: "RESTORE_ROLES", Value: strings.Join(restoreSpec.Roles, " ")})
+	}
 	container.Env = append(container.Env, r.kubegresRestoreContext.KubegresRestore.Spec.Env...)
 
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		r.addObjectStoreSnapshotDownloader(&restoreJobTemplate.Spec.Template.Spec)
+	}
+
 	if r.kubegresRestoreContext.AreResourcesSpecifiedForRestoreJob() {
 		restoreJobTemplate.Spec.Template.Spec.Containers[0].Resources = restoreSpec.Resources
 	} else {
@@ -58,7 +73,207 @@ func (r *RestoreJobResourcesCreatorTemplate) CreateRestoreJob(kubegresSpec kubeg
 	return restoreJobTemplate, nil
 }
 
+// CreatePhysicalRestoreJob creates a Job which unpacks a base backup into the PVC of the Primary of the Kubegres
+// cluster to create. If the field 'recoveryTarget' is set, it also configures PostgreSql to replay the archived WAL
+// segments up to that target once the Primary starts.
+func (r *RestoreJobResourcesCreatorTemplate) CreatePhysicalRestoreJob(kubegresSpec kubegresv1.KubegresSpec) (batchv1.Job, error) {
+	restoreJobTemplate, err := r.loadPhysicalRestoreJobFromTemplate()
+	if err != nil {
+		return restoreJobTemplate, err
+	}
+
+	restoreSpec := r.kubegresRestoreContext.KubegresRestore.Spec
+	dbVolumeMount := r.getDatabaseVolumeMount(kubegresSpec)
+	walRestoreFolder := dbVolumeMount + "/" + ctx.WalRestoreFolder
+
+	restoreJobTemplate.Name = r.kubegresRestoreContext.GetRestoreJobName()
+	restoreJobTemplate.Namespace = r.kubegresRestoreContext.KubegresRestore.Namespace
+	restoreJobTemplate.OwnerReferences = r.getOwnerReference()
+
+	restoreJobSpec := &restoreJobTemplate.Spec.Template.Spec
+	restoreJobSpec.Volumes[0].PersistentVolumeClaim.ClaimName = restoreSpec.DataSource.File.PvcName
+	restoreJobSpec.Volumes[1].PersistentVolumeClaim.ClaimName = r.kubegresRestoreContext.GetTargetPrimaryDbPvcName()
+
+	if kubegresSpec.ImagePullSecrets != nil {
+		restoreJobSpec.ImagePullSecrets = append(restoreJobSpec.ImagePullSecrets, kubegresSpec.ImagePullSecrets...)
+	}
+
+	walArchiveS3 := r.kubegresRestoreContext.GetWalArchiveS3()
+	walArchiveFolder := ""
+	if !r.kubegresRestoreContext.IsPointInTimeRecovery() {
+		restoreJobSpec.InitContainers = nil
+	} else if walArchiveS3 == nil {
+		walArchiveFolder = r.kubegresRestoreContext.GetWalArchiveFolder()
+		restoreJobSpec.InitContainers = nil
+	} else {
+		initContainer := &restoreJobSpec.InitContainers[0]
+		initContainer.Env[0].Value = walRestoreFolder
+		initContainer.Env[1].Value = ctx.GetS3Url(*walArchiveS3)
+		initContainer.VolumeMounts[0].MountPath = dbVolumeMount
+		configureS3Container(initContainer, *walArchiveS3)
+		addS3CaVolume(restoreJobSpec, *walArchiveS3)
+	}
+
+	container := &restoreJobSpec.Containers[0]
+	container.Image = kubegresSpec.Image
+	container.Env[0].Value = dbVolumeMount + "/" + ctx.DefaultDatabaseFolder
+	container.Env[1].Value = r.kubegresRestoreContext.GetSnapshotFilePath()
+	container.Env[2].Value = walArchiveFolder
+	container.Env[3].Value = walRestoreFolder
+	container.Env[4].Value = r.createRecoveryTargetSettings()
+	container.VolumeMounts[0].MountPath = r.kubegresRestoreContext.GetSnapshotFolder()
+	container.VolumeMounts[1].MountPath = dbVolumeMount
+
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		r.addObjectStoreSnapshotDownloader(restoreJobSpec)
+	}
+
+	if r.kubegresRestoreContext.AreResourcesSpecifiedForRestoreJob() {
+		container.Resources = restoreSpec.Resources
+	}
+
+	return restoreJobTemplate, nil
+}
+
+// CreateTargetPrimaryDbPvc creates the PVC of the Primary of the Kubegres cluster to create, so that a base backup
+// is restored in it before the cluster is deployed. The PVC is claimed by the StatefulSet of the Primary.
+func (r *RestoreJobResourcesCreatorTemplate) CreateTargetPrimaryDbPvc(kubegresSpec kubegresv1.KubegresSpec) (core.PersistentVolumeClaim, error) {
+
+	storageSize, err := resource.ParseQuantity(kubegresSpec.Database.Size)
+	if err != nil {
+		return core.PersistentVolumeClaim{}, err
+	}
+
+	return core.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.kubegresRestoreContext.GetTargetPrimaryDbPvcName(),
+			Namespace: r.kubegresRestoreContext.KubegresRestore.Namespace,
+			Labels: map[string]string{
+				"app":   r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName,
+				"index": "1",
+			},
+		},
+		Spec: core.PersistentVolumeClaimSpec{
+			AccessModes:      []core.PersistentVolumeAccessMode{core.ReadWriteOnce},
+			StorageClassName: kubegresSpec.Database.StorageClassName,
+			Resources: core.ResourceRequirements{
+				Requests: core.ResourceList{core.ResourceStorage: storageSize},
+			},
+		},
+	}, nil
+}
+
+// CreateVerificationJob creates a Job which runs the checks of the field 'verify' against the restored cluster.
+// Each check is passed to the Job with environment variables indexed by its position.
+func (r *RestoreJobResourcesCreatorTemplate) CreateVerificationJob(kubegresSpec kubegresv1.KubegresSpec) (batchv1.Job, error) {
+	verificationJobTemplate, err := r.loadVerificationJobFromTemplate()
+	if err != nil {
+		return verificationJobTemplate, err
+	}
+
+	verificationJobTemplate.Name = r.kubegresRestoreContext.GetVerificationJobName()
+	verificationJobTemplate.Namespace = r.kubegresRestoreContext.KubegresRestore.Namespace
+	verificationJobTemplate.OwnerReferences = r.getOwnerReference()
+
+	if kubegresSpec.ImagePullSecrets != nil {
+		verificationJobTemplate.Spec.Template.Spec.ImagePullSecrets = append(verificationJobTemplate.Spec.Template.Spec.ImagePullSecrets, kubegresSpec.ImagePullSecrets...)
+	}
+
+	checks := r.createVerificationChecks()
+
+	container := &verificationJobTemplate.Spec.Template.Spec.Containers[0]
+	container.Image = kubegresSpec.Image
+	container.Env[0].ValueFrom = r.getKubegresEnvVar(ctx.EnvVarNameOfPostgresSuperUserPsw, kubegresSpec).ValueFrom
+	container.Env[1].Value = r.kubegresRestoreContext.KubegresRestore.Spec.ClusterName
+	container.Env[2].Value = strconv.Itoa(len(checks))
+
+	for i, check := range checks {
+		envVarPrefix := "VERIFY_CHECK_" + strconv.Itoa(i) + "_"
+		container.Env = append(container.Env,
+			core.EnvVar{Name: envVarPrefix + "NAME", Value: check.Name},
+			core.EnvVar{Name: envVarPrefix + "DATABASE", Value: check.Database},
+			core.EnvVar{Name: envVarPrefix + "QUERY", Value: check.Query},
+			core.EnvVar{Name: envVarPrefix + "EXPECTED", Value: check.Expected})
+	}
+
+	return verificationJobTemplate, nil
+}
+
+// createVerificationChecks returns the built-in checks followed by the checks of the field 'verify'. The built-in
+// checks verify that 'pg_stat_database' is reachable and that the databases restored or used by the checks exist.
+func (r *RestoreJobResourcesCreatorTemplate) createVerificationChecks() []kubegresv1.VerificationQuery {
+	restoreSpec := r.kubegresRestoreContext.KubegresRestore.Spec
+	checks := []kubegresv1.VerificationQuery{{
+		Name:     "pg-stat-database-reachable",
+		Database: ctx.DefaultVerificationDatabase,
+		Query:    "SELECT count(*) FROM pg_stat_database",
+	}}
+
+	var databases []string
+	for _, database := range restoreSpec.Databases {
+		if database.RestoreAs != "" {
+			databases = append(databases, database.RestoreAs)
+		} else {
+			databases = append(databases, database.Name)
+		}
+	}
+
+	var userChecks []kubegresv1.VerificationQuery
+	for _, query := range restoreSpec.Verify.Queries {
+		query.Database = r.getVerificationDatabase(query.Database)
+		databases = append(databases, query.Database)
+		userChecks = append(userChecks, query)
+	}
+
+	for _, rowCount := range restoreSpec.Verify.RowCounts {
+		database := r.getVerificationDatabase(rowCount.Database)
+		databases = append(databases, database)
+		userChecks = append(userChecks, kubegresv1.VerificationQuery{
+			Name:     "row-count/" + database + "/" + rowCount.Table,
+			Database: database,
+			Query:    "SELECT count(*) FROM " + r.quoteQualifiedName(rowCount.Table),
+			Expected: strconv.FormatInt(rowCount.Count, 10),
+		})
+	}
+
+	checkedDatabases := make(map[string]bool)
+	for _, database := range databases {
+		if checkedDatabases[database] {
+			continue
+		}
+		checkedDatabases[database] = true
+		checks = append(checks, kubegresv1.VerificationQuery{
+			Name:     "database-exists/" + database,
+			Database: ctx.DefaultVerificationDatabase,
+			Query:    "SELECT count(*) FROM pg_database WHERE datname = '" + r.escapeSettingValue(database) + "'",
+			Expected: "1",
+		})
+	}
+
+	return append(checks, userChecks...)
+}
+
+func (r *RestoreJobResourcesCreatorTemplate) getVerificationDatabase(database string) string {
+	if database == "" {
+		return ctx.DefaultVerificationDatabase
+	}
+	return database
+}
+
+// quoteQualifiedName quotes each part of a name qualified with its schema, e.g. 'public.orders'.
+func (r *RestoreJobResourcesCreatorTemplate) quoteQualifiedName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "\"" + strings.ReplaceAll(part, "\"", "\"\"") + "\""
+	}
+	return strings.Join(parts, ".")
+}
+
 func (r *RestoreJobResourcesCreatorTemplate) CreateFileCheckerPod() (core.Pod, error) {
+	if r.kubegresRestoreContext.IsObjectStoreSource() {
+		return r.createObjectStoreFileCheckerPod()
+	}
+
 	podTemplate, err := r.loadFileCheckerPodFromTemplate()
 	if err != nil {
 		return podTemplate, err
@@ -79,6 +294,56 @@ func (r *RestoreJobResourcesCreatorTemplate) CreateFileCheckerPod() (core.Pod, e
 	return podTemplate, nil
 }
 
+// createObjectStoreFileCheckerPod creates a Pod which checks that the backup 'dataSource.objectStore.snapshot'
+// exists in the bucket 'dataSource.objectStore.s3'.
+func (r *RestoreJobResourcesCreatorTemplate) createObjectStoreFileCheckerPod() (core.Pod, error) {
+	podTemplate, err := r.loadObjectStoreFileCheckerPodFromTemplate()
+	if err != nil {
+		return podTemplate, err
+	}
+
+	objectStore := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.ObjectStore
+
+	podTemplate.Name = r.kubegresRestoreContext.GetFileCheckerPodName()
+	podTemplate.Namespace = r.kubegresRestoreContext.KubegresRestore.Namespace
+	podTemplate.OwnerReferences = r.getOwnerReference()
+
+	container := &podTemplate.Spec.Containers[0]
+	container.Env[0].Value = r.kubegresRestoreContext.GetObjectStoreSnapshotUrl()
+	container.Env[1].Value = objectStore.Snapshot
+	configureS3Container(container, *objectStore.S3)
+	addS3CaVolume(&podTemplate.Spec, *objectStore.S3)
+
+	return podTemplate, nil
+}
+
+// addObjectStoreSnapshotDownloader replaces the PVC containing the backup to restore with an empty folder in which
+// an init container downloads the backup from the field 'dataSource.objectStore'.
+func (r *RestoreJobResourcesCreatorTemplate) addObjectStoreSnapshotDownloader(podSpec *core.PodSpec) {
+
+	objectStore := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.ObjectStore
+
+	podSpec.Volumes[0].VolumeSource = core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}}
+
+	downloaderContainer := core.Container{
+		Name:                     "download-snapshot",
+		ImagePullPolicy:          core.PullIfNotPresent,
+		TerminationMessagePolicy: core.TerminationMessageFallbackToLogsOnError,
+		Command:                  []string{"bash", "-c", objectStoreSnapshotDownloadScript},
+		Env: []core.EnvVar{
+			{Name: "SNAPSHOT_S3_URL", Value: r.kubegresRestoreContext.GetObjectStoreSnapshotUrl()},
+			{Name: "RESTOREPOINT_FILEPATH", Value: r.kubegresRestoreContext.GetSnapshotFilePath()},
+		},
+		VolumeMounts: []core.VolumeMount{
+			{Name: podSpec.Volumes[0].Name, MountPath: r.kubegresRestoreContext.GetSnapshotFolder()},
+		},
+	}
+	configureS3Container(&downloaderContainer, *objectStore.S3)
+	addS3CaVolume(podSpec, *objectStore.S3)
+
+	podSpec.InitContainers = append([]core.Container{downloaderContainer}, podSpec.InitContainers...)
+}
+
 func (r *RestoreJobResourcesCreatorTemplate) CreateKubegresResource(kubegresSpec kubegresv1.KubegresSpec) kubegresv1.Kubegres {
 	var replicas int32 = 1
 	kubegres := kubegresv1.Kubegres{}
@@ -108,6 +373,44 @@ func (r *RestoreJobResourcesCreatorTemplate) getKubegresEnvVar(envName string, k
 	return core.EnvVar{}
 }
 
+func (r *RestoreJobResourcesCreatorTemplate) createRecoveryTargetSettings() string {
+
+	if !r.kubegresRestoreContext.IsPointInTimeRecovery() {
+		return ""
+	}
+
+	recoveryTarget := r.kubegresRestoreContext.KubegresRestore.Spec.RecoveryTarget
+	settings := ""
+
+	if recoveryTarget.Time != "" {
+		settings += "recovery_target_time = '" + r.escapeSettingValue(recoveryTarget.Time) + "'\n"
+	} else if recoveryTarget.Lsn != "" {
+		settings += "recovery_target_lsn = '" + r.escapeSettingValue(recoveryTarget.Lsn) + "'\n"
+	} else if recoveryTarget.Name != "" {
+		settings += "recovery_target_name = '" + r.escapeSettingValue(recoveryTarget.Name) + "'\n"
+	}
+
+	inclusive := "on"
+	if recoveryTarget.Inclusive != nil && !*recoveryTarget.Inclusive {
+		inclusive = "off"
+	}
+	settings += "recovery_target_inclusive = " + inclusive + "\n"
+	settings += "recovery_target_action = 'promote'"
+
+	return settings
+}
+
+func (r *RestoreJobResourcesCreatorTemplate) escapeSettingValue(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+func (r *RestoreJobResourcesCreatorTemplate) getDatabaseVolumeMount(kubegresSpec kubegresv1.KubegresSpec) string {
+	if kubegresSpec.Database.VolumeMount == "" {
+		return ctx.DefaultDatabaseVolumeMount
+	}
+	return kubegresSpec.Database.VolumeMount
+}
+
 func (r *RestoreJobResourcesCreatorTemplate) getOwnerReference() []metav1.OwnerReference {
 	return []metav1.OwnerReference{*metav1.NewControllerRef(r.kubegresRestoreContext.KubegresRestore, kubegresv1.GroupVersion.WithKind(ctx.KindKubegresRestore))}
 }
@@ -122,6 +425,36 @@ func (r *RestoreJobResourcesCreatorTemplate) loadRestoreJobFromTemplate() (resto
 	return *obj.(*batchv1.Job), nil
 }
 
+func (r *RestoreJobResourcesCreatorTemplate) loadPhysicalRestoreJobFromTemplate() (batchv1.Job, error) {
+	obj, err := r.decodeYaml(yaml.PhysicalRestoreJobTemplate)
+
+	if err != nil {
+		r.kubegresRestoreContext.Log.Error(err, "Unable to load Kubegres Physical Restore Job. Given error:")
+		return batchv1.Job{}, err
+	}
+	return *obj.(*batchv1.Job), nil
+}
+
+func (r *RestoreJobResourcesCreatorTemplate) loadVerificationJobFromTemplate() (batchv1.Job, error) {
+	obj, err := r.decodeYaml(yaml.VerificationJobTemplate)
+
+	if err != nil {
+		r.kubegresRestoreContext.Log.Error(err, "Unable to load Kubegres Restore Verification Job. Given error:")
+		return batchv1.Job{}, err
+	}
+	return *obj.(*batchv1.Job), nil
+}
+
+func (r *RestoreJobResourcesCreatorTemplate) loadObjectStoreFileCheckerPodFromTemplate() (core.Pod, error) {
+	obj, err := r.decodeYaml(yaml.ObjectStoreFileCheckerPodTemplate)
+
+	if err != nil {
+		r.kubegresRestoreContext.Log.Error(err, "Unable to load Kubegres Object Store File Checker Pod. Given error:")
+		return core.Pod{}, err
+	}
+	return *obj.(*core.Pod), nil
+}
+
 func (r *RestoreJobResourcesCreatorTemplate) loadFileCheckerPodFromTemplate() (core.Pod, error) {
 	obj, err := r.decodeYaml(yaml.FileCheckerPodTemplate)
 
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"os"
+	"os/exec"
+	"path/filepath"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestVerificationJobRunsRowCountChecksAfterCheckingTheirDatabasesExist(t *testing.T) {
+	verificationJob := createVerificationJobToTest(t, &v1.RestoreVerification{
+		RowCounts: []v1.VerificationRowCount{
+			{Database: "app", Table: "public.orders", Count: 42},
+			{Table: "items", Count: 0},
+		},
+	})
+
+	expectedEnvVars := map[string]string{
+		"VERIFY_CHECKS_COUNT":        "5",
+		"VERIFY_CHECK_0_NAME":        "pg-stat-database-reachable",
+		"VERIFY_CHECK_1_NAME":        "database-exists/app",
+		"VERIFY_CHECK_1_QUERY":       "SELECT count(*) FROM pg_database WHERE datname = 'app'",
+		"VERIFY_CHECK_1_EXPECTED":    "1",
+		"VERIFY_CHECK_2_NAME":        "database-exists/postgres",
+		"VERIFY_CHECK_3_NAME":        "row-count/app/public.orders",
+		"VERIFY_CHECK_3_DATABASE":    "app",
+		"VERIFY_CHECK_3_QUERY":       `SELECT count(*) FROM "public"."orders"`,
+		"VERIFY_CHECK_3_EXPECTED":    "42",
+		"VERIFY_CHECK_4_NAME":        "row-count/postgres/items",
+		"VERIFY_CHECK_4_DATABASE":    "postgres",
+		"VERIFY_CHECK_4_EXPECTED":    "0",
+		"VERIFY_TARGET_DB_HOST_NAME": "postgres",
+	}
+	container := verificationJob.Spec.Template.Spec.Containers[0]
+	for name, expectedValue := range expectedEnvVars {
+		if value := getEnvVarValue(container, name); value != expectedValue {
+			t.Errorf("Expected the env variable '%s' to be '%s', got '%s'", name, expectedValue, value)
+		}
+	}
+}
+
+func TestVerificationScriptReportsRowCountMismatchAndFails(t *testing.T) {
+	terminationLog, exitCode := runVerificationScriptToTest(t, "41", 0)
+
+	if exitCode != 1 {
+		t.Errorf("Expected the exit code 1, got %d", exitCode)
+	}
+	expectedLines := []string{
+		"PASS\tpg-stat-database-reachable",
+		"PASS\tdatabase-exists/app",
+		"FAIL\trow-count/app/public.orders\tExpected '42' but got '41'",
+	}
+	if terminationLog != strings.Join(expectedLines, "\n")+"\n" {
+		t.Errorf("Expected the termination message:\n%s\ngot:\n%s", strings.Join(expectedLines, "\n"), terminationLog)
+	}
+}
+
+func TestVerificationScriptReportsQueryErrorAndFails(t *testing.T) {
+	terminationLog, exitCode := runVerificationScriptToTest(t, "", 1)
+
+	if exitCode != 1 {
+		t.Errorf("Expected the exit code 1, got %d", exitCode)
+	}
+	expectedLine := "FAIL\trow-count/app/public.orders\tERROR:  relation \"public.orders\" does not exist"
+	if !strings.Contains(terminationLog, expectedLine+"\n") {
+		t.Errorf("Expected the termination message to contain '%s', got:\n%s", expectedLine, terminationLog)
+	}
+}
+
+func TestVerificationScriptPassesWhenRowCountMatches(t *testing.T) {
+	terminationLog, exitCode := runVerificationScriptToTest(t, "42", 0)
+
+	if exitCode != 0 {
+		t.Errorf("Expected the exit code 0, got %d", exitCode)
+	}
+	if strings.Contains(terminationLog, "FAIL") || !strings.Contains(terminationLog, "PASS\trow-count/app/public.orders\n") {
+		t.Errorf("Expected all the checks to pass, got:\n%s", terminationLog)
+	}
+}
+
+func createVerificationJobToTest(t *testing.T, verify *v1.RestoreVerification) batchv1.Job {
+	kubegresRestore := &v1.KubegresRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec:       v1.KubegresRestoreSpec{ClusterName: "postgres", Verify: verify},
+	}
+	kubegresRestoreContext := ctx.KubegresRestoreContext{
+		KubegresRestore: kubegresRestore,
+		Log:             log.LogWrapper[*v1.KubegresRestore]{Resource: kubegresRestore, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10)},
+	}
+
+	resourcesCreator := CreateRestoreJobCreator(kubegresRestoreContext)
+	verificationJob, err := resourcesCreator.CreateVerificationJob(v1.KubegresSpec{Image: "postgres:16"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return verificationJob
+}
+
+// runVerificationScriptToTest runs the command of the verification job checking the number of rows of the table
+// 'public.orders' with a fake psql. The fake psql returns the given number of rows, or an error if its exit code is
+// not 0. It returns the termination message of the script and its exit code.
+func runVerificationScriptToTest(t *testing.T, nbreRows string, psqlExitCode int) (string, int) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("The command 'bash' is required to run the verification script")
+	}
+
+	verificationJob := createVerificationJobToTest(t, &v1.RestoreVerification{
+		RowCounts: []v1.VerificationRowCount{{Database: "app", Table: "public.orders", Count: 42}},
+	})
+	container := verificationJob.Spec.Template.Spec.Containers[0]
+
+	folder := t.TempDir()
+	terminationLogPath := filepath.Join(folder, "termination-log")
+	script := strings.ReplaceAll(container.Command[2], "/dev/termination-log", terminationLogPath)
+	fakePsql := "#!/bin/bash\n" +
+		"if [[ \"$*\" != *'\"public\".\"orders\"'* ]]; then echo 1; exit 0; fi\n" +
+		"if [ " + strconv.Itoa(psqlExitCode) + " -ne 0 ]; then\n" +
+		"  echo 'ERROR:  relation \"public.orders\" does not exist' >&2\n" +
+		"  echo 'LINE 1: SELECT count(*) FROM \"public\".\"orders\"' >&2\n" +
+		"  exit " + strconv.Itoa(psqlExitCode) + "\n" +
+		"fi\n" +
+		"echo " + nbreRows + "\n"
+	writeFileToTest(t, filepath.Join(folder, "psql"), fakePsql)
+
+	cmd := exec.Command(container.Command[0], container.Command[1], script)
+	cmd.Env = append(os.Environ(), "PATH="+folder+string(os.PathListSeparator)+os.Getenv("PATH"))
+	for _, envVar := range container.Env {
+		cmd.Env = append(cmd.Env, envVar.Name+"="+envVar.Value)
+	}
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	terminationLog, _ := os.ReadFile(terminationLogPath)
+	return string(terminationLog), exitCode
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewindScriptQuotesReplicationPasswordInPrimaryConninfo(t *testing.T) {
+	autoConf := runRewindScriptToTest(t, `it's a \ pass`)
+
+	// The config file doubles the quotes and the backslashes of the conninfo, in which the password is quoted
+	expectedLine := `primary_conninfo = 'host=postgres user=replication password=''it\\''s a \\\\ pass'''`
+	if !strings.Contains(autoConf, expectedLine+"\n") {
+		t.Errorf("Expected postgresql.auto.conf to contain:\n%s\ngot:\n%s", expectedLine, autoConf)
+	}
+
+	if strings.Contains(autoConf, "primary_conninfo = 'old'") {
+		t.Errorf("Expected the former primary_conninfo to be removed, got:\n%s", autoConf)
+	}
+}
+
+func TestRewindScriptKeepsSimpleReplicationPasswordReadable(t *testing.T) {
+	autoConf := runRewindScriptToTest(t, "postgresReplicaPsw")
+
+	expectedLine := `primary_conninfo = 'host=postgres user=replication password=''postgresReplicaPsw'''`
+	if !strings.Contains(autoConf, expectedLine+"\n") {
+		t.Errorf("Expected postgresql.auto.conf to contain:\n%s\ngot:\n%s", expectedLine, autoConf)
+	}
+}
+
+// runRewindScriptToTest runs the script 'rewind_failed_primary_to_replica.sh' of the base ConfigMap with a fake
+// pg_rewind which succeeds, and returns the contents of the file postgresql.auto.conf of the rewound DB.
+func runRewindScriptToTest(t *testing.T, replicationPassword string) string {
+	for _, command := range []string{"bash", "sed"} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("The command '%s' is required to run the rewind script", command)
+		}
+	}
+
+	resourceTemplateLoader := ResourceTemplateLoader{}
+	baseConfigMap, err := resourceTemplateLoader.LoadBaseConfigMap()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	folder := t.TempDir()
+	binFolder := filepath.Join(folder, "bin")
+	pgData := filepath.Join(folder, "pgdata")
+	autoConfPath := filepath.Join(pgData, "postgresql.auto.conf")
+	scriptPath := filepath.Join(folder, "rewind_failed_primary_to_replica.sh")
+	copyScriptPath := filepath.Join(folder, "copy_primary_data_to_replica.sh")
+
+	if err = os.MkdirAll(binFolder, 0755); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err = os.MkdirAll(pgData, 0755); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	script := strings.ReplaceAll(baseConfigMap.Data["rewind_failed_primary_to_replica.sh"], "/tmp/copy_primary_data_to_replica.sh", copyScriptPath)
+	writeFileToTest(t, scriptPath, script)
+	writeFileToTest(t, copyScriptPath, "#!/bin/bash\nexit 0\n")
+	writeFileToTest(t, autoConfPath, "primary_conninfo = 'old'\n")
+	writeFileToTest(t, filepath.Join(binFolder, "pg_rewind"), "#!/bin/bash\nexit 0\n")
+	writeFileToTest(t, filepath.Join(binFolder, "chown"), "#!/bin/bash\nexit 0\n")
+	writeFileToTest(t, filepath.Join(binFolder, "su"), "#!/bin/bash\nbash -c \"${@: -1}\"\n")
+
+	cmd := exec.Command("bash", scriptPath)
+	cmd.Env = append(os.Environ(),
+		"PATH="+binFolder+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"PGDATA="+pgData,
+		"PGPASSWORD="+replicationPassword,
+		"POSTGRES_PASSWORD=superUserPassword",
+		"PRIMARY_HOST_NAME=postgres",
+		"KUBEGRES_REWIND_ID=1")
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected the rewind script to succeed, got: %v\n%s", err, output)
+	}
+
+	autoConf, err := os.ReadFile(autoConfPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return string(autoConf)
+}
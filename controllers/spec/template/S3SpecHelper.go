@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+	postgresV1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+// awsOptionsScript sets the variable "awsOptions" with the options of the AWS CLI from the environment variables set
+// by configureS3Container.
+const awsOptionsScript = `awsOptions=""
+if [ -n "$S3_ENDPOINT" ]; then
+    awsOptions="--endpoint-url $S3_ENDPOINT"
+fi
+if [ "$S3_NO_VERIFY_SSL" == "true" ]; then
+    awsOptions="$awsOptions --no-verify-ssl"
+fi
+`
+
+const backUpUploadScript = awsOptionsScript + `
+echo "$(date '+%d/%m/%Y %H:%M:%S') - Uploading the backups in '$BACKUP_DESTINATION_FOLDER' to '$BACKUP_S3_URL'";
+aws s3 cp $awsOptions --only-show-errors --recursive $BACKUP_DESTINATION_FOLDER $BACKUP_S3_URL/
+`
+
+const objectStoreSnapshotDownloadScript = awsOptionsScript + `
+echo "$(date '+%d/%m/%Y %H:%M:%S') - Downloading the snapshot '$SNAPSHOT_S3_URL' into '$RESTOREPOINT_FILEPATH'";
+aws s3 cp $awsOptions --only-show-errors $SNAPSHOT_S3_URL $RESTOREPOINT_FILEPATH
+`
+
+// describeS3 returns a description of the given S3 bucket and of the options to access it, which allows detecting
+// when they have changed.
+func describeS3(s3 postgresV1.KubegresS3) string {
+	return "s3 url=" + ctx.GetS3Url(s3) +
+		" endpoint=" + s3.Endpoint +
+		" credentialsSecret=" + s3.CredentialsSecret +
+		" image=" + s3.Image +
+		" caSecret=" + s3.Tls.CaSecret +
+		" insecureSkipVerify=" + strconv.FormatBool(s3.Tls.InsecureSkipVerify)
+}
+
+// configureS3Container sets the image, the credentials, the endpoint and the TLS options of a container transferring
+// files to and from the given S3 bucket with the AWS CLI. The scripts run by the container read the environment
+// variables "S3_ENDPOINT" and "S3_NO_VERIFY_SSL".
+func configureS3Container(container *core.Container, s3 postgresV1.KubegresS3) {
+
+	container.Image = s3.Image
+	if container.Image == "" {
+		container.Image = ctx.DefaultS3Image
+	}
+
+	noVerifySsl := ""
+	if s3.Tls.InsecureSkipVerify {
+		noVerifySsl = "true"
+	}
+
+	container.Env = append(container.Env,
+		core.EnvVar{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+		core.EnvVar{Name: "S3_NO_VERIFY_SSL", Value: noVerifySsl})
+
+	container.EnvFrom = []core.EnvFromSource{
+		{SecretRef: &core.SecretEnvSource{LocalObjectReference: core.LocalObjectReference{Name: s3.CredentialsSecret}}},
+	}
+
+	if s3.Tls.CaSecret != "" {
+		container.Env = append(container.Env, core.EnvVar{Name: "AWS_CA_BUNDLE", Value: ctx.S3CaVolumeMount + "/" + ctx.S3CaSecretKey})
+		container.VolumeMounts = append(container.VolumeMounts, core.VolumeMount{
+			Name:      ctx.S3CaVolumeName,
+			MountPath: ctx.S3CaVolumeMount,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// addS3CaVolume adds the volume of the CA bundle of the given S3 bucket in the given Pod, if it is set.
+func addS3CaVolume(podSpec *core.PodSpec, s3 postgresV1.KubegresS3) {
+
+	if s3.Tls.CaSecret == "" {
+		return
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, core.Volume{
+		Name: ctx.S3CaVolumeName,
+		VolumeSource: core.VolumeSource{
+			Secret: &core.SecretVolumeSource{SecretName: s3.Tls.CaSecret},
+		},
+	})
+}
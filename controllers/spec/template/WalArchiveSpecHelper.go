@@ -0,0 +1,208 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"strconv"
+	"strings"
+)
+
+const (
+	// WalArchiveConfigAnnotationKey is set in the Pod template of a StatefulSet with a description of the configuration
+	// applied to archive WAL segments. It allows detecting when the field 'backup.walArchive' has changed.
+	WalArchiveConfigAnnotationKey = "kubegres.reactive-tech.io/wal-archive-config"
+	walArchiveSettingPrefix       = "archive_"
+)
+
+// WalArchiveSpecHelper configures the PostgreSql container of a StatefulSet so that it archives WAL segments as set
+// in the field 'backup.walArchive'. If the segments are archived in S3, it adds a container uploading them.
+type WalArchiveSpecHelper struct {
+	kubegresContext ctx.KubegresContext
+}
+
+func CreateWalArchiveSpecHelper(kubegresContext ctx.KubegresContext) WalArchiveSpecHelper {
+	return WalArchiveSpecHelper{kubegresContext: kubegresContext}
+}
+
+func (r *WalArchiveSpecHelper) ConfigureStatefulSet(statefulSet *v1.StatefulSet) (hasStatefulSetChanged bool, differenceDetails string) {
+
+	currentConfig := statefulSet.Spec.Template.Annotations[WalArchiveConfigAnnotationKey]
+	expectedConfig := r.GetExpectedConfig()
+	if currentConfig == expectedConfig {
+		return false, ""
+	}
+
+	r.removeWalArchiveConfig(statefulSet)
+	if r.kubegresContext.IsWalArchiveEnabled() {
+		r.addWalArchiveConfig(statefulSet, expectedConfig)
+	}
+
+	if expectedConfig == "" {
+		return true, "WAL archiving is disabled"
+	}
+	return true, "WAL archiving is configured with: '" + expectedConfig + "'"
+}
+
+// GetExpectedConfig returns a description of the configuration to archive WAL segments, or an empty string if
+// the field 'backup.walArchive.enabled' is false.
+func (r *WalArchiveSpecHelper) GetExpectedConfig() string {
+
+	if !r.kubegresContext.IsWalArchiveEnabled() {
+		return ""
+	}
+
+	backUpSpec := r.kubegresContext.Kubegres.Spec.Backup
+	archiveTimeout := strconv.Itoa(int(backUpSpec.WalArchive.ArchiveTimeout))
+
+	if r.kubegresContext.IsWalArchivedInS3() {
+		return describeS3(*backUpSpec.WalArchive.S3) +
+			" walUrl=" + ctx.GetWalArchiveS3Url(*backUpSpec.WalArchive.S3, r.kubegresContext.Kubegres.Name) +
+			" archiveTimeout=" + archiveTimeout
+	}
+
+	return "pvc name=" + backUpSpec.PvcName +
+		" folder=" + r.kubegresContext.GetWalArchiveFolder() +
+		" archiveTimeout=" + archiveTimeout
+}
+
+func (r *WalArchiveSpecHelper) addWalArchiveConfig(statefulSet *v1.StatefulSet, expectedConfig string) {
+
+	walArchiveSpec := r.kubegresContext.Kubegres.Spec.Backup.WalArchive
+	podSpec := &statefulSet.Spec.Template.Spec
+	container := &podSpec.Containers[0]
+
+	if statefulSet.Spec.Template.Annotations == nil {
+		statefulSet.Spec.Template.Annotations = make(map[string]string)
+	}
+	statefulSet.Spec.Template.Annotations[WalArchiveConfigAnnotationKey] = expectedConfig
+
+	container.Args = append(container.Args,
+		"-c", "archive_mode=on",
+		"-c", "archive_command=/tmp/"+states.ConfigMapDataKeyArchiveWalScript+" %p %f",
+		"-c", "archive_timeout="+strconv.Itoa(int(walArchiveSpec.ArchiveTimeout)))
+
+	container.Env = append(container.Env, core.EnvVar{Name: ctx.EnvVarNameWalArchiveFolder, Value: r.kubegresContext.GetWalArchiveFolder()})
+
+	container.VolumeMounts = append(container.VolumeMounts, r.createScriptVolumeMount(states.ConfigMapDataKeyArchiveWalScript))
+
+	if r.kubegresContext.IsWalArchivedInS3() {
+		podSpec.Containers = append(podSpec.Containers, r.createWalArchiveUploaderContainer())
+		addS3CaVolume(podSpec, *walArchiveSpec.S3)
+		return
+	}
+
+	backUpSpec := r.kubegresContext.Kubegres.Spec.Backup
+	container.VolumeMounts = append(container.VolumeMounts, core.VolumeMount{
+		Name:      ctx.WalArchiveVolumeName,
+		MountPath: backUpSpec.VolumeMount,
+	})
+
+	podSpec.Volumes = append(podSpec.Volumes, core.Volume{
+		Name: ctx.WalArchiveVolumeName,
+		VolumeSource: core.VolumeSource{
+			PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{ClaimName: backUpSpec.PvcName},
+		},
+	})
+}
+
+func (r *WalArchiveSpecHelper) createWalArchiveUploaderContainer() core.Container {
+
+	s3 := r.kubegresContext.Kubegres.Spec.Backup.WalArchive.S3
+
+	container := core.Container{
+		Name:            ctx.WalArchiveUploaderContainerName,
+		ImagePullPolicy: core.PullIfNotPresent,
+		Command:         []string{"bash", "-c", "/tmp/" + states.ConfigMapDataKeyUploadArchivedWalScript},
+		Env: []core.EnvVar{
+			{Name: ctx.EnvVarNameWalArchiveFolder, Value: r.kubegresContext.GetWalArchiveFolder()},
+			{Name: "WAL_ARCHIVE_S3_URL", Value: ctx.GetWalArchiveS3Url(*s3, r.kubegresContext.Kubegres.Name)},
+		},
+		VolumeMounts: []core.VolumeMount{
+			{Name: ctx.DatabaseVolumeName, MountPath: r.kubegresContext.Kubegres.Spec.Database.VolumeMount},
+			r.createScriptVolumeMount(states.ConfigMapDataKeyUploadArchivedWalScript),
+		},
+	}
+
+	configureS3Container(&container, *s3)
+	return container
+}
+
+func (r *WalArchiveSpecHelper) createScriptVolumeMount(configMapDataKey string) core.VolumeMount {
+	return core.VolumeMount{
+		Name:      ctx.BaseConfigMapVolumeName,
+		MountPath: "/tmp/" + configMapDataKey,
+		SubPath:   configMapDataKey,
+	}
+}
+
+func (r *WalArchiveSpecHelper) removeWalArchiveConfig(statefulSet *v1.StatefulSet) {
+
+	delete(statefulSet.Spec.Template.Annotations, WalArchiveConfigAnnotationKey)
+
+	podSpec := &statefulSet.Spec.Template.Spec
+	container := &podSpec.Containers[0]
+
+	var args []string
+	for i := 0; i < len(container.Args); i++ {
+		if container.Args[i] == "-c" && i+1 < len(container.Args) && strings.HasPrefix(container.Args[i+1], walArchiveSettingPrefix) {
+			i++
+			continue
+		}
+		args = append(args, container.Args[i])
+	}
+	container.Args = args
+
+	var env []core.EnvVar
+	for _, envVar := range container.Env {
+		if envVar.Name != ctx.EnvVarNameWalArchiveFolder {
+			env = append(env, envVar)
+		}
+	}
+	container.Env = env
+
+	var volumeMounts []core.VolumeMount
+	for _, volumeMount := range container.VolumeMounts {
+		if volumeMount.Name != ctx.WalArchiveVolumeName && volumeMount.SubPath != states.ConfigMapDataKeyArchiveWalScript {
+			volumeMounts = append(volumeMounts, volumeMount)
+		}
+	}
+	container.VolumeMounts = volumeMounts
+
+	var volumes []core.Volume
+	for _, volume := range podSpec.Volumes {
+		if volume.Name != ctx.WalArchiveVolumeName && volume.Name != ctx.S3CaVolumeName {
+			volumes = append(volumes, volume)
+		}
+	}
+	podSpec.Volumes = volumes
+
+	var containers []core.Container
+	for _, c := range podSpec.Containers {
+		if c.Name != ctx.WalArchiveUploaderContainerName {
+			containers = append(containers, c)
+		}
+	}
+	podSpec.Containers = containers
+}
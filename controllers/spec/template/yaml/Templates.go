@@ -90,7 +90,15 @@ metadata:
 # - primary_create_replication_role.sh
 # - copy_primary_data_to_replica.sh
 # - promote_replica_to_primary.sh
-# We highly recommend that you do not modify these 3 data keys as it could break the operator.
+# - rewind_failed_primary_to_replica.sh
+# - archive_wal.sh
+# - upload_archived_wal_to_s3.sh
+# - base_backup_database.sh
+# We highly recommend that you do not modify these 7 data keys as it could break the operator.
+#
+# When a new version of Kubegres changes this ConfigMap, it updates it and restarts the Pods. The data keys
+# 'postgres.conf', 'primary_init_script.sh' and 'pg_hba.conf' are only added if missing so that your changes are kept,
+# while all other data keys are replaced.
 
 data:
 
@@ -115,6 +123,9 @@ data:
     max_connections = 100
     shared_buffers = 128MB
 
+    # Required by pg_rewind when a failed Primary rejoins the cluster as a Replica (see the field 'failover.rewindFailedPrimary')
+    wal_log_hints = on
+
     # Logging
     #log_destination = 'stderr,csvlog'
     #logging_collector = on
@@ -199,6 +210,10 @@ data:
 
     echo "$dt - DB backup completed for Kubegres resource $KUBEGRES_RESOURCE_NAME into file: $backUpFilePath";
 
+    # The name and the size of the backup file are reported in the status of the Kubegres resource
+    echo "fileName=$backUpFileName" > /dev/termination-log
+    echo "sizeBytes=$(stat -c %s $backUpFilePath)" >> /dev/termination-log
+
   # If you want to create a new Kubegres resource from a restorepoint, this bash scripts restores a
   # Postgres database into a given destination from a snapshot.
   #
@@ -209,6 +224,11 @@ data:
   # Environment
   #   RESTOREPOINT_FILEPATH
   #   BACKUP_TARGET_DB_HOST_NAME:
+  #   TERMINATE_DB_CONNECTIONS: set to "true" when restoring into an existing database (see the field 'inPlace' of
+  #   KubegresRestore), so that the connections to the databases to drop are terminated.
+  #   RESTORE_DATABASES: space separated databases to restore, each one in the format "name" or "name:restoreAs"
+  #   (see the field 'databases' of KubegresRestore). Default: all the databases.
+  #   RESTORE_ROLES: space separated roles to restore (see the field 'roles' of KubegresRestore). Default: all the roles.
   restore_database.sh: |
     #!/bin/bash
     set -e
@@ -222,10 +242,137 @@ data:
 
     echo "$dt - Restore of ${KUBEGRES_RESOURCE_NAME} from ${RESTOREPOINT_FILEPATH} has started"
 
-    echo "$dt - Running: gzip -d -kc ${RESTOREPOINT_FILEPATH} | psql -h ${BACKUP_TARGET_DB_HOST_NAME} -U postgres -w"
-    gzip -d -kc ${RESTOREPOINT_FILEPATH} | psql -h ${BACKUP_TARGET_DB_HOST_NAME} -U postgres -w
+    if [ "$TERMINATE_DB_CONNECTIONS" = "true" ]; then
+      echo "$dt - Terminating the connections to the databases of ${BACKUP_TARGET_DB_HOST_NAME}"
+      psql -h ${BACKUP_TARGET_DB_HOST_NAME} -U postgres -w -c "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE pid <> pg_backend_pid() AND datname IS NOT NULL;"
+    fi
 
-    if [ $? -ne 0 ]; then
+    # The output of pg_dumpall is filtered so that only the databases in RESTORE_DATABASES and the roles in
+    # RESTORE_ROLES are restored. A database is renamed if its entry is in the format "name:restoreAs".
+    # The statements dropping and creating the databases and the roles are rewritten so that they do not fail when a
+    # database is missing or a role already exists. Any other error stops psql (ON_ERROR_STOP) and fails the restore.
+    echo "$dt - Restoring databases: '${RESTORE_DATABASES:-all}' and roles: '${RESTORE_ROLES:-all}'"
+    echo "$dt - Running: gzip -d -kc ${RESTOREPOINT_FILEPATH} | awk (filter) | psql -v ON_ERROR_STOP=1 -h ${BACKUP_TARGET_DB_HOST_NAME} -U postgres -w"
+    set +e
+    gzip -d -kc ${RESTOREPOINT_FILEPATH} | awk -v dbs="$RESTORE_DATABASES" -v roles="$RESTORE_ROLES" '
+      function quote(name) { return "\"" name "\"" }
+      function firstIdent(s,   i, c, out) {
+        out = ""
+        if (substr(s, 1, 1) == "\"") {
+          for (i = 2; i <= length(s); i++) {
+            c = substr(s, i, 1)
+            if (c == "\"" && substr(s, i + 1, 1) == "\"") { out = out c; i++; continue }
+            if (c == "\"") break
+            out = out c
+          }
+          identLen = i
+          return out
+        }
+        for (i = 1; i <= length(s) && substr(s, i, 1) != " " && substr(s, i, 1) != ";"; i++) out = out substr(s, i, 1)
+        identLen = i - 1
+        return out
+      }
+      function replaceIdentAt(line, pos, newName) {
+        firstIdent(substr(line, pos))
+        return substr(line, 1, pos - 1) quote(newName) substr(line, pos + identLen)
+      }
+      function prefixLen(line, prefix) { return index(line, prefix) == 1 ? length(prefix) : 0 }
+      function dropDatabasePos(line,   p) {
+        p = prefixLen(line, "DROP DATABASE IF EXISTS ")
+        if (p == 0) p = prefixLen(line, "DROP DATABASE ")
+        return p
+      }
+      function roleStatementPos(line,   p) {
+        p = prefixLen(line, "CREATE ROLE ")
+        if (p == 0) p = prefixLen(line, "ALTER ROLE ")
+        if (p == 0) p = prefixLen(line, "COMMENT ON ROLE ")
+        if (p == 0) p = prefixLen(line, "DROP ROLE IF EXISTS ")
+        if (p == 0) p = prefixLen(line, "DROP ROLE ")
+        return p
+      }
+      function ignoreErrors(statement, errors) {
+        return "DO $kubegres$ BEGIN " statement " EXCEPTION WHEN " errors " THEN RAISE NOTICE USING MESSAGE = SQLERRM; END $kubegres$;"
+      }
+      function idempotentRoleStatement(line,   p) {
+        if (index(line, "CREATE ROLE ") == 1) return ignoreErrors(line, "duplicate_object")
+        p = prefixLen(line, "DROP ROLE IF EXISTS ")
+        if (p == 0) p = prefixLen(line, "DROP ROLE ")
+        if (p > 0) return ignoreErrors("DROP ROLE IF EXISTS " substr(line, p + 1), "dependent_objects_still_exist OR object_in_use")
+        return line
+      }
+      function renameDatabase(line, newName,   p) {
+        if (index(line, "\\connect ") == 1) return "\\connect " quote(newName)
+        p = dropDatabasePos(line)
+        if (p == 0) p = prefixLen(line, "CREATE DATABASE ")
+        if (p == 0) p = prefixLen(line, "ALTER DATABASE ")
+        if (p == 0) p = prefixLen(line, "COMMENT ON DATABASE ")
+        if (p > 0) return replaceIdentAt(line, p + 1, newName)
+        p = index(line, " ON DATABASE ")
+        if (p > 0 && (index(line, "GRANT ") == 1 || index(line, "REVOKE ") == 1)) return replaceIdentAt(line, p + 13, newName)
+        p = index(line, " IN DATABASE ")
+        if (p > 0 && index(line, "ALTER ROLE ") == 1) return replaceIdentAt(line, p + 13, newName)
+        return line
+      }
+      BEGIN {
+        nbreDbs = split(dbs, list, " ")
+        for (i = 1; i <= nbreDbs; i++) {
+          if (split(list[i], pair, ":") > 1) target[pair[1]] = pair[2]; else target[pair[1]] = pair[1]
+        }
+        nbreRoles = split(roles, list, " ")
+        for (i = 1; i <= nbreRoles; i++) role[list[i]] = 1
+      }
+      /^-- Database ".*" dump$/ {
+        section = substr($0, 14, length($0) - 19)
+        keep = nbreDbs == 0 || (section in target)
+        if (keep && nbreDbs > 0) found[section] = 1
+        if (keep) print (nbreDbs > 0 ? "-- Database \"" target[section] "\" dump" : $0)
+        next
+      }
+      section == "" {
+        p = dropDatabasePos($0)
+        if (p > 0) {
+          name = firstIdent(substr($0, p + 1))
+          if (nbreDbs > 0 && !(name in target)) next
+          print "DROP DATABASE IF EXISTS " (nbreDbs > 0 ? quote(target[name]) : substr($0, p + 1, identLen)) substr($0, p + 1 + identLen)
+          next
+        }
+        p = roleStatementPos($0)
+        if (p > 0) {
+          if (nbreRoles == 0 || (firstIdent(substr($0, p + 1)) in role)) print idempotentRoleStatement($0)
+          next
+        }
+        p = index($0, " TO ")
+        if (index($0, "GRANT ") == 1 && p > 0 && nbreRoles > 0) {
+          if ((firstIdent(substr($0, 7)) in role) && (firstIdent(substr($0, p + 4)) in role)) print
+          next
+        }
+        print
+        next
+      }
+      !keep { next }
+      nbreDbs > 0 && target[section] != section { print renameDatabase($0, target[section]); next }
+      { print }
+      END {
+        for (name in target) {
+          if (!(name in found)) { print "The database \"" name "\" is not found in the backup" > "/dev/stderr"; exitCode = 3 }
+        }
+        exit exitCode
+      }
+    ' | psql -v ON_ERROR_STOP=1 -h ${BACKUP_TARGET_DB_HOST_NAME} -U postgres -w
+    exitCodes=("${PIPESTATUS[@]}")
+    set -e
+
+    if [ ${exitCodes[0]} -ne 0 ]; then
+        echo "Unable to decompress the file ${RESTOREPOINT_FILEPATH}"
+        exit 1
+    fi
+
+    if [ ${exitCodes[1]} -ne 0 ]; then
+        echo "Unable to restore the databases '${RESTORE_DATABASES}' from file ${RESTOREPOINT_FILEPATH}"
+        exit ${exitCodes[1]}
+    fi
+
+    if [ ${exitCodes[2]} -ne 0 ]; then
         echo "Unable to restore the database"
         exit 1
     fi
@@ -288,6 +435,10 @@ data:
   # It is executed once, the 1st time a Replica PostgreSql container is created.
   # It is run in Replica containers.
   #
+  # When a Replica reuses the PVC of a removed StatefulSet (see the field 'failover.pvc'), its data is rewound first by
+  # the script 'rewind_failed_primary_to_replica.sh'. Otherwise, the data of a former Primary cannot be used by a
+  # Replica and it is replaced by a copy of the Primary DB.
+  #
   # If you modify this script, there is a risk of breaking the operator.
   #
   # This script will be located in the folder "/tmp"
@@ -299,6 +450,11 @@ data:
     dt=$(date '+%d/%m/%Y %H:%M:%S');
     echo "$dt - Attempting to copy Primary DB to Replica DB...";
 
+    if [ -n "$(ls -A $PGDATA)" ] && [ ! -f "$PGDATA/standby.signal" ]; then
+        echo "$dt - Replica DB folder contains the data of a former Primary DB. Removing it: $PGDATA";
+        rm -rf $PGDATA/*;
+    fi
+
     if [ -z "$(ls -A $PGDATA)" ]; then
 
         echo "$dt - Copying Primary DB to Replica DB folder: $PGDATA";
@@ -306,6 +462,10 @@ data:
 
         pg_basebackup -R -h $PRIMARY_HOST_NAME -D $PGDATA -P -U replication;
 
+        # Removes the settings of a point-in-time recovery (see the field 'recoveryTarget' of KubegresRestore),
+        # otherwise the Replica would stop replicating once it reaches the recovery target
+        sed -i '/^restore_command/d;/^recovery_target/d' $PGDATA/postgresql.auto.conf;
+
         if [ $UID == 0 ]
         then
         chown -R postgres:postgres $PGDATA;
@@ -349,6 +509,190 @@ data:
     echo "$dt - Promoting by creating the promotion trigger file: '$promotionTriggerFilePath'"
     touch $promotionTriggerFilePath
 
+
+  # This script rewinds the data of a reused PVC with pg_rewind against the Primary, so that it rejoins the cluster as
+  # a Replica without copying the whole Primary database.
+  # It is executed when the PVC of a failed Primary is reused by a new Replica and the field
+  # 'failover.rewindFailedPrimary' is set to true, when the PVC of a removed StatefulSet is reused by a new Replica
+  # (see the field 'failover.pvc'), or when the former Primary re-attaches as a Replica after a planned switchover
+  # (see the field 'switchover.targetPod'). The data of a removed Replica is rewound too, since it may have
+  # diverged from the Primary, for example if it replicated a former Primary.
+  # It is run in the Replica container rejoining the cluster, once per deployment of its StatefulSet.
+  #
+  # If the rewind fails, the data of the reused PVC is removed and a copy of the Primary DB is made by the script
+  # 'copy_primary_data_to_replica.sh'.
+  #
+  # If you modify this script, there is a risk of breaking the operator.
+  #
+  # This script will be located in the folder "/tmp"
+  rewind_failed_primary_to_replica.sh: |
+    #!/bin/bash
+    set -e
+
+    dt=$(date '+%d/%m/%Y %H:%M:%S');
+    echo "$dt - Attempting to rewind the DB of a reused PVC to rejoin as a Replica DB...";
+
+    # The data is rewound once per deployment of the StatefulSet, and not when its Pod restarts
+    rewindIdFilePath="$(dirname $PGDATA)/kubegres_rewind_id"
+
+    if [ -n "$(ls -A $PGDATA)" ] && [ "$(cat $rewindIdFilePath 2>/dev/null)" != "$KUBEGRES_REWIND_ID" ]; then
+
+        # Without 'standby.signal', pg_rewind can run the crash recovery of a DB which was not shut down cleanly
+        rm -f $PGDATA/postmaster.pid $PGDATA/promote_replica_to_primary.log $PGDATA/standby.signal;
+
+        echo "$dt - Running: pg_rewind --target-pgdata=$PGDATA --source-server='host=$PRIMARY_HOST_NAME user=postgres dbname=postgres' --progress";
+
+        rewindExitCode=0
+        if [ $UID == 0 ]
+        then
+        chown -R postgres:postgres $PGDATA;
+        PGPASSWORD="$POSTGRES_PASSWORD" su -p postgres -s /bin/bash -c "pg_rewind --target-pgdata=$PGDATA --source-server='host=$PRIMARY_HOST_NAME user=postgres dbname=postgres' --progress" || rewindExitCode=$?
+        else
+        PGPASSWORD="$POSTGRES_PASSWORD" pg_rewind --target-pgdata=$PGDATA --source-server="host=$PRIMARY_HOST_NAME user=postgres dbname=postgres" --progress || rewindExitCode=$?
+        fi
+
+        if [ $rewindExitCode -eq 0 ]; then
+
+            echo "$dt - Rewind completed. Configuring the rewound DB as a Replica DB";
+            touch $PGDATA/standby.signal;
+            # Removes the fencing of writes set on the former Primary during a planned switchover
+            sed -i '/^default_transaction_read_only/d' $PGDATA/postgresql.auto.conf;
+            # Removes the settings of a point-in-time recovery (see the field 'recoveryTarget' of KubegresRestore)
+            sed -i '/^restore_command/d;/^recovery_target/d' $PGDATA/postgresql.auto.conf;
+            sed -i '/^primary_conninfo/d' $PGDATA/postgresql.auto.conf;
+            # As with 'pg_basebackup -R', the password is quoted in the conninfo, whose value is then quoted in the config file
+            conninfoPassword=$(printf '%s' "$PGPASSWORD" | sed "s/[\\\\']/\\\\&/g");
+            primaryConninfo=$(printf '%s' "host=$PRIMARY_HOST_NAME user=replication password='$conninfoPassword'" | sed "s/[\\\\']/&&/g");
+            echo "primary_conninfo = '$primaryConninfo'" >> $PGDATA/postgresql.auto.conf;
+
+            if [ $UID == 0 ]
+            then
+            chown -R postgres:postgres $PGDATA;
+            fi
+
+        else
+            echo "$dt - Unable to rewind the DB of the reused PVC. Removing its data so that a copy of the Primary DB is made: $PGDATA";
+            rm -rf $PGDATA/*;
+        fi
+
+    else
+        echo "$dt - Skipping rewind because the DB folder is empty or it was already rewound";
+    fi
+
+    /tmp/copy_primary_data_to_replica.sh
+
+    echo -n "$KUBEGRES_REWIND_ID" > $rewindIdFilePath
+
+
+  # This script archives a WAL segment when the field 'backup.walArchive.enabled' is set to true.
+  # PostgreSql runs it with 'archive_command' for each completed WAL segment. Only the Primary archives WAL segments.
+  #
+  # It copies the segment into the folder $WAL_ARCHIVE_FOLDER, which is in the PVC 'backup.pvcName' or, if the field
+  # 'backup.walArchive.s3' is set, a spool folder in the PVC of the database from which the container
+  # 'wal-archive-uploader' uploads the segments to S3.
+  #
+  # If you modify this script, there is a risk of breaking the operator.
+  #
+  # This script will be located in the folder "/tmp"
+  archive_wal.sh: |
+    #!/bin/bash
+    set -e
+
+    walFilePath=$1
+    walFileName=$2
+    archivedWalFilePath="$WAL_ARCHIVE_FOLDER/$walFileName"
+
+    mkdir -p $WAL_ARCHIVE_FOLDER
+
+    if [ -f "$archivedWalFilePath" ]; then
+        # PostgreSql may archive again a segment which was archived before a crash. It succeeds if both are identical.
+        cmp -s $walFilePath $archivedWalFilePath && exit 0
+        echo "The WAL segment '$archivedWalFilePath' is already archived with a different content";
+        exit 1
+    fi
+
+    cp $walFilePath $archivedWalFilePath.tmp
+    mv $archivedWalFilePath.tmp $archivedWalFilePath
+
+
+  # This script uploads to S3 the WAL segments archived by the script 'archive_wal.sh' in the spool folder
+  # $WAL_ARCHIVE_FOLDER, when the field 'backup.walArchive.s3' is set. A segment is removed from the spool folder
+  # once it is uploaded.
+  # It is run in the container 'wal-archive-uploader' of the Primary and Replica Pods.
+  #
+  # If you modify this script, there is a risk of breaking the operator.
+  #
+  # This script will be located in the folder "/tmp"
+  upload_archived_wal_to_s3.sh: |
+    #!/bin/bash
+
+    awsOptions=""
+    if [ -n "$S3_ENDPOINT" ]; then
+        awsOptions="--endpoint-url $S3_ENDPOINT"
+    fi
+    if [ "$S3_NO_VERIFY_SSL" == "true" ]; then
+        awsOptions="$awsOptions --no-verify-ssl"
+    fi
+
+    mkdir -p $WAL_ARCHIVE_FOLDER
+
+    dt=$(date '+%d/%m/%Y %H:%M:%S');
+    echo "$dt - Uploading the WAL segments archived in '$WAL_ARCHIVE_FOLDER' to '$WAL_ARCHIVE_S3_URL'";
+
+    while true; do
+        for walFilePath in $WAL_ARCHIVE_FOLDER/*; do
+
+            if [ ! -f "$walFilePath" ] || [[ "$walFilePath" == *.tmp ]]; then
+                continue
+            fi
+
+            if aws s3 cp $awsOptions --only-show-errors $walFilePath $WAL_ARCHIVE_S3_URL/$(basename $walFilePath); then
+                rm -f $walFilePath
+            else
+                dt=$(date '+%d/%m/%Y %H:%M:%S');
+                echo "$dt - Unable to upload the WAL segment '$walFilePath'. It will be retried.";
+                break
+            fi
+        done
+
+        sleep 10
+    done
+
+
+  # If the field 'backup.type' is set to 'physical', which is its default value when 'backup.walArchive.enabled' is
+  # true, this bash script takes a base backup with pg_basebackup into a given destination-volume, instead of the
+  # logical backup taken by the script 'backup_database.sh'.
+  # A base backup can be restored with the archived WAL segments up to a point in time
+  # (see the field 'recoveryTarget' of KubegresRestore).
+  # It is triggered to run regularly by a Kubernetes Cronjob.
+  #
+  # It runs in a Replica container in order to not impact the performance of Primary. If there is no Replica then it runs in a Primary container.
+  #
+  # If you modify this script, there is a risk of breaking the operator.
+  #
+  base_backup_database.sh: |
+    #!/bin/bash
+    set -e
+    set -o pipefail
+
+    dt=$(date '+%d/%m/%Y %H:%M:%S');
+    fileDt=$(date '+%d_%m_%Y_%H_%M_%S');
+    backUpFileName="$KUBEGRES_RESOURCE_NAME-basebackup-$fileDt.tar.gz"
+    backUpFilePath="$BACKUP_DESTINATION_FOLDER/$backUpFileName"
+
+    echo "$dt - Starting DB base backup of Kubegres resource $KUBEGRES_RESOURCE_NAME into file: $backUpFilePath";
+    echo "$dt - Running: pg_basebackup -h $BACKUP_SOURCE_DB_HOST_NAME -U replication -D - -Ft -X fetch | gzip > $backUpFilePath"
+
+    if ! PGPASSWORD="$POSTGRES_REPLICATION_PASSWORD" pg_basebackup -h $BACKUP_SOURCE_DB_HOST_NAME -U replication -D - -Ft -X fetch | gzip > $backUpFilePath; then
+      rm -f $backUpFilePath
+      echo "Unable to execute a base BackUp. Please check DB connection settings"
+      exit 1
+    fi
+
+    echo "$dt - DB base backup completed for Kubegres resource $KUBEGRES_RESOURCE_NAME into file: $backUpFilePath";
+
+    echo "fileName=$backUpFileName" > /dev/termination-log
+    echo "sizeBytes=$(stat -c %s $backUpFilePath)" >> /dev/termination-log
 `
 FileCheckerPodTemplate = `apiVersion: v1
 kind: Pod
@@ -366,7 +710,13 @@ spec:
       if [ ! -f "${RESTOREPOINT_FILEPATH}" ]; then
         ls $(dirname "${RESTOREPOINT_FILEPATH}") -t -p | grep -v / | head -n5 > /dev/termination-log;
         exit 2;
-      else exit 0; fi
+      fi
+      if tar -tzf "${RESTOREPOINT_FILEPATH}" 2>/dev/null | grep -q -E '^(\./)?PG_VERSION$'; then
+        echo -n "physical" > /dev/termination-log;
+      else
+        echo -n "logical" > /dev/termination-log;
+      fi
+      exit 0;
     volumeMounts:
     - name: backup-volume
       mountPath: toBeReplaced
@@ -383,6 +733,494 @@ spec:
     persistentVolumeClaim:
       claimName: toBeReplaced
 `
+FormerPrimaryCheckerPodTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: postgres-name-switchover-checker
+  labels:
+    role: switchover-checker
+
+# Reads, from the PVC of a Primary stopped during a Switchover, whether it was shut down cleanly and the location of
+# its shutdown checkpoint, which is the last WAL record it wrote. The Replica to promote must have replayed it.
+spec:
+  restartPolicy: Never
+  terminationGracePeriodSeconds: 5
+
+  containers:
+    - name: switchover-checker
+      image: postgres:latest
+      imagePullPolicy: IfNotPresent
+      args:
+        - sh
+        - -c
+        - |
+          controlData=$(pg_controldata "$PGDATA") || exit 1
+          state=$(echo "$controlData" | sed -n 's/^Database cluster state: *//p')
+          checkpoint=$(echo "$controlData" | sed -n 's/^Latest checkpoint location: *//p')
+          echo "state=$state" > /dev/termination-log
+          echo "checkpoint=$checkpoint" >> /dev/termination-log
+      env:
+        - name: PGDATA
+          value: toBeReplaced
+        - name: LC_ALL
+          value: C
+      volumeMounts:
+        - name: postgres-db
+          mountPath: toBeReplaced
+      resources:
+        limits:
+          memory: "64Mi"
+          cpu: "100m"
+
+  volumes:
+    - name: postgres-db
+      persistentVolumeClaim:
+        claimName: toBeReplaced
+`
+MajorVersionUpgradeJobTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: postgres-name-major-version-upgrade
+  labels:
+    role: major-version-upgrade
+spec:
+  backoffLimit: 0
+
+  template:
+    spec:
+      restartPolicy: Never
+
+      volumes:
+        - name: postgres-db
+          persistentVolumeClaim:
+            claimName: toBeReplaced
+        - name: former-major-version-lib
+          emptyDir: {}
+        - name: former-major-version-share
+          emptyDir: {}
+        - name: former-major-version-runtime
+          emptyDir: {}
+
+      # Copies the binaries of the former major version of PostgreSql, so that pg_upgrade can run both versions.
+      # The shared libraries they depend on are copied as well, since the image of the new major version may not have
+      # them or have other versions of them. Only the libraries of the C library are not copied: it is backward
+      # compatible and it must match the dynamic loader of the image. The former binaries are run by wrappers setting
+      # LD_LIBRARY_PATH, so that the binaries of the new major version keep using their own libraries.
+      initContainers:
+        - name: copy-former-major-version
+          image: postgres:former
+          imagePullPolicy: IfNotPresent
+          args:
+            - sh
+            - -c
+            - |
+              set -e
+              FORMER_LIB=/usr/lib/postgresql/$FROM_MAJOR_VERSION
+              cp -a $FORMER_LIB/. /tmp/former-lib/
+              cp -a /usr/share/postgresql/$FROM_MAJOR_VERSION/. /tmp/former-share/
+              mkdir -p /tmp/former-runtime/lib /tmp/former-runtime/bin
+
+              for file in $FORMER_LIB/bin/* $FORMER_LIB/lib/*.so; do
+                ldd "$file" 2>/dev/null | awk '$2 == "=>" && $3 ~ /^\// { print $3 }' || true
+              done | sort -u | grep -Ev '/(ld-linux[^/]*|libc|libm|libdl|libpthread|librt|libresolv|libutil)\.so' | while read lib; do
+                cp -L "$lib" /tmp/former-runtime/lib/
+              done
+
+              for file in $FORMER_LIB/bin/*; do
+                wrapper=/tmp/former-runtime/bin/$(basename $file)
+                printf '#!/bin/sh\nLD_LIBRARY_PATH=/tmp/former-runtime/lib exec %s "$@"\n' "$file" > $wrapper
+                chmod 755 $wrapper
+              done
+          env:
+            - name: FROM_MAJOR_VERSION
+              value: toBeReplaced
+          volumeMounts:
+            - name: former-major-version-lib
+              mountPath: /tmp/former-lib
+            - name: former-major-version-share
+              mountPath: /tmp/former-share
+            - name: former-major-version-runtime
+              mountPath: /tmp/former-runtime
+
+      containers:
+        - name: upgrade-major-version
+          image: postgres:latest
+          imagePullPolicy: IfNotPresent
+          args:
+            - bash
+            - -c
+            - |
+              set -e
+
+              dt=$(date '+%d/%m/%Y %H:%M:%S');
+              NEW_PGDATA="${PGDATA}_new"
+              FORMER_PGDATA="${PGDATA}_${FROM_MAJOR_VERSION}"
+              FORMER_BINDIR=/tmp/former-runtime/bin
+
+              if [ "$(cat $PGDATA/PG_VERSION)" == "$TO_MAJOR_VERSION" ]; then
+                  echo "$dt - The DB folder '$PGDATA' is already upgraded to the major version $TO_MAJOR_VERSION";
+                  exit 0;
+              fi
+
+              if [ -e "$FORMER_PGDATA" ]; then
+                  echo "$dt - The folder '$FORMER_PGDATA' already exists. It must be moved or removed manually before upgrading.";
+                  exit 1;
+              fi
+
+              run_as_postgres() {
+                  if [ $UID == 0 ]
+                  then
+                  su -p postgres -s /bin/bash -c "$1"
+                  else
+                  bash -c "$1"
+                  fi
+              }
+
+              if [ $UID == 0 ]
+              then
+              chown -R postgres:postgres $PGDATA;
+              fi
+
+              # The new DB folder must have the same encoding and locale as the former one, otherwise pg_upgrade fails.
+              # They are read from the database 'template0' by starting the former major version locally.
+              echo "$dt - Reading the encoding and the locale of the DB folder of the major version $FROM_MAJOR_VERSION";
+              export PGPASSWORD="$POSTGRES_PASSWORD"
+              FORMER_SERVER_OPTIONS="-c listen_addresses='' -c unix_socket_directories=/tmp -c config_file=$PGDATA/postgresql.conf -c hba_file=$PGDATA/pg_hba.conf"
+              QUERY_FORMER_SERVER="psql -h /tmp -U postgres -d template1 -v ON_ERROR_STOP=1 -At -F ' ' -c"
+              ICU_LOCALE_COLUMN=daticulocale
+              if [ "$FROM_MAJOR_VERSION" -ge 17 ]; then
+                  ICU_LOCALE_COLUMN=datlocale
+              fi
+
+              localeExitCode=0
+              ICU_LOCALE=""
+              run_as_postgres "$FORMER_BINDIR/pg_ctl --pgdata=$PGDATA --wait --options=\"$FORMER_SERVER_OPTIONS\" start" || localeExitCode=$?
+              if [ $localeExitCode -eq 0 ]; then
+                  ENCODING_AND_LOCALE=$(run_as_postgres "$QUERY_FORMER_SERVER \"SELECT pg_encoding_to_char(encoding), datcollate, datctype FROM pg_database WHERE datname = 'template0'\"") || localeExitCode=$?
+                  if [ $localeExitCode -eq 0 ] && [ "$FROM_MAJOR_VERSION" -ge 15 ]; then
+                      ICU_LOCALE=$(run_as_postgres "$QUERY_FORMER_SERVER \"SELECT $ICU_LOCALE_COLUMN FROM pg_database WHERE datname = 'template0' AND datlocprovider = 'i'\"") || localeExitCode=$?
+                  fi
+                  run_as_postgres "$FORMER_BINDIR/pg_ctl --pgdata=$PGDATA --wait --mode=fast stop"
+              fi
+
+              if [ $localeExitCode -ne 0 ] || [ -z "$ENCODING_AND_LOCALE" ]; then
+                  echo "$dt - Unable to read the encoding and the locale of the DB folder of the major version $FROM_MAJOR_VERSION";
+                  exit 1;
+              fi
+
+              read DB_ENCODING DB_COLLATE DB_CTYPE <<< "$ENCODING_AND_LOCALE"
+              INITDB_LOCALE_ARGS="--encoding=$DB_ENCODING --lc-collate=$DB_COLLATE --lc-ctype=$DB_CTYPE"
+              if [ -n "$ICU_LOCALE" ]; then
+                  INITDB_LOCALE_ARGS="$INITDB_LOCALE_ARGS --locale-provider=icu --icu-locale=$ICU_LOCALE"
+              fi
+
+              echo "$dt - Initialising a DB folder for the major version $TO_MAJOR_VERSION with: $INITDB_LOCALE_ARGS";
+              rm -rf $NEW_PGDATA;
+              mkdir -p $NEW_PGDATA;
+              chmod 700 $NEW_PGDATA;
+              if [ $UID == 0 ]
+              then
+              chown -R postgres:postgres $NEW_PGDATA;
+              fi
+              run_as_postgres "initdb --username=postgres --pgdata=$NEW_PGDATA $POSTGRES_INITDB_ARGS $INITDB_LOCALE_ARGS"
+
+              echo "$dt - Running: pg_upgrade --link from the major version $FROM_MAJOR_VERSION to $TO_MAJOR_VERSION";
+              cd /tmp;
+              upgradeExitCode=0
+              run_as_postgres "pg_upgrade --link --username=postgres \
+                --old-bindir=$FORMER_BINDIR --new-bindir=/usr/lib/postgresql/$TO_MAJOR_VERSION/bin \
+                --old-datadir=$PGDATA --new-datadir=$NEW_PGDATA" || upgradeExitCode=$?
+
+              if [ $upgradeExitCode -ne 0 ]; then
+                  echo "$dt - pg_upgrade failed. Restoring the DB folder of the major version $FROM_MAJOR_VERSION: $PGDATA";
+                  if [ -f "$PGDATA/global/pg_control.old" ]; then
+                      mv $PGDATA/global/pg_control.old $PGDATA/global/pg_control;
+                  fi
+                  rm -rf $NEW_PGDATA;
+                  exit $upgradeExitCode;
+              fi
+
+              cp $PGDATA/postgresql.auto.conf $NEW_PGDATA/postgresql.auto.conf;
+              mv $PGDATA $FORMER_PGDATA;
+              mv $NEW_PGDATA $PGDATA;
+
+              # With '--link', the data files are hard links shared by both DB folders. Once the new major version has
+              # started, it writes into them, so the former DB folder cannot be used to roll back.
+              echo "$dt - Upgrade completed. The former DB folder is moved to '$FORMER_PGDATA'. It shares its data files with the upgraded DB folder and it cannot be started once the upgraded DB has started. It can be removed once the DB is checked.";
+
+          env:
+            - name: PGDATA
+              value: toBeReplaced
+            - name: FROM_MAJOR_VERSION
+              value: toBeReplaced
+            - name: TO_MAJOR_VERSION
+              value: toBeReplaced
+
+          volumeMounts:
+            - name: postgres-db
+              mountPath: toBeReplaced
+            - name: former-major-version-lib
+              mountPath: toBeReplaced
+            - name: former-major-version-share
+              mountPath: toBeReplaced
+            - name: former-major-version-runtime
+              mountPath: /tmp/former-runtime
+`
+ObjectStoreFileCheckerPodTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: kubegres-file-checker
+spec:
+  containers:
+  - image: amazon/aws-cli:latest
+    name: object-store-file-checker
+    command:
+    - bash
+    - -c
+    - |
+      awsOptions=""
+      if [ -n "$S3_ENDPOINT" ]; then
+        awsOptions="--endpoint-url $S3_ENDPOINT"
+      fi
+      if [ "$S3_NO_VERIFY_SSL" == "true" ]; then
+        awsOptions="$awsOptions --no-verify-ssl"
+      fi
+      echo "Looking for '${SNAPSHOT_S3_URL}'...";
+      snapshotsFolderUrl="$(dirname "${SNAPSHOT_S3_URL}")/";
+      if ! aws s3 ls $awsOptions "${SNAPSHOT_S3_URL}" | awk '{print $4}' | grep -q -x -F "$(basename "${SNAPSHOT}")"; then
+        aws s3 ls $awsOptions "${snapshotsFolderUrl}" | grep -v " PRE " | sort -r | head -n5 | awk '{print $4}' > /dev/termination-log;
+        exit 2;
+      fi
+      case "${SNAPSHOT}" in
+        *.tar.gz|*.tar) echo -n "physical" > /dev/termination-log ;;
+        *) echo -n "logical" > /dev/termination-log ;;
+      esac
+      exit 0;
+    resources:
+      limits:
+        memory: "128Mi"
+        cpu: "100m"
+    env:
+    - name: SNAPSHOT_S3_URL
+      value: toBeReplaced
+    - name: SNAPSHOT
+      value: toBeReplaced
+  restartPolicy: Never
+`
+PhysicalRestoreJobTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job-restore-mypostgres
+  labels:
+    app: postgres-db
+    replicationRole: none
+    clusterName: mypostgres
+    role: restore
+spec:
+  backoffLimit: 0
+
+  template:
+    spec:
+      restartPolicy: Never
+
+      volumes:
+        - name: backup-volume
+          persistentVolumeClaim:
+            claimName: toBeReplaced
+        - name: postgres-db
+          persistentVolumeClaim:
+            claimName: toBeReplaced
+
+      # Downloads the archived WAL segments from S3. It is removed if the archived WAL segments are in the backup PVC.
+      initContainers:
+        - name: download-archived-wal
+          image: amazon/aws-cli:latest
+          imagePullPolicy: IfNotPresent
+          terminationMessagePolicy: FallbackToLogsOnError
+          command:
+            - bash
+            - -c
+            - |
+              awsOptions=""
+              if [ -n "$S3_ENDPOINT" ]; then
+                  awsOptions="--endpoint-url $S3_ENDPOINT"
+              fi
+              if [ "$S3_NO_VERIFY_SSL" == "true" ]; then
+                  awsOptions="$awsOptions --no-verify-ssl"
+              fi
+
+              dt=$(date '+%d/%m/%Y %H:%M:%S');
+              echo "$dt - Downloading the archived WAL segments from '$WAL_ARCHIVE_S3_URL' into '$WAL_RESTORE_FOLDER'";
+
+              mkdir -p $WAL_RESTORE_FOLDER
+              aws s3 cp $awsOptions --only-show-errors --recursive $WAL_ARCHIVE_S3_URL $WAL_RESTORE_FOLDER
+          env:
+            - name: WAL_RESTORE_FOLDER
+              value: toBeReplaced
+            - name: WAL_ARCHIVE_S3_URL
+              value: toBeReplaced
+          volumeMounts:
+            - name: postgres-db
+              mountPath: toBeReplaced
+
+      containers:
+        - name: postgres-restore
+          image: postgres:latest
+          imagePullPolicy: IfNotPresent
+          terminationMessagePolicy: FallbackToLogsOnError
+          args:
+            - bash
+            - -c
+            - |
+              set -e
+
+              dt=$(date '+%d/%m/%Y %H:%M:%S');
+
+              if [ -n "$(ls -A $PGDATA 2>/dev/null)" ]; then
+                  echo "$dt - The DB folder '$PGDATA' is not empty. A base backup can only be restored in an empty DB folder.";
+                  exit 1;
+              fi
+
+              echo "$dt - Restoring the base backup '$RESTOREPOINT_FILEPATH' into the DB folder '$PGDATA'";
+              mkdir -p $PGDATA $WAL_RESTORE_FOLDER;
+              tar -xzf $RESTOREPOINT_FILEPATH -C $PGDATA;
+              rm -f $PGDATA/postmaster.pid $PGDATA/standby.signal $PGDATA/promote_replica_to_primary.log;
+
+              touch $PGDATA/postgresql.auto.conf;
+              sed -i '/^restore_command/d;/^recovery_target/d;/^primary_conninfo/d;/^default_transaction_read_only/d' $PGDATA/postgresql.auto.conf;
+
+              # Without a recovery target, the WAL segments fetched in the base backup are replayed when the Primary starts
+              if [ -n "$RECOVERY_TARGET_SETTINGS" ]; then
+                  if [ -n "$WAL_ARCHIVE_FOLDER" ]; then
+                      echo "$dt - Copying the archived WAL segments from '$WAL_ARCHIVE_FOLDER' into '$WAL_RESTORE_FOLDER'";
+                      if [ -n "$(ls -A $WAL_ARCHIVE_FOLDER 2>/dev/null)" ]; then
+                          cp $WAL_ARCHIVE_FOLDER/* $WAL_RESTORE_FOLDER/;
+                      fi
+                  fi
+
+                  echo "$dt - Configuring the recovery up to the target: $RECOVERY_TARGET_SETTINGS";
+                  echo "restore_command = 'cp $WAL_RESTORE_FOLDER/%f %p'" >> $PGDATA/postgresql.auto.conf;
+                  echo "$RECOVERY_TARGET_SETTINGS" >> $PGDATA/postgresql.auto.conf;
+                  touch $PGDATA/recovery.signal;
+              fi
+
+              chmod 700 $PGDATA;
+              if [ $UID == 0 ]
+              then
+              chown -R postgres:postgres $PGDATA $WAL_RESTORE_FOLDER;
+              fi
+
+              echo "$dt - Base backup restored. It is recovered once the Primary starts.";
+
+          env:
+            - name: PGDATA
+              value: toBeReplaced
+            - name: RESTOREPOINT_FILEPATH
+              value: toBeReplaced
+            - name: WAL_ARCHIVE_FOLDER
+              value: toBeReplaced
+            - name: WAL_RESTORE_FOLDER
+              value: toBeReplaced
+            - name: RECOVERY_TARGET_SETTINGS
+              value: toBeReplaced
+
+          volumeMounts:
+            - name: backup-volume
+              mountPath: toBeReplaced
+            - name: postgres-db
+              mountPath: toBeReplaced
+`
+PoolerDeploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: postgres-name-pooler
+  namespace: default
+  labels:
+    app: postgres-name-pooler
+
+spec:
+  replicas: 1
+
+  selector:
+    matchLabels:
+      app: postgres-name-pooler
+
+  template:
+    metadata:
+      labels:
+        app: postgres-name-pooler
+
+    spec:
+      volumes:
+        - name: pooler-config
+          configMap:
+            name: toBeReplaced
+            items:
+              - key: toBeReplaced
+                path: pgbouncer.ini
+
+        - name: pooler-auth
+          emptyDir:
+            medium: Memory
+
+      containers:
+        - name: pgbouncer
+          image: edoburu/pgbouncer:latest
+          imagePullPolicy: IfNotPresent
+          env:
+            - name: POSTGRES_POOLER_PASSWORD
+              value: toBeReplaced
+
+            - name: POOLER_AUTH_USER
+              value: toBeReplaced
+
+          # The auth file only contains the user which PgBouncer uses to look up the passwords of the other users.
+          # Its password is written from the env-var, so that it is never stored by Kubegres.
+          command:
+            - sh
+            - -c
+            - |
+              password=$(printf '%s' "$POSTGRES_POOLER_PASSWORD" | sed 's/"/""/g')
+              printf '"%s" "%s"\n' "$POOLER_AUTH_USER" "$password" > /var/run/pgbouncer-kubegres/userlist.txt
+              exec pgbouncer /etc/pgbouncer-kubegres/pgbouncer.ini
+
+          ports:
+            - containerPort: 6432
+
+          livenessProbe:
+            tcpSocket:
+              port: 6432
+            initialDelaySeconds: 10
+            periodSeconds: 20
+
+          readinessProbe:
+            tcpSocket:
+              port: 6432
+            initialDelaySeconds: 5
+            periodSeconds: 10
+
+          volumeMounts:
+            - name: pooler-config
+              mountPath: /etc/pgbouncer-kubegres
+              readOnly: true
+
+            - name: pooler-auth
+              mountPath: /var/run/pgbouncer-kubegres
+`
+PoolerServiceTemplate = `apiVersion: v1
+kind: Service
+metadata:
+  name: postgres-name-pooler
+  namespace: default
+  labels:
+    app: postgres-name-pooler
+spec:
+  ports:
+    - protocol: TCP
+      port: 6432
+  selector:
+    app: postgres-name-pooler
+`
 PrimaryServiceTemplate = `apiVersion: v1
 kind: Service
 metadata:
@@ -452,7 +1290,7 @@ spec:
         - name: postgres-name-0
           image: postgres:latest
           imagePullPolicy: IfNotPresent
-          args: ["-c", "config_file=/etc/postgres.conf", "-c", "hba_file=/etc/pg_hba.conf"]
+          args: ["-c", "config_file=/etc/postgres.conf", "-c", "hba_file=/etc/pg_hba.conf", "-c", "cluster_name=$(POD_NAME)"]
 
           ports:
             - containerPort: 5432
@@ -465,6 +1303,12 @@ spec:
                   apiVersion: v1
                   fieldPath: status.podIP
 
+            - name: POD_NAME
+              valueFrom:
+                fieldRef:
+                  apiVersion: v1
+                  fieldPath: metadata.name
+
           livenessProbe:
             exec:
               command:
@@ -509,6 +1353,21 @@ spec:
               mountPath: /etc/pg_hba.conf
               subPath: pg_hba.conf
 `
+ReplicaReadyServiceTemplate = `apiVersion: v1
+kind: Service
+metadata:
+  name: postgres-name-replica-ready
+  namespace: default
+  labels:
+    app: postgres-name
+spec:
+  ports:
+    - protocol: TCP
+      port: 5432
+  selector:
+    app: postgres-name
+    kubegres.reactive-tech.io/replica-ready: "true"
+`
 ReplicaServiceTemplate = `apiVersion: v1
 kind: Service
 metadata:
@@ -600,7 +1459,7 @@ spec:
         - name: postgres-name-1
           image: postgres:latest
           imagePullPolicy: IfNotPresent
-          args: ["-c", "config_file=/etc/postgres.conf", "-c", "hba_file=/etc/pg_hba.conf", "-c", "promote_trigger_file=$(PGDATA)/promote_replica_to_primary.log"]
+          args: ["-c", "config_file=/etc/postgres.conf", "-c", "hba_file=/etc/pg_hba.conf", "-c", "promote_trigger_file=$(PGDATA)/promote_replica_to_primary.log", "-c", "cluster_name=$(POD_NAME)"]
 
           ports:
             - containerPort: 5432
@@ -613,6 +1472,12 @@ spec:
                   apiVersion: v1
                   fieldPath: status.podIP
 
+            - name: POD_NAME
+              valueFrom:
+                fieldRef:
+                  apiVersion: v1
+                  fieldPath: metadata.name
+
           livenessProbe:
             exec:
               command:
@@ -677,6 +1542,7 @@ spec:
       containers:
         - name: postgres-restore
           image: postgres:latest
+          terminationMessagePolicy: FallbackToLogsOnError
           args: 
             - sh
             - -c
@@ -700,4 +1566,76 @@ spec:
             - name: RESTOREPOINT_FILEPATH
               value: toBeReplaced
 `
+VerificationJobTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job-verify-mypostgres
+  labels:
+    app: postgres-db
+    replicationRole: none
+    clusterName: mypostgres
+    role: restore-verification
+spec:
+  backoffLimit: 0
+
+  template:
+    spec:
+      restartPolicy: Never
+
+      containers:
+        - name: postgres-verify
+          image: postgres:latest
+          imagePullPolicy: IfNotPresent
+          command:
+            - bash
+            - -c
+            - |
+              nbreFailedChecks=0
+              : > /dev/termination-log
+              for i in $(seq 0 $((VERIFY_CHECKS_COUNT - 1))); do
+                nameVar="VERIFY_CHECK_${i}_NAME"
+                databaseVar="VERIFY_CHECK_${i}_DATABASE"
+                queryVar="VERIFY_CHECK_${i}_QUERY"
+                expectedVar="VERIFY_CHECK_${i}_EXPECTED"
+                name="${!nameVar}"
+                expected="${!expectedVar}"
+
+                echo "$(date) - Running check '${name}' in database '${!databaseVar}'"
+                output=$(psql -h "${VERIFY_TARGET_DB_HOST_NAME}" -U postgres -w -d "${!databaseVar}" -v ON_ERROR_STOP=1 -tAX -c "${!queryVar}" 2>&1)
+                if [ $? -ne 0 ]; then
+                  message="$(echo "${output}" | grep -m1 'ERROR:' || echo "${output}" | tail -n1)"
+                elif [ -n "${expected}" ] && [ "${output}" != "${expected}" ]; then
+                  message="Expected '${expected}' but got '${output}'"
+                else
+                  printf 'PASS\t%s\n' "${name}" >> /dev/termination-log
+                  continue
+                fi
+
+                nbreFailedChecks=$((nbreFailedChecks + 1))
+                echo "$(date) - Check '${name}' has failed: ${message}"
+                printf 'FAIL\t%s\t%s\n' "${name}" "$(printf '%s' "${message}" | tr '\t\n' '  ' | cut -c1-200)" >> /dev/termination-log
+              done
+
+              if [ ${nbreFailedChecks} -gt 0 ]; then
+                echo "$(date) - ${nbreFailedChecks} check(s) have failed"
+                exit 1
+              fi
+              echo "$(date) - All checks have passed"
+
+          resources:
+            limits:
+              memory: "128Mi"
+              cpu: "100m"
+
+          env:
+            - name: PGPASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: toBeReplaced
+                  key: superUserPassword
+            - name: VERIFY_TARGET_DB_HOST_NAME
+              value: toBeReplaced
+            - name: VERIFY_CHECKS_COUNT
+              value: "0"
+`
 )
@@ -24,8 +24,11 @@ import (
 	batch "k8s.io/api/batch/v1"
 	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
 )
 
 type BackUpStates struct {
@@ -34,6 +37,21 @@ type BackUpStates struct {
 	ConfigMap               string
 	CronJobLastScheduleTime string
 	DeployedCronJob         *batch.CronJob
+	Jobs                    []batch.Job
+
+	// LastSucceededJob is the most recent Job which completed successfully. The name and the size of its backup file
+	// are read from the termination message of the backup container.
+	LastSucceededJob       *batch.Job
+	LastSucceededFileName  string
+	LastSucceededSizeBytes int64
+
+	// LastFailedJob is the most recent Job which failed after exhausting its retries
+	LastFailedJob  *batch.Job
+	LastFailedTime *metav1.Time
+
+	// RetainedBackUps are the backups retained by the field 'backup.retention' when the last Job completed
+	RetainedBackUps []string
+	LastPruneTime   *metav1.Time
 
 	kubegresContext ctx.KubegresContext
 }
@@ -61,6 +79,18 @@ func (r *BackUpStates) loadStates() (err error) {
 		if r.DeployedCronJob.Status.LastScheduleTime != nil {
 			r.CronJobLastScheduleTime = r.DeployedCronJob.Status.LastScheduleTime.String()
 		}
+
+		r.Jobs, err = r.getJobsCreatedByCronJob()
+		if err != nil {
+			return err
+		}
+
+		r.LastFailedJob, r.LastFailedTime = r.getLastFailedJob()
+
+		err = r.loadLastSucceededJobStates()
+		if err != nil {
+			return err
+		}
 	}
 
 	backUpPvc, err := r.getDeployedPvc()
@@ -95,6 +125,132 @@ func (r *BackUpStates) getDeployedCronJob() (*batch.CronJob, error) {
 	return cronJob, err
 }
 
+func (r *BackUpStates) getJobsCreatedByCronJob() ([]batch.Job, error) {
+
+	list := &batch.JobList{}
+	err := r.kubegresContext.Client.List(r.kubegresContext.Ctx, list, client.InNamespace(r.kubegresContext.Kubegres.Namespace))
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("BackUpJobLoadingErr", err, "Unable to load the Jobs created by the BackUp CronJob.", "CronJob name", r.DeployedCronJob.Name)
+		return nil, err
+	}
+
+	var jobs []batch.Job
+	for _, job := range list.Items {
+		for _, ownerReference := range job.OwnerReferences {
+			if ownerReference.UID == r.DeployedCronJob.UID {
+				jobs = append(jobs, job)
+				break
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// loadLastSucceededJobStates reads the termination messages of the containers of the Pod of the last succeeded Job:
+// the backup container reports the name and the size of the backup file, and the container removing the old backups
+// reports the backups retained by the field 'backup.retention'.
+func (r *BackUpStates) loadLastSucceededJobStates() error {
+
+	r.LastSucceededJob = r.getLastSucceededJob()
+	if r.LastSucceededJob == nil {
+		return nil
+	}
+
+	list := &v1.PodList{}
+	err := r.kubegresContext.Client.List(r.kubegresContext.Ctx, list,
+		client.InNamespace(r.kubegresContext.Kubegres.Namespace),
+		client.MatchingLabels{"job-name": r.LastSucceededJob.Name})
+	if err != nil {
+		r.kubegresContext.Log.ErrorEvent("BackUpJobPodLoadingErr", err, "Unable to load the Pods of a Job created by the BackUp CronJob.", "Job name", r.LastSucceededJob.Name)
+		return err
+	}
+
+	for _, pod := range list.Items {
+
+		containerStatuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for _, containerStatus := range containerStatuses {
+
+			terminated := containerStatus.State.Terminated
+			if terminated == nil || terminated.ExitCode != 0 {
+				continue
+			}
+
+			switch containerStatus.Name {
+			case ctx.BackUpContainerName:
+				r.LastSucceededFileName, r.LastSucceededSizeBytes = parseBackUpFileTerminationMessage(terminated.Message)
+
+			case ctx.BackUpPrunerContainerName:
+				r.loadRetainedBackUps(terminated.Message)
+				r.LastPruneTime = r.LastSucceededJob.Status.CompletionTime
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseBackUpFileTerminationMessage parses the lines "fileName=<name>" and "sizeBytes=<size>" written by the backup
+// script in the termination message of the backup container.
+func parseBackUpFileTerminationMessage(terminationMessage string) (fileName string, sizeBytes int64) {
+	for _, line := range strings.Split(terminationMessage, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "fileName":
+			fileName = value
+		case "sizeBytes":
+			sizeBytes, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return fileName, sizeBytes
+}
+
+func (r *BackUpStates) loadRetainedBackUps(terminationMessage string) {
+	for _, backUpFileName := range strings.Split(terminationMessage, "\n") {
+		if backUpFileName != "" {
+			r.RetainedBackUps = append(r.RetainedBackUps, backUpFileName)
+		}
+	}
+}
+
+func (r *BackUpStates) getLastSucceededJob() *batch.Job {
+
+	var lastSucceededJob *batch.Job
+
+	for i, job := range r.Jobs {
+		if job.Status.CompletionTime == nil {
+			continue
+		}
+		if lastSucceededJob == nil || job.Status.CompletionTime.After(lastSucceededJob.Status.CompletionTime.Time) {
+			lastSucceededJob = &r.Jobs[i]
+		}
+	}
+
+	return lastSucceededJob
+}
+
+func (r *BackUpStates) getLastFailedJob() (*batch.Job, *metav1.Time) {
+
+	var lastFailedJob *batch.Job
+	var lastFailedTime *metav1.Time
+
+	for i, job := range r.Jobs {
+		for _, condition := range job.Status.Conditions {
+
+			if condition.Type != batch.JobFailed || condition.Status != v1.ConditionTrue {
+				continue
+			}
+
+			if lastFailedTime == nil || condition.LastTransitionTime.After(lastFailedTime.Time) {
+				lastFailedJob = &r.Jobs[i]
+				lastFailedTime = condition.LastTransitionTime.DeepCopy()
+			}
+		}
+	}
+
+	return lastFailedJob, lastFailedTime
+}
+
 func (r *BackUpStates) getDeployedPvc() (*v1.PersistentVolumeClaim, error) {
 
 	namespace := r.kubegresContext.Kubegres.Namespace
@@ -32,14 +32,22 @@ const (
 	ConfigMapDataKeyPrimaryInitScript = "primary_init_script.sh"
 	ConfigMapDataKeyPgHbaConf         = "pg_hba.conf"
 	ConfigMapDataKeyBackUpScript      = "backup_database.sh"
+
+	ConfigMapDataKeyRewindFailedPrimaryScript = "rewind_failed_primary_to_replica.sh"
+	ConfigMapDataKeyArchiveWalScript          = "archive_wal.sh"
+	ConfigMapDataKeyUploadArchivedWalScript   = "upload_archived_wal_to_s3.sh"
+	ConfigMapDataKeyBaseBackUpScript          = "base_backup_database.sh"
 )
 
 type ConfigStates struct {
-	IsBaseConfigDeployed   bool
-	BaseConfigName         string
-	IsCustomConfigDeployed bool
-	CustomConfigName       string
-	ConfigLocations        ConfigLocations
+	IsBaseConfigDeployed        bool
+	IsRewindScriptDeployed      bool
+	IsWalArchiveScriptsDeployed bool
+	BaseConfigName              string
+	BaseConfigMap               core.ConfigMap
+	IsCustomConfigDeployed      bool
+	CustomConfigName            string
+	ConfigLocations             ConfigLocations
 
 	kubegresContext ctx.KubegresContext
 }
@@ -77,6 +85,11 @@ func (r *ConfigStates) loadStates() (err error) {
 
 	if r.isBaseConfigMap(baseConfigMap) {
 		r.IsBaseConfigDeployed = true
+		r.BaseConfigMap = *baseConfigMap
+		r.IsRewindScriptDeployed = baseConfigMap.Data[ConfigMapDataKeyRewindFailedPrimaryScript] != ""
+		r.IsWalArchiveScriptsDeployed = baseConfigMap.Data[ConfigMapDataKeyArchiveWalScript] != "" &&
+			baseConfigMap.Data[ConfigMapDataKeyUploadArchivedWalScript] != "" &&
+			baseConfigMap.Data[ConfigMapDataKeyBaseBackUpScript] != ""
 	}
 
 	if r.isBaseConfigAlsoCustomConfig() {
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+)
+
+// DbPvcStates contains the PVCs of the database volume of each Primary and Replica, including the PVCs whose
+// StatefulSet was removed.
+type DbPvcStates struct {
+	All []DbPvcWrapper
+
+	kubegresContext ctx.KubegresContext
+}
+
+type DbPvcWrapper struct {
+	InstanceIndex       int32
+	IsBound             bool
+	IsDeleting          bool
+	IsReusable          bool
+	IsFailedPrimary     bool
+	IsUsedByStatefulSet bool
+	Pvc                 core.PersistentVolumeClaim
+}
+
+func loadDbPvcStates(kubegresContext ctx.KubegresContext, statefulSetsStates statefulset.StatefulSetsStates) (DbPvcStates, error) {
+	dbPvcStates := DbPvcStates{kubegresContext: kubegresContext}
+	err := dbPvcStates.loadStates(statefulSetsStates)
+	return dbPvcStates, err
+}
+
+func (r *DbPvcStates) GetByInstanceIndex(instanceIndex int32) (DbPvcWrapper, bool) {
+	for _, dbPvc := range r.All {
+		if dbPvc.InstanceIndex == instanceIndex {
+			return dbPvc, true
+		}
+	}
+	return DbPvcWrapper{}, false
+}
+
+// GetReusable returns the PVCs which are not used by any StatefulSet, which are bound and not being deleted and
+// which were marked as reusable when their StatefulSet was removed. They are sorted by instance index.
+func (r *DbPvcStates) GetReusable() []DbPvcWrapper {
+	var reusableDbPvcs []DbPvcWrapper
+	for _, dbPvc := range r.All {
+		if !dbPvc.IsUsedByStatefulSet && dbPvc.IsBound && !dbPvc.IsDeleting && dbPvc.IsReusable {
+			reusableDbPvcs = append(reusableDbPvcs, dbPvc)
+		}
+	}
+	return reusableDbPvcs
+}
+
+func (r *DbPvcStates) loadStates(statefulSetsStates statefulset.StatefulSetsStates) error {
+
+	deployedPvcs, err := r.getDeployedPvcs()
+	if err != nil {
+		return err
+	}
+
+	for _, pvc := range deployedPvcs.Items {
+
+		instanceIndex, err := strconv.ParseInt(pvc.Labels["index"], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		// The StatefulSets create a PVC for each of their volumeClaimTemplates. We only keep the database PVCs.
+		statefulSetName := r.kubegresContext.GetStatefulSetResourceName(int32(instanceIndex))
+		if pvc.Name != r.kubegresContext.GetDatabasePvcResourceName(statefulSetName) {
+			continue
+		}
+
+		_, err = statefulSetsStates.All.GetByInstanceIndex(int32(instanceIndex))
+
+		r.All = append(r.All, DbPvcWrapper{
+			InstanceIndex:       int32(instanceIndex),
+			IsBound:             pvc.Status.Phase == core.ClaimBound,
+			IsDeleting:          pvc.DeletionTimestamp != nil,
+			IsReusable:          pvc.Annotations[ctx.ReusablePvcAnnotationKey] == "true",
+			IsFailedPrimary:     pvc.Annotations[ctx.FailedPrimaryPvcAnnotationKey] == "true",
+			IsUsedByStatefulSet: err == nil,
+			Pvc:                 pvc,
+		})
+	}
+
+	sort.Slice(r.All, func(i, j int) bool {
+		return r.All[i].InstanceIndex < r.All[j].InstanceIndex
+	})
+
+	return nil
+}
+
+// The StatefulSets set the labels of their selector in their PVCs, which includes the label "app" from which the
+// PVCs are indexed by the name of their Kubegres resource.
+func (r *DbPvcStates) getDeployedPvcs() (*core.PersistentVolumeClaimList, error) {
+
+	list := &core.PersistentVolumeClaimList{}
+	opts := []client.ListOption{
+		client.InNamespace(r.kubegresContext.Kubegres.Namespace),
+		client.MatchingFields{ctx.InstanceOwnerKey: r.kubegresContext.Kubegres.Name},
+	}
+	err := r.kubegresContext.Client.List(r.kubegresContext.Ctx, list, opts...)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		} else {
+			r.kubegresContext.Log.ErrorEvent("DatabasePvcLoadingErr", err, "Unable to load any deployed database PVCs.", "Kubegres name", r.kubegresContext.Kubegres.Name)
+		}
+	}
+
+	return list, err
+}
@@ -32,12 +32,22 @@ const (
 	UndefinedExitStatus    ExitStatus = "Undefined"
 )
 
+type SnapshotFormat string
+
+const (
+	// LogicalSnapshotFormat is a dump taken with pg_dumpall which is replayed with psql in a running cluster
+	LogicalSnapshotFormat SnapshotFormat = "logical"
+	// PhysicalSnapshotFormat is a tar taken with pg_basebackup which is unpacked in the data directory of the Primary
+	PhysicalSnapshotFormat SnapshotFormat = "physical"
+)
+
 type FileCheckerPodStates struct {
 	kubegresRestoreContext ctx.KubegresRestoreContext
 
 	IsPodDeployed       bool
 	IsPodTerminated     bool
 	ExitStatus          ExitStatus
+	SnapshotFormat      SnapshotFormat
 	MostRecentSnapshots []string
 
 	Pod *core.Pod
@@ -71,13 +81,18 @@ func (r *FileCheckerPodStates) loadStates() (err error) {
 
 		if exitCode == 0 {
 			r.ExitStatus = OkExitStatus
+			r.SnapshotFormat = r.getSnapshotFormat()
 			// r.kubegresRestoreContext.Status.SetSnapshotStatus(OkExitStatus)
 		} else {
 			r.ExitStatus = FileNotFoundExitStatus
 			r.MostRecentSnapshots = r.getMostRecentSnapshots()
 			// r.kubegresRestoreContext.Status.SetSnapshotStatus(FileNotFoundExitStatus)
-			fileSource := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.File
-			r.kubegresRestoreContext.Log.Info("Unable to find snapshot '" + fileSource.Snapshot + "' in PVC '" + fileSource.PvcName + "'")
+			if r.kubegresRestoreContext.IsObjectStoreSource() {
+				r.kubegresRestoreContext.Log.Info("Unable to find snapshot '" + r.kubegresRestoreContext.GetObjectStoreSnapshotUrl() + "'")
+			} else {
+				fileSource := r.kubegresRestoreContext.KubegresRestore.Spec.DataSource.File
+				r.kubegresRestoreContext.Log.Info("Unable to find snapshot '" + fileSource.Snapshot + "' in PVC '" + fileSource.PvcName + "'")
+			}
 		}
 	} else {
 		r.ExitStatus = UndefinedExitStatus
@@ -107,6 +122,15 @@ func (r *FileCheckerPodStates) getExitCodeFromContainer() int32 {
 	return r.Pod.Status.ContainerStatuses[0].State.Terminated.ExitCode
 }
 
+func (r *FileCheckerPodStates) getSnapshotFormat() SnapshotFormat {
+	// The termination message contains the format detected from the content of the snapshot.
+	rawMessage := strings.TrimSpace(r.Pod.Status.ContainerStatuses[0].State.Terminated.Message)
+	if rawMessage == string(PhysicalSnapshotFormat) {
+		return PhysicalSnapshotFormat
+	}
+	return LogicalSnapshotFormat
+}
+
 func (r *FileCheckerPodStates) getMostRecentSnapshots() []string {
 	// The termination message contains information about most recent snapshots in the PVC.
 	rawMessage := r.Pod.Status.ContainerStatuses[0].State.Terminated.Message
@@ -0,0 +1,135 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package states
+
+import (
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+)
+
+type KubegresBackupStates struct {
+	kubegresBackupContext ctx.KubegresBackupContext
+
+	IsClusterDeployed bool
+	Cluster           *v1.Kubegres
+
+	IsCronJobDeployed bool
+	CronJob           *batch.CronJob
+
+	IsJobDeployed  bool
+	IsJobSucceeded bool
+	IsJobFailed    bool
+	Job            *batch.Job
+
+	// FileName and SizeBytes are read from the termination message of the backup container once the Job has succeeded
+	FileName  string
+	SizeBytes int64
+}
+
+func LoadKubegresBackupStates(kubegresBackupContext ctx.KubegresBackupContext) (KubegresBackupStates, error) {
+	kubegresBackupStates := KubegresBackupStates{kubegresBackupContext: kubegresBackupContext}
+	err := kubegresBackupStates.loadStates()
+	return kubegresBackupStates, err
+}
+
+func (r *KubegresBackupStates) loadStates() (err error) {
+
+	r.Cluster = &v1.Kubegres{}
+	r.IsClusterDeployed, err = r.getResource(r.kubegresBackupContext.KubegresBackup.Spec.ClusterName, r.Cluster, "KubegresLoadingErr")
+	if err != nil {
+		return err
+	}
+
+	r.CronJob = &batch.CronJob{}
+	r.IsCronJobDeployed, err = r.getResource(r.kubegresBackupContext.GetClusterBackUpCronJobName(), r.CronJob, "BackUpCronJobLoadingErr")
+	if err != nil {
+		return err
+	}
+
+	r.Job = &batch.Job{}
+	r.IsJobDeployed, err = r.getResource(r.kubegresBackupContext.GetBackupJobName(), r.Job, "BackupJobLoadingErr")
+	if err != nil || !r.IsJobDeployed {
+		return err
+	}
+
+	for _, condition := range r.Job.Status.Conditions {
+		if condition.Status != core.ConditionTrue {
+			continue
+		}
+		if condition.Type == batch.JobComplete {
+			r.IsJobSucceeded = true
+		} else if condition.Type == batch.JobFailed {
+			r.IsJobFailed = true
+		}
+	}
+
+	if r.IsJobSucceeded {
+		return r.loadBackUpFile()
+	}
+
+	return nil
+}
+
+func (r *KubegresBackupStates) loadBackUpFile() error {
+
+	list := &core.PodList{}
+	err := r.kubegresBackupContext.Client.List(r.kubegresBackupContext.Ctx, list,
+		client.InNamespace(r.kubegresBackupContext.KubegresBackup.Namespace),
+		client.MatchingLabels{"job-name": r.Job.Name})
+	if err != nil {
+		r.kubegresBackupContext.Log.ErrorEvent("BackupJobPodLoadingErr", err, "Unable to load the Pods of the backup Job.", "Job name", r.Job.Name)
+		return err
+	}
+
+	for _, pod := range list.Items {
+		containerStatuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for _, containerStatus := range containerStatuses {
+
+			terminated := containerStatus.State.Terminated
+			if containerStatus.Name == ctx.BackUpContainerName && terminated != nil && terminated.ExitCode == 0 {
+				r.FileName, r.SizeBytes = parseBackUpFileTerminationMessage(terminated.Message)
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *KubegresBackupStates) getResource(resourceName string, resource client.Object, errorEventReason string) (bool, error) {
+
+	if resourceName == "" {
+		return false, nil
+	}
+
+	resourceKey := r.kubegresBackupContext.GetNamespacesresourceName(resourceName)
+	err := r.kubegresBackupContext.Client.Get(r.kubegresBackupContext.Ctx, resourceKey, resource)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		r.kubegresBackupContext.Log.ErrorEvent(errorEventReason, err, "Unable to load a resource of KubegresBackup.", "Resource name", resourceName)
+		return false, err
+	}
+
+	return true, nil
+}
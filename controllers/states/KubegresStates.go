@@ -32,6 +32,7 @@ type KubegresStates struct {
 	IsDeployed                 bool
 	IsReady                    bool
 	IsManagedByKubegresRestore bool
+	NbreDeployedReplicas       int32
 
 	Kubegres *v1.Kubegres
 }
@@ -72,6 +73,7 @@ func (r *KubegresStates) loadStates() (err error) {
 	r.IsDeployed = true
 	r.IsReady = statefulSetStates.Primary.IsReady && serviceStates.Primary.IsDeployed
 	r.IsManagedByKubegresRestore = r.isKubegresManagedByKubegresRestore()
+	r.NbreDeployedReplicas = statefulSetStates.Replicas.NbreDeployed
 
 	if !r.IsReady {
 		r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageWaitingForCluster)
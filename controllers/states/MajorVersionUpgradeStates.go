@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	batch "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type MajorVersionUpgradeStates struct {
+	IsJobDeployed  bool
+	IsJobSucceeded bool
+	IsJobFailed    bool
+	JobName        string
+	DeployedJob    *batch.Job
+
+	kubegresContext ctx.KubegresContext
+}
+
+func loadMajorVersionUpgradeStates(kubegresContext ctx.KubegresContext) (MajorVersionUpgradeStates, error) {
+	majorVersionUpgradeStates := MajorVersionUpgradeStates{kubegresContext: kubegresContext}
+	err := majorVersionUpgradeStates.loadStates()
+	return majorVersionUpgradeStates, err
+}
+
+func (r *MajorVersionUpgradeStates) loadStates() (err error) {
+
+	r.JobName = r.kubegresContext.GetMajorVersionUpgradeJobName()
+
+	r.DeployedJob, err = r.getDeployedJob()
+	if err != nil {
+		return err
+	}
+
+	if r.DeployedJob.Name != "" {
+		r.IsJobDeployed = true
+		r.IsJobSucceeded = r.DeployedJob.Status.Succeeded > 0
+		r.IsJobFailed = r.DeployedJob.Status.Failed > 0
+	}
+
+	return nil
+}
+
+func (r *MajorVersionUpgradeStates) getDeployedJob() (*batch.Job, error) {
+
+	namespace := r.kubegresContext.Kubegres.Namespace
+	resourceKey := client.ObjectKey{Namespace: namespace, Name: r.JobName}
+	job := &batch.Job{}
+
+	err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, resourceKey, job)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		} else {
+			r.kubegresContext.Log.ErrorEvent("MajorVersionUpgradeJobLoadingErr", err, "Unable to load the deployed Job upgrading the major version of PostgreSql.", "Job name", r.JobName)
+		}
+	}
+
+	return job, err
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type PoolerStates struct {
+	Primary   PoolerWrapper
+	Replica   PoolerWrapper
+	ConfigMap PoolerConfigMapWrapper
+
+	kubegresContext ctx.KubegresContext
+}
+
+type PoolerWrapper struct {
+	Name                 string
+	IsDeploymentDeployed bool
+	IsServiceDeployed    bool
+	IsReady              bool
+	Deployment           apps.Deployment
+	Service              core.Service
+}
+
+type PoolerConfigMapWrapper struct {
+	Name       string
+	IsDeployed bool
+	ConfigMap  core.ConfigMap
+}
+
+func loadPoolerStates(kubegresContext ctx.KubegresContext) (PoolerStates, error) {
+	poolerStates := PoolerStates{kubegresContext: kubegresContext}
+	err := poolerStates.loadStates()
+	return poolerStates, err
+}
+
+func (r *PoolerStates) loadStates() (err error) {
+
+	r.Primary, err = r.loadPoolerWrapper(true)
+	if err != nil {
+		return err
+	}
+
+	r.Replica, err = r.loadPoolerWrapper(false)
+	if err != nil {
+		return err
+	}
+
+	r.ConfigMap.Name = r.kubegresContext.GetPoolerConfigMapName()
+	r.ConfigMap.IsDeployed, err = r.getDeployedResource(r.ConfigMap.Name, &r.ConfigMap.ConfigMap)
+	return err
+}
+
+func (r *PoolerStates) loadPoolerWrapper(isPrimary bool) (poolerWrapper PoolerWrapper, err error) {
+
+	poolerWrapper.Name = r.kubegresContext.GetPoolerResourceName(isPrimary)
+
+	poolerWrapper.IsDeploymentDeployed, err = r.getDeployedResource(poolerWrapper.Name, &poolerWrapper.Deployment)
+	if err != nil {
+		return poolerWrapper, err
+	}
+
+	poolerWrapper.IsServiceDeployed, err = r.getDeployedResource(poolerWrapper.Name, &poolerWrapper.Service)
+	if err != nil {
+		return poolerWrapper, err
+	}
+
+	poolerWrapper.IsReady = poolerWrapper.IsDeploymentDeployed && poolerWrapper.Deployment.Status.ReadyReplicas > 0
+	return poolerWrapper, nil
+}
+
+func (r *PoolerStates) getDeployedResource(resourceName string, resource client.Object) (bool, error) {
+
+	resourceKey := client.ObjectKey{Namespace: r.kubegresContext.Kubegres.Namespace, Name: resourceName}
+	err := r.kubegresContext.Client.Get(r.kubegresContext.Ctx, resourceKey, resource)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		r.kubegresContext.Log.ErrorEvent("PoolerLoadingErr", err, "Unable to load a deployed resource of the Pooler.", "Resource name", resourceName)
+		return false, err
+	}
+
+	return true, nil
+}
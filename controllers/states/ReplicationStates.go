@@ -0,0 +1,216 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/apimachinery/pkg/types"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/postgres"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// On a Primary, the received and replayed WAL locations are both set to the current WAL location.
+const walLocationSqlQuery = "SELECT pg_is_in_recovery(), " +
+	"(CASE WHEN pg_is_in_recovery() THEN COALESCE(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn(), '0/0') ELSE pg_current_wal_lsn() END)::text, " +
+	"(CASE WHEN pg_is_in_recovery() THEN COALESCE(pg_last_wal_replay_lsn(), '0/0') ELSE pg_current_wal_lsn() END)::text, " +
+	"current_setting('server_version_num')::int / 10000"
+
+// The last WAL location loaded from the Primary of each Kubegres resource is kept in memory, so that the lag of
+// the Replicas can still be measured once the Primary is not reachable anymore (e.g. during a failover).
+// It is lost when the operator restarts.
+var (
+	lastKnownPrimaryMutex     sync.Mutex
+	lastKnownPrimaryLocations = make(map[types.NamespacedName]WalLocationWrapper)
+)
+
+type ReplicationStates struct {
+	Primary          WalLocationWrapper
+	LastKnownPrimary WalLocationWrapper
+	Replicas         []WalLocationWrapper
+
+	kubegresContext ctx.KubegresContext
+	postgresClient  *postgres.PostgresClient
+}
+
+type WalLocationWrapper struct {
+	IsLoaded         bool
+	InstanceIndex    int32
+	StatefulSetName  string
+	IsInRecovery     bool
+	ReceivedLocation uint64
+	ReplayedLocation uint64
+	MajorVersion     int32
+}
+
+func loadReplicationStates(kubegresContext ctx.KubegresContext,
+	postgresClient *postgres.PostgresClient,
+	statefulSetsStates statefulset.StatefulSetsStates) (ReplicationStates, error) {
+
+	replicationStates := ReplicationStates{kubegresContext: kubegresContext, postgresClient: postgresClient}
+	replicationStates.loadStates(statefulSetsStates)
+	return replicationStates, nil
+}
+
+func (r *ReplicationStates) GetReplica(instanceIndex int32) (WalLocationWrapper, bool) {
+	for _, replica := range r.Replicas {
+		if replica.InstanceIndex == instanceIndex {
+			return replica, true
+		}
+	}
+	return WalLocationWrapper{}, false
+}
+
+// GetReplicaLagInBytes returns the number of bytes of WAL which were written by the Primary and not replayed yet by
+// the given Replica. The returned boolean is false if the WAL locations of the Primary or of the Replica are unknown.
+func (r *ReplicationStates) GetReplicaLagInBytes(instanceIndex int32) (int64, bool) {
+
+	replica, exists := r.GetReplica(instanceIndex)
+	if !exists || !replica.IsLoaded || !r.Primary.IsLoaded {
+		return 0, false
+	}
+
+	if replica.ReplayedLocation >= r.Primary.ReplayedLocation {
+		return 0, true
+	}
+
+	return int64(r.Primary.ReplayedLocation - replica.ReplayedLocation), true
+}
+
+// The WAL locations are loaded on a best effort basis: if a PostgreSql server cannot be queried,
+// its WAL locations are marked as not loaded and the other states are not impacted.
+// The servers are queried in parallel, so that the servers which are not reachable delay the reconciliation by
+// one connection time-out rather than by one time-out each.
+func (r *ReplicationStates) loadStates(statefulSetsStates statefulset.StatefulSetsStates) {
+
+	replicaStatefulSets := statefulSetsStates.Replicas.All.GetAllSortedByInstanceIndex()
+	replicas := make([]WalLocationWrapper, len(replicaStatefulSets))
+
+	var waitGroup sync.WaitGroup
+
+	if statefulSetsStates.Primary.IsDeployed {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			r.Primary = r.loadWalLocation(statefulSetsStates.Primary)
+		}()
+	}
+
+	for i, replicaStatefulSet := range replicaStatefulSets {
+		waitGroup.Add(1)
+		go func(i int, replicaStatefulSet statefulset.StatefulSetWrapper) {
+			defer waitGroup.Done()
+			replicas[i] = r.loadWalLocation(replicaStatefulSet)
+		}(i, replicaStatefulSet)
+	}
+
+	waitGroup.Wait()
+
+	if len(replicas) > 0 {
+		r.Replicas = replicas
+	}
+	r.LastKnownPrimary = r.updateLastKnownPrimary()
+}
+
+func (r *ReplicationStates) loadWalLocation(statefulSetWrapper statefulset.StatefulSetWrapper) WalLocationWrapper {
+
+	walLocation := WalLocationWrapper{
+		InstanceIndex:   statefulSetWrapper.InstanceIndex,
+		StatefulSetName: statefulSetWrapper.StatefulSet.Name,
+	}
+
+	if !statefulSetWrapper.Pod.IsReady {
+		return walLocation
+	}
+
+	var receivedLocation, replayedLocation string
+	err := r.postgresClient.QueryRow(statefulSetWrapper.Pod.Pod, walLocationSqlQuery,
+		&walLocation.IsInRecovery, &receivedLocation, &replayedLocation, &walLocation.MajorVersion)
+
+	if err != nil {
+		r.kubegresContext.Log.Info("Unable to load the WAL locations of a PostgreSql server.",
+			"Pod name", statefulSetWrapper.Pod.Pod.Name, "Error", err.Error())
+		return walLocation
+	}
+
+	if walLocation.ReceivedLocation, err = ParseWalLocation(receivedLocation); err != nil {
+		r.kubegresContext.Log.Error(err, "Unable to parse the received WAL location.", "Pod name", statefulSetWrapper.Pod.Pod.Name)
+		return walLocation
+	}
+
+	if walLocation.ReplayedLocation, err = ParseWalLocation(replayedLocation); err != nil {
+		r.kubegresContext.Log.Error(err, "Unable to parse the replayed WAL location.", "Pod name", statefulSetWrapper.Pod.Pod.Name)
+		return walLocation
+	}
+
+	walLocation.IsLoaded = true
+	return walLocation
+}
+
+func (r *ReplicationStates) updateLastKnownPrimary() WalLocationWrapper {
+
+	kubegres := types.NamespacedName{Namespace: r.kubegresContext.Kubegres.Namespace, Name: r.kubegresContext.Kubegres.Name}
+
+	lastKnownPrimaryMutex.Lock()
+	defer lastKnownPrimaryMutex.Unlock()
+
+	if r.Primary.IsLoaded && !r.Primary.IsInRecovery {
+		lastKnownPrimaryLocations[kubegres] = r.Primary
+	}
+	return lastKnownPrimaryLocations[kubegres]
+}
+
+// DeleteLastKnownPrimary forgets the last WAL location of the Primary of a Kubegres resource which does not exist anymore.
+func DeleteLastKnownPrimary(kubegres types.NamespacedName) {
+	lastKnownPrimaryMutex.Lock()
+	defer lastKnownPrimaryMutex.Unlock()
+	delete(lastKnownPrimaryLocations, kubegres)
+}
+
+// FormatWalLocation formats a WAL location as PostgreSql does, e.g. "16/B374D848"
+func FormatWalLocation(walLocation uint64) string {
+	return fmt.Sprintf("%X/%X", walLocation>>32, walLocation&0xFFFFFFFF)
+}
+
+// A WAL location (LSN) is formatted by PostgreSql as 2 hexadecimal numbers of 32 bits separated by a slash, e.g. "16/B374D848"
+func ParseWalLocation(walLocation string) (uint64, error) {
+
+	parts := strings.Split(walLocation, "/")
+	if len(parts) != 2 {
+		return 0, errors.New("The WAL location '" + walLocation + "' is not valid.")
+	}
+
+	high, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	low, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return high<<32 | low, nil
+}
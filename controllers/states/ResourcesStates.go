@@ -22,25 +22,60 @@ package states
 
 import (
 	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/postgres"
 	"reactive-tech.io/kubegres/controllers/states/statefulset"
 )
 
 type ResourcesStates struct {
 	DbStorageClass DbStorageClassStates
 	StatefulSets   statefulset.StatefulSetsStates
+	DbPvcs         DbPvcStates
 	Services       ServicesStates
 	Config         ConfigStates
 	BackUp         BackUpStates
+	Replication    ReplicationStates
+
+	MajorVersionUpgrade MajorVersionUpgradeStates
+	Pooler              PoolerStates
 
 	kubegresContext ctx.KubegresContext
+	postgresClient  *postgres.PostgresClient
 }
 
-func LoadResourcesStates(kubegresContext ctx.KubegresContext) (ResourcesStates, error) {
-	resourcesStates := ResourcesStates{kubegresContext: kubegresContext}
+func LoadResourcesStates(kubegresContext ctx.KubegresContext, postgresClient *postgres.PostgresClient) (ResourcesStates, error) {
+	resourcesStates := ResourcesStates{kubegresContext: kubegresContext, postgresClient: postgresClient}
 	err := resourcesStates.loadStates()
 	return resourcesStates, err
 }
 
+// LoadResourcesStatesForSpecCheck only loads the states checked by the SpecChecker, so that the webhook can check the
+// spec of a Kubegres resource without querying the PostgreSql servers.
+func LoadResourcesStatesForSpecCheck(kubegresContext ctx.KubegresContext) (ResourcesStates, error) {
+	resourcesStates := ResourcesStates{kubegresContext: kubegresContext}
+	err := resourcesStates.loadStatesForSpecCheck()
+	return resourcesStates, err
+}
+
+func (r *ResourcesStates) loadStatesForSpecCheck() (err error) {
+
+	err = r.loadDbStorageClassStates()
+	if err != nil {
+		return err
+	}
+
+	err = r.loadConfigStates()
+	if err != nil {
+		return err
+	}
+
+	err = r.loadStatefulSetsStates()
+	if err != nil {
+		return err
+	}
+
+	return r.loadBackUpStates()
+}
+
 func (r *ResourcesStates) loadStates() (err error) {
 
 	err = r.loadDbStorageClassStates()
@@ -58,6 +93,11 @@ func (r *ResourcesStates) loadStates() (err error) {
 		return err
 	}
 
+	err = r.loadDbPvcStates()
+	if err != nil {
+		return err
+	}
+
 	err = r.loadServicesStates()
 	if err != nil {
 		return err
@@ -68,6 +108,21 @@ func (r *ResourcesStates) loadStates() (err error) {
 		return err
 	}
 
+	err = r.loadReplicationStates()
+	if err != nil {
+		return err
+	}
+
+	err = r.loadMajorVersionUpgradeStates()
+	if err != nil {
+		return err
+	}
+
+	err = r.loadPoolerStates()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -86,6 +141,11 @@ func (r *ResourcesStates) loadStatefulSetsStates() (err error) {
 	return err
 }
 
+func (r *ResourcesStates) loadDbPvcStates() (err error) {
+	r.DbPvcs, err = loadDbPvcStates(r.kubegresContext, r.StatefulSets)
+	return err
+}
+
 func (r *ResourcesStates) loadServicesStates() (err error) {
 	r.Services, err = loadServicesStates(r.kubegresContext)
 	return err
@@ -95,3 +155,18 @@ func (r *ResourcesStates) loadBackUpStates() (err error) {
 	r.BackUp, err = loadBackUpStates(r.kubegresContext)
 	return err
 }
+
+func (r *ResourcesStates) loadReplicationStates() (err error) {
+	r.Replication, err = loadReplicationStates(r.kubegresContext, r.postgresClient, r.StatefulSets)
+	return err
+}
+
+func (r *ResourcesStates) loadMajorVersionUpgradeStates() (err error) {
+	r.MajorVersionUpgrade, err = loadMajorVersionUpgradeStates(r.kubegresContext)
+	return err
+}
+
+func (r *ResourcesStates) loadPoolerStates() (err error) {
+	r.Pooler, err = loadPoolerStates(r.kubegresContext)
+	return err
+}
@@ -18,10 +18,12 @@ package states
 
 import (
 	"strconv"
+	"strings"
 
 	batchv1 "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"reactive-tech.io/kubegres/controllers/ctx"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,15 +41,25 @@ const (
 type RestoreJobStates struct {
 	kubegresRestoreContext ctx.KubegresRestoreContext
 
-	IsJobDeployed bool
-	IsPvcDeployed bool
-	JobPhase      JobPhase
+	IsPhysicalRestore            bool
+	IsJobDeployed                bool
+	IsPvcDeployed                bool
+	IsTargetPrimaryDbPvcDeployed bool
+	JobPhase                     JobPhase
+
+	// Set if the restore job has failed
+	FailureTime    metav1.Time
+	FailureReason  string
+	FailurePodName string
 
 	Job *batchv1.Job
 }
 
-func loadRestoreJobStates(kubegresRestoreContext ctx.KubegresRestoreContext) (RestoreJobStates, error) {
-	restoreJobStates := RestoreJobStates{kubegresRestoreContext: kubegresRestoreContext}
+func loadRestoreJobStates(kubegresRestoreContext ctx.KubegresRestoreContext, isPhysicalRestore bool) (RestoreJobStates, error) {
+	restoreJobStates := RestoreJobStates{
+		kubegresRestoreContext: kubegresRestoreContext,
+		IsPhysicalRestore:      isPhysicalRestore,
+	}
 
 	if err := restoreJobStates.loadStates(); err != nil {
 		return restoreJobStates, err
@@ -69,6 +81,14 @@ func (r *RestoreJobStates) loadStates() (err error) {
 
 	r.IsPvcDeployed = pvc.Name != ""
 
+	if r.IsPhysicalRestore {
+		targetPrimaryDbPvc, err := r.getTargetPrimaryDbPvcResource()
+		if err != nil {
+			return err
+		}
+		r.IsTargetPrimaryDbPvcDeployed = targetPrimaryDbPvc.Name != ""
+	}
+
 	if r.Job.Name == "" {
 		r.IsJobDeployed = false
 		r.JobPhase = JobPending
@@ -87,17 +107,11 @@ func (r *RestoreJobStates) loadStates() (err error) {
 		r.kubegresRestoreContext.Status.SetIsCompleted(false)
 		r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageRestoreJobIsRunning)
 	} else if jobHasSucceded {
+		// The restore is completed by KubegresCountSpecEnforcer once the restored databases are ready and verified
 		r.JobPhase = JobSucceded
-
-		if !r.kubegresRestoreContext.Status.GetIsCompleted() {
-			r.kubegresRestoreContext.Log.InfoEvent("RestoreJobCompleted", "Restorejob has completed succesfully.")
-		}
-
-		r.kubegresRestoreContext.Status.SetIsCompleted(true)
 	} else if jobHasFailed {
 		r.JobPhase = JobFailed
-		r.kubegresRestoreContext.Status.SetIsCompleted(false)
-		r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageRestoreJobFailed)
+		r.FailureTime = r.getJobFailureTime()
 
 		jobPod, err := r.getRestoreJobPod()
 		if err != nil {
@@ -105,8 +119,8 @@ func (r *RestoreJobStates) loadStates() (err error) {
 			return err
 		}
 
-		jobExitCode := r.getExitCodeFromFailedJob(jobPod)
-		r.kubegresRestoreContext.Log.InfoEvent("RestoreJobFailed", "Unable to complete restore job, exit code "+strconv.Itoa(int(jobExitCode))+". See 'pod/"+jobPod.Name+"' for more details.", "Name of job", r.Job.Name)
+		r.FailurePodName = jobPod.Name
+		r.FailureReason = r.getFailureReasonFromFailedJob(jobPod)
 	}
 
 	return nil
@@ -180,6 +194,52 @@ func (r *RestoreJobStates) getPvcResource() (*core.PersistentVolumeClaim, error)
 	return pvc, err
 }
 
-func (r *RestoreJobStates) getExitCodeFromFailedJob(jobPod *core.Pod) int32 {
-	return jobPod.Status.ContainerStatuses[0].State.Terminated.ExitCode
+func (r *RestoreJobStates) getTargetPrimaryDbPvcResource() (*core.PersistentVolumeClaim, error) {
+	pvc := &core.PersistentVolumeClaim{}
+	pvcKey := types.NamespacedName{
+		Namespace: r.kubegresRestoreContext.KubegresRestore.Namespace,
+		Name:      r.kubegresRestoreContext.GetTargetPrimaryDbPvcName(),
+	}
+
+	err := r.kubegresRestoreContext.Client.Get(r.kubegresRestoreContext.Ctx, pvcKey, pvc)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		} else {
+			r.kubegresRestoreContext.Log.ErrorEvent("RestoreJobTargetPvcLoadingErr", err, "Unable to load deployed PVC of the Primary to restore.", "PVC Name", pvcKey)
+		}
+	}
+
+	return pvc, err
+}
+
+// getFailureReasonFromFailedJob returns the exit code of the failed container of the restore job, with the last
+// line of its termination message. The containers of the restore job fall back to their logs as termination message.
+func (r *RestoreJobStates) getFailureReasonFromFailedJob(jobPod *core.Pod) string {
+	containerStatuses := append(jobPod.Status.InitContainerStatuses, jobPod.Status.ContainerStatuses...)
+	for _, containerStatus := range containerStatuses {
+		terminated := containerStatus.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+
+		reason := "Container '" + containerStatus.Name + "' exited with code " + strconv.Itoa(int(terminated.ExitCode))
+		lines := strings.Split(strings.TrimSpace(terminated.Message), "\n")
+		if lastLine := strings.TrimSpace(lines[len(lines)-1]); lastLine != "" {
+			reason += ": " + lastLine
+		}
+		return reason
+	}
+	return "Restore job has failed without any exit code."
+}
+
+// getJobFailureTime returns the time when the restore job was marked as failed, or its creation time if unknown.
+func (r *RestoreJobStates) getJobFailureTime() metav1.Time {
+	for _, condition := range r.Job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == core.ConditionTrue {
+			return condition.LastTransitionTime
+		}
+	}
+	return r.Job.CreationTimestamp
 }
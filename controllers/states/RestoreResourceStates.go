@@ -24,6 +24,8 @@ type RestoreResourceStates struct {
 	Cluster     KubegresStates
 	Job         RestoreJobStates
 	FileChecker FileCheckerPodStates
+
+	Verification VerificationJobStates
 }
 
 func LoadRestoreResourceStates(kubegresRestoreContext ctx.KubegresRestoreContext) (RestoreResourceStates, error) {
@@ -38,12 +40,17 @@ func (r *RestoreResourceStates) loadStates() (err error) {
 		return err
 	}
 
+	err = r.loadFileCheckerStates()
+	if err != nil {
+		return err
+	}
+
 	err = r.loadJobStates()
 	if err != nil {
 		return err
 	}
 
-	err = r.loadFileCheckerStates()
+	err = r.loadVerificationStates()
 	if err != nil {
 		return err
 	}
@@ -57,7 +64,9 @@ func (r *RestoreResourceStates) loadClusterStates() (err error) {
 }
 
 func (r *RestoreResourceStates) loadJobStates() (err error) {
-	r.Job, err = loadRestoreJobStates(r.kubegresRestoreContext)
+	isPhysicalRestore := r.kubegresRestoreContext.IsPointInTimeRecovery() ||
+		r.FileChecker.SnapshotFormat == PhysicalSnapshotFormat
+	r.Job, err = loadRestoreJobStates(r.kubegresRestoreContext, isPhysicalRestore)
 	return err
 }
 
@@ -65,3 +74,8 @@ func (r *RestoreResourceStates) loadFileCheckerStates() (err error) {
 	r.FileChecker, err = loadFileCheckerPodStates(r.kubegresRestoreContext)
 	return err
 }
+
+func (r *RestoreResourceStates) loadVerificationStates() (err error) {
+	r.Verification, err = loadVerificationJobStates(r.kubegresRestoreContext)
+	return err
+}
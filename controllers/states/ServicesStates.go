@@ -28,8 +28,9 @@ import (
 )
 
 type ServicesStates struct {
-	Primary ServiceWrapper
-	Replica ServiceWrapper
+	Primary      ServiceWrapper
+	Replica      ServiceWrapper
+	ReplicaReady ServiceWrapper
 
 	kubegresContext ctx.KubegresContext
 }
@@ -55,6 +56,17 @@ func (r *ServicesStates) loadStates() (err error) {
 
 	for _, service := range deployedServices.Items {
 
+		if service.Name == r.kubegresContext.GetReplicaReadyServiceResourceName() {
+			r.ReplicaReady = ServiceWrapper{Name: service.Name, IsDeployed: true, Service: service}
+			continue
+		}
+
+		// Other Services are owned by Kubegres, e.g. the Services of the Pooler
+		if service.Name != r.kubegresContext.GetServiceResourceName(true) &&
+			service.Name != r.kubegresContext.GetServiceResourceName(false) {
+			continue
+		}
+
 		serviceWrapper := ServiceWrapper{IsDeployed: true, Service: service}
 
 		if r.isPrimary(service) {
@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	verificationPassed = "PASS"
+	verificationFailed = "FAIL"
+)
+
+// VerificationJobStates is the state of the Job which runs the checks of the field 'verify' of KubegresRestore
+// against the restored cluster.
+type VerificationJobStates struct {
+	kubegresRestoreContext ctx.KubegresRestoreContext
+
+	IsJobDeployed bool
+	JobPhase      JobPhase
+
+	// Set once the verification job has terminated
+	Results []v1.RestoreVerificationResult
+
+	Job *batchv1.Job
+}
+
+func loadVerificationJobStates(kubegresRestoreContext ctx.KubegresRestoreContext) (VerificationJobStates, error) {
+	verificationJobStates := VerificationJobStates{
+		kubegresRestoreContext: kubegresRestoreContext,
+		JobPhase:               JobPending,
+	}
+
+	if !kubegresRestoreContext.IsVerificationEnabled() {
+		return verificationJobStates, nil
+	}
+
+	err := verificationJobStates.loadStates()
+	return verificationJobStates, err
+}
+
+func (r *VerificationJobStates) loadStates() (err error) {
+	r.Job, err = r.getVerificationJobResource()
+	if err != nil {
+		return err
+	}
+
+	if r.Job.Name == "" {
+		r.IsJobDeployed = false
+		return nil
+	}
+
+	r.IsJobDeployed = true
+
+	if r.Job.Status.Active != 0 {
+		r.JobPhase = JobRunning
+		r.kubegresRestoreContext.Status.SetCurrentStage(ctx.StageVerifyingRestore)
+		return nil
+	}
+
+	if r.Job.Status.Succeeded != 0 {
+		r.JobPhase = JobSucceded
+	} else if r.Job.Status.Failed != 0 {
+		r.JobPhase = JobFailed
+	} else {
+		return nil
+	}
+
+	jobPod, err := r.getVerificationJobPod()
+	if err != nil {
+		return err
+	}
+	r.Results = r.getResultsFromTerminatedJob(jobPod)
+	return nil
+}
+
+func (r *VerificationJobStates) getVerificationJobResource() (*batchv1.Job, error) {
+	verificationJob := &batchv1.Job{}
+	verificationJobKey := r.kubegresRestoreContext.GetNamespacesresourceName(r.kubegresRestoreContext.GetVerificationJobName())
+
+	err := r.kubegresRestoreContext.Client.Get(r.kubegresRestoreContext.Ctx, verificationJobKey, verificationJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		} else {
+			r.kubegresRestoreContext.Log.ErrorEvent("VerificationJobLoadingErr", err, "Unable to load deployed verification job.", "Job name", verificationJobKey.Name)
+		}
+	}
+
+	return verificationJob, err
+}
+
+func (r *VerificationJobStates) getVerificationJobPod() (*core.Pod, error) {
+	podList := &core.PodList{}
+	jobName := r.kubegresRestoreContext.GetVerificationJobName()
+	err := r.kubegresRestoreContext.Client.List(r.kubegresRestoreContext.Ctx, podList,
+		client.InNamespace(r.kubegresRestoreContext.KubegresRestore.Namespace),
+		client.MatchingLabels{"job-name": jobName})
+
+	if err != nil {
+		r.kubegresRestoreContext.Log.ErrorEvent("VerificationJobPodLoadingErr", err, "Unable to load any pods owned by verification job.", "Job name", jobName)
+		return &core.Pod{}, err
+	}
+
+	if len(podList.Items) == 0 {
+		return &core.Pod{}, nil
+	}
+	return &podList.Items[0], nil
+}
+
+// getResultsFromTerminatedJob parses the termination message of the verification job. It contains a line per check
+// with its result, its name and an optional message separated by tabs.
+func (r *VerificationJobStates) getResultsFromTerminatedJob(jobPod *core.Pod) []v1.RestoreVerificationResult {
+	results := make([]v1.RestoreVerificationResult, 0)
+	if len(jobPod.Status.ContainerStatuses) == 0 || jobPod.Status.ContainerStatuses[0].State.Terminated == nil {
+		return results
+	}
+
+	rawMessage := jobPod.Status.ContainerStatuses[0].State.Terminated.Message
+	for _, line := range strings.Split(rawMessage, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 2 || (fields[0] != verificationPassed && fields[0] != verificationFailed) {
+			continue
+		}
+
+		result := v1.RestoreVerificationResult{Name: fields[1], Passed: fields[0] == verificationPassed}
+		if len(fields) == 3 {
+			result.Message = strings.TrimSpace(fields[2])
+		}
+		results = append(results, result)
+	}
+	return results
+}
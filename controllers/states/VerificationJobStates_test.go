@@ -0,0 +1,139 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package states
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestFailedVerificationJobResultsAreParsedFromTerminationMessage(t *testing.T) {
+	terminationMessage := "PASS\tpg-stat-database-reachable\n" +
+		"PASS\tdatabase-exists/app\n" +
+		"FAIL\trow-count/app/public.orders\tExpected '42' but got '41'\n" +
+		"This line is not a result\n"
+	kubegresRestoreContext := createVerificationContextToTest(batchv1.JobStatus{Failed: 1}, terminationMessage)
+
+	verificationJobStates, err := loadVerificationJobStates(kubegresRestoreContext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !verificationJobStates.IsJobDeployed || verificationJobStates.JobPhase != JobFailed {
+		t.Errorf("Expected the verification job to be deployed and failed, got the phase '%s'", verificationJobStates.JobPhase)
+	}
+
+	expectedResults := []v1.RestoreVerificationResult{
+		{Name: "pg-stat-database-reachable", Passed: true},
+		{Name: "database-exists/app", Passed: true},
+		{Name: "row-count/app/public.orders", Passed: false, Message: "Expected '42' but got '41'"},
+	}
+	if len(verificationJobStates.Results) != len(expectedResults) {
+		t.Fatalf("Expected %d results, got %v", len(expectedResults), verificationJobStates.Results)
+	}
+	for i, expectedResult := range expectedResults {
+		if verificationJobStates.Results[i] != expectedResult {
+			t.Errorf("Expected the result %v, got %v", expectedResult, verificationJobStates.Results[i])
+		}
+	}
+}
+
+func TestSucceededVerificationJobIsLoaded(t *testing.T) {
+	kubegresRestoreContext := createVerificationContextToTest(batchv1.JobStatus{Succeeded: 1}, "PASS\trow-count/app/public.orders\n")
+
+	verificationJobStates, err := loadVerificationJobStates(kubegresRestoreContext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if verificationJobStates.JobPhase != JobSucceded || len(verificationJobStates.Results) != 1 || !verificationJobStates.Results[0].Passed {
+		t.Errorf("Expected the verification job to be succeeded with one passed check, got the phase '%s' and %v",
+			verificationJobStates.JobPhase, verificationJobStates.Results)
+	}
+}
+
+func TestRunningVerificationJobHasNoResults(t *testing.T) {
+	kubegresRestoreContext := createVerificationContextToTest(batchv1.JobStatus{Active: 1}, "")
+
+	verificationJobStates, err := loadVerificationJobStates(kubegresRestoreContext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if verificationJobStates.JobPhase != JobRunning || len(verificationJobStates.Results) != 0 {
+		t.Errorf("Expected the verification job to be running without any result, got the phase '%s' and %v",
+			verificationJobStates.JobPhase, verificationJobStates.Results)
+	}
+	if kubegresRestoreContext.Status.GetCurrentStage() != ctx.StageVerifyingRestore {
+		t.Errorf("Expected the stage '%s', got '%s'", ctx.StageVerifyingRestore, kubegresRestoreContext.Status.GetCurrentStage())
+	}
+}
+
+func createVerificationContextToTest(jobStatus batchv1.JobStatus, terminationMessage string) ctx.KubegresRestoreContext {
+	kubegresRestore := &v1.KubegresRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+		Spec: v1.KubegresRestoreSpec{
+			ClusterName: "postgres",
+			Verify:      &v1.RestoreVerification{},
+		},
+	}
+
+	jobName := kubegresRestore.Name + ctx.VerificationJobSuffix
+	verificationJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: "default"},
+		Status:     jobStatus,
+	}
+	verificationPod := &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName + "-abcde", Namespace: "default", Labels: map[string]string{"job-name": jobName}},
+		Status: core.PodStatus{
+			ContainerStatuses: []core.ContainerStatus{{
+				Name:  "postgres-verify",
+				State: core.ContainerState{Terminated: &core.ContainerStateTerminated{Message: terminationMessage}},
+			}},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(verificationJob, verificationPod).Build()
+
+	restoreLog := log.LogWrapper[*v1.KubegresRestore]{Resource: kubegresRestore, Logger: logr.Discard(), Recorder: record.NewFakeRecorder(10)}
+	return ctx.KubegresRestoreContext{
+		KubegresRestore: kubegresRestore,
+		Status:          &status.RestoreStatusWrapper{KubegresRestore: kubegresRestore, Log: restoreLog},
+		Ctx:             context.Background(),
+		Log:             restoreLog,
+		Client:          kubeClient,
+	}
+}
@@ -19,8 +19,12 @@ func (r *ResourcesStatesLogger) Log() {
 	r.logDbStorageClassStates()
 	r.logConfigStates()
 	r.logStatefulSetsStates()
+	r.logDbPvcsStates()
 	r.logServicesStates()
 	r.logBackUpStates()
+	r.logReplicationStates()
+	r.logMajorVersionUpgradeStates()
+	r.logPoolerStates()
 }
 
 func (r *ResourcesStatesLogger) logDbStorageClassStates() {
@@ -32,6 +36,8 @@ func (r *ResourcesStatesLogger) logDbStorageClassStates() {
 func (r *ResourcesStatesLogger) logConfigStates() {
 	r.kubegresContext.Log.Info("Base Config states",
 		"IsDeployed", r.resourcesStates.Config.IsBaseConfigDeployed,
+		"IsRewindScriptDeployed", r.resourcesStates.Config.IsRewindScriptDeployed,
+		"IsWalArchiveScriptsDeployed", r.resourcesStates.Config.IsWalArchiveScriptsDeployed,
 		"name", r.resourcesStates.Config.BaseConfigName)
 
 	if r.resourcesStates.Config.BaseConfigName != r.resourcesStates.Config.CustomConfigName {
@@ -72,9 +78,21 @@ func (r *ResourcesStatesLogger) logStatefulSetWrapper(logLabel string, statefulS
 	}
 }
 
+func (r *ResourcesStatesLogger) logDbPvcsStates() {
+	for _, dbPvc := range r.resourcesStates.DbPvcs.All {
+		r.kubegresContext.Log.Info("Database PVC states: ",
+			"Name", dbPvc.Pvc.Name,
+			"IsBound", dbPvc.IsBound,
+			"IsDeleting", dbPvc.IsDeleting,
+			"IsReusable", dbPvc.IsReusable,
+			"IsUsedByStatefulSet", dbPvc.IsUsedByStatefulSet)
+	}
+}
+
 func (r *ResourcesStatesLogger) logServicesStates() {
 	r.logServiceWrapper("Primary Service states", r.resourcesStates.Services.Primary)
 	r.logServiceWrapper("Replica Service states", r.resourcesStates.Services.Replica)
+	r.logServiceWrapper("Replica-ready Service states", r.resourcesStates.Services.ReplicaReady)
 }
 
 func (r *ResourcesStatesLogger) logServiceWrapper(logLabel string, serviceWrapper states.ServiceWrapper) {
@@ -95,5 +113,58 @@ func (r *ResourcesStatesLogger) logBackUpStates() {
 		"IsCronJobDeployed", r.resourcesStates.BackUp.IsCronJobDeployed,
 		"IsPvcDeployed", r.resourcesStates.BackUp.IsPvcDeployed,
 		"ConfigMap", r.resourcesStates.BackUp.ConfigMap,
-		"CronJobLastScheduleTime", r.resourcesStates.BackUp.CronJobLastScheduleTime)
+		"CronJobLastScheduleTime", r.resourcesStates.BackUp.CronJobLastScheduleTime,
+		"NbreJobs", len(r.resourcesStates.BackUp.Jobs),
+		"NbreRetainedBackUps", len(r.resourcesStates.BackUp.RetainedBackUps))
+}
+
+func (r *ResourcesStatesLogger) logReplicationStates() {
+	replicationStates := r.resourcesStates.Replication
+	r.logWalLocationWrapper("Primary WAL location", replicationStates.Primary)
+
+	for _, replicaWalLocation := range replicationStates.Replicas {
+		r.logWalLocationWrapper("Replica WAL location", replicaWalLocation)
+	}
+}
+
+func (r *ResourcesStatesLogger) logWalLocationWrapper(logLabel string, walLocationWrapper states.WalLocationWrapper) {
+
+	if !walLocationWrapper.IsLoaded {
+		r.kubegresContext.Log.Info(logLabel+": ",
+			"IsLoaded", walLocationWrapper.IsLoaded,
+			"StatefulSet name", walLocationWrapper.StatefulSetName)
+
+	} else {
+		r.kubegresContext.Log.Info(logLabel+": ",
+			"IsLoaded", walLocationWrapper.IsLoaded,
+			"StatefulSet name", walLocationWrapper.StatefulSetName,
+			"IsInRecovery", walLocationWrapper.IsInRecovery,
+			"Received location", walLocationWrapper.ReceivedLocation,
+			"Replayed location", walLocationWrapper.ReplayedLocation,
+			"Major version", walLocationWrapper.MajorVersion)
+	}
+}
+
+func (r *ResourcesStatesLogger) logMajorVersionUpgradeStates() {
+	r.kubegresContext.Log.Info("Major version upgrade states.",
+		"IsJobDeployed", r.resourcesStates.MajorVersionUpgrade.IsJobDeployed,
+		"IsJobSucceeded", r.resourcesStates.MajorVersionUpgrade.IsJobSucceeded,
+		"IsJobFailed", r.resourcesStates.MajorVersionUpgrade.IsJobFailed,
+		"Job name", r.resourcesStates.MajorVersionUpgrade.JobName)
+}
+
+func (r *ResourcesStatesLogger) logPoolerStates() {
+	r.logPoolerWrapper("Primary Pooler states", r.resourcesStates.Pooler.Primary)
+	r.logPoolerWrapper("Replica Pooler states", r.resourcesStates.Pooler.Replica)
+	r.kubegresContext.Log.Info("Pooler config states.",
+		"IsDeployed", r.resourcesStates.Pooler.ConfigMap.IsDeployed,
+		"name", r.resourcesStates.Pooler.ConfigMap.Name)
+}
+
+func (r *ResourcesStatesLogger) logPoolerWrapper(logLabel string, poolerWrapper states.PoolerWrapper) {
+	r.kubegresContext.Log.Info(logLabel+": ",
+		"IsDeploymentDeployed", poolerWrapper.IsDeploymentDeployed,
+		"IsServiceDeployed", poolerWrapper.IsServiceDeployed,
+		"IsReady", poolerWrapper.IsReady,
+		"name", poolerWrapper.Name)
 }
@@ -21,18 +21,23 @@ func (r *RestoreResourcesStatesLogger) Log() {
 	r.logKubegresStates()
 	r.logRestoreJobStates()
 	r.logFileCheckerPodStates()
+	r.logVerificationJobStates()
 }
 
 func (r *RestoreResourcesStatesLogger) logKubegresStates() {
 	r.kubegresRestoreContext.Log.Info("Kubegres states.",
 		"IsDeployed", r.restoreResourcesStates.Cluster.IsDeployed,
-		"IsReady", r.restoreResourcesStates.Cluster.IsReady)
+		"IsReady", r.restoreResourcesStates.Cluster.IsReady,
+		"IsManagedByKubegresRestore", r.restoreResourcesStates.Cluster.IsManagedByKubegresRestore,
+		"NbreDeployedReplicas", r.restoreResourcesStates.Cluster.NbreDeployedReplicas)
 }
 
 func (r *RestoreResourcesStatesLogger) logRestoreJobStates() {
 	r.kubegresRestoreContext.Log.Info("RestoreJob states.",
+		"IsPhysicalRestore", r.restoreResourcesStates.Job.IsPhysicalRestore,
 		"IsJobDeployed", r.restoreResourcesStates.Job.IsJobDeployed,
 		"IsPvcDeployed", r.restoreResourcesStates.Job.IsPvcDeployed,
+		"IsTargetPrimaryDbPvcDeployed", r.restoreResourcesStates.Job.IsTargetPrimaryDbPvcDeployed,
 		"JobPhase", r.restoreResourcesStates.Job.JobPhase)
 }
 
@@ -41,5 +46,13 @@ func (r *RestoreResourcesStatesLogger) logFileCheckerPodStates() {
 		"IsPodDeployed", r.restoreResourcesStates.FileChecker.IsPodDeployed,
 		"IsPodTerminated", r.restoreResourcesStates.FileChecker.IsPodTerminated,
 		"ExitStatus", r.restoreResourcesStates.FileChecker.ExitStatus,
+		"SnapshotFormat", r.restoreResourcesStates.FileChecker.SnapshotFormat,
 	)
 }
+
+func (r *RestoreResourcesStatesLogger) logVerificationJobStates() {
+	r.kubegresRestoreContext.Log.Info("VerificationJob states.",
+		"IsJobDeployed", r.restoreResourcesStates.Verification.IsJobDeployed,
+		"JobPhase", r.restoreResourcesStates.Verification.JobPhase,
+		"NbreResults", len(r.restoreResourcesStates.Verification.Results))
+}
@@ -78,7 +78,7 @@ func (r *PodStates) getDeployedPods() (*core.PodList, error) {
 	list := &core.PodList{}
 	opts := []client.ListOption{
 		client.InNamespace(r.kubegresContext.Kubegres.Namespace),
-		client.MatchingLabels{"app": r.kubegresContext.Kubegres.Name},
+		client.MatchingFields{ctx.InstanceOwnerKey: r.kubegresContext.Kubegres.Name},
 	}
 	err := r.kubegresContext.Client.List(r.kubegresContext.Ctx, list, opts...)
 
@@ -96,21 +96,22 @@ func (r *PodStates) getDeployedPods() (*core.PodList, error) {
 
 func (r *PodStates) isPodReady(pod core.Pod) bool {
 
-	if len(pod.Status.ContainerStatuses) == 0 {
+	postgresContainerStatus := r.getPostgresContainerStatus(pod)
+	if postgresContainerStatus == nil {
 		return false
 	}
 
-	return pod.Status.ContainerStatuses[0].Ready
+	return postgresContainerStatus.Ready
 }
 
 func (r *PodStates) isPodStuck(pod core.Pod) bool {
 
-	if len(pod.Status.ContainerStatuses) == 0 ||
-		pod.Status.ContainerStatuses[0].State.Waiting == nil {
+	postgresContainerStatus := r.getPostgresContainerStatus(pod)
+	if postgresContainerStatus == nil || postgresContainerStatus.State.Waiting == nil {
 		return false
 	}
 
-	waitingReason := pod.Status.ContainerStatuses[0].State.Waiting.Reason
+	waitingReason := postgresContainerStatus.State.Waiting.Reason
 	if waitingReason == "CrashLoopBackOff" || waitingReason == "Error" {
 		r.kubegresContext.Log.Info("POD is waiting", "Reason", waitingReason)
 		return true
@@ -119,6 +120,23 @@ func (r *PodStates) isPodStuck(pod core.Pod) bool {
 	return false
 }
 
+// getPostgresContainerStatus returns the status of the PostgreSql container, which is the first container in the
+// spec of the Pod. The statuses of the containers are searched by name since the Pod may have sidecars.
+func (r *PodStates) getPostgresContainerStatus(pod core.Pod) *core.ContainerStatus {
+
+	if len(pod.Spec.Containers) == 0 {
+		return nil
+	}
+
+	postgresContainerName := pod.Spec.Containers[0].Name
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == postgresContainerName {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
 func (r *PodStates) getInstanceIndex(pod core.Pod) int32 {
 	instanceIndex, _ := strconv.ParseInt(pod.Labels["index"], 10, 32)
 	return int32(instanceIndex)
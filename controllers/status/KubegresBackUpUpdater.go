@@ -0,0 +1,155 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"time"
+)
+
+type KubegresBackUpUpdater struct {
+	kubegresContext ctx.KubegresContext
+	resourcesStates states.ResourcesStates
+}
+
+func CreateKubegresBackUpUpdater(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates) KubegresBackUpUpdater {
+
+	return KubegresBackUpUpdater{
+		kubegresContext: kubegresContext,
+		resourcesStates: resourcesStates,
+	}
+}
+
+// UpdateBackUp sets in the status the last successful and the last failed backups, as well as the backups retained
+// by the field 'backup.retention'. The status is kept as it is until a more recent Job of the backup CronJob has
+// completed, so that it is not cleared when the completed Jobs are removed by Kubernetes.
+func (r *KubegresBackUpUpdater) UpdateBackUp() {
+
+	if r.kubegresContext.Kubegres.Spec.Backup.Schedule == "" {
+		r.kubegresContext.Status.SetBackUp(v1.KubegresBackUpStatus{})
+		return
+	}
+
+	currentBackUpStatus := r.kubegresContext.Status.GetBackUp()
+	backUpStatus := *currentBackUpStatus.DeepCopy()
+	backUpStates := r.resourcesStates.BackUp
+
+	if lastSucceededJob := backUpStates.LastSucceededJob; lastSucceededJob != nil &&
+		r.isMoreRecent(lastSucceededJob.Status.CompletionTime, backUpStatus.LastSuccessfulBackUp) {
+
+		backUpStatus.LastSuccessfulBackUp = r.createBackUpJobStatus(lastSucceededJob.Name,
+			lastSucceededJob.Status.StartTime,
+			lastSucceededJob.Status.CompletionTime)
+		backUpStatus.LastSuccessfulBackUp.FileName = backUpStates.LastSucceededFileName
+		backUpStatus.LastSuccessfulBackUp.SizeBytes = backUpStates.LastSucceededSizeBytes
+	}
+
+	if lastFailedJob := backUpStates.LastFailedJob; lastFailedJob != nil &&
+		r.isMoreRecent(backUpStates.LastFailedTime, backUpStatus.LastFailedBackUp) {
+
+		backUpStatus.LastFailedBackUp = r.createBackUpJobStatus(lastFailedJob.Name,
+			lastFailedJob.Status.StartTime,
+			backUpStates.LastFailedTime)
+
+		r.kubegresContext.Log.WarningEvent("BackUpJobFailed", "A Job of the BackUp CronJob has failed.",
+			"Job name", lastFailedJob.Name)
+	}
+
+	if !r.kubegresContext.IsBackUpRetentionEnabled() {
+		backUpStatus.RetainedBackUps = nil
+		backUpStatus.LastPruneTime = nil
+
+	} else if backUpStates.LastPruneTime != nil {
+		backUpStatus.RetainedBackUps = backUpStates.RetainedBackUps
+		backUpStatus.LastPruneTime = backUpStates.LastPruneTime
+	}
+
+	r.checkLastSuccessfulBackUpIsRecent(&backUpStatus)
+
+	r.kubegresContext.Status.SetBackUp(backUpStatus)
+}
+
+func (r *KubegresBackUpUpdater) isMoreRecent(jobTime *metav1.Time, backUpJobStatus *v1.KubegresBackUpJobStatus) bool {
+	if jobTime == nil {
+		return false
+	}
+	return backUpJobStatus == nil ||
+		backUpJobStatus.CompletionTime == nil ||
+		jobTime.After(backUpJobStatus.CompletionTime.Time)
+}
+
+func (r *KubegresBackUpUpdater) createBackUpJobStatus(jobName string, startTime, completionTime *metav1.Time) *v1.KubegresBackUpJobStatus {
+
+	backUpJobStatus := &v1.KubegresBackUpJobStatus{
+		JobName:        jobName,
+		StartTime:      startTime,
+		CompletionTime: completionTime,
+	}
+
+	if startTime != nil && completionTime != nil {
+		backUpJobStatus.DurationSeconds = int64(completionTime.Sub(startTime.Time).Seconds())
+	}
+
+	return backUpJobStatus
+}
+
+// checkLastSuccessfulBackUpIsRecent emits a Warning event if no backup has succeeded during the number of hours set
+// in the field 'backup.maxHoursWithoutSuccess'. If no backup has ever succeeded, the hours are counted from the
+// creation of the backup CronJob. The event is emitted once per period without success, which is recorded in the
+// status until a backup succeeds.
+func (r *KubegresBackUpUpdater) checkLastSuccessfulBackUpIsRecent(backUpStatus *v1.KubegresBackUpStatus) {
+
+	maxHoursWithoutSuccess := r.kubegresContext.Kubegres.Spec.Backup.MaxHoursWithoutSuccess
+	backUpStates := r.resourcesStates.BackUp
+
+	if maxHoursWithoutSuccess <= 0 || !backUpStates.IsCronJobDeployed {
+		backUpStatus.OverdueSince = nil
+		return
+	}
+
+	lastSuccessTime := backUpStates.DeployedCronJob.CreationTimestamp.Time
+	lastSuccessTimeStr := "never"
+	lastSuccessfulBackUp := backUpStatus.LastSuccessfulBackUp
+	if lastSuccessfulBackUp != nil && lastSuccessfulBackUp.CompletionTime != nil {
+		lastSuccessTime = lastSuccessfulBackUp.CompletionTime.Time
+		lastSuccessTimeStr = lastSuccessfulBackUp.CompletionTime.UTC().Format(time.RFC3339)
+	}
+
+	overdueTime := lastSuccessTime.Add(time.Duration(maxHoursWithoutSuccess) * time.Hour).Truncate(time.Second)
+	if time.Now().Before(overdueTime) {
+		backUpStatus.OverdueSince = nil
+		return
+	}
+
+	if backUpStatus.OverdueSince != nil && !backUpStatus.OverdueSince.Time.Before(overdueTime) {
+		return
+	}
+
+	backUpStatus.OverdueSince = &metav1.Time{Time: overdueTime}
+	r.kubegresContext.Log.WarningEvent("BackUpOverdue",
+		"No backup has succeeded during the number of hours set in the field 'backup.maxHoursWithoutSuccess'.",
+		"MaxHoursWithoutSuccess", maxHoursWithoutSuccess,
+		"Last successful backup", lastSuccessTimeStr)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"github.com/go-logr/logr"
+	batch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/ctx/log"
+	"reactive-tech.io/kubegres/controllers/ctx/status"
+	"reactive-tech.io/kubegres/controllers/states"
+	"testing"
+	"time"
+)
+
+func TestBackUpOverdueEventIsEmittedOncePerPeriodWithoutSuccess(t *testing.T) {
+	lastSuccessTime := metav1.NewTime(time.Now().Add(-3 * time.Hour).Truncate(time.Second))
+	backUpUpdater, kubegres, recorder := createBackUpUpdaterToTest(&lastSuccessTime)
+
+	backUpUpdater.UpdateBackUp()
+	backUpUpdater.UpdateBackUp()
+
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected a single 'BackUpOverdue' event, got %d events", len(recorder.Events))
+	}
+
+	overdueSince := kubegres.Status.BackUp.OverdueSince
+	if overdueSince == nil || !overdueSince.Time.Equal(lastSuccessTime.Add(2*time.Hour)) {
+		t.Errorf("Expected the status to record when the backups became overdue, got %v", overdueSince)
+	}
+}
+
+func TestBackUpOverdueIsClearedOnceBackUpSucceeds(t *testing.T) {
+	lastSuccessTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	backUpUpdater, kubegres, recorder := createBackUpUpdaterToTest(&lastSuccessTime)
+	kubegres.Status.BackUp.OverdueSince = &metav1.Time{Time: lastSuccessTime.Add(-time.Hour)}
+
+	backUpUpdater.UpdateBackUp()
+
+	if kubegres.Status.BackUp.OverdueSince != nil {
+		t.Errorf("Expected the overdue time to be cleared, got %v", kubegres.Status.BackUp.OverdueSince)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no event, got: %s", <-recorder.Events)
+	}
+}
+
+func createBackUpUpdaterToTest(lastSuccessTime *metav1.Time) (KubegresBackUpUpdater, *v1.Kubegres, *record.FakeRecorder) {
+
+	kubegres := &v1.Kubegres{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+		Spec: v1.KubegresSpec{
+			Backup: v1.KubegresBackUp{Schedule: "0 */1 * * *", MaxHoursWithoutSuccess: 2},
+		},
+	}
+	kubegres.Status.BackUp.LastSuccessfulBackUp = &v1.KubegresBackUpJobStatus{JobName: "backup-1", CompletionTime: lastSuccessTime}
+
+	recorder := record.NewFakeRecorder(10)
+	logWrapper := log.LogWrapper[*v1.Kubegres]{Resource: kubegres, Logger: logr.Discard(), Recorder: recorder}
+	kubegresContext := ctx.KubegresContext{
+		Kubegres: kubegres,
+		Status:   &status.KubegresStatusWrapper{Kubegres: kubegres, Log: logWrapper},
+		Log:      logWrapper,
+	}
+
+	cronJob := &batch.CronJob{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Now().Add(-24 * time.Hour))}}
+	resourcesStates := states.ResourcesStates{}
+	resourcesStates.BackUp = states.BackUpStates{IsCronJobDeployed: true, DeployedCronJob: cronJob}
+
+	return CreateKubegresBackUpUpdater(kubegresContext, resourcesStates), kubegres, recorder
+}
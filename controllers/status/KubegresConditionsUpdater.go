@@ -0,0 +1,219 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/spec/checker"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+type KubegresConditionsUpdater struct {
+	kubegresContext   ctx.KubegresContext
+	resourcesStates   states.ResourcesStates
+	blockingOperation *operation.BlockingOperation
+}
+
+func CreateKubegresConditionsUpdater(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	blockingOperation *operation.BlockingOperation) KubegresConditionsUpdater {
+
+	return KubegresConditionsUpdater{
+		kubegresContext:   kubegresContext,
+		resourcesStates:   resourcesStates,
+		blockingOperation: blockingOperation,
+	}
+}
+
+func (r *KubegresConditionsUpdater) UpdateSpecValidCondition(specCheckResult checker.SpecCheckResult) {
+
+	if specCheckResult.HasSpecFatalError {
+		r.setCondition(v1.ConditionTypeSpecValid, metav1.ConditionFalse, "SpecCheckErr", specCheckResult.FatalErrorMessage)
+		return
+	}
+
+	r.setCondition(v1.ConditionTypeSpecValid, metav1.ConditionTrue, "SpecValid", "The spec is valid.")
+}
+
+// UpdateConditions sets the conditions which are computed from the states of the deployed resources and from the
+// active blocking operation. The phase is deduced from all the conditions, including the condition "SpecValid".
+func (r *KubegresConditionsUpdater) UpdateConditions() {
+
+	r.updatePrimaryAvailableCondition()
+	r.updateReplicasInSyncCondition()
+	r.updateBackupConfiguredCondition()
+	r.updateFailoverInProgressCondition()
+	r.updateReadyCondition()
+	r.updatePhase()
+}
+
+func (r *KubegresConditionsUpdater) updatePrimaryAvailableCondition() {
+
+	primary := r.resourcesStates.StatefulSets.Primary
+
+	if !primary.IsDeployed {
+		r.setCondition(v1.ConditionTypePrimaryAvailable, metav1.ConditionFalse, "PrimaryNotDeployed",
+			"There is no deployed Primary PostgreSql StatefulSet.")
+
+	} else if !primary.IsReady || !primary.Pod.IsReady {
+		r.setCondition(v1.ConditionTypePrimaryAvailable, metav1.ConditionFalse, "PrimaryNotReady",
+			"The Primary PostgreSql StatefulSet '"+primary.StatefulSet.Name+"' is not ready.")
+
+	} else {
+		r.setCondition(v1.ConditionTypePrimaryAvailable, metav1.ConditionTrue, "PrimaryReady",
+			"The Primary PostgreSql StatefulSet '"+primary.StatefulSet.Name+"' is ready.")
+	}
+}
+
+func (r *KubegresConditionsUpdater) updateReplicasInSyncCondition() {
+
+	nbreExpectedReplicas := r.getNbreExpectedReplicas()
+	nbreReadyReplicas := r.resourcesStates.StatefulSets.Replicas.NbreReady
+	message := fmt.Sprintf("%d of %d Replica PostgreSql StatefulSets are ready.", nbreReadyReplicas, nbreExpectedReplicas)
+
+	if nbreExpectedReplicas == 0 {
+		r.setCondition(v1.ConditionTypeReplicasInSync, metav1.ConditionTrue, "NoReplicaExpected",
+			"The spec does not require any Replica PostgreSql.")
+
+	} else if nbreReadyReplicas < nbreExpectedReplicas {
+		r.setCondition(v1.ConditionTypeReplicasInSync, metav1.ConditionFalse, "ReplicasNotReady", message)
+
+	} else {
+		r.setCondition(v1.ConditionTypeReplicasInSync, metav1.ConditionTrue, "ReplicasReady", message)
+	}
+}
+
+func (r *KubegresConditionsUpdater) updateBackupConfiguredCondition() {
+
+	if r.kubegresContext.Kubegres.Spec.Backup.Schedule == "" {
+		r.setCondition(v1.ConditionTypeBackupConfigured, metav1.ConditionFalse, "BackUpNotEnabled",
+			"The field 'spec.backup.schedule' is not set.")
+
+	} else if !r.resourcesStates.BackUp.IsCronJobDeployed {
+		r.setCondition(v1.ConditionTypeBackupConfigured, metav1.ConditionFalse, "BackUpCronJobNotDeployed",
+			"The back-up CronJob is not deployed yet.")
+
+	} else {
+		r.setCondition(v1.ConditionTypeBackupConfigured, metav1.ConditionTrue, "BackUpCronJobDeployed",
+			"The back-up CronJob '"+r.resourcesStates.BackUp.DeployedCronJob.Name+"' is deployed.")
+	}
+}
+
+func (r *KubegresConditionsUpdater) updateFailoverInProgressCondition() {
+
+	if r.isFailoverInProgress() {
+		r.setCondition(v1.ConditionTypeFailoverInProgress, metav1.ConditionTrue, "FailingOver",
+			r.blockingOperation.GetActiveOperation().StepId)
+		return
+	}
+
+	r.setCondition(v1.ConditionTypeFailoverInProgress, metav1.ConditionFalse, "NoFailover",
+		"There is no failover in progress.")
+}
+
+func (r *KubegresConditionsUpdater) updateReadyCondition() {
+
+	if r.isConditionFalse(v1.ConditionTypeSpecValid) {
+		r.setCondition(v1.ConditionTypeReady, metav1.ConditionFalse, "SpecNotValid",
+			"The spec is not valid.")
+
+	} else if !r.isConditionTrue(v1.ConditionTypePrimaryAvailable) {
+		r.setCondition(v1.ConditionTypeReady, metav1.ConditionFalse, "PrimaryNotAvailable",
+			"The Primary PostgreSql is not available.")
+
+	} else if r.isConditionTrue(v1.ConditionTypeFailoverInProgress) {
+		r.setCondition(v1.ConditionTypeReady, metav1.ConditionFalse, "FailingOver",
+			"A failover is in progress.")
+
+	} else if !r.isConditionTrue(v1.ConditionTypeReplicasInSync) {
+		r.setCondition(v1.ConditionTypeReady, metav1.ConditionFalse, "ReplicasNotInSync",
+			"Not all Replica PostgreSql are ready.")
+
+	} else {
+		r.setCondition(v1.ConditionTypeReady, metav1.ConditionTrue, "ClusterReady",
+			"All PostgreSql instances are ready.")
+	}
+}
+
+func (r *KubegresConditionsUpdater) updatePhase() {
+
+	if r.isConditionFalse(v1.ConditionTypeSpecValid) {
+		r.kubegresContext.Status.SetPhase(v1.PhaseFailed)
+
+	} else if r.isConditionTrue(v1.ConditionTypeFailoverInProgress) {
+		r.kubegresContext.Status.SetPhase(v1.PhaseFailingOver)
+
+	} else if !r.resourcesStates.StatefulSets.Primary.IsDeployed && r.kubegresContext.Status.GetLastCreatedInstanceIndex() == 0 {
+		r.kubegresContext.Status.SetPhase(v1.PhaseCreating)
+
+	} else if r.isThereActiveBlockingOperation() {
+		r.kubegresContext.Status.SetPhase(v1.PhaseUpdating)
+
+	} else if r.isConditionTrue(v1.ConditionTypeReady) {
+		r.kubegresContext.Status.SetPhase(v1.PhaseRunning)
+
+	} else {
+		r.kubegresContext.Status.SetPhase(v1.PhaseDegraded)
+	}
+}
+
+func (r *KubegresConditionsUpdater) isFailoverInProgress() bool {
+	activeOperation := r.blockingOperation.GetActiveOperation()
+	return activeOperation.OperationId == operation.OperationIdPrimaryDbCountSpecEnforcement &&
+		(activeOperation.StepId == operation.OperationStepIdPrimaryDbWaitingBeforeFailingOver ||
+			activeOperation.StepId == operation.OperationStepIdPrimaryDbFailingOver)
+}
+
+func (r *KubegresConditionsUpdater) isThereActiveBlockingOperation() bool {
+	return r.blockingOperation.GetActiveOperation().OperationId != ""
+}
+
+func (r *KubegresConditionsUpdater) getNbreExpectedReplicas() int32 {
+	nbreExpectedReplicas := r.resourcesStates.StatefulSets.SpecExpectedNbreToDeploy - 1
+	if nbreExpectedReplicas < 0 {
+		return 0
+	}
+	return nbreExpectedReplicas
+}
+
+func (r *KubegresConditionsUpdater) isConditionTrue(conditionType string) bool {
+	condition := r.kubegresContext.Status.GetCondition(conditionType)
+	return condition != nil && condition.Status == metav1.ConditionTrue
+}
+
+func (r *KubegresConditionsUpdater) isConditionFalse(conditionType string) bool {
+	condition := r.kubegresContext.Status.GetCondition(conditionType)
+	return condition != nil && condition.Status == metav1.ConditionFalse
+}
+
+func (r *KubegresConditionsUpdater) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	r.kubegresContext.Status.SetCondition(metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: r.kubegresContext.Kubegres.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
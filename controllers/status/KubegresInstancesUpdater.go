@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	v1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/states"
+	"reactive-tech.io/kubegres/controllers/states/statefulset"
+)
+
+type KubegresInstancesUpdater struct {
+	kubegresContext ctx.KubegresContext
+	resourcesStates states.ResourcesStates
+}
+
+func CreateKubegresInstancesUpdater(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates) KubegresInstancesUpdater {
+
+	return KubegresInstancesUpdater{
+		kubegresContext: kubegresContext,
+		resourcesStates: resourcesStates,
+	}
+}
+
+func (r *KubegresInstancesUpdater) UpdateInstances() {
+
+	var instances []v1.KubegresInstanceStatus
+
+	for _, statefulSetWrapper := range r.resourcesStates.StatefulSets.All.GetAllSortedByInstanceIndex() {
+		instances = append(instances, r.createInstanceStatus(statefulSetWrapper))
+	}
+
+	r.kubegresContext.Status.SetInstances(instances)
+}
+
+func (r *KubegresInstancesUpdater) createInstanceStatus(statefulSetWrapper statefulset.StatefulSetWrapper) v1.KubegresInstanceStatus {
+
+	statefulSet := statefulSetWrapper.StatefulSet
+	pod := statefulSetWrapper.Pod
+
+	instance := v1.KubegresInstanceStatus{
+		InstanceIndex: statefulSetWrapper.InstanceIndex,
+		StatefulSet:   statefulSet.Name,
+		Role:          r.getRole(statefulSetWrapper),
+		IsReady:       statefulSetWrapper.IsReady && pod.IsReady,
+		IsStuck:       pod.IsStuck,
+		PvcName:       r.getDatabasePvcName(statefulSetWrapper),
+	}
+
+	if len(statefulSet.Spec.Template.Spec.Containers) > 0 {
+		instance.Image = statefulSet.Spec.Template.Spec.Containers[0].Image
+	}
+
+	if pod.IsDeployed {
+		instance.PodName = pod.Pod.Name
+		instance.NodeName = pod.Pod.Spec.NodeName
+	}
+
+	return instance
+}
+
+func (r *KubegresInstancesUpdater) getRole(statefulSetWrapper statefulset.StatefulSetWrapper) string {
+	primary := r.resourcesStates.StatefulSets.Primary
+	if primary.IsDeployed && primary.InstanceIndex == statefulSetWrapper.InstanceIndex {
+		return ctx.PrimaryRoleName
+	}
+	return ctx.ReplicaRoleName
+}
+
+// The PVC of a StatefulSet's instance is named by Kubernetes as follows: <volumeClaimTemplate name>-<statefulSet name>-<pod ordinal>
+// Each StatefulSet deployed by Kubegres has a single Pod, so its ordinal is always 0.
+func (r *KubegresInstancesUpdater) getDatabasePvcName(statefulSetWrapper statefulset.StatefulSetWrapper) string {
+	return r.kubegresContext.GetDatabasePvcResourceName(statefulSetWrapper.StatefulSet.Name)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/metrics"
+	"reactive-tech.io/kubegres/controllers/operation"
+	"reactive-tech.io/kubegres/controllers/states"
+)
+
+type KubegresMetricsUpdater struct {
+	kubegresContext   ctx.KubegresContext
+	resourcesStates   states.ResourcesStates
+	blockingOperation *operation.BlockingOperation
+}
+
+func CreateKubegresMetricsUpdater(kubegresContext ctx.KubegresContext,
+	resourcesStates states.ResourcesStates,
+	blockingOperation *operation.BlockingOperation) KubegresMetricsUpdater {
+
+	return KubegresMetricsUpdater{
+		kubegresContext:   kubegresContext,
+		resourcesStates:   resourcesStates,
+		blockingOperation: blockingOperation,
+	}
+}
+
+func (r *KubegresMetricsUpdater) UpdateMetrics() {
+
+	kubegres := r.getKubegresNamespacedName()
+	statefulSetsStates := r.resourcesStates.StatefulSets
+
+	metrics.SetReadyInstances(kubegres, statefulSetsStates.Primary.IsReady, statefulSetsStates.Replicas.NbreReady)
+	metrics.SetReplicasLag(kubegres, r.getReplicasLag())
+
+	activeOperation := r.blockingOperation.GetActiveOperation()
+	metrics.SetBlockingOperation(kubegres, activeOperation.OperationId, activeOperation.StepId,
+		r.blockingOperation.GetNbreSecondsLeftBeforeTimeOut())
+
+	metrics.CountBackUpJobs(kubegres, r.getCompletedBackUpJobs())
+}
+
+func (r *KubegresMetricsUpdater) getKubegresNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: r.kubegresContext.Kubegres.Namespace,
+		Name:      r.kubegresContext.Kubegres.Name,
+	}
+}
+
+func (r *KubegresMetricsUpdater) getReplicasLag() map[string]int64 {
+
+	lagInBytesByInstance := make(map[string]int64)
+
+	for _, replica := range r.resourcesStates.Replication.Replicas {
+		if lagInBytes, isKnown := r.resourcesStates.Replication.GetReplicaLagInBytes(replica.InstanceIndex); isKnown {
+			lagInBytesByInstance[replica.StatefulSetName] = lagInBytes
+		}
+	}
+
+	return lagInBytesByInstance
+}
+
+func (r *KubegresMetricsUpdater) getCompletedBackUpJobs() map[types.UID]string {
+
+	outcomeByJobUid := make(map[types.UID]string)
+
+	for _, job := range r.resourcesStates.BackUp.Jobs {
+		if r.hasJobCondition(job, batch.JobComplete) {
+			outcomeByJobUid[job.UID] = metrics.OutcomeSucceeded
+
+		} else if r.hasJobCondition(job, batch.JobFailed) {
+			outcomeByJobUid[job.UID] = metrics.OutcomeFailed
+		}
+	}
+
+	return outcomeByJobUid
+}
+
+func (r *KubegresMetricsUpdater) hasJobCondition(job batch.Job, conditionType batch.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == core.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -80,6 +80,7 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "d5ccd92e.reactive-tech.io",
+		ClientDisableCacheFor:  controllers.GetObjectsNotCached(),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -116,6 +117,37 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "KubegresRestore")
 		os.Exit(1)
 	}
+
+	if err = (&controllers.KubegresBackupReconciler{
+		Client:   mgr.GetClient(),
+		Logger:   ctrl.Log.WithName("backupController").WithName(ctx2.KindKubegresBackup),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("KubegresBackup-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", ctx2.KindKubegresBackup)
+		os.Exit(1)
+	}
+	// The webhooks require a TLS certificate, which is usually provided by cert-manager. They are disabled by default
+	// and the controllers keep checking the specs, so that Kubegres can be deployed without cert-manager.
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = (&controllers.KubegresWebhook{
+			Client:   mgr.GetClient(),
+			Logger:   ctrl.Log.WithName("webhooks").WithName(ctx2.KindKubegres),
+			Recorder: mgr.GetEventRecorderFor("Kubegres-webhook"),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", ctx2.KindKubegres)
+			os.Exit(1)
+		}
+
+		if err = (&controllers.KubegresRestoreWebhook{
+			Client:   mgr.GetClient(),
+			Logger:   ctrl.Log.WithName("webhooks").WithName("KubegresRestore"),
+			Recorder: mgr.GetEventRecorderFor("KubegresRestore-webhook"),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KubegresRestore")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,242 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strings"
+	"time"
+)
+
+const kubegresBackupResourceName = "my-kubegres-backup"
+
+var _ = Describe("Creating a KubegresBackup resource", func() {
+
+	var test = KubegresBackupTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.resourceCreator.CreateBackUpPvc()
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources(resourceConfigs.BackUpPvcResourceName)
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.schedule' AND a KubegresBackup is created for it", func() {
+
+		It("THEN a backup Job is created AND the KubegresBackup status has the phase 'Succeeded' with the backup file", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.schedule' AND a KubegresBackup is created for it'")
+
+			test.givenNewKubegresSpecIsSetTo(scheduleBackupEvery2Mins, resourceConfigs.BackUpPvcResourceName, "/tmp/my-kubegres")
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.givenNewKubegresBackupIsSetTo(resourceConfigs.KubegresResourceName)
+
+			test.whenKubegresBackupIsCreated()
+
+			test.thenKubegresBackupJobShouldBeCreated()
+
+			test.thenKubegresBackupStatusShouldBeSucceeded()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.schedule' AND a KubegresBackup is created for it'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created without spec 'backup.schedule' AND a KubegresBackup is created for it", func() {
+
+		It("THEN an error event should be logged AND the KubegresBackup status has the phase 'Pending'", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created without spec 'backup.schedule' AND a KubegresBackup is created for it'")
+
+			test.givenNewKubegresSpecIsSetTo("", "", "")
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.givenNewKubegresBackupIsSetTo(resourceConfigs.KubegresResourceName)
+
+			test.whenKubegresBackupIsCreated()
+
+			test.thenErrorEventSayingClusterHasNoBackUpCronJob()
+
+			test.thenKubegresBackupStatusPhaseShouldBe(postgresv1.KubegresBackupPhasePending)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created without spec 'backup.schedule' AND a KubegresBackup is created for it'")
+		})
+	})
+})
+
+type KubegresBackupTest struct {
+	kubegresResource       *postgresv1.Kubegres
+	kubegresBackupResource *postgresv1.KubegresBackup
+	resourceCreator        util.TestResourceCreator
+	resourceRetriever      util.TestResourceRetriever
+}
+
+func (r *KubegresBackupTest) givenNewKubegresSpecIsSetTo(backupSchedule, backupPvcName, backupVolumeMount string) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.CustomConfig = ctx.BaseConfigMapName
+
+	if backupSchedule != "" {
+		r.kubegresResource.Spec.Backup.Schedule = backupSchedule
+		r.kubegresResource.Spec.Backup.PvcName = backupPvcName
+		r.kubegresResource.Spec.Backup.VolumeMount = backupVolumeMount
+	}
+}
+
+func (r *KubegresBackupTest) givenNewKubegresBackupIsSetTo(clusterName string) {
+	r.kubegresBackupResource = &postgresv1.KubegresBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubegresBackupResourceName,
+			Namespace: resourceConfigs.DefaultNamespace,
+		},
+		Spec: postgresv1.KubegresBackupSpec{ClusterName: clusterName},
+	}
+}
+
+func (r *KubegresBackupTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *KubegresBackupTest) whenKubegresBackupIsCreated() {
+	r.resourceCreator.CreateKubegresBackup(r.kubegresBackupResource)
+}
+
+func (r *KubegresBackupTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *KubegresBackupTest) thenKubegresBackupJobShouldBeCreated() bool {
+	return Eventually(func() bool {
+
+		jobs, err := r.resourceRetriever.GetJobs()
+		if err != nil {
+			log.Println("ERROR while retrieving Jobs")
+			return false
+		}
+
+		for _, job := range jobs.Items {
+			if job.Name == kubegresBackupResourceName+ctx.BackupJobSuffix &&
+				job.Labels[ctx.ManagedByKubegresBackupLabel] == kubegresBackupResourceName {
+				return true
+			}
+		}
+
+		log.Println("The backup Job of the KubegresBackup is not created. Waiting...")
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *KubegresBackupTest) thenKubegresBackupStatusShouldBeSucceeded() bool {
+	return Eventually(func() bool {
+
+		kubegresBackup, err := r.resourceRetriever.GetKubegresBackup(kubegresBackupResourceName)
+		if err != nil {
+			log.Println("ERROR while retrieving KubegresBackup resource")
+			return false
+		}
+
+		status := kubegresBackup.Status
+		if status.Phase != postgresv1.KubegresBackupPhaseSucceeded ||
+			status.CompletionTime == nil ||
+			!strings.HasPrefix(status.FileName, resourceConfigs.KubegresResourceName+"-backup-") ||
+			status.File == nil ||
+			status.File.PvcName != resourceConfigs.BackUpPvcResourceName ||
+			status.File.Snapshot != status.FileName {
+
+			log.Println("KubegresBackup status is not 'Succeeded' with the backup file. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *KubegresBackupTest) thenKubegresBackupStatusPhaseShouldBe(expectedPhase string) bool {
+	return Eventually(func() bool {
+
+		kubegresBackup, err := r.resourceRetriever.GetKubegresBackup(kubegresBackupResourceName)
+		if err != nil {
+			log.Println("ERROR while retrieving KubegresBackup resource")
+			return false
+		}
+
+		if kubegresBackup.Status.Phase != expectedPhase {
+			log.Println("KubegresBackup status phase is '" + kubegresBackup.Status.Phase + "' instead of '" + expectedPhase + "'. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *KubegresBackupTest) thenErrorEventSayingClusterHasNoBackUpCronJob() {
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "SpecCheckErr",
+		Message: "In the Resources Spec the value of 'spec.clusterName' refers to a Kubegres resource without a backup CronJob. " +
+			"Please set the field 'backup.schedule' of that Kubegres resource, otherwise this operator cannot work correctly.",
+	}
+	Eventually(func() bool {
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
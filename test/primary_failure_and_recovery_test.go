@@ -153,6 +153,40 @@ var _ = Describe("Primary instances is not available, checking recovery works",
 		})
 	})
 
+	Context("GIVEN Kubegres with 1 primary and 2 replicas AND 'failover.maxLagBytes' is set AND primary is deleted", func() {
+
+		It("THEN the failover should take place with the most advanced replica becoming primary AND existing data available", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND 'failover.maxLagBytes' is set AND primary is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(3)
+			test.givenFailoverMaxLagBytesIsSetTo(16 * 1024 * 1024)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			expectedNbreUsers := 0
+
+			test.GivenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.GivenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.wait10Seconds()
+
+			test.whenPrimaryIsDeleted()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.ThenPrimaryDbContainsExpectedNbreUsers(expectedNbreUsers)
+			test.ThenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND 'failover.maxLagBytes' is set AND primary is deleted'")
+		})
+	})
+
 })
 
 type PrimaryFailureAndRecoveryTest struct {
@@ -170,6 +204,10 @@ func (r *PrimaryFailureAndRecoveryTest) givenNewKubegresSpecIsSetTo(specNbreRepl
 	r.kubegresResource.Spec.Replicas = &specNbreReplicas
 }
 
+func (r *PrimaryFailureAndRecoveryTest) givenFailoverMaxLagBytesIsSetTo(maxLagBytes int64) {
+	r.kubegresResource.Spec.Failover.MaxLagBytes = &maxLagBytes
+}
+
 func (r *PrimaryFailureAndRecoveryTest) whenKubegresIsCreated() {
 	r.resourceCreator.CreateKubegres(r.kubegresResource)
 }
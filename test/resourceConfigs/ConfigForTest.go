@@ -52,6 +52,14 @@ const (
 	BackUpPvcResourceName2 = "test-pvc-for-backup-2"
 	BackUpPvcYamlFile      = "resourceConfigs/backupPvc.yaml"
 
+	MinioYamlFile                = "resourceConfigs/minio.yaml"
+	MinioServiceYamlFile         = "resourceConfigs/minioService.yaml"
+	MinioSecretYamlFile          = "resourceConfigs/minioSecret.yaml"
+	MinioResourceName            = "test-minio"
+	MinioCredentialsResourceName = "test-minio-credentials"
+	MinioEndpoint                = "http://test-minio:9000"
+	MinioBucket                  = "kubegres-backups"
+
 	CustomConfigMapEmptyResourceName = "config-empty"
 	CustomConfigMapEmptyYamlFile     = "resourceConfigs/customConfig/configMap_empty.yaml"
 
@@ -53,6 +53,24 @@ func LoadSecretYaml() v1.Secret {
 	return *obj.(*v1.Secret)
 }
 
+func LoadMinioYaml() v1.Pod {
+	fileContents := getFileContents(MinioYamlFile)
+	obj := decodeYaml(fileContents)
+	return *obj.(*v1.Pod)
+}
+
+func LoadMinioServiceYaml() v1.Service {
+	fileContents := getFileContents(MinioServiceYamlFile)
+	obj := decodeYaml(fileContents)
+	return *obj.(*v1.Service)
+}
+
+func LoadMinioSecretYaml() v1.Secret {
+	fileContents := getFileContents(MinioSecretYamlFile)
+	obj := decodeYaml(fileContents)
+	return *obj.(*v1.Secret)
+}
+
 func LoadYamlServiceToSqlQueryPrimaryDb() v1.Service {
 	fileContents := getFileContents(ServiceToSqlQueryPrimaryDbYamlFile)
 	obj := decodeYaml(fileContents)
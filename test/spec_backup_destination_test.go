@@ -0,0 +1,206 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strings"
+)
+
+var _ = Describe("Setting Kubegres specs 'backup.destination.*'", func() {
+
+	var test = SpecBackUpDestinationTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.resourceCreator.CreateMinio()
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.destination.s3' set AND without spec 'backup.pvcName'", func() {
+
+		It("THEN the backup CronJob uploads the backups in the S3 bucket", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.destination.s3' set AND without spec 'backup.pvcName''")
+
+			test.givenNewKubegresSpecIsSetTo(&postgresv1.KubegresS3{
+				Bucket:            resourceConfigs.MinioBucket,
+				Endpoint:          resourceConfigs.MinioEndpoint,
+				CredentialsSecret: resourceConfigs.MinioCredentialsResourceName,
+			}, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenCronJobShouldUploadBackUpsToS3()
+
+			test.thenBackUpJobShouldSucceed()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.destination.s3' set AND without spec 'backup.pvcName''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.destination.s3' set BUT WITHOUT spec 'backup.destination.s3.bucket'", func() {
+
+		It("THEN an error event should be logged", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.destination.s3' set BUT WITHOUT spec 'backup.destination.s3.bucket''")
+
+			test.givenNewKubegresSpecIsSetTo(&postgresv1.KubegresS3{
+				Endpoint:          resourceConfigs.MinioEndpoint,
+				CredentialsSecret: resourceConfigs.MinioCredentialsResourceName,
+			}, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenErrorEventShouldBeLogged("spec.backup.destination.s3.bucket")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.destination.s3' set BUT WITHOUT spec 'backup.destination.s3.bucket''")
+		})
+	})
+
+})
+
+type SpecBackUpDestinationTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *SpecBackUpDestinationTest) givenNewKubegresSpecIsSetTo(s3 *postgresv1.KubegresS3, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Backup.Schedule = scheduleBackupEveryMin
+	r.kubegresResource.Spec.Backup.Destination.S3 = s3
+}
+
+func (r *SpecBackUpDestinationTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecBackUpDestinationTest) thenErrorEventShouldBeLogged(specName string) {
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "SpecCheckErr",
+		Message:   "In the Resources Spec the value of '" + specName + "' is undefined. Please set a value otherwise this operator cannot work correctly.",
+	}
+	Eventually(func() bool {
+		_, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			return false
+		}
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpDestinationTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpDestinationTest) thenCronJobShouldUploadBackUpsToS3() bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		backUpCronJob := kubegresResources.BackUpCronJob
+		if backUpCronJob.Name == "" {
+			return false
+		}
+
+		podSpec := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec
+		destination := backUpCronJob.Annotations[template.BackUpDestinationAnnotationKey]
+
+		if len(podSpec.InitContainers) != 1 ||
+			len(podSpec.Containers) != 1 ||
+			podSpec.Containers[0].Name != ctx.BackUpUploaderContainerName ||
+			podSpec.Volumes[0].EmptyDir == nil ||
+			!strings.Contains(destination, "url=s3://"+resourceConfigs.MinioBucket) {
+
+			log.Println("CronJob '" + backUpCronJob.Name + "' does not upload the backups in the S3 bucket. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpDestinationTest) thenBackUpJobShouldSucceed() bool {
+	return Eventually(func() bool {
+
+		jobs, err := r.resourceRetriever.GetJobs()
+		if err != nil {
+			log.Println("ERROR while retrieving Jobs")
+			return false
+		}
+
+		for _, job := range jobs.Items {
+			if strings.HasPrefix(job.Name, ctx.CronJobNamePrefix) && job.Status.Succeeded > 0 {
+				log.Println("Backup Job '" + job.Name + "' uploaded a backup in the S3 bucket")
+				return true
+			}
+		}
+
+		log.Println("No backup Job has uploaded a backup in the S3 bucket yet. Waiting...")
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
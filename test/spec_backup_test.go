@@ -31,6 +31,7 @@ import (
 	"reactive-tech.io/kubegres/test/resourceConfigs"
 	"reactive-tech.io/kubegres/test/util"
 	"reactive-tech.io/kubegres/test/util/testcases"
+	"strings"
 	"time"
 )
 
@@ -172,6 +173,65 @@ var _ = Describe("Setting Kubegres specs 'backup.*'", func() {
 		})
 	})
 
+	Context("GIVEN new Kubegres is created with backup specs set AND with spec 'backup.type' set to 'physical'", func() {
+
+		It("THEN backup CronJob is created AND it takes base backups with pg_basebackup", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with backup specs set AND with spec 'backup.type' set to 'physical''")
+
+			test.givenNewKubegresSpecIsSetTo(ctx.BaseConfigMapName, scheduleBackupEveryMin, resourceConfigs.BackUpPvcResourceName, "/tmp/my-kubegres", 3)
+			test.givenKubegresBackUpTypeIsSetTo(postgresv1.BackUpTypePhysical)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenCronJobExistsWithSpec(ctx.BaseConfigMapName, scheduleBackupEveryMin, resourceConfigs.BackUpPvcResourceName, "/tmp/my-kubegres")
+			test.thenCronJobShouldRunScript("base_backup_database.sh")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with backup specs set AND with spec 'backup.type' set to 'physical''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with backup specs set AND the backup CronJob runs", func() {
+
+		It("THEN the last successful backup should be set in the status of Kubegres", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with backup specs set AND the backup CronJob runs'")
+
+			test.givenNewKubegresSpecIsSetTo(ctx.BaseConfigMapName, scheduleBackupEveryMin, resourceConfigs.BackUpPvcResourceName, "/tmp/my-kubegres", 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenKubegresStatusShouldHaveLastSuccessfulBackUp()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with backup specs set AND the backup CronJob runs'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with backup specs set AND with spec 'backup.retention.keepLast' set to 1", func() {
+
+		It("THEN backup CronJob should remove the old backups AND the retained backup should be set in the status of Kubegres", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with backup specs set AND with spec 'backup.retention.keepLast' set to 1'")
+
+			test.givenNewKubegresSpecIsSetTo(ctx.BaseConfigMapName, scheduleBackupEveryMin, resourceConfigs.BackUpPvcResourceName, "/tmp/my-kubegres", 3)
+			test.givenKubegresBackUpRetentionIsSetTo(postgresv1.KubegresBackUpRetention{KeepLast: 1})
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenCronJobShouldRemoveOldBackUps()
+
+			test.thenKubegresStatusShouldHaveRetainedBackUps(1)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with backup specs set AND with spec 'backup.retention.keepLast' set to 1'")
+		})
+	})
+
 	Context("GIVEN new Kubegres is created with backup specs set AND later Kubegres is updated with new values for backup specs", func() {
 
 		It("THEN backup CronJob is updated with the new backup specs", func() {
@@ -292,6 +352,14 @@ func (r *SpecBackUpTest) givenKubegresAnnotationIsSetTo(annotationKey, annotatio
 	r.resourceModifier.AppendAnnotation(annotationKey, annotationValue, r.kubegresResource)
 }
 
+func (r *SpecBackUpTest) givenKubegresBackUpTypeIsSetTo(backUpType string) {
+	r.kubegresResource.Spec.Backup.Type = backUpType
+}
+
+func (r *SpecBackUpTest) givenKubegresBackUpRetentionIsSetTo(retention postgresv1.KubegresBackUpRetention) {
+	r.kubegresResource.Spec.Backup.Retention = retention
+}
+
 func (r *SpecBackUpTest) givenExistingKubegresSpecIsSetTo(customConfig, backupSchedule, backupPvcName, backupVolumeMount string) {
 	var err error
 	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
@@ -492,3 +560,101 @@ func (r *SpecBackUpTest) thenCronJobSpecShouldHaveEnvVar(envVarName, envVarVal s
 
 	}, time.Second*10, time.Second*5).Should(BeTrue())
 }
+
+func (r *SpecBackUpTest) thenCronJobShouldRunScript(expectedScript string) bool {
+
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		backUpCronJob := kubegresResources.BackUpCronJob
+		if backUpCronJob.Name == "" {
+			return false
+		}
+
+		cronJobScript := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].VolumeMounts[1].SubPath
+		if expectedScript != cronJobScript {
+			log.Println("CronJob '" + backUpCronJob.Name + "' doesn't run the expected script: '" + expectedScript + "'. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, time.Second*10, time.Second*5).Should(BeTrue())
+}
+
+func (r *SpecBackUpTest) thenCronJobShouldRemoveOldBackUps() bool {
+
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		backUpCronJob := kubegresResources.BackUpCronJob
+		if backUpCronJob.Name == "" {
+			return false
+		}
+
+		podSpec := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec
+		if len(podSpec.InitContainers) != 1 || podSpec.Containers[0].Name != ctx.BackUpPrunerContainerName {
+			log.Println("CronJob '" + backUpCronJob.Name + "' doesn't remove the old backups. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, time.Second*10, time.Second*5).Should(BeTrue())
+}
+
+func (r *SpecBackUpTest) thenKubegresStatusShouldHaveRetainedBackUps(expectedNbreRetainedBackUps int) bool {
+
+	return Eventually(func() bool {
+
+		kubegres, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		backUpStatus := kubegres.Status.BackUp
+		if backUpStatus.LastPruneTime == nil || len(backUpStatus.RetainedBackUps) != expectedNbreRetainedBackUps {
+			log.Println("Kubegres status does not have the expected number of retained backups. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpTest) thenKubegresStatusShouldHaveLastSuccessfulBackUp() bool {
+
+	return Eventually(func() bool {
+
+		kubegres, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		lastSuccessfulBackUp := kubegres.Status.BackUp.LastSuccessfulBackUp
+		if lastSuccessfulBackUp == nil ||
+			lastSuccessfulBackUp.CompletionTime == nil ||
+			!strings.HasPrefix(lastSuccessfulBackUp.FileName, kubegres.Name+"-backup-") ||
+			lastSuccessfulBackUp.SizeBytes <= 0 {
+
+			log.Println("Kubegres status does not have the last successful backup. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/controllers/spec/template"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strings"
+	"time"
+)
+
+var _ = Describe("Setting Kubegres specs 'backup.walArchive.*'", func() {
+
+	var test = SpecBackUpWalArchiveTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.resourceCreator.CreateBackUpPvc()
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources(resourceConfigs.BackUpPvcResourceName)
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND without spec 'backup.walArchive.s3'", func() {
+
+		It("THEN the WAL segments are archived in the backup PVC AND backup CronJob takes base backups", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND without spec 'backup.walArchive.s3''")
+
+			test.givenNewKubegresSpecIsSetTo(true, nil, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenStatefulSetsShouldArchiveWal(true)
+
+			test.thenCronJobShouldRunScript(ctx.BaseConfigMapName, "base_backup_database.sh")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND without spec 'backup.walArchive.s3''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.walArchive.s3' set BUT WITHOUT spec 'backup.walArchive.s3.bucket'", func() {
+
+		It("THEN an error event should be logged", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.s3' set BUT WITHOUT spec 'backup.walArchive.s3.bucket''")
+
+			test.givenNewKubegresSpecIsSetTo(true, &postgresv1.KubegresS3{CredentialsSecret: "my-s3-credentials"}, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenErrorEventShouldBeLogged("spec.backup.walArchive.s3.bucket")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.s3' set BUT WITHOUT spec 'backup.walArchive.s3.bucket''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND later it is set to false", func() {
+
+		It("THEN the WAL segments are no longer archived AND backup CronJob keeps taking the base backups set by default", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND later it is set to false'")
+
+			test.givenNewKubegresSpecIsSetTo(true, nil, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenStatefulSetsShouldArchiveWal(true)
+
+			test.givenExistingKubegresWalArchiveIsSetTo(false)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenStatefulSetsShouldArchiveWal(false)
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenCronJobShouldRunScript(ctx.BaseConfigMapName, "base_backup_database.sh")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND later it is set to false'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND spec 'backup.type' set to 'logical'", func() {
+
+		It("THEN an error event should be logged", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND spec 'backup.type' set to 'logical''")
+
+			test.givenNewKubegresSpecIsSetTo(true, nil, 3)
+
+			test.givenNewKubegresBackUpTypeIsSetTo(postgresv1.BackUpTypeLogical)
+
+			test.whenKubegresIsCreated()
+
+			test.thenErrorEventShouldBeLoggedWithMessage("In the Resources Spec the value of 'spec.backup.type' " +
+				"is 'logical' while 'spec.backup.walArchive.enabled' is true. The archived WAL segments can only be " +
+				"replayed on top of a physical backup. Please set 'spec.backup.type' to 'physical' in the YAML.")
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.walArchive.enabled' set to true AND spec 'backup.type' set to 'logical''")
+		})
+	})
+
+})
+
+type SpecBackUpWalArchiveTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *SpecBackUpWalArchiveTest) givenNewKubegresSpecIsSetTo(walArchiveEnabled bool, s3 *postgresv1.KubegresS3, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Backup.Schedule = scheduleBackupEveryMin
+	r.kubegresResource.Spec.Backup.PvcName = resourceConfigs.BackUpPvcResourceName
+	r.kubegresResource.Spec.Backup.VolumeMount = "/tmp/my-kubegres"
+	r.kubegresResource.Spec.Backup.WalArchive.Enabled = walArchiveEnabled
+	r.kubegresResource.Spec.Backup.WalArchive.S3 = s3
+}
+
+func (r *SpecBackUpWalArchiveTest) givenNewKubegresBackUpTypeIsSetTo(backUpType string) {
+	r.kubegresResource.Spec.Backup.Type = backUpType
+}
+
+func (r *SpecBackUpWalArchiveTest) givenExistingKubegresWalArchiveIsSetTo(walArchiveEnabled bool) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Backup.WalArchive.Enabled = walArchiveEnabled
+}
+
+func (r *SpecBackUpWalArchiveTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecBackUpWalArchiveTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecBackUpWalArchiveTest) thenErrorEventShouldBeLogged(specName string) {
+	r.thenErrorEventShouldBeLoggedWithMessage("In the Resources Spec the value of '" + specName + "' is undefined. Please set a value otherwise this operator cannot work correctly.")
+}
+
+func (r *SpecBackUpWalArchiveTest) thenErrorEventShouldBeLoggedWithMessage(message string) {
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "SpecCheckErr",
+		Message:   message,
+	}
+	Eventually(func() bool {
+		_, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			return false
+		}
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpWalArchiveTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpWalArchiveTest) thenStatefulSetsShouldArchiveWal(expectedArchiveMode bool) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if len(kubegresResources.Resources) == 0 {
+			return false
+		}
+
+		for _, resource := range kubegresResources.Resources {
+			statefulSetSpec := resource.StatefulSet.Spec
+			_, hasAnnotation := statefulSetSpec.Template.Annotations[template.WalArchiveConfigAnnotationKey]
+			hasArchiveModeArg := strings.Contains(strings.Join(statefulSetSpec.Template.Spec.Containers[0].Args, " "), "archive_mode=on")
+
+			if hasAnnotation != expectedArchiveMode || hasArchiveModeArg != expectedArchiveMode {
+				log.Println("StatefulSet '" + resource.StatefulSet.Name + "' does not have the expected WAL archive config. Waiting...")
+				return false
+			}
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecBackUpWalArchiveTest) thenCronJobShouldRunScript(expectedConfigMapName, expectedScript string) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		backUpCronJob := kubegresResources.BackUpCronJob
+		if backUpCronJob.Name == "" {
+			return false
+		}
+
+		podSpec := backUpCronJob.Spec.JobTemplate.Spec.Template.Spec
+		if podSpec.Volumes[1].ConfigMap.Name != expectedConfigMapName ||
+			podSpec.Containers[0].VolumeMounts[1].SubPath != expectedScript {
+			log.Println("CronJob '" + backUpCronJob.Name + "' does not run the expected script '" + expectedScript + "'. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
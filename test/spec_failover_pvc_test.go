@@ -0,0 +1,289 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strconv"
+	"time"
+)
+
+var _ = Describe("Setting Kubegres spec 'failover.pvc'", func() {
+
+	var test = SpecFailoverPvcTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.connectionPrimaryDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryPrimaryDbNodePort, true)
+		test.connectionReplicaDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryReplicaDbNodePort, false)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'delete' AND primary is deleted", func() {
+
+		It("THEN the failover should take place AND the PVC of the failed primary should be deleted", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'delete' AND primary is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.FailoverPvcDelete, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			failedPrimaryPvcName := test.whenPrimaryIsDeleted()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPvcShouldNotExist(failedPrimaryPvcName)
+
+			test.thenNbreDatabasePvcsShouldBe(3)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'delete' AND primary is deleted'")
+		})
+	})
+
+	Context("GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'reuse' AND primary is deleted", func() {
+
+		It("THEN the failover should take place AND the new replica should reuse the PVC of the failed primary AND existing data available", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'reuse' AND primary is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.FailoverPvcReuse, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.GivenUserAddedInPrimaryDb()
+
+			failedPrimaryPvcName := test.whenPrimaryIsDeleted()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPvcShouldBeUsedByReplica(failedPrimaryPvcName)
+
+			test.thenNbreDatabasePvcsShouldBe(3)
+
+			test.ThenReplicaDbContainsExpectedNbreUsers(1)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.pvc' set to 'reuse' AND primary is deleted'")
+		})
+	})
+
+	Context("GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.rewindFailedPrimary' set to true AND primary is deleted", func() {
+
+		It("THEN the failover should take place AND the failed primary should rejoin as a replica using its PVC AND new data replicated", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.rewindFailedPrimary' set to true AND primary is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.FailoverPvcDelete, 3)
+
+			test.givenRewindFailedPrimaryIsSetTo(true)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.GivenUserAddedInPrimaryDb()
+
+			failedPrimaryPvcName := test.whenPrimaryIsDeleted()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPvcShouldBeUsedByReplica(failedPrimaryPvcName)
+
+			test.thenNbreDatabasePvcsShouldBe(3)
+
+			test.GivenUserAddedInPrimaryDb()
+
+			test.ThenReplicaDbContainsExpectedNbreUsers(2)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 2 replicas AND spec 'failover.rewindFailedPrimary' set to true AND primary is deleted'")
+		})
+	})
+
+})
+
+type SpecFailoverPvcTest struct {
+	kubegresResource    *postgresv1.Kubegres
+	connectionPrimaryDb util.DbConnectionDbUtil
+	connectionReplicaDb util.DbConnectionDbUtil
+	resourceCreator     util.TestResourceCreator
+	resourceRetriever   util.TestResourceRetriever
+}
+
+func (r *SpecFailoverPvcTest) givenNewKubegresSpecIsSetTo(failoverPvc string, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Failover.Pvc = failoverPvc
+}
+
+func (r *SpecFailoverPvcTest) givenRewindFailedPrimaryIsSetTo(rewindFailedPrimary bool) {
+	r.kubegresResource.Spec.Failover.RewindFailedPrimary = rewindFailedPrimary
+}
+
+func (r *SpecFailoverPvcTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecFailoverPvcTest) whenPrimaryIsDeleted() string {
+	kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+	Expect(err).Should(Succeed())
+
+	for _, kubegresResource := range kubegresResources.Resources {
+		if kubegresResource.IsPrimary {
+			log.Println("Attempting to delete StatefulSet: '" + kubegresResource.StatefulSet.Name + "'")
+			Expect(r.resourceCreator.DeleteResource(kubegresResource.StatefulSet.Resource, kubegresResource.StatefulSet.Name)).Should(BeTrue())
+			time.Sleep(5 * time.Second)
+			return kubegresResource.Pvc.Name
+		}
+	}
+
+	Fail("Primary StatefulSet not found")
+	return ""
+}
+
+func (r *SpecFailoverPvcTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecFailoverPvcTest) thenPvcShouldNotExist(pvcName string) bool {
+	return Eventually(func() bool {
+
+		pvcs, err := r.resourceRetriever.GetKubegresPvc()
+		if err != nil {
+			log.Println("ERROR while retrieving PVCs")
+			return false
+		}
+
+		for _, pvc := range pvcs.Items {
+			if pvc.Name == pvcName {
+				log.Println("PVC '" + pvcName + "' still exists")
+				return false
+			}
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecFailoverPvcTest) thenPvcShouldBeUsedByReplica(pvcName string) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		for _, kubegresResource := range kubegresResources.Resources {
+			if !kubegresResource.IsPrimary && kubegresResource.Pvc.Name == pvcName {
+				return true
+			}
+		}
+
+		log.Println("PVC '" + pvcName + "' is not used by any Replica")
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecFailoverPvcTest) thenNbreDatabasePvcsShouldBe(expectedNbrePvcs int) bool {
+	return Eventually(func() bool {
+
+		pvcs, err := r.resourceRetriever.GetKubegresPvc()
+		if err != nil {
+			log.Println("ERROR while retrieving PVCs")
+			return false
+		}
+
+		nbrePvcs := 0
+		for _, pvc := range pvcs.Items {
+			if pvc.DeletionTimestamp == nil {
+				nbrePvcs++
+			}
+		}
+
+		if nbrePvcs != expectedNbrePvcs {
+			log.Println("The number of PVCs is not the expected one. Expected: " + strconv.Itoa(expectedNbrePvcs) + " Given: " + strconv.Itoa(nbrePvcs))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecFailoverPvcTest) GivenUserAddedInPrimaryDb() {
+	Eventually(func() bool {
+		return r.connectionPrimaryDb.InsertUser()
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecFailoverPvcTest) ThenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionReplicaDb.GetUsers()
+		r.connectionReplicaDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Replica DB does not contain the expected number of users: " + strconv.Itoa(expectedNbreUsers))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
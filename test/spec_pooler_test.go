@@ -0,0 +1,354 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"time"
+)
+
+const (
+	primaryPoolerResourceName = resourceConfigs.KubegresResourceName + ctx.PoolerNameSuffix
+	replicaPoolerResourceName = resourceConfigs.KubegresResourceName + ctx.PoolerNameSuffix + "-replica"
+	poolerConfigMapName       = resourceConfigs.KubegresResourceName + ctx.PoolerNameSuffix + "-config"
+)
+
+var _ = Describe("Setting Kubegres spec 'pooler'", func() {
+
+	var test = SpecPoolerTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'pooler.enabled' set to true and 'pooler.enabledForReplicas' NOT set", func() {
+
+		It("THEN a pooler Deployment and Service in front of the Primary should be deployed AND no pooler should be deployed in front of the Replicas", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'pooler.enabled' set to true and 'pooler.enabledForReplicas' NOT set'")
+
+			test.givenNewKubegresSpecIsSetTo(true, false)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPoolerShouldBeDeployed(primaryPoolerResourceName)
+
+			test.thenPoolerShouldNotBeDeployed(replicaPoolerResourceName)
+
+			test.thenPoolerConfigMapShouldBeDeployed()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'pooler.enabled' set to true and 'pooler.enabledForReplicas' NOT set'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'pooler.enabled' and 'pooler.enabledForReplicas' set to true", func() {
+
+		It("THEN a pooler Deployment and Service in front of the Primary AND in front of the Replicas should be deployed", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'pooler.enabled' and 'pooler.enabledForReplicas' set to true'")
+
+			test.givenNewKubegresSpecIsSetTo(true, true)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPoolerShouldBeDeployed(primaryPoolerResourceName)
+
+			test.thenPoolerShouldBeDeployed(replicaPoolerResourceName)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'pooler.enabled' and 'pooler.enabledForReplicas' set to true'")
+		})
+	})
+
+	Context("GIVEN Kubegres with spec 'pooler.enabled' set to true AND once deployed we update YAML with 'pooler.enabled' set to false", func() {
+
+		It("THEN the pooler Deployments, Services and ConfigMap should be removed", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with spec 'pooler.enabled' set to true AND once deployed we update YAML with 'pooler.enabled' set to false'")
+
+			test.givenNewKubegresSpecIsSetTo(true, true)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenPoolerShouldBeDeployed(primaryPoolerResourceName)
+
+			test.givenExistingKubegresSpecIsSetTo(false)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenPoolerShouldNotBeDeployed(primaryPoolerResourceName)
+
+			test.thenPoolerShouldNotBeDeployed(replicaPoolerResourceName)
+
+			test.thenPoolerConfigMapShouldNotBeDeployed()
+
+			log.Print("END OF: Test 'GIVEN Kubegres with spec 'pooler.enabled' set to true AND once deployed we update YAML with 'pooler.enabled' set to false'")
+		})
+	})
+
+	Context("GIVEN Kubegres with spec 'pooler.enabled' set to true AND the Primary is deleted", func() {
+
+		It("THEN a failover should happen AND the pooler in front of the Primary should follow the new Primary", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with spec 'pooler.enabled' set to true AND the Primary is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(true, false)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			formerPrimaryStatefulSetName := test.getPrimaryStatefulSetName()
+
+			test.thenPrimaryPoolerShouldFollow(formerPrimaryStatefulSetName)
+
+			test.whenPrimaryIsDeleted()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			newPrimaryStatefulSetName := test.getPrimaryStatefulSetName()
+
+			Expect(newPrimaryStatefulSetName).ShouldNot(Equal(formerPrimaryStatefulSetName))
+
+			test.thenPrimaryPoolerShouldFollow(newPrimaryStatefulSetName)
+
+			test.thenPoolerShouldBeDeployed(primaryPoolerResourceName)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with spec 'pooler.enabled' set to true AND the Primary is deleted'")
+		})
+	})
+})
+
+type SpecPoolerTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+	resourceModifier  util.TestResourceModifier
+}
+
+func (r *SpecPoolerTest) givenNewKubegresSpecIsSetTo(isPoolerEnabled, isPoolerEnabledForReplicas bool) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.resourceModifier.AppendEnvVarFromSecretKey(ctx.EnvVarNameOfPostgresPoolerUserPsw, "poolerUserPassword", r.kubegresResource)
+	specNbreReplicas := int32(3)
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Pooler.Enabled = isPoolerEnabled
+	r.kubegresResource.Spec.Pooler.EnabledForReplicas = isPoolerEnabledForReplicas
+}
+
+func (r *SpecPoolerTest) givenExistingKubegresSpecIsSetTo(isPoolerEnabled bool) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Pooler.Enabled = isPoolerEnabled
+}
+
+func (r *SpecPoolerTest) getPrimaryStatefulSetName() string {
+
+	kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+	if err != nil {
+		Expect(err).Should(Succeed())
+		return ""
+	}
+
+	for _, kubegresResource := range kubegresResources.Resources {
+		if kubegresResource.IsPrimary {
+			return kubegresResource.StatefulSet.Name
+		}
+	}
+
+	return ""
+}
+
+func (r *SpecPoolerTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecPoolerTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecPoolerTest) whenPrimaryIsDeleted() {
+	kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+	if err != nil {
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	nbreDeleted := 0
+	for _, kubegresResource := range kubegresResources.Resources {
+		if kubegresResource.IsPrimary {
+			log.Println("Attempting to delete StatefulSet: '" + kubegresResource.StatefulSet.Name + "'")
+			if r.resourceCreator.DeleteResource(kubegresResource.StatefulSet.Resource, kubegresResource.StatefulSet.Name) {
+				nbreDeleted++
+				time.Sleep(5 * time.Second)
+			}
+		}
+	}
+
+	Expect(nbreDeleted).Should(Equal(1))
+}
+
+func (r *SpecPoolerTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPoolerTest) thenPoolerShouldBeDeployed(poolerResourceName string) {
+	Eventually(func() bool {
+
+		deployment, err := r.resourceRetriever.GetDeployment(poolerResourceName)
+		if err != nil {
+			log.Println("Pooler Deployment '" + poolerResourceName + "' is not deployed yet")
+			return false
+		}
+
+		if deployment.Status.ReadyReplicas < 1 {
+			log.Println("Pooler Deployment '" + poolerResourceName + "' is not ready yet")
+			return false
+		}
+
+		service, err := r.resourceRetriever.GetService(poolerResourceName)
+		if err != nil {
+			log.Println("Pooler Service '" + poolerResourceName + "' is not deployed yet")
+			return false
+		}
+
+		if len(service.Spec.Ports) != 1 || service.Spec.Ports[0].Port != ctx.DefaultPoolerPortNumber {
+			log.Println("Pooler Service '" + poolerResourceName + "' does not expose the expected port")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPoolerTest) thenPoolerShouldNotBeDeployed(poolerResourceName string) {
+	Eventually(func() bool {
+
+		_, err := r.resourceRetriever.GetDeployment(poolerResourceName)
+		if !apierrors.IsNotFound(err) {
+			log.Println("Pooler Deployment '" + poolerResourceName + "' is still deployed")
+			return false
+		}
+
+		_, err = r.resourceRetriever.GetService(poolerResourceName)
+		if !apierrors.IsNotFound(err) {
+			log.Println("Pooler Service '" + poolerResourceName + "' is still deployed")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPoolerTest) thenPoolerConfigMapShouldBeDeployed() {
+	Eventually(func() bool {
+
+		_, err := r.resourceRetriever.GetConfigMap(poolerConfigMapName)
+		if err != nil {
+			log.Println("Pooler config ConfigMap '" + poolerConfigMapName + "' is not deployed yet")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPoolerTest) thenPoolerConfigMapShouldNotBeDeployed() {
+	Eventually(func() bool {
+
+		_, err := r.resourceRetriever.GetConfigMap(poolerConfigMapName)
+		if !apierrors.IsNotFound(err) {
+			log.Println("Pooler config ConfigMap '" + poolerConfigMapName + "' is still deployed")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPoolerTest) thenPrimaryPoolerShouldFollow(primaryStatefulSetName string) {
+	Eventually(func() bool {
+
+		deployment, err := r.resourceRetriever.GetDeployment(primaryPoolerResourceName)
+		if err != nil {
+			log.Println("Pooler Deployment '" + primaryPoolerResourceName + "' is not deployed yet")
+			return false
+		}
+
+		followedStatefulSetName := deployment.Spec.Template.Annotations[ctx.PoolerPrimaryAnnotationKey]
+		if followedStatefulSetName != primaryStatefulSetName {
+			log.Println("Pooler Deployment does not follow the Primary yet. Expected: '" + primaryStatefulSetName + "' Given: '" + followedStatefulSetName + "'")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
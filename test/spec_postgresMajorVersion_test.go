@@ -0,0 +1,299 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strconv"
+	"time"
+)
+
+var _ = Describe("Setting Kubegres spec 'image' with a different major version of PostgreSql", func() {
+
+	var test = SpecPostgresMajorVersionTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.connectionPrimaryDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryPrimaryDbNodePort, true)
+		test.connectionReplicaDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryReplicaDbNodePort, false)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:15.1'", func() {
+
+		It("THEN the Primary should be upgraded with pg_upgrade AND the Replica should be re-seeded AND no data should be lost", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:15.1''")
+
+			test.givenNewKubegresSpecIsSetTo("postgres:14.5", 2)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe("postgres:14.5", 1, 1)
+
+			test.thenStatusMajorVersionShouldBe(14)
+
+			expectedNbreUsers := 0
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.givenExistingKubegresSpecIsSetTo("postgres:15.1")
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenMajorVersionUpgradeStatusShouldBe(postgresv1.MajorVersionUpgradePhaseSucceeded, 14, 15)
+
+			test.thenStatusMajorVersionShouldBe(15)
+
+			test.thenPodsStatesShouldBe("postgres:15.1", 1, 1)
+
+			test.thenPrimaryDbContainsExpectedNbreUsers(expectedNbreUsers)
+			test.thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers)
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:15.1''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:13.8'", func() {
+
+		It("THEN an error event should be logged saying downgrading is not supported AND the image of the Pods should NOT change", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:13.8''")
+
+			test.givenNewKubegresSpecIsSetTo("postgres:14.5", 2)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe("postgres:14.5", 1, 1)
+
+			test.thenStatusMajorVersionShouldBe(14)
+
+			test.givenExistingKubegresSpecIsSetTo("postgres:13.8")
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenErrorEventShouldBeLogged(13, 14)
+
+			time.Sleep(time.Second * 10)
+
+			test.thenPodsStatesShouldBe("postgres:14.5", 1, 1)
+
+			test.thenStatusMajorVersionShouldBe(14)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'image' set to 'postgres:14.5' and spec 'replica' set to 2 and later 'image' is updated to 'postgres:13.8''")
+		})
+	})
+})
+
+type SpecPostgresMajorVersionTest struct {
+	kubegresResource    *postgresv1.Kubegres
+	connectionPrimaryDb util.DbConnectionDbUtil
+	connectionReplicaDb util.DbConnectionDbUtil
+	resourceCreator     util.TestResourceCreator
+	resourceRetriever   util.TestResourceRetriever
+}
+
+func (r *SpecPostgresMajorVersionTest) givenNewKubegresSpecIsSetTo(image string, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Image = image
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+}
+
+func (r *SpecPostgresMajorVersionTest) givenExistingKubegresSpecIsSetTo(image string) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Image = image
+}
+
+func (r *SpecPostgresMajorVersionTest) givenUserAddedInPrimaryDb() {
+	Eventually(func() bool {
+		return r.connectionPrimaryDb.InsertUser()
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecPostgresMajorVersionTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecPostgresMajorVersionTest) thenPodsStatesShouldBe(image string, nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		for _, resource := range kubegresResources.Resources {
+			currentImage := resource.Pod.Spec.Containers[0].Image
+			if currentImage != image {
+				log.Println("Pod '" + resource.Pod.Name + "' doesn't have the expected image: '" + image + "'. " +
+					"Current value: '" + currentImage + "'. Waiting...")
+				return false
+			}
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) thenStatusMajorVersionShouldBe(expectedMajorVersion int32) {
+	Eventually(func() bool {
+
+		kubegresResource, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		currentMajorVersion := kubegresResource.Status.PostgresMajorVersion
+		if currentMajorVersion != expectedMajorVersion {
+			log.Println("The field 'status.postgresMajorVersion' is not as expected. Expected: " +
+				strconv.Itoa(int(expectedMajorVersion)) + " Given: " + strconv.Itoa(int(currentMajorVersion)))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) thenMajorVersionUpgradeStatusShouldBe(expectedPhase string, fromMajorVersion, toMajorVersion int32) {
+	Eventually(func() bool {
+
+		kubegresResource, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		upgrade := kubegresResource.Status.MajorVersionUpgrade
+		if upgrade.Phase != expectedPhase ||
+			upgrade.FromMajorVersion != fromMajorVersion ||
+			upgrade.ToMajorVersion != toMajorVersion {
+			log.Println("The field 'status.majorVersionUpgrade' is not as expected yet. Given phase: '" + upgrade.Phase + "'")
+			return false
+		}
+
+		if upgrade.RollbackDataDirectory == "" {
+			log.Println("The field 'status.majorVersionUpgrade.rollbackDataDirectory' is not set")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) thenErrorEventShouldBeLogged(specMajorVersion, runningMajorVersion int) {
+
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "MajorVersionDowngradeErr",
+		Message: "The major version " + strconv.Itoa(specMajorVersion) + " of PostgreSql in the spec is lower than the " +
+			"major version " + strconv.Itoa(runningMajorVersion) + " running on the Primary. Downgrading is not supported. " +
+			"The image of the Primary and of the Replicas is not updated.",
+	}
+	Eventually(func() bool {
+		_, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			return false
+		}
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) thenPrimaryDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionPrimaryDb.GetUsers()
+		r.connectionPrimaryDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Primary DB does not contain the expected number of users. Expected: " + strconv.Itoa(expectedNbreUsers) + " Given: " + strconv.Itoa(len(users)))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecPostgresMajorVersionTest) thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionReplicaDb.GetUsers()
+		r.connectionReplicaDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Replica DB does not contain the expected number of users. Expected: " + strconv.Itoa(expectedNbreUsers) + " Given: " + strconv.Itoa(len(users)))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ = Describe("Setting Kubegres spec 'replication'", func() {
+
+	var test = SpecReplicationTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.connectionPrimaryDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryPrimaryDbNodePort, true)
+		test.connectionReplicaDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryReplicaDbNodePort, false)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'replication.mode' set to 'sync' and spec 'replica' set to 3 and later 'replication.mode' is updated to 'async'", func() {
+
+		It("THEN the synchronous standbys of the primary should be the 2 replicas AND later they should be reset", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'replication.mode' set to 'sync' and spec 'replica' set to 3'")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.ReplicationModeSync, 1, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenSynchronousStandbyNamesShouldBe("FIRST 1", 2)
+
+			test.GivenUserAddedInPrimaryDb()
+
+			test.ThenReplicaDbContainsExpectedNbreUsers(1)
+
+			test.givenExistingKubegresSpecIsSetTo(postgresv1.ReplicationModeAsync)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenSynchronousStandbyNamesShouldBe("", 0)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'replication.mode' set to 'sync' and spec 'replica' set to 3'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'replication.mode' set to 'quorum' and spec 'replica' set to 3 and later 'replica' is updated to 2", func() {
+
+		It("THEN the synchronous standbys of the primary should only contain the remaining replica", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'replication.mode' set to 'quorum' and spec 'replica' set to 3'")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.ReplicationModeQuorum, 2, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenSynchronousStandbyNamesShouldBe("ANY 2", 2)
+
+			test.givenExistingKubegresReplicasIsSetTo(2)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenPodsStatesShouldBe(1, 1)
+
+			test.thenSynchronousStandbyNamesShouldBe("ANY 1", 1)
+
+			test.GivenUserAddedInPrimaryDb()
+
+			test.ThenReplicaDbContainsExpectedNbreUsers(1)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'replication.mode' set to 'quorum' and spec 'replica' set to 3'")
+		})
+	})
+
+})
+
+type SpecReplicationTest struct {
+	kubegresResource    *postgresv1.Kubegres
+	connectionPrimaryDb util.DbConnectionDbUtil
+	connectionReplicaDb util.DbConnectionDbUtil
+	resourceCreator     util.TestResourceCreator
+	resourceRetriever   util.TestResourceRetriever
+}
+
+func (r *SpecReplicationTest) givenNewKubegresSpecIsSetTo(mode string, numSyncStandbys int32, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Replication.Mode = mode
+	r.kubegresResource.Spec.Replication.NumSyncStandbys = &numSyncStandbys
+}
+
+func (r *SpecReplicationTest) givenExistingKubegresSpecIsSetTo(mode string) {
+	r.loadExistingKubegres()
+	r.kubegresResource.Spec.Replication.Mode = mode
+}
+
+func (r *SpecReplicationTest) givenExistingKubegresReplicasIsSetTo(specNbreReplicas int32) {
+	r.loadExistingKubegres()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+}
+
+func (r *SpecReplicationTest) loadExistingKubegres() {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+	}
+}
+
+func (r *SpecReplicationTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecReplicationTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecReplicationTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecReplicationTest) thenSynchronousStandbyNamesShouldBe(expectedMethod string, expectedNbreStandbys int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		var replicaPodNames []string
+		for _, kubegresResource := range kubegresResources.Resources {
+			if !kubegresResource.IsPrimary {
+				replicaPodNames = append(replicaPodNames, "\""+kubegresResource.Pod.Name+"\"")
+			}
+		}
+		sort.Strings(replicaPodNames)
+
+		expectedStandbyNames := ""
+		if expectedNbreStandbys > 0 {
+			expectedStandbyNames = expectedMethod + " (" + strings.Join(replicaPodNames, ", ") + ")"
+		}
+
+		synchronousStandbyNames, ok := r.connectionPrimaryDb.GetSynchronousStandbyNames()
+		r.connectionPrimaryDb.Close()
+
+		if !ok || synchronousStandbyNames != expectedStandbyNames || len(replicaPodNames) != expectedNbreStandbys {
+			log.Println("Primary DB does not have the expected synchronous standbys. Expected: '" + expectedStandbyNames + "' " +
+				"for " + strconv.Itoa(expectedNbreStandbys) + " standbys. Given: '" + synchronousStandbyNames + "'")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecReplicationTest) GivenUserAddedInPrimaryDb() {
+	Eventually(func() bool {
+		return r.connectionPrimaryDb.InsertUser()
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecReplicationTest) ThenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionReplicaDb.GetUsers()
+		r.connectionReplicaDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Replica DB does not contain the expected number of users: " + strconv.Itoa(expectedNbreUsers))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strconv"
+	"time"
+)
+
+const replicaReadyServiceResourceName = resourceConfigs.KubegresResourceName + ctx.ReplicaReadyServiceNameSuffix
+
+var _ = Describe("Setting Kubegres spec 'services.replicaReady'", func() {
+
+	var test = SpecServicesReplicaReadyTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'services.replicaReady.enabled' set to true and spec 'replica' set to 3", func() {
+
+		It("THEN the Replica-ready Service should be deployed AND only the 2 Replicas should be selected by it", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'services.replicaReady.enabled' set to true and spec 'replica' set to 3'")
+
+			test.givenNewKubegresSpecIsSetTo(true, false, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenReplicaReadyServiceShouldBeDeployed()
+
+			test.thenSelectedPodsShouldBe(0, 2)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'services.replicaReady.enabled' set to true and spec 'replica' set to 3'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'services.replicaReady.includePrimaryWhenNoReplicaReady' set to true and spec 'replica' set to 1", func() {
+
+		It("THEN the Replica-ready Service should be deployed AND the Primary should be selected by it", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'services.replicaReady.includePrimaryWhenNoReplicaReady' set to true and spec 'replica' set to 1'")
+
+			test.givenNewKubegresSpecIsSetTo(true, true, 1)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 0)
+
+			test.thenReplicaReadyServiceShouldBeDeployed()
+
+			test.thenSelectedPodsShouldBe(1, 0)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'services.replicaReady.includePrimaryWhenNoReplicaReady' set to true and spec 'replica' set to 1'")
+		})
+	})
+
+	Context("GIVEN Kubegres with spec 'services.replicaReady.enabled' set to true AND once deployed we update YAML with 'services.replicaReady.enabled' set to false", func() {
+
+		It("THEN the Replica-ready Service should be removed AND no Pods should be selected", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with spec 'services.replicaReady.enabled' set to true AND once deployed we update YAML with 'services.replicaReady.enabled' set to false'")
+
+			test.givenNewKubegresSpecIsSetTo(true, false, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenSelectedPodsShouldBe(0, 2)
+
+			test.givenExistingKubegresSpecIsSetTo(false)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenReplicaReadyServiceShouldNotBeDeployed()
+
+			test.thenSelectedPodsShouldBe(0, 0)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with spec 'services.replicaReady.enabled' set to true AND once deployed we update YAML with 'services.replicaReady.enabled' set to false'")
+		})
+	})
+})
+
+type SpecServicesReplicaReadyTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *SpecServicesReplicaReadyTest) givenNewKubegresSpecIsSetTo(isEnabled, includePrimary bool, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Services.ReplicaReady.Enabled = isEnabled
+	r.kubegresResource.Spec.Services.ReplicaReady.IncludePrimaryWhenNoReplicaReady = includePrimary
+}
+
+func (r *SpecServicesReplicaReadyTest) givenExistingKubegresSpecIsSetTo(isEnabled bool) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Services.ReplicaReady.Enabled = isEnabled
+}
+
+func (r *SpecServicesReplicaReadyTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecServicesReplicaReadyTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecServicesReplicaReadyTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecServicesReplicaReadyTest) thenReplicaReadyServiceShouldBeDeployed() {
+	Eventually(func() bool {
+
+		service, err := r.resourceRetriever.GetService(replicaReadyServiceResourceName)
+		if err != nil {
+			log.Println("Replica-ready Service '" + replicaReadyServiceResourceName + "' is not deployed yet")
+			return false
+		}
+
+		if service.Spec.Selector[ctx.ReplicaReadyLabelKey] != "true" {
+			log.Println("Replica-ready Service '" + replicaReadyServiceResourceName + "' does not have the expected selector")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecServicesReplicaReadyTest) thenReplicaReadyServiceShouldNotBeDeployed() {
+	Eventually(func() bool {
+
+		_, err := r.resourceRetriever.GetService(replicaReadyServiceResourceName)
+		if !apierrors.IsNotFound(err) {
+			log.Println("Replica-ready Service '" + replicaReadyServiceResourceName + "' is still deployed")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecServicesReplicaReadyTest) thenSelectedPodsShouldBe(nbreSelectedPrimary, nbreSelectedReplicas int) {
+	Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		selectedPrimary := 0
+		selectedReplicas := 0
+		for _, resource := range kubegresResources.Resources {
+			if resource.Pod.Metadata.Labels[ctx.ReplicaReadyLabelKey] != "true" {
+				continue
+			}
+			if resource.IsPrimary {
+				selectedPrimary++
+			} else {
+				selectedReplicas++
+			}
+		}
+
+		if selectedPrimary != nbreSelectedPrimary || selectedReplicas != nbreSelectedReplicas {
+			log.Println("The Pods selected by the Replica-ready Service are not the expected ones yet. " +
+				"Selected Primary: " + strconv.Itoa(selectedPrimary) + ", selected Replicas: " + strconv.Itoa(selectedReplicas))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"time"
+)
+
+const (
+	primaryServiceResourceName = resourceConfigs.KubegresResourceName
+	replicaServiceResourceName = resourceConfigs.KubegresResourceName + "-replica"
+)
+
+var _ = Describe("Setting Kubegres spec 'services'", func() {
+
+	var test = SpecServicesTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created without spec 'services'", func() {
+
+		It("THEN the Services of the Primary and of the Replicas should be headless", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created without spec 'services''")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.KubegresServices{})
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenServiceShouldBe(primaryServiceResourceName, postgresv1.KubegresService{}, true)
+
+			test.thenServiceShouldBe(replicaServiceResourceName, postgresv1.KubegresService{}, true)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created without spec 'services''")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'services.primary' of type 'NodePort' and 'services.replica' of type 'ClusterIP'", func() {
+
+		It("THEN the Services of the Primary and of the Replicas should have those types, labels and annotations", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'services.primary' of type 'NodePort' and 'services.replica' of type 'ClusterIP''")
+
+			services := postgresv1.KubegresServices{
+				Primary: test.givenServiceSpec(v12.ServiceTypeNodePort, v12.ServiceExternalTrafficPolicyTypeLocal),
+				Replica: test.givenServiceSpec(v12.ServiceTypeClusterIP, ""),
+			}
+			test.givenNewKubegresSpecIsSetTo(services)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenServiceShouldBe(primaryServiceResourceName, services.Primary, false)
+
+			test.thenServiceShouldBe(replicaServiceResourceName, services.Replica, false)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'services.primary' of type 'NodePort' and 'services.replica' of type 'ClusterIP''")
+		})
+	})
+
+	Context("GIVEN Kubegres without spec 'services' AND once deployed we update YAML with 'services.primary' of type 'NodePort'", func() {
+
+		It("THEN the headless Service of the Primary should be re-created with the type 'NodePort' AND the Service of the Replicas should stay headless", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres without spec 'services' AND once deployed we update YAML with 'services.primary' of type 'NodePort''")
+
+			test.givenNewKubegresSpecIsSetTo(postgresv1.KubegresServices{})
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenServiceShouldBe(primaryServiceResourceName, postgresv1.KubegresService{}, true)
+
+			services := postgresv1.KubegresServices{
+				Primary: test.givenServiceSpec(v12.ServiceTypeNodePort, ""),
+			}
+			test.givenExistingKubegresSpecIsSetTo(services)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenServiceShouldBe(primaryServiceResourceName, services.Primary, false)
+
+			test.thenServiceShouldBe(replicaServiceResourceName, postgresv1.KubegresService{}, true)
+
+			log.Print("END OF: Test 'GIVEN Kubegres without spec 'services' AND once deployed we update YAML with 'services.primary' of type 'NodePort''")
+		})
+	})
+
+	Context("GIVEN Kubegres with spec 'services.primary' of type 'NodePort' AND once deployed we update YAML with 'services.primary' of type 'ClusterIP' and other annotations", func() {
+
+		It("THEN the Service of the Primary should be updated with the type 'ClusterIP' and the new annotations", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with spec 'services.primary' of type 'NodePort' AND once deployed we update YAML with 'services.primary' of type 'ClusterIP' and other annotations'")
+
+			services := postgresv1.KubegresServices{
+				Primary: test.givenServiceSpec(v12.ServiceTypeNodePort, ""),
+			}
+			test.givenNewKubegresSpecIsSetTo(services)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenServiceShouldBe(primaryServiceResourceName, services.Primary, false)
+
+			services.Primary = test.givenServiceSpec(v12.ServiceTypeClusterIP, "")
+			services.Primary.Annotations = map[string]string{"example.com/owner": "another-team"}
+			test.givenExistingKubegresSpecIsSetTo(services)
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenServiceShouldBe(primaryServiceResourceName, services.Primary, false)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with spec 'services.primary' of type 'NodePort' AND once deployed we update YAML with 'services.primary' of type 'ClusterIP' and other annotations'")
+		})
+	})
+})
+
+type SpecServicesTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *SpecServicesTest) givenServiceSpec(serviceType v12.ServiceType, externalTrafficPolicy v12.ServiceExternalTrafficPolicyType) postgresv1.KubegresService {
+	return postgresv1.KubegresService{
+		Type:                  serviceType,
+		Annotations:           map[string]string{"example.com/owner": "dba-team"},
+		Labels:                map[string]string{"exposure": string(serviceType)},
+		ExternalTrafficPolicy: externalTrafficPolicy,
+	}
+}
+
+func (r *SpecServicesTest) givenNewKubegresSpecIsSetTo(services postgresv1.KubegresServices) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	specNbreReplicas := int32(3)
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+	r.kubegresResource.Spec.Services = services
+}
+
+func (r *SpecServicesTest) givenExistingKubegresSpecIsSetTo(services postgresv1.KubegresServices) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Services = services
+}
+
+func (r *SpecServicesTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecServicesTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecServicesTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecServicesTest) thenServiceShouldBe(serviceResourceName string, expectedServiceSpec postgresv1.KubegresService, isHeadless bool) {
+	Eventually(func() bool {
+
+		service, err := r.resourceRetriever.GetService(serviceResourceName)
+		if err != nil {
+			log.Println("Service '" + serviceResourceName + "' is not deployed yet")
+			return false
+		}
+
+		expectedType := expectedServiceSpec.Type
+		if expectedType == "" {
+			expectedType = v12.ServiceTypeClusterIP
+		}
+
+		if service.Spec.Type != expectedType {
+			log.Println("Service '" + serviceResourceName + "' does not have the expected type yet. " +
+				"Expected: '" + string(expectedType) + "' Given: '" + string(service.Spec.Type) + "'")
+			return false
+		}
+
+		if (service.Spec.ClusterIP == v12.ClusterIPNone) != isHeadless {
+			log.Println("Service '" + serviceResourceName + "' does not have the expected clusterIP yet. Given: '" + service.Spec.ClusterIP + "'")
+			return false
+		}
+
+		for key, value := range expectedServiceSpec.Annotations {
+			if service.Annotations[key] != value {
+				log.Println("Service '" + serviceResourceName + "' does not have the expected annotation '" + key + "' yet")
+				return false
+			}
+		}
+
+		for key, value := range expectedServiceSpec.Labels {
+			if service.Labels[key] != value {
+				log.Println("Service '" + serviceResourceName + "' does not have the expected label '" + key + "' yet")
+				return false
+			}
+		}
+
+		if expectedServiceSpec.ExternalTrafficPolicy != "" && service.Spec.ExternalTrafficPolicy != expectedServiceSpec.ExternalTrafficPolicy {
+			log.Println("Service '" + serviceResourceName + "' does not have the expected externalTrafficPolicy yet")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"log"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"reactive-tech.io/kubegres/test/util/testcases"
+)
+
+var _ = Describe("Setting Kubegres specs 'sidecars' and 'initContainers'", func() {
+
+	var test = SpecSidecarsTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.dbQueryTestCases = testcases.InitDbQueryTestCases(test.resourceCreator, resourceConfigs.KubegresResourceName)
+	})
+
+	AfterEach(func() {
+		if !test.keepCreatedResourcesForNextTest {
+			test.resourceCreator.DeleteAllTestResources()
+		} else {
+			test.keepCreatedResourcesForNextTest = false
+		}
+	})
+
+	Context("GIVEN new Kubegres is created with specs 'sidecars' and 'initContainers' and spec 'replica' set to 3 and later 'sidecars' is updated", func() {
+
+		It("GIVEN new Kubegres is created with specs 'sidecars' and 'initContainers' and spec 'replica' set to 3 THEN 1 primary and 2 replica should be created with the sidecar and the init container", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with specs 'sidecars' and 'initContainers' and spec 'replica' set to 3'")
+
+			sidecar := test.givenContainer("log-shipper")
+			initContainer := test.givenContainer("wait-for-network")
+
+			test.givenNewKubegresSpecIsSetTo([]v12.Container{sidecar}, []v12.Container{initContainer}, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenStatefulSetStatesShouldBe("log-shipper", "wait-for-network", 1, 2)
+
+			test.dbQueryTestCases.ThenWeCanSqlQueryPrimaryDb()
+			test.dbQueryTestCases.ThenWeCanSqlQueryReplicaDb()
+
+			test.keepCreatedResourcesForNextTest = true
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with specs 'sidecars' and 'initContainers' and spec 'replica' set to 3'")
+		})
+
+		It("GIVEN existing Kubegres is updated with spec 'sidecars' set to a new container THEN 1 primary and 2 replica should be re-deployed with the new sidecar", func() {
+
+			log.Print("START OF: Test 'GIVEN existing Kubegres is updated with spec 'sidecars' set to a new container'")
+
+			newSidecar := test.givenContainer("metrics-exporter")
+
+			test.givenExistingKubegresSpecIsSetTo([]v12.Container{newSidecar})
+
+			test.whenKubernetesIsUpdated()
+
+			test.thenStatefulSetStatesShouldBe("metrics-exporter", "wait-for-network", 1, 2)
+
+			test.dbQueryTestCases.ThenWeCanSqlQueryPrimaryDb()
+			test.dbQueryTestCases.ThenWeCanSqlQueryReplicaDb()
+
+			log.Print("END OF: Test 'GIVEN existing Kubegres is updated with spec 'sidecars' set to a new container'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with a 'sidecars' which has a reserved name", func() {
+
+		It("THEN an error event should be logged as it is not possible to use a reserved name", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with a 'sidecars' which has a reserved name'")
+
+			sidecar := test.givenContainer(ctx.WalArchiveUploaderContainerName)
+
+			test.givenNewKubegresSpecIsSetTo([]v12.Container{sidecar}, nil, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenErrorEventShouldBeLoggedAboutContainerName()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with a 'sidecars' which has a reserved name'")
+		})
+	})
+
+})
+
+type SpecSidecarsTest struct {
+	keepCreatedResourcesForNextTest bool
+	kubegresResource                *postgresv1.Kubegres
+	dbQueryTestCases                testcases.DbQueryTestCases
+	resourceCreator                 util.TestResourceCreator
+	resourceRetriever               util.TestResourceRetriever
+}
+
+func (r *SpecSidecarsTest) givenContainer(name string) v12.Container {
+	return v12.Container{
+		Name:    name,
+		Image:   "busybox",
+		Command: []string{"sh", "-c", "sleep 3600"},
+	}
+}
+
+func (r *SpecSidecarsTest) givenNewKubegresSpecIsSetTo(sidecars, initContainers []v12.Container, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Sidecars = sidecars
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+
+	// An init container must terminate
+	for i := range initContainers {
+		initContainers[i].Command = []string{"sh", "-c", "echo ready"}
+	}
+	r.kubegresResource.Spec.InitContainers = initContainers
+}
+
+func (r *SpecSidecarsTest) givenExistingKubegresSpecIsSetTo(sidecars []v12.Container) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Sidecars = sidecars
+}
+
+func (r *SpecSidecarsTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecSidecarsTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecSidecarsTest) thenErrorEventShouldBeLoggedAboutContainerName() {
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "SpecCheckErr",
+		Message: "In the Resources Spec the value of 'spec.Sidecars' or 'spec.InitContainers' has an entry with a container " +
+			"name which is a reserved name: " + ctx.WalArchiveUploaderContainerName + " . That name cannot be used and it is " +
+			"reserved for Kubegres internal usages. Please change that name in the YAML.",
+	}
+	Eventually(func() bool {
+		_, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			return false
+		}
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSidecarsTest) thenStatefulSetStatesShouldBe(expectedSidecarName, expectedInitContainerName string, nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		for _, resource := range kubegresResources.Resources {
+			podSpec := resource.StatefulSet.Spec.Template.Spec
+
+			if podSpec.Containers[0].Name != resource.StatefulSet.Name {
+				log.Println("StatefulSet '" + resource.StatefulSet.Name + "' doesn't have the PostgreSql container as first container. " +
+					"Current value: '" + podSpec.Containers[0].Name + "'. Waiting...")
+				return false
+			}
+
+			if !r.hasContainer(podSpec.Containers, expectedSidecarName) || len(podSpec.Containers) != 2 {
+				log.Println("StatefulSet '" + resource.StatefulSet.Name + "' doesn't have the expected sidecar: '" + expectedSidecarName + "'. Waiting...")
+				return false
+			}
+
+			if !r.hasContainer(podSpec.InitContainers, expectedInitContainerName) {
+				log.Println("StatefulSet '" + resource.StatefulSet.Name + "' doesn't have the expected init container: '" + expectedInitContainerName + "'. Waiting...")
+				return false
+			}
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSidecarsTest) hasContainer(containers []v12.Container, name string) bool {
+	for _, container := range containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strconv"
+	"time"
+)
+
+var _ = Describe("Setting Kubegres spec 'switchover.targetPod'", func() {
+
+	var test = SpecSwitchoverTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.connectionPrimaryDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryPrimaryDbNodePort, true)
+		test.connectionReplicaDb = util.InitDbConnectionDbUtil(test.resourceCreator, resourceConfigs.KubegresResourceName, resourceConfigs.ServiceToSqlQueryReplicaDbNodePort, false)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to the replica Pod name", func() {
+
+		It("THEN the replica Pod should become the new primary AND the former primary should re-attach as a replica AND no data should be lost", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to the replica Pod name'")
+
+			test.givenNewKubegresSpecIsSetTo(2)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 1)
+
+			expectedNbreUsers := 0
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			primaryPodName, replicaPodName := test.getDeployedPodNames()
+
+			test.givenExistingKubegresSpecIsSetTo(replicaPodName)
+
+			test.whenKubernetesIsUpdated()
+
+			time.Sleep(time.Second * 10)
+
+			test.thenTargetPodFieldInSpecShouldBeCleared()
+
+			test.thenPodsStatesShouldBe(1, 1)
+
+			test.thenDeployedPodNamesMatch(replicaPodName, primaryPodName)
+
+			test.thenPrimaryDbContainsExpectedNbreUsers(expectedNbreUsers)
+			test.thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers)
+
+			test.givenUserAddedInPrimaryDb()
+			expectedNbreUsers++
+
+			test.thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to the replica Pod name'")
+		})
+	})
+
+	Context("GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to a Pod name which does NOT exist", func() {
+
+		It("THEN nothing should happen AND an error message should be logged as event saying the switchover cannot happen", func() {
+
+			log.Print("START OF: Test 'GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to a Pod name which does NOT exist'")
+
+			test.givenNewKubegresSpecIsSetTo(2)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 1)
+
+			test.givenUserAddedInPrimaryDb()
+
+			primaryPodName, replicaPodName := test.getDeployedPodNames()
+
+			targetPodName := "Pod_does_not_exist"
+
+			test.givenExistingKubegresSpecIsSetTo(targetPodName)
+
+			test.whenKubernetesIsUpdated()
+
+			time.Sleep(time.Second * 10)
+
+			test.thenTargetPodFieldInSpecShouldBeCleared()
+
+			test.thenErrorEventShouldBeLogged(targetPodName)
+
+			test.thenPodsStatesShouldBe(1, 1)
+
+			test.thenDeployedPodNamesMatch(primaryPodName, replicaPodName)
+
+			test.thenPrimaryDbContainsExpectedNbreUsers(1)
+
+			log.Print("END OF: Test 'GIVEN Kubegres with 1 primary and 1 replica AND once deployed we update YAML with 'switchover.targetPod' set to a Pod name which does NOT exist'")
+		})
+	})
+})
+
+type SpecSwitchoverTest struct {
+	kubegresResource    *postgresv1.Kubegres
+	connectionPrimaryDb util.DbConnectionDbUtil
+	connectionReplicaDb util.DbConnectionDbUtil
+	resourceCreator     util.TestResourceCreator
+	resourceRetriever   util.TestResourceRetriever
+}
+
+func (r *SpecSwitchoverTest) givenNewKubegresSpecIsSetTo(specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+}
+
+func (r *SpecSwitchoverTest) givenExistingKubegresSpecIsSetTo(targetPodName string) {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Switchover.TargetPod = targetPodName
+}
+
+func (r *SpecSwitchoverTest) givenUserAddedInPrimaryDb() {
+	Eventually(func() bool {
+		return r.connectionPrimaryDb.InsertUser()
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) getDeployedPodNames() (primaryPodName, replicaPodName string) {
+
+	kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+	if err != nil {
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	for _, kubegresResource := range kubegresResources.Resources {
+		if kubegresResource.IsPrimary {
+			primaryPodName = kubegresResource.Pod.Name
+		} else {
+			replicaPodName = kubegresResource.Pod.Name
+		}
+	}
+
+	return primaryPodName, replicaPodName
+}
+
+func (r *SpecSwitchoverTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *SpecSwitchoverTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *SpecSwitchoverTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) thenTargetPodFieldInSpecShouldBeCleared() {
+	Eventually(func() bool {
+
+		kubegresResource, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		if kubegresResource.Spec.Switchover.TargetPod != "" {
+			log.Println("The field 'switchover.targetPod' is not cleared yet. Given: '" + kubegresResource.Spec.Switchover.TargetPod + "'")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) thenDeployedPodNamesMatch(expectedPrimaryPodName, expectedReplicaPodName string) {
+	Eventually(func() bool {
+
+		primaryPodName, replicaPodName := r.getDeployedPodNames()
+		if primaryPodName != expectedPrimaryPodName || replicaPodName != expectedReplicaPodName {
+			log.Println("Deployed Pod names do not match. Expected Primary: '" + expectedPrimaryPodName + "' Given: '" + primaryPodName +
+				"'. Expected Replica: '" + expectedReplicaPodName + "' Given: '" + replicaPodName + "'")
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) thenErrorEventShouldBeLogged(podName string) {
+
+	expectedErrorEvent := util.EventRecord{
+		Eventtype: v12.EventTypeWarning,
+		Reason:    "SwitchoverCannotHappenErr",
+		Message: "A Switchover to promote the Pod '" + podName + "' as Primary was requested. " +
+			"However, it cannot happen. The Pod '" + podName + "' is not a ready Replica. " +
+			"Resetting the field 'switchover.targetPod'.",
+	}
+	Eventually(func() bool {
+		_, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			return false
+		}
+		return eventRecorderTest.CheckEventExist(expectedErrorEvent)
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) thenPrimaryDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionPrimaryDb.GetUsers()
+		r.connectionPrimaryDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Primary DB does not contain the expected number of users. Expected: " + strconv.Itoa(expectedNbreUsers) + " Given: " + strconv.Itoa(len(users)))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *SpecSwitchoverTest) thenReplicaDbContainsExpectedNbreUsers(expectedNbreUsers int) {
+	Eventually(func() bool {
+
+		users := r.connectionReplicaDb.GetUsers()
+		r.connectionReplicaDb.Close()
+
+		if len(users) != expectedNbreUsers {
+			log.Println("Replica DB does not contain the expected number of users. Expected: " + strconv.Itoa(expectedNbreUsers) + " Given: " + strconv.Itoa(len(users)))
+			return false
+		}
+
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+)
+
+var _ = Describe("Checking the conditions and the phase in the status of a Kubegres resource", func() {
+
+	var test = StatusConditionsTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with 1 primary and 2 replicas", func() {
+
+		It("THEN the status should have the phase 'Running' AND the conditions 'Ready', 'PrimaryAvailable' and 'ReplicasInSync' set to true", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas'")
+
+			test.givenNewKubegresSpecIsSetTo(3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenStatusShouldBe(postgresv1.PhaseRunning, metav1.ConditionTrue, metav1.ConditionTrue)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created with 1 primary and 2 replicas AND once deployed we update YAML with 'failover.isDisabled' true AND we delete a replica", func() {
+
+		It("THEN the status should have the phase 'Degraded' AND the conditions 'Ready' and 'ReplicasInSync' set to false", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas AND once deployed we update YAML with 'failover.isDisabled' true AND we delete a replica'")
+
+			test.givenNewKubegresSpecIsSetTo(3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenStatusShouldBe(postgresv1.PhaseRunning, metav1.ConditionTrue, metav1.ConditionTrue)
+
+			test.givenExistingKubegresWithFailoverDisabled()
+
+			test.whenKubernetesIsUpdated()
+
+			test.whenOneReplicaIsDeleted()
+
+			test.thenStatusShouldBe(postgresv1.PhaseDegraded, metav1.ConditionFalse, metav1.ConditionFalse)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas AND once deployed we update YAML with 'failover.isDisabled' true AND we delete a replica'")
+		})
+	})
+
+})
+
+type StatusConditionsTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *StatusConditionsTest) givenNewKubegresSpecIsSetTo(specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+}
+
+func (r *StatusConditionsTest) givenExistingKubegresWithFailoverDisabled() {
+	var err error
+	r.kubegresResource, err = r.resourceRetriever.GetKubegres()
+
+	if err != nil {
+		log.Println("Error while getting Kubegres resource : ", err)
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	r.kubegresResource.Spec.Failover.IsDisabled = true
+}
+
+func (r *StatusConditionsTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *StatusConditionsTest) whenKubernetesIsUpdated() {
+	r.resourceCreator.UpdateResource(r.kubegresResource, "Kubegres")
+}
+
+func (r *StatusConditionsTest) whenOneReplicaIsDeleted() {
+	kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+	if err != nil {
+		Expect(err).Should(Succeed())
+		return
+	}
+
+	for _, kubegresResource := range kubegresResources.Resources {
+		if !kubegresResource.IsPrimary {
+			log.Println("Attempting to delete StatefulSet: '" + kubegresResource.StatefulSet.Name + "'")
+			Expect(r.resourceCreator.DeleteResource(kubegresResource.StatefulSet.Resource, kubegresResource.StatefulSet.Name)).Should(BeTrue())
+			return
+		}
+	}
+}
+
+func (r *StatusConditionsTest) thenStatusShouldBe(expectedPhase string,
+	expectedReadyStatus metav1.ConditionStatus,
+	expectedReplicasInSyncStatus metav1.ConditionStatus) bool {
+
+	return Eventually(func() bool {
+
+		kubegres, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		conditions := kubegres.Status.Conditions
+		if kubegres.Status.Phase != expectedPhase ||
+			!meta.IsStatusConditionPresentAndEqual(conditions, postgresv1.ConditionTypeReady, expectedReadyStatus) ||
+			!meta.IsStatusConditionPresentAndEqual(conditions, postgresv1.ConditionTypeReplicasInSync, expectedReplicasInSyncStatus) ||
+			!meta.IsStatusConditionTrue(conditions, postgresv1.ConditionTypePrimaryAvailable) ||
+			!meta.IsStatusConditionTrue(conditions, postgresv1.ConditionTypeSpecValid) {
+
+			log.Println("Kubegres status does not have the expected phase '" + expectedPhase + "' and conditions. Current phase: '" + kubegres.Status.Phase + "'")
+			return false
+		}
+
+		log.Println("Kubegres status check successful")
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"strconv"
+	"time"
+)
+
+var _ = Describe("Checking the list of instances in the status of a Kubegres resource", func() {
+
+	var test = StatusInstancesTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources()
+	})
+
+	Context("GIVEN new Kubegres is created with 1 primary and 2 replicas", func() {
+
+		It("THEN the status should list 3 ready instances matching the deployed Pods, StatefulSets and PVCs", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas'")
+
+			test.givenNewKubegresSpecIsSetTo(3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenStatusInstancesShouldMatchDeployedResources(1, 2)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with 1 primary and 2 replicas'")
+		})
+	})
+
+})
+
+type StatusInstancesTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *StatusInstancesTest) givenNewKubegresSpecIsSetTo(specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+}
+
+func (r *StatusInstancesTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *StatusInstancesTest) thenStatusInstancesShouldMatchDeployedResources(nbrePrimary, nbreReplicas int) bool {
+
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil || !kubegresResources.AreAllReady ||
+			kubegresResources.NbreDeployedPrimary != nbrePrimary ||
+			kubegresResources.NbreDeployedReplicas != nbreReplicas {
+			log.Println("Kubegres resources are not ready yet")
+			return false
+		}
+
+		kubegres, err := r.resourceRetriever.GetKubegres()
+		if err != nil {
+			log.Println("ERROR while retrieving Kubegres resource")
+			return false
+		}
+
+		instances := kubegres.Status.Instances
+		if len(instances) != nbrePrimary+nbreReplicas {
+			log.Println("Kubegres status does not list the expected number of instances: " + strconv.Itoa(nbrePrimary+nbreReplicas))
+			return false
+		}
+
+		for _, kubegresResource := range kubegresResources.Resources {
+			if !r.isResourceListedInInstances(kubegresResource, instances) {
+				log.Println("Kubegres status does not list the instance with Pod '" + kubegresResource.Pod.Name + "'")
+				return false
+			}
+		}
+
+		time.Sleep(resourceConfigs.TestRetryInterval)
+		log.Println("Kubegres status instances check successful")
+		return true
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *StatusInstancesTest) isResourceListedInInstances(kubegresResource util.TestKubegresResource,
+	instances []postgresv1.KubegresInstanceStatus) bool {
+
+	expectedRole := "replica"
+	if kubegresResource.IsPrimary {
+		expectedRole = resourceConfigs.PrimaryReplicationRole
+	}
+
+	for _, instance := range instances {
+		if instance.PodName == kubegresResource.Pod.Name &&
+			instance.StatefulSet == kubegresResource.StatefulSet.Name &&
+			instance.PvcName == kubegresResource.Pvc.Name &&
+			instance.NodeName == kubegresResource.Pod.Spec.NodeName &&
+			instance.Image == kubegresResource.Pod.Spec.Containers[0].Image &&
+			instance.Role == expectedRole &&
+			instance.IsReady &&
+			!instance.IsStuck {
+			return true
+		}
+	}
+
+	return false
+}
@@ -92,7 +92,8 @@ var _ = BeforeSuite(func(done Done) {
 	Expect(k8sClientTest).ToNot(BeNil())
 
 	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
-		Scheme: scheme.Scheme,
+		Scheme:                scheme.Scheme,
+		ClientDisableCacheFor: controllers.GetObjectsNotCached(),
 	})
 	Expect(err).ToNot(HaveOccurred())
 
@@ -107,6 +108,14 @@ var _ = BeforeSuite(func(done Done) {
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&controllers.KubegresBackupReconciler{
+		Client:   k8sManager.GetClient(),
+		Logger:   mockLogger,
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: record.EventRecorder(&eventRecorderTest),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		err = k8sManager.Start(ctrl.SetupSignalHandler())
 		if err != nil {
@@ -217,6 +217,24 @@ func (r *DbConnectionDbUtil) GetUsers() []AccountUser {
 	return accountUsers
 }
 
+func (r *DbConnectionDbUtil) GetSynchronousStandbyNames() (string, bool) {
+
+	if !r.connect() {
+		return "", false
+	}
+
+	var synchronousStandbyNames string
+	sqlQuery := "SHOW synchronous_standby_names"
+	err := r.db.QueryRow(sqlQuery).Scan(&synchronousStandbyNames)
+	if err != nil {
+		r.logError("Error of query: "+sqlQuery+" ", err)
+		return "", false
+	}
+
+	r.logInfo("Success of: " + sqlQuery + " : '" + synchronousStandbyNames + "'")
+	return synchronousStandbyNames, true
+}
+
 func (r *DbConnectionDbUtil) createTableIfDoesItNotExist() bool {
 
 	sqlQuery := "SELECT to_regclass('" + resourceConfigs.TableName + "');"
@@ -69,6 +69,17 @@ func (r *TestResourceCreator) CreateKubegres(resourceToCreate *postgresv1.Kubegr
 	}
 }
 
+func (r *TestResourceCreator) CreateKubegresBackup(resourceToCreate *postgresv1.KubegresBackup) {
+	ctx := context.Background()
+	err := r.client.Create(ctx, resourceToCreate)
+	if err != nil {
+		log.Println("Error while creating KubegresBackup resource : ", err)
+		gomega.Expect(err).Should(gomega.Succeed())
+	} else {
+		log.Println("KubegresBackup resource created")
+	}
+}
+
 func (r *TestResourceCreator) UpdateResource(resourceToUpdate client.Object, resourceName string) {
 	ctx := context.Background()
 	err := r.client.Update(ctx, resourceToUpdate)
@@ -102,6 +113,24 @@ func (r *TestResourceCreator) CreateBackUpPvc2() {
 	r.createResourceFromYaml("BackUp PVC 2", resourceConfigs2.BackUpPvcResourceName2, &existingResource, resourceToCreate)
 }
 
+// CreateMinio deploys a MinIO server which stands in for an S3 bucket, with the bucket 'resourceConfigs.MinioBucket'.
+func (r *TestResourceCreator) CreateMinio() {
+	existingSecret := v1.Secret{}
+	secretToCreate := resourceConfigs2.LoadMinioSecretYaml()
+	secretToCreate.Namespace = r.namespace
+	r.createResourceFromYaml("MinIO credentials Secret", resourceConfigs2.MinioCredentialsResourceName, &existingSecret, &secretToCreate)
+
+	existingPod := v1.Pod{}
+	podToCreate := resourceConfigs2.LoadMinioYaml()
+	podToCreate.Namespace = r.namespace
+	r.createResourceFromYaml("MinIO Pod", resourceConfigs2.MinioResourceName, &existingPod, &podToCreate)
+
+	existingService := v1.Service{}
+	serviceToCreate := resourceConfigs2.LoadMinioServiceYaml()
+	serviceToCreate.Namespace = r.namespace
+	r.createResourceFromYaml("MinIO Service", resourceConfigs2.MinioResourceName, &existingService, &serviceToCreate)
+}
+
 func (r *TestResourceCreator) CreateConfigMapEmpty() {
 	existingResource := v1.ConfigMap{}
 	resourceToCreate := resourceConfigs2.LoadCustomConfigMapYaml(resourceConfigs2.CustomConfigMapEmptyYamlFile)
@@ -223,6 +252,12 @@ func (r *TestResourceCreator) DeleteAllTestResources(resourceNamesToNotDelete ..
 		}
 	}
 
+	kubegresBackupList := &postgresv1.KubegresBackupList{}
+	r.searchList(kubegresBackupList)
+	for _, resourceToDelete := range kubegresBackupList.Items {
+		r.DeleteResource(&resourceToDelete, resourceToDelete.Name)
+	}
+
 	kubegresList := &postgresv1.KubegresList{}
 	r.searchList(kubegresList)
 	for _, resourceToDelete := range kubegresList.Items {
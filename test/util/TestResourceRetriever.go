@@ -57,8 +57,9 @@ type TestKubegresResource struct {
 }
 
 type TestKubegresBackUpCronJob struct {
-	Name string
-	Spec batch.CronJobSpec
+	Name        string
+	Annotations map[string]string
+	Spec        batch.CronJobSpec
 }
 
 type TestKubegresPod struct {
@@ -103,12 +104,30 @@ func (r *TestResourceRetriever) GetKubegresByName(resourceName string) (*postgre
 	return resourceToRetrieve, err
 }
 
+func (r *TestResourceRetriever) GetKubegresBackup(resourceName string) (*postgresv1.KubegresBackup, error) {
+	resourceToRetrieve := &postgresv1.KubegresBackup{}
+	err := r.getResource(resourceName, resourceToRetrieve)
+	return resourceToRetrieve, err
+}
+
 func (r *TestResourceRetriever) GetService(serviceResourceName string) (*core.Service, error) {
 	resourceToRetrieve := &core.Service{}
 	err := r.getResource(serviceResourceName, resourceToRetrieve)
 	return resourceToRetrieve, err
 }
 
+func (r *TestResourceRetriever) GetDeployment(deploymentResourceName string) (*v1.Deployment, error) {
+	resourceToRetrieve := &v1.Deployment{}
+	err := r.getResource(deploymentResourceName, resourceToRetrieve)
+	return resourceToRetrieve, err
+}
+
+func (r *TestResourceRetriever) GetConfigMap(configMapResourceName string) (*core.ConfigMap, error) {
+	resourceToRetrieve := &core.ConfigMap{}
+	err := r.getResource(configMapResourceName, resourceToRetrieve)
+	return resourceToRetrieve, err
+}
+
 func (r *TestResourceRetriever) GetBackUpPvc() (*core.PersistentVolumeClaim, error) {
 	resourceToRetrieve := &core.PersistentVolumeClaim{}
 	err := r.getResource(resourceConfigs.BackUpPvcResourceName, resourceToRetrieve)
@@ -131,6 +150,12 @@ func (r *TestResourceRetriever) GetKubegresPvcByKubegresName(kubegresName string
 	return list, err
 }
 
+func (r *TestResourceRetriever) GetJobs() (*batch.JobList, error) {
+	list := &batch.JobList{}
+	err := r.client.List(context.Background(), list, client.InNamespace(r.namespace))
+	return list, err
+}
+
 func (r *TestResourceRetriever) getResource(resourceNameToRetrieve string, resourceToRetrieve client.Object) error {
 	ctx := context.Background()
 	lookupKey := types.NamespacedName{Name: resourceNameToRetrieve, Namespace: r.namespace}
@@ -162,8 +187,9 @@ func (r *TestResourceRetriever) GetKubegresResourcesByName(kubegresName string)
 	err = r.getResource(cronJobName, cronJob)
 	if err == nil {
 		testKubegresResources.BackUpCronJob = TestKubegresBackUpCronJob{
-			Name: cronJobName,
-			Spec: cronJob.Spec,
+			Name:        cronJobName,
+			Annotations: cronJob.Annotations,
+			Spec:        cronJob.Spec,
 		}
 	}
 
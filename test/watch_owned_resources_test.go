@@ -0,0 +1,200 @@
+/*
+Copyright 2021 Reactive Tech Limited.
+"Reactive Tech Limited" is a company located in England, United Kingdom.
+https://www.reactive-tech.io
+
+Lead Developer: Alex Arica
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batch "k8s.io/api/batch/v1"
+	v12 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"log"
+	postgresv1 "reactive-tech.io/kubegres/api/v1"
+	"reactive-tech.io/kubegres/controllers/ctx"
+	"reactive-tech.io/kubegres/test/resourceConfigs"
+	"reactive-tech.io/kubegres/test/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// The operator refreshes the replication states every 30 seconds. A deleted resource must be re-created before
+// that refresh, which shows that its deletion triggered the reconciliation.
+const timeoutToReCreateWatchedResource = time.Second * 20
+
+var _ = Describe("Watching the resources used by Kubegres", func() {
+
+	var test = WatchOwnedResourcesTest{}
+
+	BeforeEach(func() {
+		//Skip("Temporarily skipping test")
+
+		namespace := resourceConfigs.DefaultNamespace
+		test.resourceRetriever = util.CreateTestResourceRetriever(k8sClientTest, namespace)
+		test.resourceCreator = util.CreateTestResourceCreator(k8sClientTest, test.resourceRetriever, namespace)
+		test.resourceCreator.CreateBackUpPvc()
+	})
+
+	AfterEach(func() {
+		test.resourceCreator.DeleteAllTestResources(resourceConfigs.BackUpPvcResourceName)
+	})
+
+	Context("GIVEN new Kubegres is created with spec 'backup.schedule' AND the backup CronJob is deleted", func() {
+
+		It("THEN the backup CronJob should be re-created straight away", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created with spec 'backup.schedule' AND the backup CronJob is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo(scheduleBackupEveryMin, 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.thenBackupCronJobShouldExist(resourceConfigs.TestTimeout)
+
+			test.whenBackupCronJobIsDeleted()
+
+			test.thenBackupCronJobShouldExist(timeoutToReCreateWatchedResource)
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created with spec 'backup.schedule' AND the backup CronJob is deleted'")
+		})
+	})
+
+	Context("GIVEN new Kubegres is created AND the base ConfigMap is deleted", func() {
+
+		It("THEN the base ConfigMap should be re-created straight away", func() {
+
+			log.Print("START OF: Test 'GIVEN new Kubegres is created AND the base ConfigMap is deleted'")
+
+			test.givenNewKubegresSpecIsSetTo("", 3)
+
+			test.whenKubegresIsCreated()
+
+			test.thenPodsStatesShouldBe(1, 2)
+
+			test.whenBaseConfigMapIsDeleted()
+
+			test.thenBaseConfigMapShouldExist()
+
+			log.Print("END OF: Test 'GIVEN new Kubegres is created AND the base ConfigMap is deleted'")
+		})
+	})
+})
+
+type WatchOwnedResourcesTest struct {
+	kubegresResource  *postgresv1.Kubegres
+	resourceCreator   util.TestResourceCreator
+	resourceRetriever util.TestResourceRetriever
+}
+
+func (r *WatchOwnedResourcesTest) givenNewKubegresSpecIsSetTo(backupSchedule string, specNbreReplicas int32) {
+	r.kubegresResource = resourceConfigs.LoadKubegresYaml()
+	r.kubegresResource.Spec.Replicas = &specNbreReplicas
+
+	if backupSchedule != "" {
+		r.kubegresResource.Spec.Backup.Schedule = backupSchedule
+		r.kubegresResource.Spec.Backup.PvcName = resourceConfigs.BackUpPvcResourceName
+		r.kubegresResource.Spec.Backup.VolumeMount = "/tmp/my-kubegres"
+	}
+}
+
+func (r *WatchOwnedResourcesTest) whenKubegresIsCreated() {
+	r.resourceCreator.CreateKubegres(r.kubegresResource)
+}
+
+func (r *WatchOwnedResourcesTest) whenBackupCronJobIsDeleted() {
+	cronJob := &batch.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ctx.CronJobNamePrefix + resourceConfigs.KubegresResourceName,
+			Namespace: resourceConfigs.DefaultNamespace,
+		},
+	}
+	Expect(r.resourceCreator.DeleteResource(cronJob, cronJob.Name)).Should(BeTrue())
+}
+
+func (r *WatchOwnedResourcesTest) whenBaseConfigMapIsDeleted() {
+	configMap := &v12.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ctx.BaseConfigMapName,
+			Namespace: resourceConfigs.DefaultNamespace,
+		},
+	}
+	Expect(r.resourceCreator.DeleteResource(configMap, configMap.Name)).Should(BeTrue())
+}
+
+func (r *WatchOwnedResourcesTest) thenPodsStatesShouldBe(nbrePrimary, nbreReplicas int) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.AreAllReady &&
+			kubegresResources.NbreDeployedPrimary == nbrePrimary &&
+			kubegresResources.NbreDeployedReplicas == nbreReplicas {
+
+			time.Sleep(resourceConfigs.TestRetryInterval)
+			log.Println("Deployed and Ready StatefulSets check successful")
+			return true
+		}
+
+		return false
+
+	}, resourceConfigs.TestTimeout, resourceConfigs.TestRetryInterval).Should(BeTrue())
+}
+
+func (r *WatchOwnedResourcesTest) thenBackupCronJobShouldExist(timeout time.Duration) bool {
+	return Eventually(func() bool {
+
+		kubegresResources, err := r.resourceRetriever.GetKubegresResources()
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Println("ERROR while retrieving Kubegres kubegresResources")
+			return false
+		}
+
+		if kubegresResources.BackUpCronJob.Name == "" {
+			log.Println("Backup CronJob is not deployed yet. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, timeout, time.Second*2).Should(BeTrue())
+}
+
+func (r *WatchOwnedResourcesTest) thenBaseConfigMapShouldExist() bool {
+	return Eventually(func() bool {
+
+		configMap := &v12.ConfigMap{}
+		err := k8sClientTest.Get(context.Background(), client.ObjectKey{Namespace: resourceConfigs.DefaultNamespace, Name: ctx.BaseConfigMapName}, configMap)
+		if err != nil {
+			log.Println("Base ConfigMap is not deployed yet. Waiting...")
+			return false
+		}
+
+		return true
+
+	}, timeoutToReCreateWatchedResource, time.Second*2).Should(BeTrue())
+}